@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrAborted = errors.New("Run aborted")
+
+// Returned by a run stopped with RunHandle.Abort; it also matches context.Canceled
+type AbortError struct {
+	Reason string
+}
+
+func (e *AbortError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrAborted, e.Reason)
+}
+
+func (e *AbortError) Unwrap() error {
+	return context.Canceled
+}
+
+func (e *AbortError) Is(target error) bool {
+	return target == ErrAborted
+}
+
+// A run going in the background, started with Start
+type RunHandle struct {
+	done   chan struct{}
+	report *Report
+	err    error
+	// Read by the run loop
+	approvals chan approvalDecision
+
+	mu       sync.Mutex
+	cancel   context.CancelCauseFunc
+	finished bool
+	aborted  bool
+}
+
+// Start runs the graph in the background and returns a handle to wait on, abort or decide its
+// approval nodes with
+func (peg *ParallelizedExecutableGraph) Start(ctx context.Context, opts ...ExecOption) *RunHandle {
+	ctx, cancel := context.WithCancelCause(ctx)
+	h := &RunHandle{done: make(chan struct{}), cancel: cancel, approvals: make(chan approvalDecision)}
+	opts = append(opts[:len(opts):len(opts)], func(c *ExecConfig) {
+		c.approvals = h.approvals
+	})
+
+	go func() {
+		report, err := peg.Run(ctx, opts...)
+
+		h.mu.Lock()
+		h.finished = true
+		h.mu.Unlock()
+		cancel(nil)
+
+		h.report, h.err = report, err
+		close(h.done)
+	}()
+
+	return h
+}
+
+// Abort cancels the run like canceling its ctx would, except that interrupted nodes are reported
+// as aborted, the report and event log record reason and the run returns an AbortError. It
+// returns false, doing nothing, once the run has finished or been aborted already.
+func (h *RunHandle) Abort(reason string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.finished || h.aborted {
+		return false
+	}
+	h.aborted = true
+	h.cancel(&AbortError{Reason: reason})
+	return true
+}
+
+// Closed once the run has finished
+func (h *RunHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Waits for the run to finish and returns what Run would have
+func (h *RunHandle) Wait() (*Report, error) {
+	<-h.done
+	return h.report, h.err
+}
@@ -0,0 +1,100 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// upload blocks until stopped; publish depends on it
+func blockingUpload(started chan graph.NodeID) *graph.ParallelizedExecutableGraph {
+	g := graph.NewGraph("release")
+	g.Add(graph.NewNode("upload", nil, blockUntilCanceled(started)))
+	g.Add(graph.NewNode("publish", graph.Deps("upload"), graph.NoOp()))
+	return g.CompileToExecutable()
+}
+
+func eventKinds(report *graph.Report) map[graph.EventKind]int {
+	kinds := map[graph.EventKind]int{}
+	for _, e := range report.Events {
+		kinds[e.Kind]++
+	}
+	return kinds
+}
+
+func TestAbortMidRun(t *testing.T) {
+	started := make(chan graph.NodeID, 1)
+	h := blockingUpload(started).Start(ctx(t), graph.WithEventLog(100))
+	<-started
+
+	if !h.Abort("deploy window closed") {
+		t.Fatal("Expected the first Abort to stop the run")
+	}
+	if h.Abort("again") {
+		t.Error("Expected a second Abort to do nothing")
+	}
+	report, err := h.Wait()
+
+	var abort *graph.AbortError
+	if !errors.As(err, &abort) || abort.Reason != "deploy window closed" {
+		t.Fatalf("Expected an AbortError with the reason, got %v", err)
+	}
+	if !errors.Is(err, graph.ErrAborted) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the error to match ErrAborted and context.Canceled, got %v", err)
+	}
+	if report.AbortReason != "deploy window closed" {
+		t.Errorf("Expected the reason in the report, got %q", report.AbortReason)
+	}
+	if nr := report.Nodes["upload"]; nr.Status != graph.StatusCanceled || nr.Reason != graph.ReasonAborted {
+		t.Errorf("Expected upload canceled as aborted, got %s/%s", nr.Status, nr.Reason)
+	}
+	expectSettled(t, report, 2)
+	if report.Nodes["publish"].Status == graph.StatusSucceeded {
+		t.Error("Expected publish not to run")
+	}
+
+	kinds := eventKinds(report)
+	if kinds[graph.EventRunAborted] != 1 || kinds[graph.EventRunCanceled] != 0 {
+		t.Errorf("Expected one run-aborted event in place of run-canceled, got %v", kinds)
+	}
+}
+
+func TestAbortAfterCompletion(t *testing.T) {
+	h := diamond(t).CompileToExecutable().Start(ctx(t))
+	report, err := h.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h.Abort("too late") {
+		t.Error("Expected Abort after the run finished to return false")
+	}
+	if report.AbortReason != "" {
+		t.Errorf("Expected no abort reason, got %q", report.AbortReason)
+	}
+}
+
+// Canceling the ctx stops the run too, but without a reason to attribute it to
+func TestContextCancelIsNotAbort(t *testing.T) {
+	started := make(chan graph.NodeID, 1)
+	runCtx, cancel := context.WithCancel(ctx(t))
+	h := blockingUpload(started).Start(runCtx, graph.WithEventLog(100))
+	<-started
+	cancel()
+
+	report, err := h.Wait()
+	if !errors.Is(err, context.Canceled) || errors.Is(err, graph.ErrAborted) {
+		t.Errorf("Expected a plain cancellation, got %v", err)
+	}
+	if report.AbortReason != "" || report.Nodes["upload"].Reason == graph.ReasonAborted {
+		t.Errorf("Expected nothing attributed to an abort, got %q and %s", report.AbortReason, report.Nodes["upload"].Reason)
+	}
+	if kinds := eventKinds(report); kinds[graph.EventRunAborted] != 0 {
+		t.Errorf("Expected no run-aborted event, got %v", kinds)
+	}
+	if h.Abort("after cancel") {
+		t.Error("Expected Abort after the run finished to return false")
+	}
+}
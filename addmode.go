@@ -0,0 +1,70 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// Rejects dependencies on nodes that aren't in the graph yet as soon as they're declared, by Add,
+// AddEdge, AddAll or Merge. This is the default.
+func WithStrictAdd() GraphOption {
+	return func(g *Graph) {
+		g.lazyAdd = false
+	}
+}
+
+// Lets nodes depend on nodes added later. A dependency still missing is reported by Validate and
+// Sort, and by Run of a graph compiled while it was missing. Edge policies are checked for such
+// an edge once its target is added.
+func WithLazyAdd() GraphOption {
+	return func(g *Graph) {
+		g.lazyAdd = true
+	}
+}
+
+// What keeps a compiled graph from running: a dependency WithLazyAdd let through that's still
+// missing, or a selector that matches nothing
+func (g *Graph) broken() error {
+	if g.lazyAdd {
+		if err := g.missingDependency(); err != nil {
+			return err
+		}
+	}
+	return g.checkSelectors()
+}
+
+// The first dependency, by node id, that isn't in the graph
+func (g *Graph) missingDependency() error {
+	for _, id := range sortedIDs(g.nodes) {
+		for _, depId := range sortedIDs(g.nodes[id].Dependencies) {
+			if !g.exists(depId) {
+				return fmt.Errorf("Node %s is missing dependency %s", id, depId)
+			}
+		}
+	}
+	return nil
+}
+
+// Checks the edges of nodes already in the graph that were waiting on node
+func (g *Graph) checkWaiting(node *Node) error {
+	if !g.lazyAdd || len(g.policies) == 0 {
+		return nil
+	}
+
+	id := node.Identifier()
+	lookup := func(depId NodeID) *Node {
+		if depId == id {
+			return node
+		}
+		return g.nodes[depId]
+	}
+
+	for _, ref := range sortedIDs(g.nodes) {
+		if _, ok := g.nodes[ref].Dependencies[id]; !ok {
+			continue
+		}
+		if err := g.checkPolicies(g.nodes[ref], NodeIDs{id: {}}, lookup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package graph_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func expectMissing(t *testing.T, err error, id, depId string) {
+	t.Helper()
+
+	if err == nil || !strings.Contains(err.Error(), "Node "+id+" is missing dependency "+depId) {
+		t.Errorf("Expected %s's missing dependency %s reported, got %v", id, depId, err)
+	}
+}
+
+func TestStrictAddIsDefault(t *testing.T) {
+	for name, g := range map[string]*graph.Graph{
+		"default": graph.NewGraph("strict"),
+		"strict":  graph.NewGraph("strict", graph.WithLazyAdd(), graph.WithStrictAdd()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			g.Add(graph.NewNode("a", nil, graph.NoOp()))
+
+			_, err := g.Add(graph.NewNode("b", graph.Deps("later"), graph.NoOp()))
+			expectMissing(t, err, "b", "later")
+			if g.Has("b") {
+				t.Error("Expected the rejected node left out")
+			}
+
+			if err := g.AddEdge("a", "later"); err == nil {
+				t.Error("Expected AddEdge to an unknown node to fail")
+			}
+
+			err = g.AddAll([]*graph.Node{
+				graph.NewNode("c", nil, graph.NoOp()),
+				graph.NewNode("d", graph.Deps("later"), graph.NoOp()),
+			})
+			expectMissing(t, err, "d", "later")
+			if g.Has("c") {
+				t.Error("Expected the rejected batch left out")
+			}
+
+			other := graph.NewGraph("other", graph.WithLazyAdd())
+			other.Add(graph.NewNode("e", graph.Deps("later"), graph.NoOp()))
+			expectMissing(t, g.Merge(other), "e", "later")
+		})
+	}
+}
+
+func TestStrictAddAcceptsBatchOrder(t *testing.T) {
+	// Within one AddAll or Merge, dependencies may come in any order
+	g := graph.NewGraph("strict")
+	err := g.AddAll([]*graph.Node{
+		graph.NewNode("b", graph.Deps("a"), graph.NoOp()),
+		graph.NewNode("a", nil, graph.NoOp()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := graph.NewGraph("other", graph.WithLazyAdd())
+	other.Add(graph.NewNode("d", graph.Deps("c", "a"), graph.NoOp()))
+	other.Add(graph.NewNode("c", nil, graph.NoOp()))
+	if err := g.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLazyAdd(t *testing.T) {
+	g := graph.NewGraph("lazy", graph.WithLazyAdd())
+	if _, err := g.Add(graph.NewNode("b", graph.Deps("a"), graph.NoOp())); err != nil {
+		t.Fatalf("Expected a dependency on a later node accepted, got %v", err)
+	}
+	if err := g.AddEdge("b", "c"); err != nil {
+		t.Fatalf("Expected an edge to a later node accepted, got %v", err)
+	}
+	g.AddAll([]*graph.Node{graph.NewNode("d", graph.Deps("e"), graph.NoOp())})
+
+	// Validate and Sort stop at the first missing node they meet
+	missing := map[string]bool{"Node a does not exist": true, "Node c does not exist": true, "Node e does not exist": true}
+	if err := g.Validate(); err == nil || !missing[err.Error()] {
+		t.Errorf("Expected Validate to report a missing node, got %v", err)
+	}
+	if _, err := g.Sort(); err == nil || !missing[err.Error()] {
+		t.Errorf("Expected Sort to report a missing node, got %v", err)
+	}
+
+	// Compiled while a was missing, so the run refuses
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err == nil {
+		t.Error("Expected a run with a missing dependency to fail")
+	}
+
+	for _, id := range []string{"a", "c", "e"} {
+		g.Add(graph.NewNode(id, nil, graph.NoOp()))
+	}
+	if err := g.Validate(); err != nil {
+		t.Fatalf("Expected the graph complete, got %v", err)
+	}
+	ids := mustSort(t, g)
+	if !before(ids, "a", "b") || !before(ids, "c", "b") || !before(ids, "e", "d") {
+		t.Errorf("Expected the late nodes first, got %v", ids)
+	}
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Errorf("Expected the completed graph to run, got %v", err)
+	}
+}
+
+func TestLazyAddChecksPoliciesOnArrival(t *testing.T) {
+	g := graph.NewGraph("payments", graph.WithLazyAdd(), graph.WithEdgePolicy(noPaymentsOnExperimental))
+	g.Add(graph.NewNode("charge", graph.Deps("beta-fraud"), graph.NoOp(), graph.WithMetadata("team", "payments")))
+
+	_, err := g.Add(graph.NewNode("beta-fraud", nil, graph.NoOp(), graph.WithMetadata("env", "experimental")))
+	if !errors.Is(err, graph.ErrPolicyViolation) {
+		t.Fatalf("Expected the waiting edge checked once its target arrived, got %v", err)
+	}
+	assertViolation(t, err, "charge", "beta-fraud")
+	if g.Has("beta-fraud") {
+		t.Error("Expected the node that broke the policy left out")
+	}
+}
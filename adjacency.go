@@ -0,0 +1,201 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The adjacency format is one node per line, `node: dep1 dep2`, with # starting a comment.
+// Soft dependencies are prefixed with ~ and optional ones with ?. Aliases are written resolved.
+const (
+	softPrefix     = "~"
+	optionalPrefix = "?"
+)
+
+func adjacencyName(id NodeID) error {
+	s := string(id)
+	if s == "" || strings.ContainsAny(s, ":# \t\r\n") || strings.HasPrefix(s, softPrefix) || strings.HasPrefix(s, optionalPrefix) {
+		return fmt.Errorf("Node id %q can't be written in the adjacency format", s)
+	}
+	return nil
+}
+
+func (g *Graph) WriteAdjacency(w io.Writer) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+
+	for _, id := range sortedIDs(g.nodes) {
+		node := g.nodes[id]
+		if err := adjacencyName(id); err != nil {
+			return err
+		}
+
+		deps := []string{}
+		written := NodeIDs{}
+		for _, depId := range sortedIDs(node.Dependencies) {
+			resolved := g.resolve(depId)
+			if err := adjacencyName(resolved); err != nil {
+				return err
+			}
+			written[resolved] = struct{}{}
+
+			if node.DependencyKind(depId) == EdgeSoft {
+				deps = append(deps, softPrefix+string(resolved))
+			} else {
+				deps = append(deps, string(resolved))
+			}
+		}
+		for _, depId := range sortedIDs(node.optionalDependencies) {
+			resolved := g.resolve(depId)
+			if err := adjacencyName(resolved); err != nil {
+				return err
+			}
+			deps = append(deps, optionalPrefix+string(resolved))
+			written[resolved] = struct{}{}
+		}
+		// Selectors can't be written, only the dependencies they match
+		for _, s := range node.selectors {
+			for _, depId := range sortedIDs(g.selected(node, s)) {
+				if _, ok := written[depId]; ok {
+					continue
+				}
+				if err := adjacencyName(depId); err != nil {
+					return err
+				}
+				deps = append(deps, string(depId))
+				written[depId] = struct{}{}
+			}
+		}
+
+		line := string(id) + ":"
+		if len(deps) > 0 {
+			line += " " + strings.Join(deps, " ")
+		}
+		if _, err := fmt.Fprintln(bw, line); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+type adjacencyLine struct {
+	number  int
+	content string
+	id      NodeID
+	deps    []string
+}
+
+func (l adjacencyLine) errorf(format string, args ...any) error {
+	return fmt.Errorf("Line %d %q: %s", l.number, l.content, fmt.Sprintf(format, args...))
+}
+
+// Nodes may be declared in any order; fnFactory is called once per node.
+func ParseAdjacency(r io.Reader, fnFactory func(name string) NodeFn) (*Graph, error) {
+	lines := []adjacencyLine{}
+	seen := map[NodeID]adjacencyLine{}
+
+	scanner := bufio.NewScanner(r)
+	number := 0
+	for scanner.Scan() {
+		number++
+		content := scanner.Text()
+
+		text := content
+		if idx := strings.Index(text, "#"); idx >= 0 {
+			text = text[:idx]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		line := adjacencyLine{number: number, content: content}
+		name, deps, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, line.errorf("expected `node: dependencies`")
+		}
+
+		line.id = NodeID(strings.TrimSpace(name))
+		if err := adjacencyName(line.id); err != nil {
+			return nil, line.errorf("%s", err)
+		}
+		if prev, ok := seen[line.id]; ok {
+			return nil, line.errorf("node %s already declared on line %d", line.id, prev.number)
+		}
+
+		line.deps = strings.Fields(deps)
+		seen[line.id] = line
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[NodeID]*Node, len(lines))
+	for _, line := range lines {
+		node := NewNode(string(line.id), NodeIDs{}, fnFactory(string(line.id)))
+
+		for _, dep := range line.deps {
+			switch {
+			case strings.HasPrefix(dep, optionalPrefix):
+				WithOptionalDependency(NodeID(strings.TrimPrefix(dep, optionalPrefix)))(node)
+			case strings.HasPrefix(dep, softPrefix):
+				depId := NodeID(strings.TrimPrefix(dep, softPrefix))
+				if _, ok := seen[depId]; !ok {
+					return nil, line.errorf("unknown dependency %s", depId)
+				}
+				WithSoftDependency(depId)(node)
+			default:
+				if _, ok := seen[NodeID(dep)]; !ok {
+					return nil, line.errorf("unknown dependency %s", dep)
+				}
+				node.Dependencies[NodeID(dep)] = struct{}{}
+			}
+		}
+		nodes[line.id] = node
+	}
+
+	// Add in dependency order so forward references resolve: one pass of Kahn's algorithm, with
+	// ready nodes taken in the order they were declared
+	waiting := make(map[NodeID]int, len(lines))
+	dependents := make(map[NodeID][]NodeID, len(lines))
+	ready := []NodeID{}
+	for _, line := range lines {
+		deps := nodes[line.id].Dependencies
+		for _, depId := range sortedIDs(deps) {
+			dependents[depId] = append(dependents[depId], line.id)
+		}
+		if waiting[line.id] = len(deps); len(deps) == 0 {
+			ready = append(ready, line.id)
+		}
+	}
+
+	g := NewGraph("")
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		if _, err := g.Add(nodes[id]); err != nil {
+			return nil, seen[id].errorf("%s", err)
+		}
+
+		for _, dependent := range dependents[id] {
+			if waiting[dependent]--; waiting[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	// Whatever is still waiting is on a cycle or behind one
+	for _, line := range lines {
+		if waiting[line.id] > 0 {
+			return nil, line.errorf("node %s is part of, or depends on, a cycle", line.id)
+		}
+	}
+
+	return g, nil
+}
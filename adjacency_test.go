@@ -0,0 +1,126 @@
+package graph_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func noOps(name string) graph.NodeFn {
+	return graph.NoOp()
+}
+
+func parseAdjacencyFile(t *testing.T, name string) (*graph.Graph, error) {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	return graph.ParseAdjacency(f, noOps)
+}
+
+func TestParseAdjacencyFixture(t *testing.T) {
+	called := []string{}
+	f, err := os.Open(filepath.Join("testdata", "pipeline.adj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	g, err := graph.ParseAdjacency(f, func(name string) graph.NodeFn {
+		called = append(called, name)
+		return graph.NoOp()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(called) != 4 {
+		t.Errorf("Expected a fn per node, got %v", called)
+	}
+
+	deploy, ok := g.Get("deploy")
+	if !ok {
+		t.Fatal("Expected the forward-referencing node to be added")
+	}
+	if kind := deploy.DependencyKind("lint"); kind != graph.EdgeSoft {
+		t.Errorf("Expected ~lint to be soft, got %s", kind)
+	}
+	deps, _ := g.ResolvedDependencies("deploy")
+	if len(deps) != 3 {
+		t.Errorf("Expected build, test and lint with the optional approve dropped, got %v", deps)
+	}
+}
+
+func TestAdjacencyRoundTrip(t *testing.T) {
+	g, err := parseAdjacencyFile(t, "pipeline.adj")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first bytes.Buffer
+	if err := g.WriteAdjacency(&first); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "pipeline.adj.golden", first.String())
+
+	again, err := graph.ParseAdjacency(&first, noOps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var second bytes.Buffer
+	if err := again.WriteAdjacency(&second); err != nil {
+		t.Fatal(err)
+	}
+	if second.String() != readFile(t, "pipeline.adj.golden") {
+		t.Errorf("Expected writing the parsed output to give the same text, got:\n%s", second.String())
+	}
+}
+
+func TestParseAdjacencyDuplicateLine(t *testing.T) {
+	_, err := parseAdjacencyFile(t, "duplicate.adj")
+	if err == nil {
+		t.Fatal("Expected a duplicate node line to fail")
+	}
+	if msg := err.Error(); !strings.Contains(msg, `Line 3 "build: test"`) || !strings.Contains(msg, "already declared on line 1") {
+		t.Errorf("Expected the error to name the line and its content, got %q", msg)
+	}
+}
+
+func TestParseAdjacencyErrors(t *testing.T) {
+	cases := map[string]string{
+		"missing colon":      "build\n",
+		"unknown dependency": "test: build\n",
+		"cycle":              "a: b\nb: a\n",
+	}
+	for name, input := range cases {
+		if _, err := graph.ParseAdjacency(strings.NewReader(input), noOps); err == nil {
+			t.Errorf("Expected %s to fail", name)
+		} else if !strings.HasPrefix(err.Error(), "Line ") {
+			t.Errorf("Expected a line number for %s, got %q", name, err)
+		}
+	}
+}
+
+func TestParseAdjacencyReverseOrder(t *testing.T) {
+	g, err := graph.ParseAdjacency(strings.NewReader("d: c\nc: b\nb: a\na:\n"), noOps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := mustSort(t, g); !before(ids, "a", "b") || !before(ids, "b", "c") || !before(ids, "c", "d") {
+		t.Errorf("Expected the chain ordered, got %v", ids)
+	}
+}
+
+func TestParseAdjacencyCycleNamesFirstLine(t *testing.T) {
+	// root is fine; tail waits behind the cycle, and is declared first
+	_, err := graph.ParseAdjacency(strings.NewReader("root:\ntail: b\na: b root\nb: a\n"), noOps)
+	if err == nil || !strings.HasPrefix(err.Error(), `Line 2 "tail: b": node tail is part of, or depends on, a cycle`) {
+		t.Errorf("Expected the first node left waiting named, got %v", err)
+	}
+}
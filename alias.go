@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"fmt"
+)
+
+const defaultMaxAliasDepth = 8
+
+// How many aliases may be chained before reaching a node; zero means the default of 8
+func WithMaxAliasDepth(n int) GraphOption {
+	return func(g *Graph) {
+		g.maxAliasDepth = n
+	}
+}
+
+// Alias makes dependencies on alias resolve to target, which may itself be an alias. The target
+// must already exist and can't be removed while the alias points at it.
+func (g *Graph) Alias(alias NodeID, target NodeID) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	alias, target = g.normalize(alias), g.normalize(target)
+
+	if g.frozen {
+		return ErrGraphFrozen
+	}
+
+	if _, ok := g.nodes[alias]; ok {
+		return fmt.Errorf("Alias %s collides with a node of the same id", alias)
+	}
+	if existing, ok := g.aliases[alias]; ok {
+		return fmt.Errorf("Alias %s already points to %s", alias, existing)
+	}
+
+	depth := 1
+	for id := target; ; depth++ {
+		if _, ok := g.nodes[id]; ok {
+			break
+		}
+		next, ok := g.aliases[id]
+		if !ok {
+			return fmt.Errorf("Alias %s points to missing node %s", alias, id)
+		}
+		id = next
+	}
+
+	max := g.maxAliasDepth
+	if max <= 0 {
+		max = defaultMaxAliasDepth
+	}
+	if depth > max {
+		return fmt.Errorf("Alias %s is %d aliases deep, more than the maximum of %d", alias, depth, max)
+	}
+
+	if g.aliases == nil {
+		g.aliases = make(map[NodeID]NodeID)
+	}
+	g.aliases[alias] = target
+	if g.order != nil {
+		// Optional dependencies on alias bind now, which may close a cycle
+		if err := g.rebuildOrder(); err != nil {
+			delete(g.aliases, alias)
+			return err
+		}
+	}
+	g.changed()
+	return nil
+}
+
+// The node an id refers to once aliases are followed; ids that aren't aliases resolve to themselves
+func (g *Graph) ResolveAlias(id NodeID) NodeID {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	id = g.normalize(id)
+
+	return g.resolve(id)
+}
+
+func (g *Graph) resolve(id NodeID) NodeID {
+	for {
+		target, ok := g.aliases[id]
+		if !ok {
+			return id
+		}
+		id = target
+	}
+}
+
+func (g *Graph) exists(id NodeID) bool {
+	_, ok := g.nodes[g.resolve(id)]
+	return ok
+}
+
+// The node's dependencies declared through an alias, mapped to the node they resolve to
+func (g *Graph) aliased(node *Node) map[NodeID]NodeID {
+	if len(g.aliases) == 0 {
+		return nil
+	}
+
+	aliased := map[NodeID]NodeID{}
+	for _, set := range []NodeIDs{node.Dependencies, node.optionalDependencies} {
+		for depId := range set {
+			if target := g.resolve(depId); target != depId {
+				aliased[depId] = target
+			}
+		}
+	}
+	return aliased
+}
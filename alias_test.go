@@ -0,0 +1,143 @@
+package graph_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// An environment's graph: "deploy" aliases target, and the shared downstream nodes depend on it
+func environment(t *testing.T, target string, ran *sync.Map) *graph.Graph {
+	t.Helper()
+
+	record := func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		ran.Store(ec.ID, true)
+		return string(ec.ID), nil
+	}
+
+	g := graph.NewGraph(target)
+	g.Add(graph.NewNode(target, nil, record))
+	if err := g.Alias("deploy", graph.NodeID(target)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Add(graph.NewNode("smoke", graph.Deps("deploy"), record)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Add(graph.NewNode("notify", graph.Deps("smoke", "deploy"), record)); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestAliasSwapsImplementations(t *testing.T) {
+	for _, target := range []string{"noop", "release"} {
+		t.Run(target, func(t *testing.T) {
+			var ran sync.Map
+			g := environment(t, target, &ran)
+
+			ids := mustSort(t, g)
+			if !before(ids, graph.NodeID(target), "smoke") {
+				t.Errorf("Expected %s before smoke, got %v", target, ids)
+			}
+			for _, id := range ids {
+				if id == "deploy" {
+					t.Errorf("Expected the alias left out of the order, got %v", ids)
+				}
+			}
+
+			if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range []graph.NodeID{graph.NodeID(target), "smoke", "notify"} {
+				if _, ok := ran.Load(id); !ok {
+					t.Errorf("Expected %s to run", id)
+				}
+			}
+		})
+	}
+}
+
+func TestAliasResolvesInDependenciesAndResults(t *testing.T) {
+	g := graph.NewGraph("alias")
+	g.Add(graph.NewNode("release", nil, returns("released")))
+	g.Alias("deploy", "release")
+
+	var deps graph.NodeIDs
+	var results graph.Results
+	g.Add(graph.NewNode("notify", graph.Deps("deploy"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		deps, results = ec.Dependencies, ec.Results
+		return nil, nil
+	}))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := deps["release"]; !ok || len(deps) != 1 {
+		t.Errorf("Expected notify to depend on release, got %v", deps)
+	}
+	if results["release"] != "released" {
+		t.Errorf("Expected release's result, got %v", results)
+	}
+	if got := g.ResolveAlias("deploy"); got != "release" {
+		t.Errorf("Expected deploy to resolve to release, got %s", got)
+	}
+	if got := g.ResolveAlias("release"); got != "release" {
+		t.Errorf("Expected a node to resolve to itself, got %s", got)
+	}
+}
+
+func TestAliasErrors(t *testing.T) {
+	g := diamond(t)
+
+	if err := g.Alias("x", "missing"); err == nil {
+		t.Error("Expected an error for a dangling target")
+	}
+	if err := g.Alias("a", "b"); err == nil {
+		t.Error("Expected an error for an alias colliding with a node")
+	}
+	if err := g.Alias("x", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Alias("x", "b"); err == nil {
+		t.Error("Expected an error re-pointing an existing alias")
+	}
+	if err := g.Remove("a"); err == nil {
+		t.Error("Expected an error removing an alias's target")
+	}
+}
+
+func TestAliasChainDepth(t *testing.T) {
+	g := graph.NewGraph("chain", graph.WithMaxAliasDepth(2))
+	g.Add(graph.NewNode("real", nil, graph.NoOp()))
+
+	if err := g.Alias("one", "real"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Alias("two", "one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Alias("three", "two"); err == nil {
+		t.Error("Expected an error past the maximum depth")
+	}
+	if got := g.ResolveAlias("two"); got != "real" {
+		t.Errorf("Expected the chain to resolve to real, got %s", got)
+	}
+}
+
+func TestAliasInExports(t *testing.T) {
+	var ran sync.Map
+	g := environment(t, "release", &ran)
+
+	authored := g.ToDOT()
+	if !strings.Contains(authored, `"deploy" -> "release"`) {
+		t.Errorf("Expected the authored DOT to draw the alias, got %s", authored)
+	}
+
+	compiled := g.CompileToExecutable().ToDOT()
+	if !strings.Contains(compiled, `"smoke" -> "release"`) || !strings.Contains(compiled, "via deploy") {
+		t.Errorf("Expected the compiled DOT to show the resolved edge via the alias, got %s", compiled)
+	}
+}
@@ -0,0 +1,17 @@
+package graph
+
+// Makes the node best-effort: if its last attempt fails or times out, it's reported as
+// FailedAllowed with its error kept, dependents run as if it succeeded with a nil result, and
+// the run's error leaves it out. Hooks, logs and metrics still see the failure.
+func AllowFailure() NodeOption {
+	return func(n *Node) {
+		n.allowFailure = true
+	}
+}
+
+// Turns the final failure of a best-effort node into an allowed one
+func allowed(node *executableNode, nr *NodeReport) {
+	if node.allowFailure && (nr.Status == StatusFailed || nr.Status == StatusTimedOut) {
+		nr.Status = StatusFailedAllowed
+	}
+}
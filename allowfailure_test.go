@@ -0,0 +1,115 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A notification that fails after its retries, with a report step depending on it
+func bestEffort(t *testing.T, fn graph.NodeFn, opts ...graph.NodeOption) (*graph.Graph, *bool) {
+	t.Helper()
+
+	ran := false
+	g := graph.NewGraph("nightly")
+	g.Add(graph.NewNode("notify", nil, fn, append([]graph.NodeOption{graph.AllowFailure()}, opts...)...))
+	g.Add(graph.NewNode("report", graph.Deps("notify"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		if result, ok := ec.Results["notify"]; !ok || result != nil {
+			return nil, errors.New("expected a nil result for notify")
+		}
+		ran = true
+		return nil, nil
+	}))
+	return g, &ran
+}
+
+func TestAllowFailureRootDoesNotFailTheRun(t *testing.T) {
+	g, ran := bestEffort(t, failWith(errors.New("webhook down")), graph.WithRetry(graph.RetryPolicy{MaxAttempts: 2}))
+
+	report, err := g.CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatalf("Expected the allowed failure left out of the run's error, got %v", err)
+	}
+	if !*ran {
+		t.Error("Expected the dependent to run with a nil result")
+	}
+
+	nr := report.Nodes["notify"]
+	if nr.Status != graph.StatusFailedAllowed || nr.Err == nil || len(nr.Attempts) != 2 {
+		t.Errorf("Expected FailedAllowed with its error after both attempts, got %s, %v, %d attempts", nr.Status, nr.Err, len(nr.Attempts))
+	}
+	if got := report.WithStatus(graph.StatusFailedAllowed); len(got) != 1 {
+		t.Errorf("Expected notify listed as an allowed failure, got %v", got)
+	}
+}
+
+func TestAllowFailureTimeout(t *testing.T) {
+	g, ran := bestEffort(t, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, graph.WithTimeout(10*time.Millisecond))
+
+	report, err := g.CompileToExecutable().Run(ctx(t))
+	if err != nil || !*ran {
+		t.Fatalf("Expected the timed-out best-effort node not to stop the run, got %v", err)
+	}
+	if nr := report.Nodes["notify"]; nr.Status != graph.StatusFailedAllowed {
+		t.Errorf("Expected the timeout allowed, got %s", nr.Status)
+	}
+}
+
+func TestAllowFailureStillObserved(t *testing.T) {
+	g, _ := bestEffort(t, failWith(errors.New("webhook down")))
+
+	var mu sync.Mutex
+	var finished []graph.AttemptReport
+	var results []graph.NodeStatus
+	sink := graph.NewAggregatingSink()
+	_, err := g.CompileToExecutable().Run(ctx(t), graph.WithMetrics(sink), graph.WithHooks(graph.Hooks{
+		OnNodeFinish: func(id graph.NodeID, attempt graph.AttemptReport) {
+			mu.Lock()
+			defer mu.Unlock()
+			if id == "notify" {
+				finished = append(finished, attempt)
+			}
+		},
+		OnNodeResult: func(id graph.NodeID, result graph.NodeReport) {
+			mu.Lock()
+			defer mu.Unlock()
+			if id == "notify" {
+				results = append(results, result.Status)
+			}
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(finished) != 1 || finished[0].Err == nil {
+		t.Errorf("Expected OnNodeFinish to see the failed attempt, got %+v", finished)
+	}
+	if len(results) != 1 || results[0] != graph.StatusFailedAllowed {
+		t.Errorf("Expected OnNodeResult to see FailedAllowed, got %v", results)
+	}
+	if stats := sink.Stats(); len(stats) != 1 || stats[0].Statuses[graph.StatusFailedAllowed] != 1 {
+		t.Errorf("Expected the metrics to count the allowed failure, got %+v", stats)
+	}
+}
+
+func TestFailureNotAllowedByDefault(t *testing.T) {
+	g := graph.NewGraph("nightly")
+	g.Add(graph.NewNode("notify", nil, failWith(errors.New("webhook down"))))
+	g.Add(graph.NewNode("report", graph.Deps("notify"), graph.NoOp()))
+
+	report, err := g.CompileToExecutable().Run(ctx(t))
+	if err == nil {
+		t.Error("Expected the failure to fail the run")
+	}
+	if nr := report.Nodes["report"]; nr.Status != graph.StatusSkipped {
+		t.Errorf("Expected the dependent skipped, got %s", nr.Status)
+	}
+}
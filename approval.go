@@ -0,0 +1,179 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrRejected = errors.New("Approval rejected")
+
+// Why an approval node failed
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrRejected, e.Reason)
+}
+
+func (e *RejectedError) Is(target error) bool {
+	return target == ErrRejected
+}
+
+// What an approval node does once its WithApprovalTimeout runs out
+type ApprovalTimeoutBehavior int
+
+const (
+	OnApprovalTimeoutReject ApprovalTimeoutBehavior = iota
+	OnApprovalTimeoutApprove
+)
+
+// A gate that waits for someone to approve it. Once ready it parks, holding no concurrency slot
+// or budget, is reported as StatusAwaitingApproval and announces a token through the
+// EventAwaitingApproval event and the OnAwaitingApproval hook. RunHandle.Approve succeeds it;
+// RunHandle.Reject fails it with a RejectedError, skipping its dependents. A run started with
+// Run rather than Start can only settle it through WithApprovalTimeout.
+//
+// Only decisions reach the run state: a node still parked when a state is taken parks again, with
+// a new token, in the run resumed from it.
+func NewApprovalNode(name string, deps NodeIDs, opts ...NodeOption) *Node {
+	node := NewNode(name, deps, NoOp(), opts...)
+	node.approval = true
+	return node
+}
+
+// Settles an approval node nobody has decided on within d, timed by the run's clock from when it
+// parked. Rejects it unless told otherwise.
+func WithApprovalTimeout(d time.Duration, behavior ...ApprovalTimeoutBehavior) NodeOption {
+	return func(n *Node) {
+		n.approvalTimeout = d
+		n.onApprovalTimeout = OnApprovalTimeoutReject
+		if len(behavior) > 0 {
+			n.onApprovalTimeout = behavior[0]
+		}
+	}
+}
+
+func (n *Node) IsApproval() bool {
+	return n.approval
+}
+
+type approvalDecision struct {
+	token    string
+	approve  bool
+	reason   string
+	timedOut bool
+	// Told whether the token was waiting; nil for timeouts
+	reply chan bool
+}
+
+// A parked approval node; stop ends its timeout
+type gate struct {
+	id   NodeID
+	stop chan struct{}
+}
+
+// Approves the node waiting on token. It returns false, doing nothing, when no node in the run is
+// waiting on it or the run has finished.
+func (h *RunHandle) Approve(token string) bool {
+	return h.decide(approvalDecision{token: token, approve: true})
+}
+
+// Rejects the node waiting on token with reason, like Approve otherwise
+func (h *RunHandle) Reject(token, reason string) bool {
+	return h.decide(approvalDecision{token: token, reason: reason})
+}
+
+func (h *RunHandle) decide(d approvalDecision) bool {
+	d.reply = make(chan bool, 1)
+	select {
+	case h.approvals <- d:
+		return <-d.reply
+	case <-h.done:
+		return false
+	}
+}
+
+func (r *run) park(id NodeID) {
+	node := r.peg.nodes[id]
+	token := fmt.Sprintf("%s:%s", r.report.RunID, id)
+	g := gate{id: id, stop: make(chan struct{})}
+	if r.gates == nil {
+		r.gates = make(map[string]gate)
+		r.decisions = make(chan approvalDecision, len(r.peg.nodes))
+	}
+	r.gates[token] = g
+
+	nr := r.report.Nodes[id]
+	nr.Status, nr.Start = StatusAwaitingApproval, r.clock().Now()
+	r.event(Event{Kind: EventAwaitingApproval, Node: id, Status: StatusAwaitingApproval, Token: token})
+	if r.cfg.Hooks.OnAwaitingApproval != nil {
+		r.cfg.Hooks.OnAwaitingApproval(id, token)
+	}
+
+	if node.approvalTimeout > 0 {
+		after := r.clock().After(node.approvalTimeout)
+		approve := node.onApprovalTimeout == OnApprovalTimeoutApprove
+		go func() {
+			select {
+			case <-after:
+				r.decisions <- approvalDecision{token: token, approve: approve, reason: "Approval timed out", timedOut: true}
+			case <-g.stop:
+			}
+		}()
+	}
+}
+
+func (r *run) decided(ctx context.Context, d approvalDecision) {
+	g, ok := r.gates[d.token]
+	if d.reply != nil {
+		d.reply <- ok
+	}
+	if !ok {
+		return
+	}
+	delete(r.gates, d.token)
+	close(g.stop)
+
+	nr := *r.report.Nodes[g.id]
+	nr.End = r.clock().Now()
+	if d.approve {
+		nr.Status, nr.Reason = StatusSucceeded, ReasonApproved
+	} else {
+		nr.Status, nr.Reason, nr.Category = StatusFailed, ReasonRejected, CategoryPermanent
+		nr.Err = &NodeError{ID: g.id, Category: nr.Category, Err: &RejectedError{Reason: d.reason}}
+	}
+	if d.timedOut {
+		nr.Reason = ReasonApprovalTimeout
+	}
+	allowed(r.peg.nodes[g.id], &nr)
+
+	*r.report.Nodes[g.id] = nr
+	r.cfg.State.record(nr, nil)
+	r.finished(nr)
+	r.release(ctx, g.id, satisfies(nr))
+}
+
+// Settles the nodes still parked when the run stops: interrupted if its ctx ended, otherwise put
+// back to pending so they're reported like any other node that didn't get to run
+func (r *run) unpark(ctx context.Context) {
+	for _, token := range sortedKeys(r.gates) {
+		g := r.gates[token]
+		close(g.stop)
+
+		nr := r.report.Nodes[g.id]
+		if ctx.Err() == nil {
+			*nr = NodeReport{ID: g.id}
+			continue
+		}
+		var err error
+		nr.Status, nr.Reason, nr.Category, err = r.interruption(ctx)
+		nr.Err = &NodeError{ID: g.id, Category: nr.Category, Err: err}
+		nr.End = r.clock().Now()
+		r.cfg.State.record(*nr, nil)
+		r.finished(*nr)
+	}
+	r.gates = nil
+}
@@ -0,0 +1,268 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// build, then the production gate, then deploy
+func release(deploy graph.NodeFn, opts ...graph.NodeOption) *graph.Graph {
+	g := graph.NewGraph("release")
+	g.Add(graph.NewNode("build", nil, graph.NoOp()))
+	g.Add(graph.NewApprovalNode("approve-prod", graph.Deps("build"), opts...))
+	g.Add(graph.NewNode("deploy", graph.Deps("approve-prod"), deploy))
+	return g
+}
+
+// release with a deploy that counts its calls
+func gatedDeploy(deploys *atomic.Int32, opts ...graph.NodeOption) *graph.Graph {
+	return release(func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		deploys.Add(1)
+		return nil, nil
+	}, opts...)
+}
+
+// Hooks passing on each token a gate parks with
+func approvalTokens() (graph.ExecOption, <-chan string) {
+	tokens := make(chan string, 4)
+	return graph.WithHooks(graph.Hooks{OnAwaitingApproval: func(id graph.NodeID, token string) {
+		tokens <- token
+	}}), tokens
+}
+
+func TestApprovalReleasesDependents(t *testing.T) {
+	var deploys atomic.Int32
+	hooks, tokens := approvalTokens()
+	h := gatedDeploy(&deploys).CompileToExecutable().Start(ctx(t), hooks, graph.WithEventLog(100))
+
+	token := <-tokens
+	if n := deploys.Load(); n != 0 {
+		t.Fatalf("Expected deploy to wait at the gate, got %d calls", n)
+	}
+	if !h.Approve(token) {
+		t.Fatal("Expected the parked gate to take the approval")
+	}
+	report, err := h.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nr := report.Nodes["approve-prod"]; nr.Status != graph.StatusSucceeded || nr.Reason != graph.ReasonApproved {
+		t.Errorf("Expected the gate approved, got %s/%s", nr.Status, nr.Reason)
+	}
+	if nr := report.Nodes["deploy"]; nr.Status != graph.StatusSucceeded || deploys.Load() != 1 {
+		t.Errorf("Expected deploy to run once, got %s after %d calls", nr.Status, deploys.Load())
+	}
+	var parked []graph.Event
+	for _, e := range report.Events {
+		if e.Kind == graph.EventAwaitingApproval {
+			parked = append(parked, e)
+		}
+	}
+	if len(parked) != 1 || parked[0].Node != "approve-prod" || parked[0].Token != token {
+		t.Errorf("Expected one awaiting-approval event with the token, got %+v", parked)
+	}
+}
+
+func TestRejectionSkipsDependents(t *testing.T) {
+	var deploys atomic.Int32
+	hooks, tokens := approvalTokens()
+	h := gatedDeploy(&deploys).CompileToExecutable().Start(ctx(t), hooks)
+
+	if !h.Reject(<-tokens, "change freeze") {
+		t.Fatal("Expected the parked gate to take the rejection")
+	}
+	report, _ := h.Wait()
+
+	nr := report.Nodes["approve-prod"]
+	if nr.Status != graph.StatusFailed || nr.Reason != graph.ReasonRejected {
+		t.Errorf("Expected the gate rejected, got %s/%s", nr.Status, nr.Reason)
+	}
+	var rejected *graph.RejectedError
+	if !errors.As(nr.Err, &rejected) || rejected.Reason != "change freeze" || !errors.Is(nr.Err, graph.ErrRejected) {
+		t.Errorf("Expected the rejection's reason attached, got %v", nr.Err)
+	}
+	if nr := report.Nodes["deploy"]; nr.Status != graph.StatusSkipped || nr.Reason != graph.ReasonUpstreamFailed {
+		t.Errorf("Expected deploy skipped, got %s/%s", nr.Status, nr.Reason)
+	}
+	if n := deploys.Load(); n != 0 {
+		t.Errorf("Expected deploy never called, got %d calls", n)
+	}
+}
+
+func TestApprovalUnknownToken(t *testing.T) {
+	var deploys atomic.Int32
+	hooks, tokens := approvalTokens()
+	h := gatedDeploy(&deploys).CompileToExecutable().Start(ctx(t), hooks)
+
+	token := <-tokens
+	if h.Approve("nobody:waiting") {
+		t.Error("Expected a token nobody waits on to be refused")
+	}
+	if !h.Approve(token) {
+		t.Fatal("Expected the real token to be taken")
+	}
+	h.Wait()
+	if h.Approve(token) || h.Reject(token, "late") {
+		t.Error("Expected decisions after the run to be refused")
+	}
+}
+
+func TestApprovalHoldsNoSlot(t *testing.T) {
+	var deploys atomic.Int32
+	hooks, tokens := approvalTokens()
+	g := gatedDeploy(&deploys)
+	ran := make(chan struct{})
+	g.Add(graph.NewNode("docs", graph.Deps("build"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		close(ran)
+		return nil, nil
+	}))
+	h := g.CompileToExecutable().Start(ctx(t), hooks, graph.WithMaxConcurrency(1))
+
+	token := <-tokens
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected docs to run while the gate is parked in the only slot")
+	}
+	h.Approve(token)
+	if _, err := h.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApprovalTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		behavior []graph.ApprovalTimeoutBehavior
+		status   graph.NodeStatus
+		deploys  int32
+	}{
+		{"rejects by default", nil, graph.StatusFailed, 0},
+		{"approves when told to", []graph.ApprovalTimeoutBehavior{graph.OnApprovalTimeoutApprove}, graph.StatusSucceeded, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := newFakeClock()
+			var deploys atomic.Int32
+			peg := gatedDeploy(&deploys, graph.WithApprovalTimeout(time.Hour, tc.behavior...)).CompileToExecutable()
+
+			// Run rather than Start: only the timeout can settle the gate
+			done := runAsync(peg, graph.WithClock(clock))
+			clock.waitFor(t, 1)
+			clock.Advance(time.Hour)
+			res := <-done
+
+			nr := res.report.Nodes["approve-prod"]
+			if nr.Status != tc.status || nr.Reason != graph.ReasonApprovalTimeout {
+				t.Errorf("Expected %s/approval-timeout, got %s/%s", tc.status, nr.Status, nr.Reason)
+			}
+			if n := deploys.Load(); n != tc.deploys {
+				t.Errorf("Expected %d deploys, got %d", tc.deploys, n)
+			}
+		})
+	}
+}
+
+func TestApprovalInterruptedByCancel(t *testing.T) {
+	var deploys atomic.Int32
+	hooks, tokens := approvalTokens()
+	c, cancel := context.WithCancel(context.Background())
+	h := gatedDeploy(&deploys).CompileToExecutable().Start(c, hooks)
+
+	<-tokens
+	cancel()
+	report, err := h.Wait()
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the run canceled, got %v", err)
+	}
+	if nr := report.Nodes["approve-prod"]; nr.Status != graph.StatusCanceled {
+		t.Errorf("Expected the parked gate interrupted, got %s/%s", nr.Status, nr.Reason)
+	}
+	expectSettled(t, report, 3)
+}
+
+func TestApprovalDecisionSurvivesResume(t *testing.T) {
+	// The first process gets past the gate and dies while deploy runs
+	started := make(chan graph.NodeID, 1)
+	peg := release(blockUntilCanceled(started)).CompileToExecutable()
+	state := graph.NewRunState()
+	hooks, tokens := approvalTokens()
+
+	c, cancel := context.WithCancel(context.Background())
+	h := peg.Start(c, hooks, graph.WithRunState(state))
+	h.Approve(<-tokens)
+	<-started
+	blob, err := peg.SnapshotState(state)
+	cancel()
+	h.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var deploys atomic.Int32
+	report, err := graph.ResumeFromState(ctx(t), gatedDeploy(&deploys), blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nr := report.Nodes["approve-prod"]; nr.Status != graph.StatusSucceeded || nr.Reason != graph.ReasonPrecompleted {
+		t.Errorf("Expected the approved gate precompleted, got %s/%s", nr.Status, nr.Reason)
+	}
+	if n := deploys.Load(); n != 1 {
+		t.Errorf("Expected deploy to run in the resumed run, got %d calls", n)
+	}
+}
+
+func TestParkedApprovalParksAgainOnResume(t *testing.T) {
+	var deploys atomic.Int32
+	peg := gatedDeploy(&deploys).CompileToExecutable()
+	state := graph.NewRunState()
+	hooks, tokens := approvalTokens()
+
+	c, cancel := context.WithCancel(context.Background())
+	h := peg.Start(c, hooks, graph.WithRunState(state))
+	first := <-tokens
+	blob, err := peg.SnapshotState(state)
+	cancel()
+	h.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := newFakeClock()
+	done := make(chan runResult, 1)
+	go func() {
+		report, err := graph.ResumeFromState(ctx(t), gatedDeploy(&deploys, graph.WithApprovalTimeout(time.Minute, graph.OnApprovalTimeoutApprove)), blob, hooks, graph.WithClock(clock))
+		done <- runResult{report, err}
+	}()
+	if second := <-tokens; second == first {
+		t.Errorf("Expected a new token in the resumed run, got %s again", second)
+	}
+	clock.waitFor(t, 1)
+	clock.Advance(time.Minute)
+	if res := <-done; res.err != nil || deploys.Load() != 1 {
+		t.Errorf("Expected the gate to park again and deploy once settled, got %v after %d deploys", res.err, deploys.Load())
+	}
+}
+
+func TestApprovalInterruptedByAbort(t *testing.T) {
+	var deploys atomic.Int32
+	hooks, tokens := approvalTokens()
+	h := gatedDeploy(&deploys).CompileToExecutable().Start(ctx(t), hooks)
+
+	<-tokens
+	if !h.Abort("release called off") {
+		t.Fatal("Expected Abort to stop the parked run")
+	}
+	report, err := h.Wait()
+	if !errors.Is(err, graph.ErrAborted) {
+		t.Errorf("Expected the run aborted, got %v", err)
+	}
+	if nr := report.Nodes["approve-prod"]; nr.Status != graph.StatusCanceled || nr.Reason != graph.ReasonAborted {
+		t.Errorf("Expected the parked gate aborted, got %s/%s", nr.Status, nr.Reason)
+	}
+}
@@ -0,0 +1,62 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrAssertionFailed = errors.New("Assertion failed")
+
+// What an assertion node's check returned, categorized as CategoryAssertionFailed
+type AssertionError struct {
+	Err error
+}
+
+func (e *AssertionError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrAssertionFailed, e.Err)
+}
+
+func (e *AssertionError) Is(target error) bool {
+	return target == ErrAssertionFailed
+}
+
+func (e *AssertionError) Unwrap() error {
+	return e.Err
+}
+
+func (e *AssertionError) Category() ErrorCategory {
+	return CategoryAssertionFailed
+}
+
+// A check between stages, given its dependencies' results. An error from assert fails the node
+// with an AssertionError, which the report and hooks categorize as CategoryAssertionFailed, and
+// skips its dependents. Give it AllowFailure to make a failed check a warning instead: the node
+// ends FailedAllowed and its dependents run. Exports draw assertions as hexagons.
+func NewAssertionNode(name string, deps NodeIDs, assert func(ctx context.Context, results Results) error, opts ...NodeOption) *Node {
+	fn := func(ctx context.Context, ec *ExecutionContext) (any, error) {
+		if err := assert(ctx, ec.Results); err != nil {
+			return nil, &AssertionError{Err: err}
+		}
+		return nil, nil
+	}
+
+	node := NewNode(name, deps, fn, opts...)
+	node.assertion = true
+	return node
+}
+
+func (n *Node) IsAssertion() bool {
+	return n.assertion
+}
+
+// Assertion nodes whose check failed, whether that failed the run or was allowed, sorted by id
+func (r *Report) FailedAssertions() SortedNodeIDs {
+	ids := SortedNodeIDs{}
+	for _, id := range sortedIDs(r.Nodes) {
+		if r.Nodes[id].Category == CategoryAssertionFailed {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
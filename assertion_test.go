@@ -0,0 +1,132 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// load returns a row count, check compares it with what extract returned, publish needs check
+func checked(t *testing.T, loaded int, opts ...graph.NodeOption) (*graph.Graph, *runLog) {
+	t.Helper()
+
+	l := &runLog{}
+	g := graph.NewGraph("warehouse")
+	g.Add(graph.NewNode("extract", nil, returns(100)))
+	g.Add(graph.NewNode("load", graph.Deps("extract"), returns(loaded)))
+	g.Add(graph.NewAssertionNode("row-counts-match", graph.Deps("extract", "load"), func(ctx context.Context, results graph.Results) error {
+		if results["extract"] != results["load"] {
+			return fmt.Errorf("extracted %v rows, loaded %v", results["extract"], results["load"])
+		}
+		return nil
+	}, opts...))
+	g.Add(graph.NewNode("publish", graph.Deps("row-counts-match"), l.fn))
+	return g, l
+}
+
+func TestAssertionPasses(t *testing.T) {
+	g, l := checked(t, 100)
+	report, err := g.CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nr := report.Nodes["row-counts-match"]; nr.Status != graph.StatusSucceeded || nr.Category != "" {
+		t.Errorf("Expected the check to pass, got %s %q", nr.Status, nr.Category)
+	}
+	if len(l.ids) != 1 || len(report.FailedAssertions()) != 0 {
+		t.Errorf("Expected publish to run and nothing flagged, got %v and %v", l.ids, report.FailedAssertions())
+	}
+}
+
+func TestAssertionFailsTheRun(t *testing.T) {
+	var mu sync.Mutex
+	categories := map[graph.NodeID]graph.ErrorCategory{}
+	hooks := graph.Hooks{OnNodeFinish: func(id graph.NodeID, attempt graph.AttemptReport) {
+		mu.Lock()
+		defer mu.Unlock()
+		categories[id] = attempt.Category
+	}}
+
+	g, l := checked(t, 99)
+	report, err := g.CompileToExecutable().Run(ctx(t), graph.WithHooks(hooks))
+	if err == nil {
+		t.Fatal("Expected the failed check to fail the run")
+	}
+
+	nr := report.Nodes["row-counts-match"]
+	if nr.Status != graph.StatusFailed {
+		t.Errorf("Expected the check to fail, got %s", nr.Status)
+	}
+	expectCategory(t, nr, graph.CategoryAssertionFailed)
+	if categories["row-counts-match"] != graph.CategoryAssertionFailed {
+		t.Errorf("Expected the hook to see the category, got %q", categories["row-counts-match"])
+	}
+
+	var aerr *graph.AssertionError
+	if !errors.As(nr.Err, &aerr) || !errors.Is(nr.Err, graph.ErrAssertionFailed) {
+		t.Fatalf("Expected an AssertionError, got %v", nr.Err)
+	}
+	if got := aerr.Error(); got != "Assertion failed: extracted 100 rows, loaded 99" {
+		t.Errorf("Expected the check's message kept, got %q", got)
+	}
+
+	if report.Nodes["publish"].Status != graph.StatusSkipped || len(l.ids) != 0 {
+		t.Errorf("Expected publish skipped, got %s", report.Nodes["publish"].Status)
+	}
+	if got := report.FailedAssertions(); len(got) != 1 || got[0] != "row-counts-match" {
+		t.Errorf("Expected the check listed, got %v", got)
+	}
+}
+
+func TestAssertionAsWarning(t *testing.T) {
+	g, l := checked(t, 99, graph.AllowFailure())
+	report, err := g.CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatalf("Expected an allowed failure to leave the run passing, got %v", err)
+	}
+
+	nr := report.Nodes["row-counts-match"]
+	if nr.Status != graph.StatusFailedAllowed || nr.Category != graph.CategoryAssertionFailed {
+		t.Errorf("Expected FailedAllowed with the assertion category, got %s %q", nr.Status, nr.Category)
+	}
+	if len(l.ids) != 1 {
+		t.Error("Expected publish to run after the downgraded check")
+	}
+	if got := report.FailedAssertions(); len(got) != 1 {
+		t.Errorf("Expected the allowed failure still listed, got %v", got)
+	}
+}
+
+func TestAssertionOnlyFromAssertionNodes(t *testing.T) {
+	g := graph.NewGraph("plain")
+	g.Add(graph.NewNode("n", nil, failWith(errors.New("boom"))))
+	report, _ := g.CompileToExecutable().Run(ctx(t))
+	if got := report.FailedAssertions(); len(got) != 0 || report.Nodes["n"].Category != graph.CategoryFn {
+		t.Errorf("Expected an ordinary failure not counted, got %v", got)
+	}
+
+	node := graph.NewAssertionNode("check", nil, func(ctx context.Context, results graph.Results) error { return nil })
+	if !node.IsAssertion() || graph.NewNode("n", nil, graph.NoOp()).IsAssertion() {
+		t.Error("Expected only the assertion node marked")
+	}
+}
+
+func TestAssertionDrawnDistinctly(t *testing.T) {
+	g, _ := checked(t, 100)
+	dot, mermaid := g.ToDOT(), g.ToMermaid()
+
+	if !strings.Contains(dot, `"row-counts-match" [shape=hexagon]`) || strings.Count(dot, "hexagon") != 1 {
+		t.Errorf("Expected only the check drawn as a hexagon in DOT, got\n%s", dot)
+	}
+	if !strings.Contains(mermaid, `{{"row-counts-match"}}`) || strings.Count(mermaid, "{{") != 1 {
+		t.Errorf("Expected only the check drawn as a hexagon in Mermaid, got\n%s", mermaid)
+	}
+	if compiled := g.CompileToExecutable().ToDOT(); !strings.Contains(compiled, "hexagon") {
+		t.Errorf("Expected the compiled graph to draw it too, got\n%s", compiled)
+	}
+}
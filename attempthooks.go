@@ -0,0 +1,43 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+)
+
+type BeforeAttemptFn func(ctx context.Context, attempt int) error
+type AfterAttemptFn func(ctx context.Context, attempt int, err error)
+
+// Runs fn before every attempt of the node, the first included, so state a failed attempt left
+// behind can be reset. It shares the attempt's ctx, timeout and panic recovery with the node's
+// fn; an error fails the attempt without calling the node's fn.
+func WithBeforeAttempt(fn BeforeAttemptFn) NodeOption {
+	return func(n *Node) {
+		n.beforeAttempt = fn
+	}
+}
+
+// Runs fn after every attempt of the node with the attempt's error, nil if it succeeded. It runs
+// once the outcome is known, after a panic has been recovered, and before any retry starts. A fn
+// abandoned past its timeout may still be running at that point.
+func WithAfterAttempt(fn AfterAttemptFn) NodeOption {
+	return func(n *Node) {
+		n.afterAttempt = fn
+	}
+}
+
+func (r *run) beforeAttempt(ctx context.Context, node *executableNode, attempt int) error {
+	if node.beforeAttempt == nil {
+		return nil
+	}
+	if err := node.beforeAttempt(ctx, attempt); err != nil {
+		return fmt.Errorf("Before attempt %d: %w", attempt, err)
+	}
+	return nil
+}
+
+func (r *run) afterAttempt(ctx context.Context, node *executableNode, a AttemptReport) {
+	if node.afterAttempt != nil {
+		node.afterAttempt(ctx, a.Attempt, a.Err)
+	}
+}
@@ -0,0 +1,129 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Records the hook and fn calls of one node in order
+type sequence struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (s *sequence) add(format string, v ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, fmt.Sprintf(format, v...))
+}
+
+// Hooks recording each attempt, with before also called ahead of every attempt when given
+func (s *sequence) hooks(before func(attempt int) error) []graph.NodeOption {
+	return []graph.NodeOption{
+		graph.WithBeforeAttempt(func(ctx context.Context, attempt int) error {
+			s.add("before %d", attempt)
+			if before != nil {
+				return before(attempt)
+			}
+			return nil
+		}),
+		graph.WithAfterAttempt(func(ctx context.Context, attempt int, err error) {
+			s.add("after %d: %v", attempt, err)
+		}),
+		graph.WithRetry(graph.RetryPolicy{MaxAttempts: 3}),
+	}
+}
+
+func TestAttemptHooksResetState(t *testing.T) {
+	seq := &sequence{}
+	// The fn adds to a counter that a half-done attempt leaves dirty; BeforeAttempt resets it
+	counter := 0
+	opts := seq.hooks(func(attempt int) error {
+		seq.add("counter %d", counter)
+		counter = 0
+		return nil
+	})
+	nr := runOne(t, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		counter++
+		seq.add("fn %d, counter %d", ec.Attempt, counter)
+		if ec.Attempt == 1 {
+			counter += 10
+			return nil, errors.New("flaky")
+		}
+		return nil, nil
+	}, opts)
+
+	if nr.Status != graph.StatusSucceeded || len(nr.Attempts) != 2 {
+		t.Fatalf("Expected success on the second attempt, got %s after %d", nr.Status, len(nr.Attempts))
+	}
+	want := []string{
+		"before 1",
+		"counter 0",
+		"fn 1, counter 1",
+		"after 1: Node n failed: flaky",
+		"before 2",
+		"counter 11",
+		"fn 2, counter 1",
+		"after 2: <nil>",
+	}
+	if !reflect.DeepEqual(seq.calls, want) {
+		t.Errorf("Expected\n%q\ngot\n%q", want, seq.calls)
+	}
+}
+
+func TestBeforeAttemptErrorFailsTheAttempt(t *testing.T) {
+	seq := &sequence{}
+	opts := seq.hooks(func(attempt int) error {
+		if attempt == 1 {
+			return errors.New("no temp dir")
+		}
+		return nil
+	})
+	nr := runOne(t, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		seq.add("fn %d", ec.Attempt)
+		return nil, nil
+	}, opts)
+
+	if nr.Status != graph.StatusSucceeded || len(nr.Attempts) != 2 {
+		t.Fatalf("Expected the second attempt to succeed, got %s after %d", nr.Status, len(nr.Attempts))
+	}
+	if err := nr.Attempts[0].Err; err == nil || err.Error() != "Node n failed: Before attempt 1: no temp dir" {
+		t.Errorf("Expected the first attempt to fail with the hook's error, got %v", err)
+	}
+	want := []string{
+		"before 1",
+		"after 1: Node n failed: Before attempt 1: no temp dir",
+		"before 2",
+		"fn 2",
+		"after 2: <nil>",
+	}
+	if !reflect.DeepEqual(seq.calls, want) {
+		t.Errorf("Expected the fn skipped on the failed attempt\n%q\ngot\n%q", want, seq.calls)
+	}
+}
+
+func TestAfterAttemptRunsAfterPanic(t *testing.T) {
+	seq := &sequence{}
+	nr := runOne(t, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		if ec.Attempt == 1 {
+			panic("boom")
+		}
+		return nil, nil
+	}, seq.hooks(nil))
+
+	if nr.Status != graph.StatusSucceeded || len(nr.Attempts) != 2 {
+		t.Fatalf("Expected the retry to succeed, got %s after %d", nr.Status, len(nr.Attempts))
+	}
+	if len(seq.calls) != 4 || seq.calls[1] == "after 1: <nil>" {
+		t.Errorf("Expected AfterAttempt to see the recovered panic as an error, got %q", seq.calls)
+	}
+	if nr.Attempts[0].Category != graph.CategoryPanicked {
+		t.Errorf("Expected the first attempt marked panicked, got %s", nr.Attempts[0].Category)
+	}
+}
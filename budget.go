@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"time"
+)
+
+// How long the fn has left before the soonest of its node timeout, the run's WithRunTimeout,
+// its soft deadline and any deadline on the ctx the run was given, timed by the run's clock.
+// It's worked out when the attempt starts from the same settings that bound the fn's ctx, though
+// reaching the soft deadline doesn't interrupt the fn. False when nothing bounds the attempt;
+// zero once the time is up.
+func (ec *ExecutionContext) RemainingBudget() (time.Duration, bool) {
+	if ec.deadline.IsZero() {
+		return 0, false
+	}
+
+	left := ec.deadline.Sub(ec.clock.Now())
+	if left < 0 {
+		left = 0
+	}
+	return left, true
+}
+
+// The soonest deadline bounding an attempt that starts at now; zero if there's none
+func (r *run) budget(node *executableNode, now time.Time) time.Time {
+	soonest := time.Time{}
+	consider := func(t time.Time) {
+		if !t.IsZero() && (soonest.IsZero() || t.Before(soonest)) {
+			soonest = t
+		}
+	}
+
+	if node.timeout > 0 {
+		consider(now.Add(node.timeout))
+	}
+	consider(r.runDeadline)
+	consider(r.cfg.SoftDeadline)
+	// The caller's deadline is in real time, so it's carried over as what's left of it. The
+	// run's own ctx reports the run timeout as a deadline too, but that's counted above.
+	if !r.callerDeadline.IsZero() {
+		consider(now.Add(time.Until(r.callerDeadline)))
+	}
+	return soonest
+}
@@ -0,0 +1,130 @@
+package graph_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+type budget struct {
+	left time.Duration
+	ok   bool
+	// What the fn's ctx said was left, by the wall clock
+	ctxLeft time.Duration
+	ctxOk   bool
+}
+
+// A node that records its budget when it starts; sent on the channel it returns
+func budgeted(deps graph.NodeIDs, opts ...graph.NodeOption) (*graph.Node, <-chan budget) {
+	seen := make(chan budget, 1)
+	return graph.NewNode("call", deps, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		b := budget{}
+		b.left, b.ok = ec.RemainingBudget()
+		if d, ok := ctx.Deadline(); ok {
+			b.ctxLeft, b.ctxOk = time.Until(d), true
+		}
+		seen <- b
+		return nil, nil
+	}, opts...), seen
+}
+
+func TestRemainingBudgetInsideRunTimeout(t *testing.T) {
+	clock := newFakeClock()
+	g := graph.NewGraph("budget")
+	g.Add(graph.NewNode("warmup", nil, takes(clock, 6*time.Second)))
+	call, seen := budgeted(graph.Deps("warmup"), graph.WithTimeout(10*time.Second))
+	g.Add(call)
+
+	done := runAsync(g.CompileToExecutable(), graph.WithClock(clock), graph.WithRunTimeout(10*time.Second))
+	// The run timeout and warmup
+	clock.waitFor(t, 2)
+	clock.Advance(6 * time.Second)
+
+	if b := <-seen; !b.ok || b.left != 4*time.Second {
+		t.Errorf("Expected the 4s left of the run to bound the 10s node timeout, got %s, %t", b.left, b.ok)
+	}
+	if r := <-done; r.err != nil {
+		t.Fatal(r.err)
+	}
+}
+
+func TestRemainingBudgetSoonestDeadline(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		nodeOpts []graph.NodeOption
+		execOpts func(clock *fakeClock) []graph.ExecOption
+		want     time.Duration
+	}{
+		{"node timeout", []graph.NodeOption{graph.WithTimeout(10 * time.Second)}, func(clock *fakeClock) []graph.ExecOption {
+			return []graph.ExecOption{graph.WithRunTimeout(time.Minute)}
+		}, 10 * time.Second},
+		{"run timeout", []graph.NodeOption{graph.WithTimeout(time.Minute)}, func(clock *fakeClock) []graph.ExecOption {
+			return []graph.ExecOption{graph.WithRunTimeout(20 * time.Second)}
+		}, 20 * time.Second},
+		{"soft deadline", []graph.NodeOption{graph.WithTimeout(time.Minute)}, func(clock *fakeClock) []graph.ExecOption {
+			return []graph.ExecOption{graph.WithSoftDeadline(clock.Now().Add(5 * time.Second))}
+		}, 5 * time.Second},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := newFakeClock()
+			call, seen := budgeted(nil, tc.nodeOpts...)
+			g := graph.NewGraph("budget")
+			g.Add(call)
+
+			if _, err := g.CompileToExecutable().Run(ctx(t), append(tc.execOpts(clock), graph.WithClock(clock))...); err != nil {
+				t.Fatal(err)
+			}
+			if b := <-seen; !b.ok || b.left != tc.want {
+				t.Errorf("Expected %s left, got %s, %t", tc.want, b.left, b.ok)
+			}
+		})
+	}
+}
+
+func TestRemainingBudgetUnbounded(t *testing.T) {
+	call, seen := budgeted(nil)
+	g := graph.NewGraph("budget")
+	g.Add(call)
+	if _, err := g.CompileToExecutable().Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if b := <-seen; b.ok || b.ctxOk {
+		t.Errorf("Expected no budget and no ctx deadline, got %+v", b)
+	}
+}
+
+// The fn's ctx carries the same deadline, apart from the soft deadline, which doesn't stop the fn
+func TestRemainingBudgetMatchesCtxDeadline(t *testing.T) {
+	for name, opts := range map[string][]graph.ExecOption{
+		"node timeout": {graph.WithRunTimeout(2 * time.Hour)},
+		"run timeout":  {graph.WithRunTimeout(30 * time.Minute)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			call, seen := budgeted(nil, graph.WithTimeout(time.Hour))
+			g := graph.NewGraph("budget")
+			g.Add(call)
+			if _, err := g.CompileToExecutable().Run(ctx(t), opts...); err != nil {
+				t.Fatal(err)
+			}
+
+			b := <-seen
+			if diff := b.left - b.ctxLeft; !b.ok || !b.ctxOk || diff < -time.Second || diff > time.Second {
+				t.Errorf("Expected the budget to match the ctx deadline, got %s and %s", b.left, b.ctxLeft)
+			}
+		})
+	}
+
+	parent, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	call, seen := budgeted(nil, graph.WithTimeout(time.Hour))
+	g := graph.NewGraph("budget")
+	g.Add(call)
+	if _, err := g.CompileToExecutable().Run(parent); err != nil {
+		t.Fatal(err)
+	}
+	if b := <-seen; b.left > 10*time.Minute || b.ctxLeft > 10*time.Minute {
+		t.Errorf("Expected the caller's deadline to bound both, got %s and %s", b.left, b.ctxLeft)
+	}
+}
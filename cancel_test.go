@@ -0,0 +1,112 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A fn that signals started and then waits for its ctx
+func blockUntilCanceled(started chan<- graph.NodeID) graph.NodeFn {
+	return func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		started <- ec.ID
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+}
+
+func expectSettled(t *testing.T, report *graph.Report, total int) map[graph.NodeStatus]int {
+	t.Helper()
+
+	counts := map[graph.NodeStatus]int{}
+	for id, nr := range report.Nodes {
+		switch nr.Status {
+		case graph.StatusPending, graph.StatusRunning:
+			t.Errorf("Expected %s settled, got %s", id, nr.Status)
+		}
+		counts[nr.Status]++
+	}
+
+	sum := 0
+	for _, n := range counts {
+		sum += n
+	}
+	if sum != total {
+		t.Errorf("Expected %d nodes in the report, got %d", total, sum)
+	}
+	return counts
+}
+
+func TestCancelReportsPartialProgress(t *testing.T) {
+	started := make(chan graph.NodeID, 1)
+	g := graph.NewGraph("cancel")
+	g.Add(graph.NewNode("quick", nil, graph.NoOp()))
+	g.Add(graph.NewNode("stuck", graph.Deps("quick"), blockUntilCanceled(started)))
+	g.Add(graph.NewNode("after", graph.Deps("stuck"), graph.NoOp()))
+
+	c, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	report, err := g.CompileToExecutable().Run(c)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the ctx error, got %v", err)
+	}
+	if report == nil {
+		t.Fatal("Expected a report with the error")
+	}
+
+	expectSettled(t, report, 3)
+	for id, want := range map[graph.NodeID]graph.NodeStatus{
+		"quick": graph.StatusSucceeded,
+		"stuck": graph.StatusCanceled,
+		"after": graph.StatusNotRun,
+	} {
+		if got := report.Nodes[id].Status; got != want {
+			t.Errorf("Expected %s to be %s, got %s", id, want, got)
+		}
+	}
+}
+
+func TestCancelWithQueuedNodes(t *testing.T) {
+	const roots, limit = 50, 4
+	started := make(chan graph.NodeID, roots)
+	g := graph.NewGraph("wide")
+	for i := 0; i < roots; i++ {
+		g.Add(graph.NewNode(fmt.Sprintf("n%d", i), nil, blockUntilCanceled(started)))
+	}
+
+	c, cancel := context.WithCancel(context.Background())
+	go func() {
+		for i := 0; i < limit; i++ {
+			<-started
+		}
+		cancel()
+	}()
+
+	report, err := g.CompileToExecutable().Run(c, graph.WithMaxConcurrency(limit))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the ctx error, got %v", err)
+	}
+
+	counts := expectSettled(t, report, roots)
+	if counts[graph.StatusCanceled] != limit || counts[graph.StatusNotRun] != roots-limit {
+		t.Errorf("Expected %d canceled and %d not run, got %v", limit, roots-limit, counts)
+	}
+}
+
+func TestCancelBeforeRun(t *testing.T) {
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := diamond(t).CompileToExecutable().Run(c)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the ctx error, got %v", err)
+	}
+	expectSettled(t, report, 4)
+}
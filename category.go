@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"errors"
+)
+
+// What kind of failure a NodeError is. The executor sets the predefined categories; fns can
+// return errors implementing Categorizer to use their own.
+type ErrorCategory string
+
+const (
+	// The fn returned an error with no category of its own
+	CategoryFn       ErrorCategory = "fn"
+	CategoryCanceled ErrorCategory = "canceled"
+	CategoryTimedOut ErrorCategory = "timed-out"
+	CategoryPanicked ErrorCategory = "panicked"
+	// For errors retrying can't fix; never retried by default
+	CategoryPermanent ErrorCategory = "permanent"
+	// An assertion node's check failed
+	CategoryAssertionFailed ErrorCategory = "assertion-failed"
+)
+
+type Categorizer interface {
+	Category() ErrorCategory
+}
+
+// The category of an error returned by a fn
+func categorize(err error) ErrorCategory {
+	var c Categorizer
+	if errors.As(err, &c) {
+		if category := c.Category(); category != "" {
+			return category
+		}
+	}
+	return CategoryFn
+}
+
+// Wraps err so it's categorized as permanent
+func Permanent(err error) error {
+	return &categorizedError{category: CategoryPermanent, err: err}
+}
+
+type categorizedError struct {
+	category ErrorCategory
+	err      error
+}
+
+func (e *categorizedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *categorizedError) Unwrap() error {
+	return e.err
+}
+
+func (e *categorizedError) Category() ErrorCategory {
+	return e.category
+}
+
+func (p RetryPolicy) retries(category ErrorCategory) bool {
+	if category == CategoryCanceled {
+		return false
+	}
+	if len(p.RetryOn) == 0 {
+		return category != CategoryPermanent
+	}
+
+	for _, c := range p.RetryOn {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+type quotaError struct{}
+
+func (quotaError) Error() string                 { return "quota exceeded" }
+func (quotaError) Category() graph.ErrorCategory { return "quota" }
+
+// Runs fn as the graph's only node and returns its report
+func runOne(t *testing.T, fn graph.NodeFn, nodeOpts []graph.NodeOption, opts ...graph.ExecOption) *graph.NodeReport {
+	t.Helper()
+
+	g := graph.NewGraph("categories")
+	g.Add(graph.NewNode("n", nil, fn, nodeOpts...))
+	report, _ := g.CompileToExecutable().Run(context.Background(), opts...)
+	return report.Nodes["n"]
+}
+
+func expectCategory(t *testing.T, nr *graph.NodeReport, category graph.ErrorCategory) {
+	t.Helper()
+
+	if nr.Category != category {
+		t.Errorf("Expected the report to say %s, got %q", category, nr.Category)
+	}
+	var nerr *graph.NodeError
+	if !errors.As(nr.Err, &nerr) {
+		t.Fatalf("Expected a NodeError, got %v", nr.Err)
+	}
+	if nerr.Category != category {
+		t.Errorf("Expected the error to say %s, got %q", category, nerr.Category)
+	}
+}
+
+func TestCategoryFn(t *testing.T) {
+	expectCategory(t, runOne(t, failWith(errors.New("boom")), nil), graph.CategoryFn)
+}
+
+func TestCategoryPanicked(t *testing.T) {
+	nr := runOne(t, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		panic("boom")
+	}, nil)
+	expectCategory(t, nr, graph.CategoryPanicked)
+}
+
+func TestCategoryTimedOut(t *testing.T) {
+	clock := newFakeClock()
+	done := make(chan *graph.NodeReport, 1)
+	go func() {
+		done <- runOne(t, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}, []graph.NodeOption{graph.WithTimeout(time.Second)}, graph.WithClock(clock))
+	}()
+
+	clock.waitFor(t, 1)
+	clock.Advance(2 * time.Second)
+	expectCategory(t, <-done, graph.CategoryTimedOut)
+}
+
+func TestCategoryCanceled(t *testing.T) {
+	g := graph.NewGraph("categories")
+	started := make(chan struct{})
+	g.Add(graph.NewNode("n", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+
+	c, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	report, _ := g.CompileToExecutable().Run(c)
+	expectCategory(t, report.Nodes["n"], graph.CategoryCanceled)
+}
+
+func TestCategoryFromCategorizer(t *testing.T) {
+	nr := runOne(t, failWith(quotaError{}), nil)
+	expectCategory(t, nr, "quota")
+}
+
+func TestPermanentErrorSkipsRetries(t *testing.T) {
+	var calls atomic.Int32
+	fn := func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		calls.Add(1)
+		return nil, graph.Permanent(errors.New("bad config"))
+	}
+
+	nr := runOne(t, fn, []graph.NodeOption{graph.WithRetry(graph.RetryPolicy{MaxAttempts: 3})})
+	expectCategory(t, nr, graph.CategoryPermanent)
+	if calls.Load() != 1 || len(nr.Attempts) != 1 {
+		t.Errorf("Expected a permanent error to run once, got %d calls", calls.Load())
+	}
+}
+
+func TestRetryOnlyListedCategories(t *testing.T) {
+	var calls atomic.Int32
+	fn := func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		if calls.Add(1) == 1 {
+			return nil, quotaError{}
+		}
+		return nil, errors.New("other")
+	}
+
+	policy := graph.RetryPolicy{MaxAttempts: 5, RetryOn: []graph.ErrorCategory{"quota"}}
+	nr := runOne(t, fn, []graph.NodeOption{graph.WithRetry(policy)})
+	// The quota error is retried, the uncategorized one after it isn't
+	if calls.Load() != 2 {
+		t.Errorf("Expected 2 attempts, got %d", calls.Load())
+	}
+	expectCategory(t, nr, graph.CategoryFn)
+}
@@ -0,0 +1,41 @@
+package graph
+
+import (
+	"context"
+	"time"
+)
+
+const defaultCancelGrace = 5 * time.Second
+
+// Cleanup for a node whose attempt was interrupted by cancellation or its timeout
+type CancelFn func(ctx context.Context, id NodeID) error
+
+// Registers fn to run after an attempt of the node is canceled or times out, never after it
+// succeeds or fails. fn gets a fresh ctx that expires after grace (5s by default); its error is
+// recorded in the report as the node's CleanupErr.
+func WithOnCancel(fn CancelFn, grace ...time.Duration) NodeOption {
+	return func(n *Node) {
+		n.onCancel = fn
+		n.cancelGrace = defaultCancelGrace
+		if len(grace) > 0 {
+			n.cancelGrace = grace[0]
+		}
+	}
+}
+
+func (r *run) cleanup(node *executableNode, id NodeID, a AttemptReport) error {
+	if node.onCancel == nil {
+		return nil
+	}
+	if a.Category != CategoryCanceled && a.Category != CategoryTimedOut {
+		return nil
+	}
+
+	ctx, cancel := withClockTimeout(context.Background(), r.clock(), node.cancelGrace)
+	defer cancel()
+
+	if err := node.onCancel(ctx, id); err != nil {
+		return &NodeError{ID: id, Attempt: a.Attempt, Err: err}
+	}
+	return nil
+}
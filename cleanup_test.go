@@ -0,0 +1,84 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A CancelFn that counts its calls and returns err
+func countCleanups(calls *atomic.Int32, err error) graph.CancelFn {
+	return func(ctx context.Context, id graph.NodeID) error {
+		calls.Add(1)
+		if ctx.Err() != nil {
+			return errors.New("Expected a live grace ctx")
+		}
+		return err
+	}
+}
+
+func TestOnCancelRunsOnceWhenCanceled(t *testing.T) {
+	var sleeping, quick atomic.Int32
+	started := make(chan graph.NodeID, 1)
+	g := graph.NewGraph("cleanup")
+	g.Add(graph.NewNode("quick", nil, graph.NoOp(), graph.WithOnCancel(countCleanups(&quick, nil))))
+	g.Add(graph.NewNode("sleeping", graph.Deps("quick"), blockUntilCanceled(started), graph.WithOnCancel(countCleanups(&sleeping, nil))))
+
+	c, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	report, _ := g.CompileToExecutable().Run(c)
+
+	if sleeping.Load() != 1 {
+		t.Errorf("Expected the canceled node's cleanup to run once, got %d", sleeping.Load())
+	}
+	if quick.Load() != 0 {
+		t.Errorf("Expected the succeeded node's cleanup never to run, got %d", quick.Load())
+	}
+	if err := report.Nodes["sleeping"].CleanupErr; err != nil {
+		t.Errorf("Expected no cleanup error, got %v", err)
+	}
+}
+
+func TestOnCancelNotOnFailure(t *testing.T) {
+	var calls atomic.Int32
+	g := graph.NewGraph("cleanup")
+	g.Add(graph.NewNode("failing", nil, failWith(errors.New("boom")), graph.WithOnCancel(countCleanups(&calls, nil))))
+
+	g.CompileToExecutable().Run(ctx(t))
+	if calls.Load() != 0 {
+		t.Errorf("Expected no cleanup after an ordinary failure, got %d", calls.Load())
+	}
+}
+
+func TestOnCancelOnTimeout(t *testing.T) {
+	clock := newFakeClock()
+	var calls atomic.Int32
+	g := graph.NewGraph("cleanup")
+	g.Add(graph.NewNode("slow", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, graph.WithTimeout(time.Second), graph.WithOnCancel(countCleanups(&calls, errors.New("leaked")))))
+
+	done := runAsync(g.CompileToExecutable(), graph.WithClock(clock))
+	clock.waitFor(t, 1)
+	clock.Advance(2 * time.Second)
+	report := (<-done).report
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected the timed out node's cleanup to run once, got %d", calls.Load())
+	}
+	slow := report.Nodes["slow"]
+	if slow.CleanupErr == nil {
+		t.Error("Expected the cleanup error recorded")
+	}
+	if slow.Status != graph.StatusTimedOut {
+		t.Errorf("Expected the cleanup error to leave the status alone, got %s", slow.Status)
+	}
+}
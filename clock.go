@@ -0,0 +1,59 @@
+package graph
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time for timers and timestamps, so tests can substitute a fake one
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Like context.WithTimeout, but d runs out by clock, so a fake clock drives it. Deadline reports
+// the wall-clock time d from now, as ctx deadlines are read as real time.
+func withClockTimeout(ctx context.Context, clock Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	deadline := time.Now().Add(d)
+	cctx, cancel := context.WithCancelCause(ctx)
+	expired := clock.After(d)
+	go func() {
+		select {
+		case <-expired:
+			cancel(context.DeadlineExceeded)
+		case <-cctx.Done():
+		}
+	}()
+	return &clockTimeoutCtx{Context: cctx, deadline: deadline}, func() { cancel(nil) }
+}
+
+type clockTimeoutCtx struct {
+	context.Context
+	deadline time.Time
+}
+
+func (c *clockTimeoutCtx) Deadline() (time.Time, bool) {
+	if parent, ok := c.Context.Deadline(); ok && parent.Before(c.deadline) {
+		return parent, true
+	}
+	return c.deadline, true
+}
+
+// DeadlineExceeded once d has run out, as from context.WithTimeout
+func (c *clockTimeoutCtx) Err() error {
+	err := c.Context.Err()
+	if err != nil && context.Cause(c.Context) == context.DeadlineExceeded {
+		return context.DeadlineExceeded
+	}
+	return err
+}
@@ -0,0 +1,31 @@
+package graph
+
+import (
+	"errors"
+)
+
+// Registers fn to release a resource the graph's fns use. The graph owns its closers: clones,
+// snapshots and compiled graphs don't carry them, and a compiled graph run after Close sees
+// whatever the closers left behind.
+func (g *Graph) OnClose(fn func() error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.closers = append(g.closers, fn)
+}
+
+// Runs the closers registered since the last Close, most recent first, and joins their errors.
+// Closing again only runs closers registered in between, so a second Close is safe.
+func (g *Graph) Close() error {
+	g.mu.Lock()
+	closers := g.closers
+	g.closers = nil
+	g.mu.Unlock()
+
+	// Without the lock, so closers may use the graph
+	errs := make([]error, 0, len(closers))
+	for i := len(closers) - 1; i >= 0; i-- {
+		errs = append(errs, closers[i]())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,96 @@
+package graph_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestCloseRunsClosersInReverse(t *testing.T) {
+	g := diamond(t)
+	order := []string{}
+	for _, name := range []string{"file", "conn", "cache"} {
+		name := name
+		g.OnClose(func() error {
+			order = append(order, name)
+			return nil
+		})
+	}
+
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"cache", "conn", "file"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Expected closers run last first %v, got %v", want, order)
+	}
+}
+
+func TestCloseJoinsErrors(t *testing.T) {
+	g := diamond(t)
+	errFile, errConn := errors.New("close file"), errors.New("close conn")
+	ran := 0
+	g.OnClose(func() error { ran++; return errFile })
+	g.OnClose(func() error { ran++; return nil })
+	g.OnClose(func() error { ran++; return errConn })
+
+	err := g.Close()
+	if !errors.Is(err, errFile) || !errors.Is(err, errConn) {
+		t.Errorf("Expected both errors joined, got %v", err)
+	}
+	if ran != 3 {
+		t.Errorf("Expected every closer run despite the errors, ran %d", ran)
+	}
+	if err.Error() != "close conn\nclose file" {
+		t.Errorf("Expected the errors in the order the closers ran, got %q", err)
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	g := diamond(t)
+	calls := 0
+	g.OnClose(func() error { calls++; return nil })
+
+	g.Close()
+	if err := g.Close(); err != nil || calls != 1 {
+		t.Errorf("Expected a second Close to do nothing, got %v after %d calls", err, calls)
+	}
+
+	// A closer registered after Close runs on the next one
+	g.OnClose(func() error { calls++; return nil })
+	g.Close()
+	if calls != 2 {
+		t.Errorf("Expected the new closer run, got %d calls", calls)
+	}
+	if err := graph.NewGraph("empty").Close(); err != nil {
+		t.Errorf("Expected nothing to close to be fine, got %v", err)
+	}
+}
+
+func TestClosersMayUseTheGraph(t *testing.T) {
+	g := diamond(t)
+	g.OnClose(func() error {
+		_, err := g.Sort()
+		return err
+	})
+	if err := g.Close(); err != nil {
+		t.Errorf("Expected a closer to read the graph without deadlocking, got %v", err)
+	}
+}
+
+func TestClosersStayWithTheGraph(t *testing.T) {
+	g := diamond(t)
+	closed := 0
+	g.OnClose(func() error { closed++; return nil })
+
+	peg := g.CompileToExecutable()
+	g.Clone().Close()
+	if closed != 0 {
+		t.Error("Expected a clone to carry no closers")
+	}
+	g.Close()
+	if _, err := peg.Run(ctx(t)); err != nil || closed != 1 {
+		t.Errorf("Expected the compiled graph to still run after Close, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func main() {
+	g := graph.NewGraph("my-graph")
+
+	doodad := func() graph.NodeFn {
+		return graph.FromIDFunc(func(name graph.NodeID) error {
+			fmt.Printf("Running node %s\n", name)
+			return nil
+		})
+	}
+
+	// Create a bunch of new nodes
+	n1 := graph.NewNode("a", graph.NodeIDs{}, doodad())
+	n3 := graph.NewNode("b", graph.NodeIDs{n1.Identifier(): {}}, doodad())
+	n2 := graph.NewNode("c", graph.NodeIDs{n3.Identifier(): {}}, doodad())
+	n4 := graph.NewNode("d", graph.NodeIDs{n2.Identifier(): {}}, doodad())
+	n5 := graph.NewNode("e", graph.NodeIDs{}, doodad())
+
+	// Add all the nodes
+	_, err := g.Add(n1)
+	_, err = g.Add(n3)
+	_, err = g.Add(n2)
+	_, err = g.Add(n4)
+	_, err = g.Add(n5)
+
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	wf := g.CompileToExecutable()
+	if _, err := wf.Run(context.Background()); err != nil {
+		fmt.Println(err.Error())
+	}
+}
@@ -0,0 +1,38 @@
+package graph
+
+// Go maps keep the room they grew to after entries are deleted, so a long-lived graph that has
+// had many nodes removed holds memory sized for its peak. Compact rebuilds the graph's maps at
+// their current size and returns how many more nodes they had grown to hold than the graph has
+// now. It takes the write lock, so call it between runs and mutations; compiled graphs and
+// snapshots keep their own maps and are unaffected.
+func (g *Graph) Compact() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	reclaimed := g.peakNodes - len(g.nodes)
+	if reclaimed < 0 {
+		reclaimed = 0
+	}
+
+	if g.shared {
+		// Owning the map already copies it
+		g.own()
+	} else {
+		nodes := make(Nodes, len(g.nodes))
+		for id, node := range g.nodes {
+			nodes[id] = node
+		}
+		g.nodes = nodes
+		g.borrowed = copyMap(g.borrowed)
+	}
+	g.aliases = copyMap(g.aliases)
+	// Counted again by the next Remove
+	g.dependents = nil
+	if g.order != nil {
+		// The order held no cycle before, so it can't hold one now
+		g.rebuildOrder()
+	}
+
+	g.peakNodes = len(g.nodes)
+	return reclaimed
+}
@@ -0,0 +1,95 @@
+package graph_test
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Adds n unconnected nodes and removes all but keep of them
+func churned(t testing.TB, n, keep int, opts ...graph.GraphOption) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("churn", opts...)
+	for i := 0; i < n; i++ {
+		if _, err := g.Add(graph.NewNode(fmt.Sprintf("n%d", i), nil, graph.NoOp())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := keep; i < n; i++ {
+		if err := g.Remove(graph.NodeID(fmt.Sprintf("n%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+func heapInUse() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+func TestCompactReclaimsMaps(t *testing.T) {
+	g := churned(t, 100000, 5000)
+
+	before := heapInUse()
+	if reclaimed := g.Compact(); reclaimed != 95000 {
+		t.Errorf("Expected room for 95000 nodes reclaimed, got %d", reclaimed)
+	}
+	after := heapInUse()
+	runtime.KeepAlive(g)
+
+	t.Logf("heap %d KiB before Compact, %d KiB after", before/1024, after/1024)
+	// The node map alone had grown to hold 100k entries
+	if before < after || before-after < 1<<20 {
+		t.Errorf("Expected Compact to free at least 1 MiB, heap went from %d to %d bytes", before, after)
+	}
+
+	if reclaimed := g.Compact(); reclaimed != 0 {
+		t.Errorf("Expected nothing left to reclaim, got %d", reclaimed)
+	}
+}
+
+func TestCompactKeepsTheGraph(t *testing.T) {
+	g := churned(t, 1000, 10, graph.WithIncrementalCycleCheck())
+	g.Add(graph.NewNode("after", graph.Deps("n0", "n9"), graph.NoOp()))
+	g.Alias("first", "n0")
+
+	g.Compact()
+	if ids := mustSort(t, g); len(ids) != 11 || !before(ids, "n0", "after") || !before(ids, "n9", "after") {
+		t.Errorf("Expected the same nodes and edges after Compact, got %v", ids)
+	}
+	if got := g.ResolveAlias("first"); got != "n0" {
+		t.Errorf("Expected the alias kept, got %s", got)
+	}
+	// The cycle check still works on the rebuilt order
+	if err := g.AddEdge("n0", "after"); err == nil {
+		t.Error("Expected the incremental check to still reject a cycle")
+	}
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompactLeavesSnapshotsAlone(t *testing.T) {
+	g := churned(t, 100, 10)
+	snapshot := g.Snapshot()
+	g.Compact()
+	g.Add(graph.NewNode("new", nil, graph.NoOp()))
+	if snapshot.Has("new") || len(mustSort(t, snapshot)) != 10 {
+		t.Error("Expected the snapshot unaffected by Compact and later adds")
+	}
+}
+
+func BenchmarkCompactAfterChurn(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		g := churned(b, 100000, 5000)
+		b.StartTimer()
+		g.Compact()
+	}
+}
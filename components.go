@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// Components splits the graph into its weakly connected components: sets of nodes joined by
+// edges in either direction, counting edges through aliases and selectors. Each comes back as a
+// graph of its own holding copies of its nodes, along with the aliases and entry points that
+// lead to them and the graph's settings, so it compiles and runs without the others. Components
+// are named after the graph with a "-1", "-2" suffix and ordered by their smallest node id.
+func (g *Graph) Components() []*Graph {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	components := g.components()
+	graphs := make([]*Graph, 0, len(components))
+	for i, ids := range components {
+		graphs = append(graphs, g.subgraph(fmt.Sprintf("%s-%d", g.name, i+1), ids))
+	}
+	return graphs
+}
+
+// How many graphs Components would return, without copying anything
+func (g *Graph) ComponentCount() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return len(g.components())
+}
+
+func (g *Graph) components() []NodeIDs {
+	neighbors := make(map[NodeID][]NodeID, len(g.nodes))
+	for id, node := range g.nodes {
+		for depId := range g.dependencies(node) {
+			// Missing dependencies, allowed by WithLazyAdd, join nothing
+			if _, ok := g.nodes[depId]; ok {
+				neighbors[id] = append(neighbors[id], depId)
+				neighbors[depId] = append(neighbors[depId], id)
+			}
+		}
+	}
+
+	components := []NodeIDs{}
+	seen := make(NodeIDs, len(g.nodes))
+	for _, id := range sortedIDs(g.nodes) {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+
+		component := NodeIDs{id: {}}
+		seen[id] = struct{}{}
+		for queue := []NodeID{id}; len(queue) > 0; queue = queue[1:] {
+			for _, other := range neighbors[queue[0]] {
+				if _, ok := seen[other]; !ok {
+					seen[other] = struct{}{}
+					component[other] = struct{}{}
+					queue = append(queue, other)
+				}
+			}
+		}
+		components = append(components, component)
+	}
+	return components
+}
@@ -0,0 +1,136 @@
+package graph_test
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Three clusters: a diamond-ish build, a docs pair and a lone cleanup
+func clusters(l *runLog) *graph.Graph {
+	g := graph.NewGraph("monorepo")
+	g.Add(graph.NewNode("build", nil, l.fn, graph.WithMetadata("team", "core")))
+	g.Add(graph.NewNode("test", graph.Deps("build"), l.fn))
+	g.Add(graph.NewNode("lint", nil, l.fn))
+	g.Add(graph.NewNode("release", graph.Deps("test"), l.fn, graph.WithSoftDependency("lint")))
+	g.Add(graph.NewNode("docs", nil, l.fn))
+	g.Add(graph.NewNode("publish-docs", graph.Deps("docs"), l.fn))
+	g.Add(graph.NewNode("cleanup", nil, l.fn))
+	return g
+}
+
+func TestComponentsPartition(t *testing.T) {
+	l := &runLog{}
+	g := clusters(l)
+
+	components := g.Components()
+	if n := g.ComponentCount(); n != 3 || len(components) != 3 {
+		t.Fatalf("Expected 3 components, got %d and %d", n, len(components))
+	}
+
+	want := []graph.SortedNodeIDs{
+		{"build", "lint", "release", "test"},
+		{"cleanup"},
+		{"docs", "publish-docs"},
+	}
+	all := graph.SortedNodeIDs{}
+	for i, c := range components {
+		ids := mustSort(t, c)
+		all = append(all, ids...)
+		sorted := append(graph.SortedNodeIDs{}, ids...)
+		sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+		if !reflect.DeepEqual(sorted, want[i]) {
+			t.Errorf("Expected component %d to hold %v, got %v", i+1, want[i], sorted)
+		}
+	}
+	sort.Slice(all, func(a, b int) bool { return all[a] < all[b] })
+	if original := graph.SortedFrom(graph.Deps(mustSort(t, g)...)); !reflect.DeepEqual(all, original) {
+		t.Errorf("Expected the components to cover every node once, got %v", all)
+	}
+
+	// Edges don't cross components, so together they have all of them
+	edges := []graph.Edge{}
+	for _, c := range components {
+		edges = append(edges, c.Edges()...)
+	}
+	if got := sortEdges(edges); !reflect.DeepEqual(got, g.Edges()) {
+		t.Errorf("Expected the components' edges to be the graph's, got %v", got)
+	}
+}
+
+func TestComponentsRunIndependently(t *testing.T) {
+	l := &runLog{}
+	g := clusters(l)
+
+	for i, c := range g.Components() {
+		l.ids = nil
+		report, err := c.CompileToExecutable().Run(ctx(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("monorepo-%d", i+1); report.Graph != want {
+			t.Errorf("Expected the component named %s, got %s", want, report.Graph)
+		}
+		if len(l.ids) != len(report.Nodes) {
+			t.Errorf("Expected the original fns to run, got %v for %d nodes", l.ids, len(report.Nodes))
+		}
+	}
+
+	build := mustGet(t, g.Components()[0], "build")
+	if build.Metadata["team"] != "core" {
+		t.Errorf("Expected metadata kept, got %v", build.Metadata)
+	}
+	if kind := mustGet(t, g.Components()[0], "release").DependencyKind("lint"); kind != graph.EdgeSoft {
+		t.Errorf("Expected the soft edge kept, got %v", kind)
+	}
+}
+
+func TestComponentsAreCopies(t *testing.T) {
+	g := clusters(&runLog{})
+	fingerprint := g.Fingerprint()
+
+	c := g.Components()[1]
+	c.Add(graph.NewNode("extra", graph.Deps("cleanup"), graph.NoOp()))
+	if err := c.Remove("extra"); err != nil {
+		t.Fatal(err)
+	}
+	c.Remove("cleanup")
+	if g.Fingerprint() != fingerprint || !g.Has("cleanup") {
+		t.Error("Expected changes to a component to leave the graph alone")
+	}
+}
+
+func TestComponentsJoinThroughAliasesAndSelectors(t *testing.T) {
+	g := graph.NewGraph("joined", graph.WithLazyAdd())
+	g.Add(graph.NewNode("db", nil, graph.NoOp(), graph.WithMetadata("kind", "store")))
+	g.Add(graph.NewNode("cache", nil, graph.NoOp(), graph.WithMetadata("kind", "store")))
+	g.Add(graph.NewNode("warm", nil, graph.NoOp(), graph.WithDependencySelector("kind", "store")))
+	g.Alias("storage", "db")
+	g.Add(graph.NewNode("api", graph.Deps("storage"), graph.NoOp()))
+	// A missing dependency joins nothing
+	g.Add(graph.NewNode("orphan", graph.Deps("later"), graph.NoOp()))
+
+	components := g.Components()
+	if len(components) != 2 {
+		t.Fatalf("Expected 2 components, got %d", len(components))
+	}
+	if got := components[0].ResolveAlias("storage"); got != "db" {
+		t.Errorf("Expected the alias carried with its node, got %s", got)
+	}
+	if deps, _ := components[0].ResolvedDependencies("warm"); !deps.Equal(graph.Deps("db", "cache")) {
+		t.Errorf("Expected the selector to resolve in its component, got %v", deps)
+	}
+	if !components[1].Has("orphan") {
+		t.Error("Expected the node with a missing dependency on its own")
+	}
+}
+
+func TestComponentsOfEmptyGraph(t *testing.T) {
+	g := graph.NewGraph("empty")
+	if got := g.Components(); len(got) != 0 || g.ComponentCount() != 0 {
+		t.Errorf("Expected no components, got %d", len(got))
+	}
+}
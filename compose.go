@@ -0,0 +1,99 @@
+package graph
+
+import (
+	"fmt"
+)
+
+type NamedFn struct {
+	Name    string
+	Fn      NodeFn
+	Options []NodeOption
+}
+
+// A piece of a graph built by Compose: a NamedFn, a Series or a Parallel
+type Step interface {
+	// Adds the step after the given nodes and returns the nodes it ends with
+	compose(c *composer, after NodeIDs) (NodeIDs, error)
+}
+
+type series []Step
+
+type parallel []Step
+
+// Runs the steps one after another; each starts once everything in the one before has finished
+func Series(steps ...Step) Step {
+	return series(steps)
+}
+
+// Runs the steps independently of each other
+func Parallel(steps ...Step) Step {
+	return parallel(steps)
+}
+
+type composer struct {
+	g    *Graph
+	used NodeIDs
+}
+
+// Builds a graph from nested Series and Parallel steps, such as
+// Series(a, Parallel(b, c), d). A name used more than once gets a numeric suffix, so the second
+// "test" becomes "test-2"; an empty name counts as "step".
+func Compose(name string, step Step, opts ...GraphOption) (*Graph, error) {
+	c := &composer{g: NewGraph(name, opts...), used: NodeIDs{}}
+	if _, err := step.compose(c, nil); err != nil {
+		return nil, err
+	}
+	return c.g, nil
+}
+
+func (c *composer) unique(name string) NodeID {
+	if name == "" {
+		name = "step"
+	}
+
+	id := NodeID(name)
+	for i := 2; c.used.Has(id); i++ {
+		id = NodeID(fmt.Sprintf("%s-%d", name, i))
+	}
+	c.used[id] = struct{}{}
+	return id
+}
+
+func (f NamedFn) compose(c *composer, after NodeIDs) (NodeIDs, error) {
+	node := NewNode(string(c.unique(f.Name)), Deps(sortedIDs(after)...), f.Fn, f.Options...)
+	id, err := c.g.Add(node)
+	if err != nil {
+		return nil, err
+	}
+	return Deps(id), nil
+}
+
+func (s series) compose(c *composer, after NodeIDs) (NodeIDs, error) {
+	for _, step := range s {
+		var err error
+		if after, err = step.compose(c, after); err != nil {
+			return nil, err
+		}
+	}
+	return after, nil
+}
+
+// A Parallel with nothing in it ends where it started; empty steps inside one add nothing
+func (p parallel) compose(c *composer, after NodeIDs) (NodeIDs, error) {
+	var ends NodeIDs
+	for _, step := range p {
+		added := len(c.used)
+		stepEnds, err := step.compose(c, after)
+		if err != nil {
+			return nil, err
+		}
+		if len(c.used) > added {
+			ends = ends.Union(stepEnds)
+		}
+	}
+
+	if ends == nil {
+		return after, nil
+	}
+	return ends, nil
+}
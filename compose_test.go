@@ -0,0 +1,109 @@
+package graph_test
+
+import (
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func step(l *runLog, name string) graph.NamedFn {
+	return graph.NamedFn{Name: name, Fn: l.fn}
+}
+
+func TestComposeNested(t *testing.T) {
+	l := &runLog{}
+	g, err := graph.Compose("script", graph.Series(
+		step(l, "a"),
+		graph.Parallel(step(l, "b"), graph.Series(step(l, "c1"), step(l, "c2"))),
+		step(l, "d"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []graph.Edge{
+		{From: "b", To: "a"},
+		{From: "c1", To: "a"},
+		{From: "c2", To: "c1"},
+		{From: "d", To: "b"},
+		{From: "d", To: "c2"},
+	}
+	if got := g.Edges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected edges %v, got %v", want, got)
+	}
+
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+	ran := graph.SortedNodeIDs(l.ids)
+	if len(ran) != 5 {
+		t.Fatalf("Expected every step to run once, got %v", ran)
+	}
+	for _, e := range want {
+		if !before(ran, e.To, e.From) {
+			t.Errorf("Expected %s to run before %s, got %v", e.To, e.From, ran)
+		}
+	}
+}
+
+func TestComposeUniqueNames(t *testing.T) {
+	l := &runLog{}
+	g, err := graph.Compose("script", graph.Series(
+		step(l, "test"),
+		graph.Parallel(step(l, "test"), step(l, ""), step(l, "")),
+		graph.Series(step(l, "test")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []graph.Edge{
+		{From: "step", To: "test"},
+		{From: "step-2", To: "test"},
+		{From: "test-2", To: "test"},
+		{From: "test-3", To: "step"},
+		{From: "test-3", To: "step-2"},
+		{From: "test-3", To: "test-2"},
+	}
+	if got := g.Edges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected edges %v, got %v", want, got)
+	}
+}
+
+func TestComposeEmptySteps(t *testing.T) {
+	l := &runLog{}
+	g, err := graph.Compose("script", graph.Series(
+		step(l, "a"),
+		graph.Parallel(),
+		graph.Parallel(graph.Series(), step(l, "b")),
+		graph.Series(),
+		step(l, "c"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []graph.Edge{
+		{From: "b", To: "a"},
+		{From: "c", To: "b"},
+	}
+	if got := g.Edges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the empty steps to add no edges, got %v", got)
+	}
+}
+
+func TestComposeKeepsOptionsAndGraphOptions(t *testing.T) {
+	g, err := graph.Compose("script", graph.Series(
+		graph.NamedFn{Name: "a", Fn: graph.NoOp(), Options: []graph.NodeOption{graph.WithMetadata("team", "data")}},
+	), graph.WithNamespace("ops"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node := mustGet(t, g, "a"); node.Metadata["team"] != "data" {
+		t.Errorf("Expected the step's options applied, got %v", node.Metadata)
+	}
+	if got := g.Namespace(); got != "ops" {
+		t.Errorf("Expected the graph options applied, got namespace %q", got)
+	}
+}
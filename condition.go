@@ -0,0 +1,45 @@
+package graph
+
+import (
+	"context"
+)
+
+// Decides, from the results of a node's dependencies, keyed as the fn would see them, whether it
+// runs. It's called from the run's goroutine once the node is ready.
+type ConditionFn func(results Results) bool
+
+// Runs the node only when cond says so; otherwise it's Skipped with reason condition-false and
+// its dependents run without its result. name identifies cond in JSON definitions, which bind
+// it back to a fn through LoadConditions.
+func WithCondition(name string, cond ConditionFn) NodeOption {
+	return func(n *Node) {
+		n.conditionName, n.condition = name, cond
+	}
+}
+
+// The conditions a definition's nodes may name, by name
+func LoadConditions(conditions map[string]ConditionFn) LoadOption {
+	return func(c *loadConfig) {
+		if c.conditions == nil {
+			c.conditions = make(map[string]ConditionFn, len(conditions))
+		}
+		for name, cond := range conditions {
+			c.conditions[name] = cond
+		}
+	}
+}
+
+func (r *run) conditionMet(id NodeID) bool {
+	node := r.peg.nodes[id]
+	return node.condition == nil || node.condition(r.dependencyResults(node))
+}
+
+func (r *run) settleConditionFalse(ctx context.Context, id NodeID) {
+	now := r.clock().Now()
+	nr := NodeReport{ID: id, Status: StatusSkipped, Reason: ReasonConditionFalse, Start: now, End: now}
+	*r.report.Nodes[id] = nr
+	r.cfg.State.record(nr, nil)
+	r.finished(nr)
+
+	r.release(ctx, id, true)
+}
@@ -0,0 +1,80 @@
+package graph_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Runs only when its dependency found something to do
+func hasWork(results graph.Results) bool {
+	return results["scan"] != 0
+}
+
+// scan finds count items; process is conditional on there being any, and report needs process
+func conditional(count int, seen *sync.Map) *graph.Graph {
+	g := graph.NewGraph("batch")
+	g.Add(graph.NewNode("scan", nil, returns(count)))
+	g.Add(graph.NewNode("process", graph.Deps("scan"), returns("processed"), graph.WithCondition("has-work", hasWork)))
+	g.Add(graph.NewNode("report", graph.Deps("process"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		seen.Store("process", ec.Results["process"])
+		return nil, nil
+	}))
+	return g
+}
+
+func TestConditionFalseSkipsNode(t *testing.T) {
+	seen := &sync.Map{}
+	report, err := conditional(0, seen).CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nr := report.Nodes["process"]; nr.Status != graph.StatusSkipped || nr.Reason != graph.ReasonConditionFalse {
+		t.Errorf("Expected process skipped with condition-false, got %s/%s", nr.Status, nr.Reason)
+	}
+	if nr := report.Nodes["report"]; nr.Status != graph.StatusSucceeded {
+		t.Errorf("Expected report to run anyway, got %s/%s", nr.Status, nr.Reason)
+	}
+	if result, ok := seen.Load("process"); !ok || result != nil {
+		t.Errorf("Expected report to get a nil result, got %v", result)
+	}
+}
+
+func TestConditionTrueRuns(t *testing.T) {
+	seen := &sync.Map{}
+	report, err := conditional(3, seen).CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nr := report.Nodes["process"]; nr.Status != graph.StatusSucceeded {
+		t.Errorf("Expected process to run, got %s/%s", nr.Status, nr.Reason)
+	}
+	if result, _ := seen.Load("process"); result != "processed" {
+		t.Errorf("Expected report to get process's result, got %v", result)
+	}
+	if settings := effective(t, conditional(3, seen), "process"); settings.Condition != "has-work" {
+		t.Errorf("Expected the condition's name in the settings, got %q", settings.Condition)
+	}
+}
+
+func TestConditionSeesAliasedResults(t *testing.T) {
+	var got any
+	g := graph.NewGraph("batch")
+	g.Add(graph.NewNode("scan", nil, returns(2)))
+	g.Alias("source", "scan")
+	g.Add(graph.NewNode("process", graph.Deps("source"), graph.NoOp(), graph.WithCondition("peek", func(results graph.Results) bool {
+		got = results["source"]
+		return true
+	})))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+	if got != 2 {
+		t.Errorf("Expected the condition to see the result under the alias, got %v", got)
+	}
+}
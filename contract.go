@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrContractViolation = errors.New("Contract violation")
+
+// What a sub-graph may depend on from outside and what it must contain
+type Contract struct {
+	Requires []NodeID
+	Provides []NodeID
+}
+
+type ContractViolationError struct {
+	Node NodeID
+	// The external dependency the contract doesn't allow; empty when Node is a missing provided node
+	Dependency NodeID
+}
+
+func (e *ContractViolationError) Error() string {
+	if e.Dependency == "" {
+		return fmt.Sprintf("%s: node %s is provided by the contract but not in the graph", ErrContractViolation, e.Node)
+	}
+	return fmt.Sprintf("%s: node %s depends on %s, which the contract doesn't require", ErrContractViolation, e.Node, e.Dependency)
+}
+
+func (e *ContractViolationError) Is(target error) bool {
+	return target == ErrContractViolation
+}
+
+// CheckContract returns every violation of c, joined. Hard and soft dependencies must exist when
+// a node is added, so the external dependencies are the optional ones the graph doesn't contain.
+func (g *Graph) CheckContract(c Contract) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	requires := make(NodeIDs, len(c.Requires))
+	for _, id := range c.Requires {
+		requires[id] = struct{}{}
+	}
+
+	errs := []error{}
+	for _, id := range sortedIDs(g.nodes) {
+		for _, depId := range sortedIDs(g.nodes[id].optionalDependencies) {
+			if g.exists(depId) {
+				continue
+			}
+			if _, ok := requires[depId]; !ok {
+				errs = append(errs, &ContractViolationError{Node: id, Dependency: depId})
+			}
+		}
+	}
+
+	for _, id := range c.Provides {
+		if !g.exists(id) {
+			errs = append(errs, &ContractViolationError{Node: id})
+		}
+	}
+
+	return errors.Join(errs...)
+}
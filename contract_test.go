@@ -0,0 +1,89 @@
+package graph_test
+
+import (
+	"errors"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A team's sub-graph: "report" optionally depends on the externally provided "warehouse"
+func subgraph(t *testing.T) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("analytics")
+	g.Add(graph.NewNode("extract", nil, graph.NoOp(), graph.WithOptionalDependency("warehouse")))
+	g.Add(graph.NewNode("report", graph.Deps("extract"), graph.NoOp(), graph.WithOptionalDependency("warehouse"), graph.WithOptionalDependency("billing")))
+	return g
+}
+
+func violations(t *testing.T, err error) []*graph.ContractViolationError {
+	t.Helper()
+
+	if !errors.Is(err, graph.ErrContractViolation) {
+		t.Fatalf("Expected ErrContractViolation, got %v", err)
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Expected a joined error, got %T", err)
+	}
+
+	out := []*graph.ContractViolationError{}
+	for _, e := range joined.Unwrap() {
+		var v *graph.ContractViolationError
+		if !errors.As(e, &v) {
+			t.Fatalf("Expected a ContractViolationError, got %T", e)
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func TestContractSatisfied(t *testing.T) {
+	c := graph.Contract{Requires: []graph.NodeID{"warehouse", "billing"}, Provides: []graph.NodeID{"report"}}
+	if err := subgraph(t).CheckContract(c); err != nil {
+		t.Errorf("Expected the contract to hold, got %v", err)
+	}
+}
+
+func TestContractUndeclaredDependency(t *testing.T) {
+	c := graph.Contract{Requires: []graph.NodeID{"warehouse"}}
+
+	got := violations(t, subgraph(t).CheckContract(c))
+	if len(got) != 1 || got[0].Node != "report" || got[0].Dependency != "billing" {
+		t.Errorf("Expected report's dependency on billing flagged, got %v", got)
+	}
+}
+
+func TestContractMissingProvided(t *testing.T) {
+	c := graph.Contract{Requires: []graph.NodeID{"warehouse", "billing"}, Provides: []graph.NodeID{"report", "dashboard"}}
+
+	got := violations(t, subgraph(t).CheckContract(c))
+	if len(got) != 1 || got[0].Node != "dashboard" || got[0].Dependency != "" {
+		t.Errorf("Expected the missing dashboard flagged, got %v", got)
+	}
+}
+
+func TestContractListsEveryViolation(t *testing.T) {
+	c := graph.Contract{Provides: []graph.NodeID{"dashboard"}}
+
+	// extract and report on warehouse, report on billing, and the missing dashboard
+	if got := violations(t, subgraph(t).CheckContract(c)); len(got) != 4 {
+		t.Errorf("Expected 4 violations, got %v", got)
+	}
+}
+
+func TestContractClosedAfterMerge(t *testing.T) {
+	g := subgraph(t)
+	provider := graph.NewGraph("platform")
+	provider.Add(graph.NewNode("warehouse", nil, graph.NoOp()))
+	provider.Add(graph.NewNode("billing", nil, graph.NoOp()))
+	if err := g.Merge(provider); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dependencies now inside the graph need no declaring
+	if err := g.CheckContract(graph.Contract{Provides: []graph.NodeID{"report", "warehouse"}}); err != nil {
+		t.Errorf("Expected the composition to be closed, got %v", err)
+	}
+}
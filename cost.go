@@ -0,0 +1,61 @@
+package graph
+
+// What the node takes out of the run's WithBudget while it runs; zero or less means the default
+// of 1. Markers cost nothing.
+func WithCost(n int) NodeOption {
+	return func(node *Node) {
+		node.cost = n
+	}
+}
+
+// Admits ready nodes only while the costs of the running ones plus theirs fit in n. A node that
+// doesn't fit waits for room while nodes that were ready alongside it fill the gap; nodes that
+// become ready after it wait their turn, so a costly node isn't starved by cheaper ones. A node
+// costing more than the whole budget runs alone. Zero means no budget.
+func WithBudget(n int) ExecOption {
+	return func(c *ExecConfig) {
+		c.Budget = n
+	}
+}
+
+func (node *executableNode) costs() int {
+	switch {
+	case node.marker:
+		return 0
+	case node.cost < 1:
+		return 1
+	}
+	return node.cost
+}
+
+func (r *run) fits(id NodeID) bool {
+	return r.cfg.Budget <= 0 || r.spent == 0 || r.spent+r.peg.nodes[id].costs() <= r.cfg.Budget
+}
+
+// Takes the next node to dispatch, or returns false if there's no room for it. A node that
+// doesn't fit is held until it does; the nodes that were already waiting when it was held may go
+// ahead of it meanwhile, but ones that become ready later queue behind it.
+func (r *run) admit() (NodeID, bool) {
+	if r.held == "" {
+		id := r.next()
+		if r.fits(id) {
+			return id, true
+		}
+		r.held, r.bypasses = id, len(r.ready)
+	} else if r.fits(r.held) {
+		id := r.held
+		r.held = ""
+		return id, true
+	}
+
+	if r.bypasses == 0 || len(r.ready) == 0 {
+		return "", false
+	}
+	id := r.next()
+	if !r.fits(id) {
+		r.requeue(id)
+		return "", false
+	}
+	r.bypasses--
+	return id, true
+}
@@ -0,0 +1,160 @@
+package graph_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Tracks which nodes run at once and what they cost together
+type spending struct {
+	mu      sync.Mutex
+	running map[graph.NodeID]int
+	peak    int
+	// The nodes each node saw running when it started
+	alongside map[graph.NodeID][]graph.NodeID
+	started   []graph.NodeID
+}
+
+func (s *spending) fn(cost int, hold time.Duration) graph.NodeFn {
+	return func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		s.mu.Lock()
+		if s.running == nil {
+			s.running, s.alongside = map[graph.NodeID]int{}, map[graph.NodeID][]graph.NodeID{}
+		}
+		spent := cost
+		for id, c := range s.running {
+			spent += c
+			s.alongside[ec.ID] = append(s.alongside[ec.ID], id)
+			s.alongside[id] = append(s.alongside[id], ec.ID)
+		}
+		s.running[ec.ID] = cost
+		s.started = append(s.started, ec.ID)
+		if spent > s.peak {
+			s.peak = spent
+		}
+		s.mu.Unlock()
+
+		time.Sleep(hold)
+
+		s.mu.Lock()
+		delete(s.running, ec.ID)
+		s.mu.Unlock()
+		return nil, nil
+	}
+}
+
+func (s *spending) overlapped(a, b graph.NodeID) bool {
+	for _, id := range s.alongside[a] {
+		if id == b {
+			return true
+		}
+	}
+	return false
+}
+
+func costly(s *spending, g *graph.Graph, id string, cost int, deps ...graph.NodeID) {
+	g.Add(graph.NewNode(id, graph.Deps(deps...), s.fn(cost, 20*time.Millisecond), graph.WithCost(cost)))
+}
+
+func TestBudgetAdmission(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		s := &spending{}
+		g := graph.NewGraph("budget")
+		costly(s, g, "big-a", 6)
+		costly(s, g, "big-b", 6)
+		costly(s, g, "small", 3)
+
+		if _, err := g.CompileToExecutable().Run(ctx(t), graph.WithBudget(10)); err != nil {
+			t.Fatal(err)
+		}
+		if s.overlapped("big-a", "big-b") {
+			t.Fatal("Expected the two 6s never to overlap")
+		}
+		if !s.overlapped("small", "big-a") && !s.overlapped("small", "big-b") {
+			t.Fatal("Expected the 3 to overlap one of the 6s")
+		}
+		if s.peak > 10 {
+			t.Fatalf("Expected at most 10 in flight, got %d", s.peak)
+		}
+	}
+}
+
+func TestBudgetDefaultCost(t *testing.T) {
+	s := &spending{}
+	g := graph.NewGraph("budget")
+	for _, id := range []string{"a", "b", "c", "d"} {
+		g.Add(graph.NewNode(id, nil, s.fn(1, 10*time.Millisecond)))
+	}
+	g.Add(graph.NewNode("zero", nil, s.fn(1, 10*time.Millisecond), graph.WithCost(0)))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t), graph.WithBudget(2)); err != nil {
+		t.Fatal(err)
+	}
+	if s.peak != 2 {
+		t.Errorf("Expected nodes without a cost to cost 1 and two to run at once, got a peak of %d", s.peak)
+	}
+}
+
+func TestBudgetOversizedNodeRunsAlone(t *testing.T) {
+	s := &spending{}
+	g := graph.NewGraph("budget")
+	costly(s, g, "huge", 15)
+	costly(s, g, "a", 1)
+	costly(s, g, "b", 1)
+
+	report, err := g.CompileToExecutable().Run(ctx(t), graph.WithBudget(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nr := report.Nodes["huge"]; nr.Status != graph.StatusSucceeded {
+		t.Fatalf("Expected the node over budget to run, got %s", nr.Status)
+	}
+	if len(s.alongside["huge"]) != 0 {
+		t.Errorf("Expected the node over budget to run alone, it overlapped %v", s.alongside["huge"])
+	}
+}
+
+// A costly node waiting for room isn't passed by cheap nodes that become ready after it
+func TestBudgetDoesNotStarveCostlyNode(t *testing.T) {
+	s := &spending{}
+	g := graph.NewGraph("budget")
+	costly(s, g, "first", 2)
+	costly(s, g, "wide", 3)
+	// Each cheap node readies the next, so without the hold there's always one to fit
+	prev := graph.NodeID("first")
+	for _, id := range []string{"c1", "c2", "c3", "c4"} {
+		costly(s, g, id, 2, prev)
+		prev = graph.NodeID(id)
+	}
+
+	if _, err := g.CompileToExecutable().Run(ctx(t), graph.WithBudget(4), graph.WithDeterministicScheduling(1)); err != nil {
+		t.Fatal(err)
+	}
+	ran := graph.SortedNodeIDs(s.started)
+	if !before(ran, "wide", "c2") {
+		t.Errorf("Expected the costly node to get room before the chain ran on, got %v", ran)
+	}
+	if s.peak > 4 {
+		t.Errorf("Expected at most 4 in flight, got %d", s.peak)
+	}
+}
+
+func TestBudgetMarkersCostNothing(t *testing.T) {
+	s := &spending{}
+	g := graph.NewGraph("budget")
+	costly(s, g, "a", 1)
+	g.Add(graph.NewNode("barrier", graph.Deps("a"), graph.NoOp(), graph.AsMarker(), graph.WithCost(5)))
+	costly(s, g, "b", 1, "barrier")
+
+	report, err := g.CompileToExecutable().Run(ctx(t), graph.WithBudget(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nr := report.Nodes["b"]; nr.Status != graph.StatusSucceeded {
+		t.Errorf("Expected the run to pass the marker, got %s for b", nr.Status)
+	}
+}
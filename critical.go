@@ -0,0 +1,88 @@
+package graph
+
+import (
+	"time"
+)
+
+// Work that ran in parallel relative to the wall time, TotalWork / Duration
+func (r *Report) Speedup() float64 {
+	if r.Duration() <= 0 {
+		return 0
+	}
+	return float64(r.TotalWork) / float64(r.Duration())
+}
+
+// How close the run came to its critical path, CriticalPathDuration / Duration; 1 means the
+// wall time was spent entirely on the critical path
+func (r *Report) Efficiency() float64 {
+	if r.Duration() <= 0 {
+		return 0
+	}
+	return float64(r.CriticalPathDuration) / float64(r.Duration())
+}
+
+// Fills in the report's work and critical path from the measured node durations, plus edge
+// weights when the run prices them. Nodes that never ran count for nothing and break any chain
+// through them, except markers, which pass the chain on.
+func (r *run) analyze() {
+	ran := func(id NodeID) bool {
+		return len(r.report.Nodes[id].Attempts) > 0
+	}
+	marker := func(id NodeID) bool {
+		return r.report.Nodes[id].Reason == ReasonMarker
+	}
+
+	finish := make(map[NodeID]time.Duration, len(r.report.Nodes))
+	prev := map[NodeID]NodeID{}
+
+	var visit func(id NodeID) time.Duration
+	visit = func(id NodeID) time.Duration {
+		if d, ok := finish[id]; ok {
+			return d
+		}
+		if !ran(id) && !marker(id) {
+			finish[id] = 0
+			return 0
+		}
+
+		var longest time.Duration
+		node := r.peg.nodes[id]
+		for _, depId := range sortedIDs(node.dependencies) {
+			d := visit(depId)
+			if ran(depId) || marker(depId) {
+				d += time.Duration(node.weights[depId] * float64(r.cfg.EdgeWeightCost))
+			}
+			if d > longest {
+				longest = d
+				prev[id] = depId
+			}
+		}
+
+		finish[id] = longest + r.report.Nodes[id].Duration()
+		return finish[id]
+	}
+
+	var end NodeID
+	r.report.TotalWork = 0
+	for _, id := range sortedIDs(r.report.Nodes) {
+		if !ran(id) {
+			continue
+		}
+
+		r.report.TotalWork += r.report.Nodes[id].Duration()
+		if d := visit(id); end == "" || d > finish[end] {
+			end = id
+		}
+	}
+
+	path := []NodeID{}
+	if end != "" {
+		for id, ok := end, true; ok; id, ok = prev[id] {
+			if !marker(id) {
+				path = append([]NodeID{id}, path...)
+			}
+		}
+		r.report.CriticalPathDuration = finish[end]
+	}
+	r.report.CriticalPath = path
+}
@@ -0,0 +1,122 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A fn that takes d by clock
+func takes(clock *fakeClock, d time.Duration) graph.NodeFn {
+	return func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		<-clock.After(d)
+		return nil, nil
+	}
+}
+
+// a feeds b and c, which both feed d, with b and c given their own fns
+func timedDiamond(clock *fakeClock, b, c graph.NodeFn) *graph.Graph {
+	g := graph.NewGraph("timed")
+	g.Add(graph.NewNode("a", nil, takes(clock, 10*time.Second)))
+	g.Add(graph.NewNode("b", graph.Deps("a"), b))
+	g.Add(graph.NewNode("c", graph.Deps("a"), c))
+	g.Add(graph.NewNode("d", graph.Deps("b", "c"), takes(clock, 10*time.Second)))
+	return g
+}
+
+// Hooks sending each node to the channel as it finishes, so the test can wait for a node's end
+// to be timed before moving the clock on
+func finishes() (graph.ExecOption, chan graph.NodeID) {
+	ch := make(chan graph.NodeID, 16)
+	return graph.WithHooks(graph.Hooks{OnNodeResult: func(id graph.NodeID, result graph.NodeReport) {
+		ch <- id
+	}}), ch
+}
+
+func awaitFinish(t *testing.T, finished chan graph.NodeID, id graph.NodeID) {
+	t.Helper()
+
+	for {
+		select {
+		case got := <-finished:
+			if got == id {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected %s to finish", id)
+		}
+	}
+}
+
+func TestCriticalPathOnDiamond(t *testing.T) {
+	clock := newFakeClock()
+	g := timedDiamond(clock, takes(clock, 20*time.Second), takes(clock, 40*time.Second))
+	hooks, finished := finishes()
+	done := runAsync(g.CompileToExecutable(), graph.WithClock(clock), hooks)
+
+	clock.waitFor(t, 1)
+	clock.Advance(10 * time.Second)
+	// b and c
+	clock.waitFor(t, 2)
+	clock.Advance(20 * time.Second)
+	awaitFinish(t, finished, "b")
+	clock.Advance(20 * time.Second)
+	clock.waitFor(t, 1)
+	clock.Advance(10 * time.Second)
+
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	report := res.report
+
+	if report.TotalWork != 80*time.Second {
+		t.Errorf("Expected 80s of work, got %s", report.TotalWork)
+	}
+	if report.CriticalPathDuration != 60*time.Second {
+		t.Errorf("Expected a 60s critical path, got %s", report.CriticalPathDuration)
+	}
+	if want := []graph.NodeID{"a", "c", "d"}; !reflect.DeepEqual(report.CriticalPath, want) {
+		t.Errorf("Expected the critical path %v, got %v", want, report.CriticalPath)
+	}
+	if report.Duration() != 60*time.Second {
+		t.Errorf("Expected 60s of wall time, got %s", report.Duration())
+	}
+	if got := report.Speedup(); got != 80.0/60.0 {
+		t.Errorf("Expected a speedup of 4/3, got %v", got)
+	}
+	if got := report.Efficiency(); got != 1 {
+		t.Errorf("Expected an efficiency of 1, got %v", got)
+	}
+}
+
+func TestCriticalPathSkipsNodesThatNeverRan(t *testing.T) {
+	clock := newFakeClock()
+	g := timedDiamond(clock, takes(clock, 20*time.Second), failWith(errors.New("boom")))
+	done := runAsync(g.CompileToExecutable(), graph.WithClock(clock))
+
+	clock.waitFor(t, 1)
+	clock.Advance(10 * time.Second)
+	clock.waitFor(t, 1)
+	clock.Advance(20 * time.Second)
+
+	report := (<-done).report
+	if report.Nodes["d"].Status == graph.StatusSucceeded {
+		t.Fatal("Expected d not to run")
+	}
+
+	// c failed instantly, so it adds nothing; d never ran
+	if report.TotalWork != 30*time.Second {
+		t.Errorf("Expected 30s of work, got %s", report.TotalWork)
+	}
+	if want := []graph.NodeID{"a", "b"}; !reflect.DeepEqual(report.CriticalPath, want) {
+		t.Errorf("Expected the critical path %v, got %v", want, report.CriticalPath)
+	}
+	if report.CriticalPathDuration != 30*time.Second {
+		t.Errorf("Expected a 30s critical path, got %s", report.CriticalPathDuration)
+	}
+}
@@ -0,0 +1,308 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var ErrCycle = errors.New("Cycle detected")
+
+type CycleError struct {
+	// Starts and ends on the same node; each node depends on the next
+	Path []NodeID
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrCycle, joinPath(e.Path))
+}
+
+func (e *CycleError) Is(target error) bool {
+	return target == ErrCycle
+}
+
+func joinPath(path []NodeID) string {
+	s := ""
+	for i, id := range path {
+		if i > 0 {
+			s += " -> "
+		}
+		s += string(id)
+	}
+	return s
+}
+
+// Makes Add, AddEdge, Alias and Merge reject changes that would create a cycle. The graph keeps a
+// topological order up to date incrementally (Pearce-Kelly), so each insert only visits the
+// nodes between the edge's endpoints in that order. Without it, cycles are found by Sort.
+func WithIncrementalCycleCheck() GraphOption {
+	return func(g *Graph) {
+		g.order = newTopoOrder()
+	}
+}
+
+// A topological order of the graph's resolved edges, dependencies first
+type topoOrder struct {
+	ord        map[NodeID]int
+	next       int
+	dependents map[NodeID]NodeIDs
+	// Optional dependency ids, as declared, to the nodes declaring them
+	optionalRefs map[NodeID]NodeIDs
+	// Nodes with dependency selectors, the only ones a newly added node can gain as dependents
+	// without being named
+	selecting NodeIDs
+}
+
+func newTopoOrder() *topoOrder {
+	return &topoOrder{
+		ord:          make(map[NodeID]int),
+		dependents:   make(map[NodeID]NodeIDs),
+		optionalRefs: make(map[NodeID]NodeIDs),
+		selecting:    make(NodeIDs),
+	}
+}
+
+func (t *topoOrder) link(from, to NodeID) {
+	if t.dependents[to] == nil {
+		t.dependents[to] = make(NodeIDs)
+	}
+	t.dependents[to][from] = struct{}{}
+}
+
+// Builds the order from scratch; fails if g has a cycle
+func (g *Graph) rebuildOrder() error {
+	t := newTopoOrder()
+	pending := make(map[NodeID]int, len(g.nodes))
+	queue := SortedNodeIDs{}
+
+	for _, id := range sortedIDs(g.nodes) {
+		node := g.nodes[id]
+		deps := g.dependencies(node)
+		for depId := range deps {
+			t.link(id, depId)
+			// Missing dependencies, allowed by WithLazyAdd, are ordered once they're added
+			if _, ok := g.nodes[depId]; ok {
+				pending[id]++
+			}
+		}
+		for depId := range node.optionalDependencies {
+			t.ref(id, depId)
+		}
+		if len(node.selectors) > 0 {
+			t.selecting[id] = struct{}{}
+		}
+		if pending[id] == 0 {
+			queue = append(queue, id)
+		}
+	}
+
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		t.ord[id] = t.next
+		t.next++
+		for _, dependent := range sortedIDs(t.dependents[id]) {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	if len(t.ord) < len(g.nodes) {
+		return stuckCycle(pending, func(id NodeID) NodeIDs { return g.dependencies(g.nodes[id]) })
+	}
+
+	g.order = t
+	return nil
+}
+
+func (t *topoOrder) ref(id, optional NodeID) {
+	if t.optionalRefs[optional] == nil {
+		t.optionalRefs[optional] = make(NodeIDs)
+	}
+	t.optionalRefs[optional][id] = struct{}{}
+}
+
+// Places a newly added node, then binds the optional edges of other nodes that now resolve to it.
+// On error the node is taken out of the order again.
+func (g *Graph) orderAdd(node *Node) error {
+	t := g.order
+	id := node.Identifier()
+	// Nodes that depended on id before it was added
+	waiting := t.dependents[id]
+	t.dependents[id] = nil
+
+	t.ord[id] = t.next
+	t.next++
+	for depId := range g.dependencies(node) {
+		t.link(id, depId)
+	}
+	for depId := range node.optionalDependencies {
+		t.ref(id, depId)
+	}
+	if len(node.selectors) > 0 {
+		t.selecting[id] = struct{}{}
+	}
+
+	// Those, nodes whose selectors match it, and optional edges declared on id itself or on an
+	// alias of it
+	referrers := NodeIDs{}
+	for ref := range waiting {
+		referrers[ref] = struct{}{}
+	}
+	for ref := range t.selecting {
+		if g.nodes[ref].selects(node) {
+			referrers[ref] = struct{}{}
+		}
+	}
+	for ref := range t.optionalRefs[id] {
+		referrers[ref] = struct{}{}
+	}
+	for alias := range g.aliases {
+		if g.resolve(alias) == id {
+			for ref := range t.optionalRefs[alias] {
+				referrers[ref] = struct{}{}
+			}
+		}
+	}
+
+	for _, ref := range sortedIDs(referrers) {
+		if ref == id {
+			continue
+		}
+		if err := t.insert(ref, id, g); err != nil {
+			g.orderRemove(node)
+			t.dependents[id] = waiting
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Graph) orderRemove(node *Node) {
+	t := g.order
+	id := node.Identifier()
+
+	delete(t.ord, id)
+	delete(t.dependents, id)
+	delete(t.selecting, id)
+	for depId := range g.dependencies(node) {
+		delete(t.dependents[depId], id)
+	}
+	for depId := range node.optionalDependencies {
+		delete(t.optionalRefs[depId], id)
+	}
+}
+
+// Every node Kahn's algorithm couldn't place still waits on another such node, so following
+// those dependencies from any of them must come back around
+func stuckCycle(pending map[NodeID]int, dependencies func(id NodeID) NodeIDs) error {
+	stuck := SortedNodeIDs{}
+	for id, count := range pending {
+		if count > 0 {
+			stuck = append(stuck, id)
+		}
+	}
+	sortIDs(stuck)
+
+	path := []NodeID{}
+	at := map[NodeID]int{}
+	for id := stuck[0]; ; {
+		if i, ok := at[id]; ok {
+			return &CycleError{Path: append(path[i:], id)}
+		}
+		at[id] = len(path)
+		path = append(path, id)
+
+		for _, depId := range sortedIDs(dependencies(id)) {
+			if pending[depId] > 0 {
+				id = depId
+				break
+			}
+		}
+	}
+}
+
+// Adds the edge from -> to (from depends on to), reordering the affected region, or returns a
+// CycleError when to already depends on from.
+func (t *topoOrder) insert(from, to NodeID, g *Graph) error {
+	if from == to {
+		return &CycleError{Path: []NodeID{from, from}}
+	}
+
+	lower, upper := t.ord[from], t.ord[to]
+	if upper < lower {
+		t.link(from, to)
+		return nil
+	}
+
+	// Dependents of from that sit at or before to in the order
+	forward := []NodeID{}
+	parent := map[NodeID]NodeID{}
+	seen := NodeIDs{from: {}}
+	stack := []NodeID{from}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		forward = append(forward, id)
+
+		for dependent := range t.dependents[id] {
+			if dependent == to {
+				path := []NodeID{from, to}
+				for at := id; at != from; at = parent[at] {
+					path = append(path, at)
+				}
+				return &CycleError{Path: append(path, from)}
+			}
+			if _, ok := seen[dependent]; ok || t.ord[dependent] > upper {
+				continue
+			}
+			seen[dependent] = struct{}{}
+			parent[dependent] = id
+			stack = append(stack, dependent)
+		}
+	}
+
+	// Dependencies of to that sit at or after from in the order
+	backward := []NodeID{}
+	seen = NodeIDs{to: {}}
+	stack = []NodeID{to}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		backward = append(backward, id)
+
+		for depId := range g.dependencies(g.nodes[id]) {
+			if _, ok := g.nodes[depId]; !ok {
+				continue
+			}
+			if _, ok := seen[depId]; ok || t.ord[depId] < lower {
+				continue
+			}
+			seen[depId] = struct{}{}
+			stack = append(stack, depId)
+		}
+	}
+
+	// Everything to needs goes first, then everything that needs from, reusing their slots
+	byOrd := func(ids []NodeID) {
+		sort.Slice(ids, func(i, j int) bool { return t.ord[ids[i]] < t.ord[ids[j]] })
+	}
+	byOrd(backward)
+	byOrd(forward)
+
+	moved := append(backward, forward...)
+	slots := make([]int, len(moved))
+	for i, id := range moved {
+		slots[i] = t.ord[id]
+	}
+	sort.Ints(slots)
+	for i, id := range moved {
+		t.ord[id] = slots[i]
+	}
+
+	t.link(from, to)
+	return nil
+}
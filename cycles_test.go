@@ -0,0 +1,137 @@
+package graph_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Checks path is a cycle of real dependencies once from depends on to
+func expectCyclePath(t *testing.T, g *graph.Graph, err error, from, to graph.NodeID) {
+	t.Helper()
+
+	var cerr *graph.CycleError
+	if !errors.As(err, &cerr) || !errors.Is(err, graph.ErrCycle) {
+		t.Fatalf("Expected a CycleError, got %v", err)
+	}
+	path := cerr.Path
+	if len(path) < 2 || path[0] != path[len(path)-1] {
+		t.Fatalf("Expected a path that starts and ends on the same node, got %v", path)
+	}
+
+	for i := 0; i+1 < len(path); i++ {
+		if path[i] == from && path[i+1] == to {
+			continue
+		}
+		deps, _ := g.ResolvedDependencies(path[i])
+		if _, ok := deps[path[i+1]]; !ok {
+			t.Errorf("Expected %s to depend on %s in %v", path[i], path[i+1], path)
+		}
+	}
+}
+
+func TestIncrementalCycleCheckRejectsEdge(t *testing.T) {
+	g := diamond(t, graph.WithIncrementalCycleCheck())
+
+	err := g.AddEdge("a", "d")
+	expectCyclePath(t, g, err, "a", "d")
+
+	// The graph is left as it was
+	if deps, _ := g.ResolvedDependencies("a"); len(deps) != 0 {
+		t.Errorf("Expected the rejected edge left out, got %v", deps)
+	}
+	if _, err := g.Sort(); err != nil {
+		t.Errorf("Expected the graph to still sort, got %v", err)
+	}
+
+	// Edges that keep it acyclic are still fine
+	g.Add(graph.NewNode("e", nil, graph.NoOp()))
+	if err := g.AddEdge("a", "e"); err != nil {
+		t.Errorf("Expected an acyclic edge accepted, got %v", err)
+	}
+	if err := g.AddEdge("e", "d"); err == nil {
+		t.Error("Expected an edge through e back to d rejected")
+	}
+}
+
+func TestIncrementalCycleCheckSelfEdge(t *testing.T) {
+	g := diamond(t, graph.WithIncrementalCycleCheck())
+	if err := g.AddEdge("b", "b"); !errors.Is(err, graph.ErrCycle) {
+		t.Errorf("Expected a self edge rejected as a cycle, got %v", err)
+	}
+}
+
+func TestCyclesDeferredByDefault(t *testing.T) {
+	g := diamond(t)
+	if err := g.AddEdge("a", "d"); err != nil {
+		t.Fatalf("Expected AddEdge to accept the edge without the check, got %v", err)
+	}
+
+	if _, err := g.Sort(); err == nil {
+		t.Error("Expected Sort to find the cycle")
+	}
+}
+
+func TestIncrementalCycleCheckMatchesSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const n = 60
+
+	checked := graph.NewGraph("checked", graph.WithIncrementalCycleCheck())
+	for i := 0; i < n; i++ {
+		checked.Add(graph.NewNode(fmt.Sprintf("n%d", i), nil, graph.NoOp()))
+	}
+
+	for i := 0; i < 500; i++ {
+		from := graph.NodeID(fmt.Sprintf("n%d", rng.Intn(n)))
+		to := graph.NodeID(fmt.Sprintf("n%d", rng.Intn(n)))
+
+		// The same edge on a copy without the check, where Sort decides
+		plain := graph.NewGraph("plain")
+		plain.Merge(checked)
+		plain.AddEdge(from, to)
+		_, sortErr := plain.Sort()
+
+		err := checked.AddEdge(from, to)
+		if (err != nil) != (sortErr != nil) {
+			t.Fatalf("Expected AddEdge(%s, %s) to agree with Sort, got %v and %v", from, to, err, sortErr)
+		}
+	}
+}
+
+// A layered DAG of n nodes with each node depending on up to three in earlier layers, with the
+// edges added in random order
+func BenchmarkAddEdgeIncremental(b *testing.B) {
+	for _, n := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			rng := rand.New(rand.NewSource(1))
+			type edge struct{ from, to graph.NodeID }
+			edges := []edge{}
+			for i := 1; i < n; i++ {
+				for j := 0; j < 3; j++ {
+					edges = append(edges, edge{graph.NodeID(fmt.Sprintf("n%d", i)), graph.NodeID(fmt.Sprintf("n%d", rng.Intn(i)))})
+				}
+			}
+			rng.Shuffle(len(edges), func(i, j int) { edges[i], edges[j] = edges[j], edges[i] })
+
+			b.ResetTimer()
+			for k := 0; k < b.N; k++ {
+				b.StopTimer()
+				g := graph.NewGraph("bulk", graph.WithIncrementalCycleCheck())
+				for i := 0; i < n; i++ {
+					g.Add(graph.NewNode(fmt.Sprintf("n%d", i), nil, graph.NoOp()))
+				}
+				b.StartTimer()
+
+				for _, e := range edges {
+					if err := g.AddEdge(e.from, e.to); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(edges)), "ns/edge")
+		})
+	}
+}
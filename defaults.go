@@ -0,0 +1,152 @@
+package graph
+
+import (
+	"fmt"
+	"time"
+)
+
+// Applies opts to every node given to Add or AddAll as a base its own options override, setting
+// by setting: a node with its own timeout keeps it, and metadata, inputs and fn variants merge
+// key by key with the node's winning. Dependency options among opts are ignored. Defaults are
+// filled in when a node is added, so nodes merged in from other graphs keep what they had.
+func WithDefaultNodeOptions(opts ...NodeOption) GraphOption {
+	return func(g *Graph) {
+		g.nodeDefaults = append(g.nodeDefaults, opts...)
+	}
+}
+
+// A node's configuration as it will run, graph defaults included
+type NodeSettings struct {
+	Timeout     time.Duration
+	OnTimeout   TimeoutBehavior
+	Retry       RetryPolicy
+	CancelGrace time.Duration
+	Metadata    map[string]string
+	Inputs      map[string]any
+	// Names of the fn variants
+	Variants     []string
+	Marker       bool
+	AllowFailure bool
+	// As given to WithCost; zero means the default of 1
+	Cost     int
+	Priority int
+	// The name given to WithCondition; empty without one
+	Condition string
+	// The settings taken from the graph's defaults: "timeout", "retry", "on-cancel",
+	// "before-attempt", "after-attempt", "marker", "allow-failure", "cost", "priority",
+	// "condition", or "metadata.", "input." or "variant." followed by a key
+	Inherited []string
+}
+
+func (g *Graph) EffectiveOptions(id NodeID) (NodeSettings, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	id = g.normalize(id)
+
+	node, ok := g.nodes[id]
+	if !ok {
+		return NodeSettings{}, fmt.Errorf("Node %s does not exist", id)
+	}
+
+	return NodeSettings{
+		Timeout:      node.timeout,
+		OnTimeout:    node.onTimeout,
+		Retry:        node.retry,
+		CancelGrace:  node.cancelGrace,
+		Metadata:     copyMap(node.Metadata),
+		Inputs:       copyMap(node.Inputs),
+		Variants:     sortedKeys(node.variants),
+		Marker:       node.marker,
+		AllowFailure: node.allowFailure,
+		Cost:         node.cost,
+		Priority:     node.priority,
+		Condition:    node.conditionName,
+		Inherited:    append([]string{}, node.inherited...),
+	}, nil
+}
+
+func (p RetryPolicy) isZero() bool {
+	return p.MaxAttempts == 0 && p.Backoff == 0 && len(p.RetryOn) == 0
+}
+
+// A copy of node with its ids normalized and the graph's defaults filled in, or node itself if
+// the graph has neither a normalizer nor defaults
+func (g *Graph) withDefaults(node *Node) *Node {
+	node = g.normalized(node)
+	if len(g.nodeDefaults) == 0 {
+		return node
+	}
+
+	base := &Node{}
+	for _, opt := range g.nodeDefaults {
+		opt(base)
+	}
+
+	n := node.clone()
+	n.inherited = nil
+	inherit := func(setting string) {
+		n.inherited = append(n.inherited, setting)
+	}
+
+	if n.timeout == 0 && base.timeout != 0 {
+		n.timeout, n.onTimeout = base.timeout, base.onTimeout
+		inherit("timeout")
+	}
+	if n.retry.isZero() && !base.retry.isZero() {
+		n.retry = base.retry
+		inherit("retry")
+	}
+	if n.onCancel == nil && base.onCancel != nil {
+		n.onCancel, n.cancelGrace = base.onCancel, base.cancelGrace
+		inherit("on-cancel")
+	}
+	if n.beforeAttempt == nil && base.beforeAttempt != nil {
+		n.beforeAttempt = base.beforeAttempt
+		inherit("before-attempt")
+	}
+	if n.afterAttempt == nil && base.afterAttempt != nil {
+		n.afterAttempt = base.afterAttempt
+		inherit("after-attempt")
+	}
+	if !n.marker && base.marker {
+		n.marker = true
+		inherit("marker")
+	}
+	if !n.allowFailure && base.allowFailure {
+		n.allowFailure = true
+		inherit("allow-failure")
+	}
+	if n.cost == 0 && base.cost != 0 {
+		n.cost = base.cost
+		inherit("cost")
+	}
+	if n.priority == 0 && base.priority != 0 {
+		n.priority = base.priority
+		inherit("priority")
+	}
+	if n.condition == nil && base.condition != nil {
+		n.conditionName, n.condition = base.conditionName, base.condition
+		inherit("condition")
+	}
+
+	for _, key := range sortedKeys(base.Metadata) {
+		if _, ok := n.Metadata[key]; !ok {
+			WithMetadata(key, base.Metadata[key])(n)
+			inherit("metadata." + key)
+		}
+	}
+	for _, key := range sortedKeys(base.Inputs) {
+		if _, ok := n.Inputs[key]; !ok {
+			WithInputs(map[string]any{key: base.Inputs[key]})(n)
+			inherit("input." + key)
+		}
+	}
+	for _, name := range sortedKeys(base.variants) {
+		if _, ok := n.variants[name]; !ok {
+			WithFnVariant(name, base.variants[name])(n)
+			inherit("variant." + name)
+		}
+	}
+	return n
+}
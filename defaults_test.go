@@ -0,0 +1,113 @@
+package graph_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func withDefaults(t *testing.T) *graph.Graph {
+	t.Helper()
+
+	return graph.NewGraph("defaults", graph.WithDefaultNodeOptions(
+		graph.WithTimeout(30*time.Second),
+		graph.WithRetry(graph.RetryPolicy{MaxAttempts: 3}),
+		graph.WithMetadata("team", "data"),
+		graph.WithMetadata("tier", "2"),
+	))
+}
+
+func effective(t *testing.T, g *graph.Graph, id graph.NodeID) graph.NodeSettings {
+	t.Helper()
+
+	settings, err := g.EffectiveOptions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return settings
+}
+
+func TestDefaultNodeOptionsInherited(t *testing.T) {
+	g := withDefaults(t)
+	g.Add(graph.NewNode("plain", nil, graph.NoOp()))
+
+	settings := effective(t, g, "plain")
+	if settings.Timeout != 30*time.Second || settings.Retry.MaxAttempts != 3 {
+		t.Errorf("Expected the default timeout and retry, got %s and %d attempts", settings.Timeout, settings.Retry.MaxAttempts)
+	}
+	if want := map[string]string{"team": "data", "tier": "2"}; !reflect.DeepEqual(settings.Metadata, want) {
+		t.Errorf("Expected the default metadata %v, got %v", want, settings.Metadata)
+	}
+	if want := []string{"timeout", "retry", "metadata.team", "metadata.tier"}; !reflect.DeepEqual(settings.Inherited, want) {
+		t.Errorf("Expected inherited %v, got %v", want, settings.Inherited)
+	}
+}
+
+func TestDefaultNodeOptionsOverridden(t *testing.T) {
+	g := withDefaults(t)
+	g.Add(graph.NewNode("own", nil, graph.NoOp(), graph.WithTimeout(time.Minute), graph.WithMetadata("tier", "1")))
+
+	settings := effective(t, g, "own")
+	if settings.Timeout != time.Minute {
+		t.Errorf("Expected the node's own timeout, got %s", settings.Timeout)
+	}
+	if want := map[string]string{"team": "data", "tier": "1"}; !reflect.DeepEqual(settings.Metadata, want) {
+		t.Errorf("Expected metadata merged key by key, got %v", settings.Metadata)
+	}
+	if want := []string{"retry", "metadata.team"}; !reflect.DeepEqual(settings.Inherited, want) {
+		t.Errorf("Expected inherited %v, got %v", want, settings.Inherited)
+	}
+}
+
+func TestDefaultNodeOptionsApplyAtRun(t *testing.T) {
+	g := graph.NewGraph("defaults", graph.WithDefaultNodeOptions(graph.WithTimeout(10*time.Millisecond)))
+	g.Add(graph.NewNode("slow", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+
+	report, _ := g.CompileToExecutable().Run(ctx(t))
+	if nr := report.Nodes["slow"]; nr.Status != graph.StatusTimedOut {
+		t.Errorf("Expected the default timeout to stop the node, got %s/%s", nr.Status, nr.Reason)
+	}
+}
+
+// Defaults are filled in when a node is added to the graph that has them; nodes merged in keep
+// what they had
+func TestDefaultNodeOptionsNotAppliedOnMerge(t *testing.T) {
+	g := withDefaults(t)
+	other := graph.NewGraph("other")
+	other.Add(graph.NewNode("merged", nil, graph.NoOp()))
+	if err := g.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	if settings := effective(t, g, "merged"); settings.Timeout != 0 || len(settings.Inherited) != 0 {
+		t.Errorf("Expected the merged node to keep its own settings, got %+v", settings)
+	}
+
+	if err := g.AddAll([]*graph.Node{graph.NewNode("batched", nil, graph.NoOp())}); err != nil {
+		t.Fatal(err)
+	}
+	if settings := effective(t, g, "batched"); settings.Timeout != 30*time.Second {
+		t.Errorf("Expected AddAll to apply the defaults, got %s", settings.Timeout)
+	}
+}
+
+func TestDefaultNodeOptionsIgnoreDependencies(t *testing.T) {
+	g := graph.NewGraph("defaults", graph.WithDefaultNodeOptions(graph.WithSoftDependency("setup")))
+	g.Add(graph.NewNode("setup", nil, graph.NoOp()))
+	g.Add(graph.NewNode("work", nil, graph.NoOp()))
+
+	if deps, _ := g.ResolvedDependencies("work"); len(deps) != 0 {
+		t.Errorf("Expected dependency defaults ignored, got %v", deps)
+	}
+}
+
+func TestEffectiveOptionsUnknownNode(t *testing.T) {
+	if _, err := withDefaults(t).EffectiveOptions("missing"); err == nil {
+		t.Error("Expected an unknown node to error")
+	}
+}
@@ -0,0 +1,536 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The version WriteJSON writes. ParseJSON reads every version up to it, migrating older ones.
+const DefinitionSchemaVersion = 2
+
+// Version 1 of the definition format: the graph's structure only
+type definitionV1JSON struct {
+	SchemaVersion     int                    `json:"schemaVersion"`
+	Name              string                 `json:"name"`
+	Namespace         string                 `json:"namespace,omitempty"`
+	AllowEmpty        bool                   `json:"allowEmpty,omitempty"`
+	DefaultEdgeWeight float64                `json:"defaultEdgeWeight,omitempty"`
+	Nodes             []nodeDefinitionV1JSON `json:"nodes"`
+	Aliases           map[NodeID]NodeID      `json:"aliases,omitempty"`
+}
+
+type nodeDefinitionV1JSON struct {
+	ID                   NodeID             `json:"id"`
+	Dependencies         []NodeID           `json:"dependencies,omitempty"`
+	SoftDependencies     []NodeID           `json:"softDependencies,omitempty"`
+	OptionalDependencies []NodeID           `json:"optionalDependencies,omitempty"`
+	Selectors            []selectorJSON     `json:"selectors,omitempty"`
+	Weights              map[NodeID]float64 `json:"weights,omitempty"`
+}
+
+// The current definition format: the graph's structure plus each node's execution attributes and
+// the graph's defaults. Fns, fn variants, attempt hooks and cleanups live in code; metadata,
+// string-valued inputs, timeouts, retries, allow-failure, costs, priorities and markers are
+// written, and conditions by name. Inputs of any other type can't be written faithfully as JSON,
+// so they're left out and stay in code.
+type definitionJSON struct {
+	SchemaVersion     int                  `json:"schemaVersion"`
+	Name              string               `json:"name"`
+	Namespace         string               `json:"namespace,omitempty"`
+	AllowEmpty        bool                 `json:"allowEmpty,omitempty"`
+	DefaultEdgeWeight float64              `json:"defaultEdgeWeight,omitempty"`
+	Defaults          *attributesJSON      `json:"defaults,omitempty"`
+	Nodes             []nodeDefinitionJSON `json:"nodes"`
+	Aliases           map[NodeID]NodeID    `json:"aliases,omitempty"`
+}
+
+type nodeDefinitionJSON struct {
+	ID NodeID `json:"id"`
+	// Hard dependencies; soft ones are listed apart
+	Dependencies         []NodeID           `json:"dependencies,omitempty"`
+	SoftDependencies     []NodeID           `json:"softDependencies,omitempty"`
+	OptionalDependencies []NodeID           `json:"optionalDependencies,omitempty"`
+	Selectors            []selectorJSON     `json:"selectors,omitempty"`
+	Weights              map[NodeID]float64 `json:"weights,omitempty"`
+	// The node's own settings; ones it takes from the graph's defaults are left out
+	Attributes *attributesJSON `json:"attributes,omitempty"`
+}
+
+type selectorJSON struct {
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+	AllowEmpty bool   `json:"allowEmpty,omitempty"`
+}
+
+type attributesJSON struct {
+	Timeout      textDuration `json:"timeout,omitempty"`
+	OnTimeout    string       `json:"onTimeout,omitempty"`
+	Retry        *retryJSON   `json:"retry,omitempty"`
+	AllowFailure bool         `json:"allowFailure,omitempty"`
+	Cost         int          `json:"cost,omitempty"`
+	Priority     int          `json:"priority,omitempty"`
+	Marker       bool         `json:"marker,omitempty"`
+	// The name given to WithCondition, bound back to a fn through LoadConditions
+	Condition string            `json:"condition,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	// The string-valued inputs
+	Inputs map[string]string `json:"inputs,omitempty"`
+}
+
+type retryJSON struct {
+	MaxAttempts int             `json:"maxAttempts,omitempty"`
+	Backoff     textDuration    `json:"backoff,omitempty"`
+	RetryOn     []ErrorCategory `json:"retryOn,omitempty"`
+}
+
+// Written as a Go duration string such as "1m30s"
+type textDuration time.Duration
+
+func (d textDuration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *textDuration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = textDuration(parsed)
+	return nil
+}
+
+var timeoutBehaviorNames = map[TimeoutBehavior]string{
+	OnTimeoutFail: "fail",
+	OnTimeoutSkip: "skip",
+}
+
+// The attributes of n, leaving out any in skip; nil if there are none
+func nodeAttributes(n *Node, skip map[string]struct{}) *attributesJSON {
+	skipped := func(setting string) bool {
+		_, ok := skip[setting]
+		return ok
+	}
+
+	a := attributesJSON{}
+	if n.timeout != 0 && !skipped("timeout") {
+		a.Timeout = textDuration(n.timeout)
+		if n.onTimeout != OnTimeoutFail {
+			a.OnTimeout = timeoutBehaviorNames[n.onTimeout]
+		}
+	}
+	if !n.retry.isZero() && !skipped("retry") {
+		a.Retry = &retryJSON{
+			MaxAttempts: n.retry.MaxAttempts,
+			Backoff:     textDuration(n.retry.Backoff),
+			RetryOn:     n.retry.RetryOn,
+		}
+	}
+	a.AllowFailure = n.allowFailure && !skipped("allow-failure")
+	if !skipped("cost") {
+		a.Cost = n.cost
+	}
+	if !skipped("priority") {
+		a.Priority = n.priority
+	}
+	a.Marker = n.marker && !skipped("marker")
+	if n.condition != nil && !skipped("condition") {
+		a.Condition = n.conditionName
+	}
+	for key, value := range n.Metadata {
+		if !skipped("metadata." + key) {
+			if a.Metadata == nil {
+				a.Metadata = map[string]string{}
+			}
+			a.Metadata[key] = value
+		}
+	}
+	for key, value := range n.Inputs {
+		if s, ok := value.(string); ok && !skipped("input."+key) {
+			if a.Inputs == nil {
+				a.Inputs = map[string]string{}
+			}
+			a.Inputs[key] = s
+		}
+	}
+
+	if reflect.DeepEqual(a, attributesJSON{}) {
+		return nil
+	}
+	return &a
+}
+
+func (a *attributesJSON) options(cfg loadConfig) ([]NodeOption, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	opts := []NodeOption{}
+	if a.Timeout != 0 {
+		behavior := OnTimeoutFail
+		if a.OnTimeout != "" {
+			found := false
+			for b, name := range timeoutBehaviorNames {
+				if name == a.OnTimeout {
+					behavior, found = b, true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("Unknown timeout behavior %q", a.OnTimeout)
+			}
+		}
+		opts = append(opts, WithTimeout(time.Duration(a.Timeout), behavior))
+	} else if a.OnTimeout != "" {
+		return nil, fmt.Errorf("Timeout behavior %q is set without a timeout", a.OnTimeout)
+	}
+	if a.Retry != nil {
+		opts = append(opts, WithRetry(RetryPolicy{
+			MaxAttempts: a.Retry.MaxAttempts,
+			Backoff:     time.Duration(a.Retry.Backoff),
+			RetryOn:     a.Retry.RetryOn,
+		}))
+	}
+	if a.AllowFailure {
+		opts = append(opts, AllowFailure())
+	}
+	if a.Cost != 0 {
+		opts = append(opts, WithCost(a.Cost))
+	}
+	if a.Priority != 0 {
+		opts = append(opts, WithPriority(a.Priority))
+	}
+	if a.Marker {
+		opts = append(opts, AsMarker())
+	}
+	if a.Condition != "" {
+		cond, ok := cfg.conditions[a.Condition]
+		if !ok {
+			return nil, fmt.Errorf("Unknown condition %q", a.Condition)
+		}
+		opts = append(opts, WithCondition(a.Condition, cond))
+	}
+	for _, key := range sortedKeys(a.Metadata) {
+		opts = append(opts, WithMetadata(key, a.Metadata[key]))
+	}
+	if len(a.Inputs) > 0 {
+		inputs := make(map[string]any, len(a.Inputs))
+		for key, value := range a.Inputs {
+			inputs[key] = value
+		}
+		opts = append(opts, WithInputs(inputs))
+	}
+	return opts, nil
+}
+
+// Writes the graph in the JSON definition format, nodes sorted by id. Aliases are written as
+// aliases and dependencies as declared.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	out := definitionJSON{
+		SchemaVersion:     DefinitionSchemaVersion,
+		Name:              g.name,
+		Namespace:         g.namespace,
+		AllowEmpty:        g.allowEmpty,
+		DefaultEdgeWeight: g.defaultWeight,
+		Nodes:             make([]nodeDefinitionJSON, 0, len(g.nodes)),
+		Aliases:           copyMap(g.aliases),
+	}
+	if len(g.nodeDefaults) > 0 {
+		base := &Node{}
+		for _, opt := range g.nodeDefaults {
+			opt(base)
+		}
+		out.Defaults = nodeAttributes(base, nil)
+	}
+
+	for _, id := range sortedIDs(g.nodes) {
+		node := g.nodes[id]
+		inherited := make(map[string]struct{}, len(node.inherited))
+		for _, setting := range node.inherited {
+			inherited[setting] = struct{}{}
+		}
+
+		def := nodeDefinitionJSON{
+			ID:                   id,
+			OptionalDependencies: sortedIDs(node.optionalDependencies),
+			Weights:              copyMap(node.weights),
+			Attributes:           nodeAttributes(node, inherited),
+		}
+		for _, depId := range sortedIDs(node.Dependencies) {
+			if node.DependencyKind(depId) == EdgeSoft {
+				def.SoftDependencies = append(def.SoftDependencies, depId)
+			} else {
+				def.Dependencies = append(def.Dependencies, depId)
+			}
+		}
+		if len(def.OptionalDependencies) == 0 {
+			def.OptionalDependencies = nil
+		}
+		for _, s := range node.selectors {
+			def.Selectors = append(def.Selectors, selectorJSON{Key: s.key, Value: s.value, AllowEmpty: s.match == SelectorAllowEmpty})
+		}
+		out.Nodes = append(out.Nodes, def)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+type LoadOption func(*loadConfig)
+
+type loadConfig struct {
+	// Nil makes unknown keys an error
+	lax        Logger
+	graphOpts  []GraphOption
+	conditions map[string]ConditionFn
+}
+
+// Logs keys the format doesn't know to logger instead of failing on them
+func LaxLoad(logger Logger) LoadOption {
+	return func(c *loadConfig) {
+		c.lax = logger
+	}
+}
+
+// Applied to the loaded graph after the settings the definition carries, for those it can't,
+// such as WithIDNormalizer or WithEdgePolicy
+func LoadGraphOptions(opts ...GraphOption) LoadOption {
+	return func(c *loadConfig) {
+		c.graphOpts = append(c.graphOpts, opts...)
+	}
+}
+
+// Reads a graph written by WriteJSON in any schema version up to DefinitionSchemaVersion;
+// fnFactory is called once per node. Nodes may be listed in any order. The graph's defaults are applied to its nodes the way Add applies them, so a node
+// loads with the same effective options it was written with.
+func ParseJSON(r io.Reader, fnFactory func(name string) NodeFn, opts ...LoadOption) (*Graph, error) {
+	cfg := loadConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var stamp struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &stamp); err != nil {
+		return nil, err
+	}
+	if stamp.SchemaVersion > DefinitionSchemaVersion {
+		return nil, fmt.Errorf("Definition schema version %d is newer than the supported version %d", stamp.SchemaVersion, DefinitionSchemaVersion)
+	}
+	decode, ok := definitionDecoders[stamp.SchemaVersion]
+	if !ok {
+		return nil, fmt.Errorf("Unknown definition schema version %d", stamp.SchemaVersion)
+	}
+
+	in, err := decode(data, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return in.graph(fnFactory, cfg)
+}
+
+// Each version's decoder, migrating what it reads to the current format. Definitions written
+// before the format was versioned have no schemaVersion and read as version 1.
+var definitionDecoders = map[int]func(data []byte, cfg loadConfig) (*definitionJSON, error){
+	0: decodeV1,
+	1: decodeV1,
+	2: func(data []byte, cfg loadConfig) (*definitionJSON, error) {
+		return decodeDefinition[definitionJSON](data, cfg)
+	},
+}
+
+func decodeV1(data []byte, cfg loadConfig) (*definitionJSON, error) {
+	v1, err := decodeDefinition[definitionV1JSON](data, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &definitionJSON{
+		SchemaVersion:     DefinitionSchemaVersion,
+		Name:              v1.Name,
+		Namespace:         v1.Namespace,
+		AllowEmpty:        v1.AllowEmpty,
+		DefaultEdgeWeight: v1.DefaultEdgeWeight,
+		Nodes:             make([]nodeDefinitionJSON, 0, len(v1.Nodes)),
+		Aliases:           v1.Aliases,
+	}
+	for _, def := range v1.Nodes {
+		out.Nodes = append(out.Nodes, def.current())
+	}
+	return out, nil
+}
+
+func (def nodeDefinitionV1JSON) current() nodeDefinitionJSON {
+	return nodeDefinitionJSON{
+		ID:                   def.ID,
+		Dependencies:         def.Dependencies,
+		SoftDependencies:     def.SoftDependencies,
+		OptionalDependencies: def.OptionalDependencies,
+		Selectors:            def.Selectors,
+		Weights:              def.Weights,
+	}
+}
+
+// Decodes data as a T, failing on keys T doesn't know unless the load is lax
+func decodeDefinition[T any](data []byte, cfg loadConfig) (*T, error) {
+	in := new(T)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if cfg.lax == nil {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(in); err != nil {
+		return nil, err
+	}
+	if cfg.lax != nil {
+		for _, key := range unknownKeys(data, reflect.TypeOf(in), "") {
+			cfg.lax.Printf("Ignoring unknown key %s", key)
+		}
+	}
+	return in, nil
+}
+
+func (in *definitionJSON) graph(fnFactory func(name string) NodeFn, cfg loadConfig) (*Graph, error) {
+	g, err := in.newGraph(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(Nodes, len(in.Nodes))
+	for _, def := range in.Nodes {
+		if _, ok := nodes[g.normalize(def.ID)]; ok {
+			return nil, fmt.Errorf("Definition lists node %s more than once", def.ID)
+		}
+
+		node, err := def.node(fnFactory, cfg)
+		if err != nil {
+			return nil, err
+		}
+		nodes[g.normalize(def.ID)] = g.withDefaults(node)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	_, aliases, err := g.normalizedAll(nil, in.Aliases)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.merge(nodes, aliases, newMergeConfig(nil), true); err != nil {
+		return nil, err
+	}
+	for _, alias := range sortedIDs(aliases) {
+		if !g.exists(alias) {
+			return nil, fmt.Errorf("Alias %s points to missing node %s", alias, aliases[alias])
+		}
+	}
+	return g, nil
+}
+
+// An empty graph with the definition's settings, with cfg's graph options on top
+func (in *definitionJSON) newGraph(cfg loadConfig) (*Graph, error) {
+	graphOpts := []GraphOption{WithNamespace(in.Namespace), WithDefaultEdgeWeight(in.DefaultEdgeWeight)}
+	if in.AllowEmpty {
+		graphOpts = append(graphOpts, AllowEmpty())
+	}
+	defaults, err := in.Defaults.options(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Graph defaults: %w", err)
+	}
+	if len(defaults) > 0 {
+		graphOpts = append(graphOpts, WithDefaultNodeOptions(defaults...))
+	}
+	return NewGraph(in.Name, append(graphOpts, cfg.graphOpts...)...), nil
+}
+
+// The node def describes, before the graph's defaults are applied
+func (def nodeDefinitionJSON) node(fnFactory func(name string) NodeFn, cfg loadConfig) (*Node, error) {
+	nodeOpts, err := def.Attributes.options(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Node %s: %w", def.ID, err)
+	}
+	for _, depId := range def.SoftDependencies {
+		nodeOpts = append(nodeOpts, WithSoftDependency(depId))
+	}
+	for _, depId := range def.OptionalDependencies {
+		nodeOpts = append(nodeOpts, WithOptionalDependency(depId))
+	}
+	for _, s := range def.Selectors {
+		match := SelectorRequireMatch
+		if s.AllowEmpty {
+			match = SelectorAllowEmpty
+		}
+		nodeOpts = append(nodeOpts, WithDependencySelector(s.Key, s.Value, match))
+	}
+
+	node := NewNode(string(def.ID), Deps(def.Dependencies...), fnFactory(string(def.ID)), nodeOpts...)
+	node.weights = copyMap(def.Weights)
+	return node, nil
+}
+
+// The keys in data, dotted from the top, that t has no json field for
+func unknownKeys(data []byte, t reflect.Type, path string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) != nil {
+			return nil
+		}
+		unknown := []string{}
+		for i, item := range items {
+			unknown = append(unknown, unknownKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return unknown
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(data, &fields) != nil {
+			return nil
+		}
+
+		known := map[string]reflect.Type{}
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			known[name] = t.Field(i).Type
+		}
+
+		unknown := []string{}
+		for key, value := range fields {
+			at := key
+			if path != "" {
+				at = path + "." + key
+			}
+			// Decoding matches keys case-insensitively too
+			ft, ok := known[key]
+			for name, t := range known {
+				if !ok && strings.EqualFold(name, key) {
+					ft, ok = t, true
+				}
+			}
+			if !ok {
+				unknown = append(unknown, at)
+				continue
+			}
+			unknown = append(unknown, unknownKeys(value, ft, at)...)
+		}
+		sort.Strings(unknown)
+		return unknown
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package graph_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Every attribute and edge kind the format carries: graph defaults, nodes overriding them,
+// soft, optional, selector and weighted edges, and an alias
+func attributed(t *testing.T) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("nightly", graph.WithNamespace("etl"), graph.WithDefaultEdgeWeight(1), graph.WithDefaultNodeOptions(
+		graph.WithTimeout(30*time.Second),
+		graph.WithRetry(graph.RetryPolicy{MaxAttempts: 3, Backoff: time.Second}),
+		graph.WithMetadata("team", "data"),
+	))
+	g.Add(graph.NewNode("extract", nil, graph.NoOp(), graph.WithMetadata("phase", "extract")))
+	g.Add(graph.NewNode("lookup", nil, graph.NoOp(),
+		graph.WithMetadata("phase", "extract"),
+		graph.WithTimeout(5*time.Second, graph.OnTimeoutSkip),
+		graph.WithRetry(graph.RetryPolicy{MaxAttempts: 5, RetryOn: []graph.ErrorCategory{graph.CategoryTimedOut}}),
+		graph.AllowFailure(),
+	))
+	g.Add(graph.NewNode("extracted", nil, graph.NoOp(), graph.AsMarker(), graph.WithDependencySelector("phase", "extract")))
+	g.Add(graph.NewNode("transform", graph.Deps("extracted"), graph.NoOp(), graph.WithCost(4), graph.WithMetadata("team", "analytics")))
+	g.Add(graph.NewNode("load", nil, graph.NoOp(), graph.WithOptionalDependency("cache")))
+	if err := g.AddEdge("load", "transform", 2.5); err != nil {
+		t.Fatal(err)
+	}
+	g.Alias("warehouse", "load")
+	g.Add(graph.NewNode("report", graph.Deps("warehouse"), graph.NoOp(), graph.WithSoftDependency("lookup")))
+	return g
+}
+
+func writeJSON(t *testing.T, g *graph.Graph) string {
+	t.Helper()
+
+	var b bytes.Buffer
+	if err := g.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func parseJSON(t *testing.T, def string, opts ...graph.LoadOption) *graph.Graph {
+	t.Helper()
+
+	g, err := graph.ParseJSON(strings.NewReader(def), noOps, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestDefinitionGolden(t *testing.T) {
+	golden(t, "definition.json", writeJSON(t, attributed(t)))
+}
+
+func TestDefinitionRoundTrip(t *testing.T) {
+	g := attributed(t)
+	loaded := parseJSON(t, writeJSON(t, g))
+
+	for _, id := range mustSort(t, g) {
+		if want, got := effective(t, g, id), effective(t, loaded, id); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %s to load with\n%+v\ngot\n%+v", id, want, got)
+		}
+	}
+	if want, got := g.WeightedEdges(), loaded.WeightedEdges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected edges %v, got %v", want, got)
+	}
+	if deps, _ := loaded.ResolvedDependencies("extracted"); !deps.Equal(graph.Deps("extract", "lookup")) {
+		t.Errorf("Expected the selector to resolve again, got %v", deps)
+	}
+	if resolved, _ := loaded.ResolvedDependencies("report"); !resolved.Has("load") {
+		t.Errorf("Expected the alias kept, got %v", resolved)
+	}
+	if loaded.Fingerprint() != g.Fingerprint() {
+		t.Error("Expected the loaded graph to fingerprint the same")
+	}
+	if again := writeJSON(t, loaded); again != writeJSON(t, g) {
+		t.Errorf("Expected the loaded graph to write the same definition, got\n%s", again)
+	}
+}
+
+func TestDefinitionInputs(t *testing.T) {
+	g := graph.NewGraph("downloads", graph.WithDefaultNodeOptions(graph.WithInputs(map[string]any{"region": "eu"})))
+	g.Add(graph.NewNode("fetch", nil, graph.NoOp(), graph.WithInputs(map[string]any{"url": "https://example.com/a", "retries": 3})))
+	g.Add(graph.NewNode("mirror", nil, graph.NoOp(), graph.WithInputs(map[string]any{"url": "https://example.com/b", "region": "us"})))
+
+	def := writeJSON(t, g)
+	if strings.Contains(def, "retries") {
+		t.Errorf("Expected the non-string input left out, got\n%s", def)
+	}
+	if n := strings.Count(def, `"region": "eu"`); n != 1 {
+		t.Errorf("Expected the default input written once, got %d times in\n%s", n, def)
+	}
+
+	loaded := parseJSON(t, def)
+	want := map[graph.NodeID]map[string]any{
+		"fetch":  {"url": "https://example.com/a", "region": "eu"},
+		"mirror": {"url": "https://example.com/b", "region": "us"},
+	}
+	for id, inputs := range want {
+		if got := effective(t, loaded, id); !reflect.DeepEqual(got.Inputs, inputs) {
+			t.Errorf("Expected %s to load with inputs %v, got %v", id, inputs, got.Inputs)
+		}
+	}
+	if settings := effective(t, loaded, "fetch"); !reflect.DeepEqual(settings.Inherited, []string{"input.region"}) {
+		t.Errorf("Expected the default input inherited again, got %v", settings.Inherited)
+	}
+	if again := writeJSON(t, loaded); again != def {
+		t.Errorf("Expected the loaded graph to write the same definition, got\n%s", again)
+	}
+	if streamed := streamJSON(t, def); !reflect.DeepEqual(effective(t, streamed, "mirror").Inputs, want["mirror"]) {
+		t.Errorf("Expected the stream loader to read inputs too, got %v", effective(t, streamed, "mirror").Inputs)
+	}
+}
+
+func TestDefinitionPriorityTagsAndConditions(t *testing.T) {
+	g := graph.NewGraph("batch", graph.WithDefaultNodeOptions(graph.WithPriority(1)))
+	g.Add(graph.NewNode("scan", nil, graph.NoOp(), graph.WithPriority(3), graph.WithMetadata(graph.DocTagsKey, "io,nightly")))
+	g.Add(graph.NewNode("process", graph.Deps("scan"), graph.NoOp(), graph.WithCondition("has-work", hasWork)))
+	def := writeJSON(t, g)
+	if !strings.Contains(def, `"condition": "has-work"`) || !strings.Contains(def, `"priority": 3`) {
+		t.Errorf("Expected the priority and condition written, got\n%s", def)
+	}
+
+	conditions := graph.LoadConditions(map[string]graph.ConditionFn{"has-work": hasWork})
+	for name, loaded := range map[string]*graph.Graph{"parse": parseJSON(t, def, conditions), "stream": streamJSON(t, def, conditions)} {
+		for _, id := range mustSort(t, g) {
+			if want, got := effective(t, g, id), effective(t, loaded, id); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: expected %s to load with\n%+v\ngot\n%+v", name, id, want, got)
+			}
+		}
+		if again := writeJSON(t, loaded); again != def {
+			t.Errorf("%s: expected the loaded graph to write the same definition, got\n%s", name, again)
+		}
+
+		// The bound fn decides: scan returns nil, which counts as work
+		report, err := loaded.CompileToExecutable().Run(ctx(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nr := report.Nodes["process"]; nr.Status != graph.StatusSucceeded {
+			t.Errorf("%s: expected the loaded condition to let process run, got %s/%s", name, nr.Status, nr.Reason)
+		}
+	}
+
+	// A condition the registry doesn't have can't be bound
+	if _, err := graph.ParseJSON(strings.NewReader(def), noOps); err == nil || err.Error() != `Node process: Unknown condition "has-work"` {
+		t.Errorf("Expected the unbound condition refused, got %v", err)
+	}
+}
+
+func TestDefinitionLeavesOutInheritedAttributes(t *testing.T) {
+	def := writeJSON(t, attributed(t))
+	// The defaults are written once; extract only adds its phase
+	if n := strings.Count(def, `"30s"`); n != 1 {
+		t.Errorf("Expected the default timeout written once, got %d times in\n%s", n, def)
+	}
+	if n := strings.Count(def, `"team": "data"`); n != 1 {
+		t.Errorf("Expected the default team written once, got %d times", n)
+	}
+}
+
+func TestDefinitionRunsLikeTheOriginal(t *testing.T) {
+	loaded := parseJSON(t, writeJSON(t, attributed(t)))
+	loaded.Add(graph.NewNode("cache", nil, graph.NoOp()))
+
+	report, err := loaded.CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, nr := range report.Nodes {
+		if nr.Status != graph.StatusSucceeded {
+			t.Errorf("Expected %s to succeed, got %s", id, nr.Status)
+		}
+	}
+}
+
+func TestDefinitionUnknownKeys(t *testing.T) {
+	def := `{
+  "schemaVersion": 2,
+  "name": "nightly",
+  "owner": "data",
+  "nodes": [
+    {"id": "extract", "attributes": {"timeout": "1s", "queue": 3}}
+  ]
+}`
+	if _, err := graph.ParseJSON(strings.NewReader(def), noOps); err == nil || !strings.Contains(err.Error(), "owner") {
+		t.Errorf("Expected the unknown key to fail a strict load, got %v", err)
+	}
+
+	logger := &recordingLogger{}
+	g := parseJSON(t, def, graph.LaxLoad(logger))
+	want := []string{"Ignoring unknown key nodes[0].attributes.queue", "Ignoring unknown key owner"}
+	if !reflect.DeepEqual(logger.lines, want) {
+		t.Errorf("Expected %q logged, got %q", want, logger.lines)
+	}
+	if settings := effective(t, g, "extract"); settings.Timeout != time.Second {
+		t.Errorf("Expected the known attributes loaded, got %s", settings.Timeout)
+	}
+}
+
+func TestDefinitionVersion1(t *testing.T) {
+	for name, stamp := range map[string]string{"unversioned": "", "version 1": `"schemaVersion": 1, `} {
+		t.Run(name, func(t *testing.T) {
+			g := parseJSON(t, `{`+stamp+`"name": "old", "nodes": [
+  {"id": "b", "dependencies": ["a"], "weights": {"a": 2}},
+  {"id": "a"}
+]}`)
+			if w, err := g.EdgeWeight("b", "a"); err != nil || w != 2 {
+				t.Errorf("Expected the v1 edge and weight, got %v, %v", w, err)
+			}
+			if settings := effective(t, g, "b"); settings.Timeout != 0 || settings.Retry.MaxAttempts != 0 {
+				t.Errorf("Expected no attributes, got %+v", settings)
+			}
+		})
+	}
+
+	// Attributes came in version 2
+	if _, err := graph.ParseJSON(strings.NewReader(`{"schemaVersion": 1, "name": "old", "nodes": [{"id": "a", "attributes": {}}]}`), noOps); err == nil {
+		t.Error("Expected attributes in a v1 definition to be unknown")
+	}
+}
+
+func TestDefinitionMigratesV1Fixture(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "definition_v1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := parseJSON(t, string(data))
+
+	// The same graph built by hand
+	g := graph.NewGraph("nightly", graph.WithNamespace("etl"), graph.WithDefaultEdgeWeight(1))
+	g.Add(graph.NewNode("extract", nil, graph.NoOp()))
+	g.Add(graph.NewNode("lookup", nil, graph.NoOp()))
+	g.Add(graph.NewNode("extracted", nil, graph.NoOp(), graph.WithDependencySelector("phase", "extract", graph.SelectorAllowEmpty)))
+	g.Add(graph.NewNode("transform", graph.Deps("extracted"), graph.NoOp()))
+	g.Add(graph.NewNode("load", nil, graph.NoOp(), graph.WithOptionalDependency("cache")))
+	if err := g.AddEdge("load", "transform", 2.5); err != nil {
+		t.Fatal(err)
+	}
+	g.Alias("warehouse", "load")
+	g.Add(graph.NewNode("report", graph.Deps("warehouse"), graph.NoOp(), graph.WithSoftDependency("lookup")))
+
+	if loaded.Fingerprint() != g.Fingerprint() {
+		t.Error("Expected the v1 fixture to fingerprint like the hand-built graph")
+	}
+	if want, got := g.WeightedEdges(), loaded.WeightedEdges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected edges %v, got %v", want, got)
+	}
+	for _, id := range mustSort(t, g) {
+		if want, got := effective(t, g, id), effective(t, loaded, id); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %s to load with\n%+v\ngot\n%+v", id, want, got)
+		}
+	}
+	// Written back at the current version
+	if want, got := writeJSON(t, g), writeJSON(t, loaded); got != want {
+		t.Errorf("Expected the migrated graph written as\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestDefinitionRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name, def, err string
+	}{
+		{
+			"newer schema",
+			`{"schemaVersion": 3, "name": "g", "nodes": []}`,
+			"Definition schema version 3 is newer than the supported version 2",
+		},
+		{
+			"unknown timeout behavior",
+			`{"schemaVersion": 2, "name": "g", "nodes": [{"id": "a", "attributes": {"timeout": "1s", "onTimeout": "retry"}}]}`,
+			`Node a: Unknown timeout behavior "retry"`,
+		},
+		{
+			"timeout behavior without timeout",
+			`{"schemaVersion": 2, "name": "g", "nodes": [{"id": "a", "attributes": {"onTimeout": "skip"}}]}`,
+			`Node a: Timeout behavior "skip" is set without a timeout`,
+		},
+		{
+			"bad default",
+			`{"schemaVersion": 2, "name": "g", "defaults": {"onTimeout": "skip"}, "nodes": []}`,
+			`Graph defaults: Timeout behavior "skip" is set without a timeout`,
+		},
+		{
+			"unknown schema",
+			`{"schemaVersion": -1, "name": "g", "nodes": []}`,
+			"Unknown definition schema version -1",
+		},
+		{
+			"duplicate node",
+			`{"schemaVersion": 2, "name": "g", "nodes": [{"id": "a"}, {"id": "a"}]}`,
+			"Definition lists node a more than once",
+		},
+		{
+			"dangling alias",
+			`{"schemaVersion": 2, "name": "g", "nodes": [{"id": "a"}], "aliases": {"b": "missing"}}`,
+			"Alias b points to missing node missing",
+		},
+	} {
+		if _, err := graph.ParseJSON(strings.NewReader(tc.def), noOps); err == nil || err.Error() != tc.err {
+			t.Errorf("%s: expected %q, got %v", tc.name, tc.err, err)
+		}
+	}
+}
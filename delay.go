@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Waits d, timed by the run's clock, between the node becoming ready and its fn starting. The
+// wait holds no concurrency slot or budget, ends early with the run's ctx and is reported as the
+// node's StartDelay, apart from its duration.
+func WithStartDelay(d time.Duration) NodeOption {
+	return func(n *Node) {
+		n.startDelay = d
+	}
+}
+
+type delayed struct {
+	id     NodeID
+	waited time.Duration
+}
+
+// Waits a random time under max, timed by the run's clock, before every node's fn starts, so nodes
+// ready at once don't all start at once. The wait is drawn from the run's seed under
+// WithDeterministicScheduling, holds no concurrency slot or budget and is added to the node's
+// StartDelay; NodeReport.StartJitter says how much of it was jitter. WithNodeStartJitter
+// overrides it per node.
+func WithStartJitter(max time.Duration) ExecOption {
+	return func(c *ExecConfig) {
+		c.StartJitter = max
+	}
+}
+
+// The node's start jitter in place of the run's WithStartJitter; zero turns it off for the node
+func WithNodeStartJitter(max time.Duration) NodeOption {
+	return func(n *Node) {
+		n.startJitter = max
+		n.ownJitter = true
+	}
+}
+
+// The most jitter the node may get. Approval nodes get none since they have no fn to stagger.
+func (r *run) maxJitter(node *executableNode) time.Duration {
+	if node.approval {
+		return 0
+	}
+	if node.ownJitter {
+		return node.startJitter
+	}
+	return r.cfg.StartJitter
+}
+
+// Called from the run loop only, so under deterministic scheduling the draws follow dispatch order
+func (r *run) drawJitter(max time.Duration) time.Duration {
+	if !r.cfg.Deterministic {
+		return time.Duration(rand.Int63n(int64(max)))
+	}
+	if r.jitterRand == nil {
+		r.jitterRand = rand.New(rand.NewSource(r.cfg.Seed))
+	}
+	return time.Duration(r.jitterRand.Int63n(int64(max)))
+}
+
+// Starts the node's wait; it comes back through r.delays and is queued again once it's over
+func (r *run) delay(ctx context.Context, id NodeID) {
+	d := r.peg.nodes[id].startDelay
+	if max := r.maxJitter(r.peg.nodes[id]); max > 0 {
+		jitter := r.drawJitter(max)
+		if r.jitters == nil {
+			r.jitters = make(map[NodeID]time.Duration)
+		}
+		r.jitters[id] = jitter
+		d += jitter
+	}
+	r.delaying++
+	start := r.clock().Now()
+	after := r.clock().After(d)
+
+	go func() {
+		select {
+		case <-after:
+		case <-ctx.Done():
+		}
+		r.delays <- delayed{id: id, waited: r.clock().Now().Sub(start)}
+	}()
+}
+
+// Whether the node still has its start delay ahead of it
+func (r *run) pendingDelay(id NodeID) bool {
+	if r.peg.nodes[id].startDelay <= 0 && r.maxJitter(r.peg.nodes[id]) <= 0 {
+		return false
+	}
+	_, waited := r.waited[id]
+	return !waited
+}
+
+func (r *run) delayOver(ctx context.Context, d delayed) {
+	r.delaying--
+	if ctx.Err() != nil {
+		return
+	}
+
+	if r.waited == nil {
+		r.waited = make(map[NodeID]time.Duration)
+	}
+	r.waited[d.id] = d.waited
+	r.ready = append(r.ready, d.id)
+}
@@ -0,0 +1,295 @@
+package graph_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// dns waits out propagation after register; invoked gets the clock's time when dns's fn starts
+func propagating(clock *fakeClock, invoked chan<- time.Time, delay time.Duration) *graph.Graph {
+	g := graph.NewGraph("dns")
+	g.Add(graph.NewNode("register", nil, graph.NoOp()))
+	g.Add(graph.NewNode("dns", graph.Deps("register"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		invoked <- clock.Now()
+		return nil, nil
+	}, graph.WithStartDelay(delay)))
+	return g
+}
+
+func TestStartDelayWaitsAfterDependencies(t *testing.T) {
+	clock := newFakeClock()
+	t0 := clock.Now()
+	invoked := make(chan time.Time, 1)
+	done := runAsync(propagating(clock, invoked, 5*time.Minute).CompileToExecutable(), graph.WithClock(clock))
+
+	clock.waitFor(t, 1)
+	select {
+	case <-invoked:
+		t.Fatal("Expected the fn to wait out the delay")
+	default:
+	}
+	clock.Advance(5 * time.Minute)
+
+	if at := <-invoked; !at.Equal(t0.Add(5 * time.Minute)) {
+		t.Errorf("Expected the fn invoked at t0+5m, got t0+%s", at.Sub(t0))
+	}
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+
+	nr := res.report.Nodes["dns"]
+	if nr.StartDelay != 5*time.Minute {
+		t.Errorf("Expected a start delay of 5m, got %s", nr.StartDelay)
+	}
+	if !nr.Start.Equal(t0.Add(5 * time.Minute)) {
+		t.Errorf("Expected the node to start after its wait, got t0+%s", nr.Start.Sub(t0))
+	}
+	if d := nr.End.Sub(nr.Start); d != 0 {
+		t.Errorf("Expected the wait left out of the duration, got %s", d)
+	}
+	if nr := res.report.Nodes["register"]; nr.StartDelay != 0 {
+		t.Errorf("Expected no delay on register, got %s", nr.StartDelay)
+	}
+}
+
+func TestStartDelayHoldsNoSlot(t *testing.T) {
+	clock := newFakeClock()
+	invoked := make(chan time.Time, 1)
+	g := propagating(clock, invoked, time.Minute)
+	ran := make(chan struct{})
+	g.Add(graph.NewNode("other", graph.Deps("register"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		close(ran)
+		return nil, nil
+	}))
+
+	done := runAsync(g.CompileToExecutable(), graph.WithClock(clock), graph.WithMaxConcurrency(1))
+	clock.waitFor(t, 1)
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected other to run while dns waits in the only slot")
+	}
+
+	clock.Advance(time.Minute)
+	<-invoked
+	if res := <-done; res.err != nil {
+		t.Fatal(res.err)
+	}
+}
+
+func TestStartDelayEndsWithRun(t *testing.T) {
+	clock := newFakeClock()
+	var calls atomic.Int32
+	g := graph.NewGraph("dns")
+	g.Add(graph.NewNode("dns", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		calls.Add(1)
+		return nil, nil
+	}, graph.WithStartDelay(time.Hour)))
+
+	c, cancel := context.WithCancel(context.Background())
+	done := make(chan runResult, 1)
+	go func() {
+		report, err := g.CompileToExecutable().Run(c, graph.WithClock(clock))
+		done <- runResult{report, err}
+	}()
+	clock.waitFor(t, 1)
+	cancel()
+
+	select {
+	case res := <-done:
+		if res.err == nil {
+			t.Error("Expected the canceled run to fail")
+		}
+		// It never started, like the nodes still queued
+		if nr := res.report.Nodes["dns"]; nr.Status != graph.StatusNotRun {
+			t.Errorf("Expected the waiting node not run, got %s", nr.Status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the run to end without waiting out the delay")
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("Expected the fn never invoked, got %d calls", n)
+	}
+}
+
+func TestStartDelayReported(t *testing.T) {
+	clock := newFakeClock()
+	invoked := make(chan time.Time, 1)
+	done := runAsync(propagating(clock, invoked, 90*time.Second).CompileToExecutable(), graph.WithClock(clock))
+	clock.waitFor(t, 1)
+	clock.Advance(90 * time.Second)
+	<-invoked
+	res := <-done
+
+	if s := res.report.Nodes["dns"].String(); !strings.Contains(s, "started 1m30s late") {
+		t.Errorf("Expected the wait in %q", s)
+	}
+
+	data, err := json.Marshal(res.report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded graph.Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if d := decoded.Nodes["dns"].StartDelay; d != 90*time.Second {
+		t.Errorf("Expected the delay to survive JSON, got %s", d)
+	}
+}
+
+type invocation struct {
+	id graph.NodeID
+	at time.Time
+}
+
+// n unrelated nodes that each say when their fn started
+func herd(clock *fakeClock, n int, invoked chan<- invocation, opts ...graph.NodeOption) *graph.Graph {
+	g := graph.NewGraph("herd")
+	for i := 0; i < n; i++ {
+		g.Add(graph.NewNode(fmt.Sprintf("call-%d", i), nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+			invoked <- invocation{ec.ID, clock.Now()}
+			return nil, nil
+		}, opts...))
+	}
+	return g
+}
+
+// Runs the herd with jitter up to a minute, letting the whole minute pass at once
+func jittered(t *testing.T, seed int64) *graph.Report {
+	t.Helper()
+
+	clock := newFakeClock()
+	invoked := make(chan invocation, 5)
+	done := runAsync(herd(clock, 5, invoked).CompileToExecutable(), graph.WithClock(clock), graph.WithStartJitter(time.Minute), graph.WithDeterministicScheduling(seed))
+	clock.waitFor(t, 5)
+	clock.Advance(time.Minute)
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	return res.report
+}
+
+func TestStartJitterStaggersStarts(t *testing.T) {
+	jitters := map[graph.NodeID]time.Duration{}
+	for id, nr := range jittered(t, 7).Nodes {
+		// The clock jumped the whole minute, so each node waited at least its jitter
+		if nr.StartJitter < 0 || nr.StartJitter >= time.Minute || nr.StartDelay < nr.StartJitter {
+			t.Fatalf("Expected %s's jitter under a minute and within its delay, got %s of %s", id, nr.StartJitter, nr.StartDelay)
+		}
+		jitters[id] = nr.StartJitter
+	}
+
+	// The same seed draws the same jitter, so step the clock from one start to the next
+	clock := newFakeClock()
+	t0 := clock.Now()
+	invoked := make(chan invocation, 5)
+	done := runAsync(herd(clock, 5, invoked).CompileToExecutable(), graph.WithClock(clock), graph.WithStartJitter(time.Minute), graph.WithDeterministicScheduling(7), graph.WithMaxConcurrency(1))
+	// Every node waits at once, though only one slot is free
+	clock.waitFor(t, 5)
+
+	order := make([]graph.NodeID, 0, len(jitters))
+	for id := range jitters {
+		order = append(order, id)
+	}
+	sort.Slice(order, func(i, j int) bool { return jitters[order[i]] < jitters[order[j]] })
+	for _, id := range order {
+		clock.Advance(t0.Add(jitters[id]).Sub(clock.Now()))
+		got := <-invoked
+		if got.id != id || !got.at.Equal(t0.Add(jitters[id])) {
+			t.Errorf("Expected %s to start at t0+%s, got %s at t0+%s", id, jitters[id], got.id, got.at.Sub(t0))
+		}
+	}
+
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	for id, nr := range res.report.Nodes {
+		if nr.StartJitter != jitters[id] || nr.StartDelay != jitters[id] {
+			t.Errorf("Expected %s to draw and wait %s again, got %s and %s", id, jitters[id], nr.StartJitter, nr.StartDelay)
+		}
+	}
+	if reflect.DeepEqual(jitters, startJitters(jittered(t, 8))) {
+		t.Error("Expected another seed to draw other jitter")
+	}
+}
+
+func startJitters(report *graph.Report) map[graph.NodeID]time.Duration {
+	jitters := map[graph.NodeID]time.Duration{}
+	for id, nr := range report.Nodes {
+		jitters[id] = nr.StartJitter
+	}
+	return jitters
+}
+
+func TestNodeStartJitterOverridesRun(t *testing.T) {
+	clock := newFakeClock()
+	invoked := make(chan invocation, 3)
+	g := herd(clock, 1, invoked, graph.WithNodeStartJitter(0))
+	g.Add(graph.NewNode("short", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		invoked <- invocation{ec.ID, clock.Now()}
+		return nil, nil
+	}, graph.WithNodeStartJitter(time.Second)))
+
+	done := runAsync(g.CompileToExecutable(), graph.WithClock(clock), graph.WithStartJitter(time.Hour), graph.WithDeterministicScheduling(3))
+	// call-0 opted out, so it starts without the clock moving
+	if got := <-invoked; got.id != "call-0" {
+		t.Fatalf("Expected call-0 to start straight away, got %s", got.id)
+	}
+	clock.waitFor(t, 1)
+	clock.Advance(time.Second)
+	<-invoked
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+
+	if nr := res.report.Nodes["call-0"]; nr.StartJitter != 0 || nr.StartDelay != 0 {
+		t.Errorf("Expected no jitter for call-0, got %s", nr.StartJitter)
+	}
+	if nr := res.report.Nodes["short"]; nr.StartJitter >= time.Second {
+		t.Errorf("Expected short's own limit used, got %s", nr.StartJitter)
+	}
+}
+
+func TestStartJitterAddsToStartDelay(t *testing.T) {
+	clock := newFakeClock()
+	invoked := make(chan time.Time, 1)
+	done := runAsync(propagating(clock, invoked, time.Minute).CompileToExecutable(), graph.WithClock(clock), graph.WithStartJitter(time.Minute), graph.WithDeterministicScheduling(1))
+	// register is jittered too
+	clock.waitFor(t, 1)
+	clock.Advance(time.Minute)
+	clock.waitFor(t, 1)
+	clock.Advance(2 * time.Minute)
+	<-invoked
+	res := <-done
+
+	nr := res.report.Nodes["dns"]
+	if nr.StartJitter <= 0 || nr.StartDelay < time.Minute+nr.StartJitter {
+		t.Errorf("Expected the node to wait out the delay and the jitter, got %s with %s jitter", nr.StartDelay, nr.StartJitter)
+	}
+
+	data, err := json.Marshal(res.report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded graph.Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.Nodes["dns"].StartJitter; got != nr.StartJitter {
+		t.Errorf("Expected the jitter to survive JSON, got %s", got)
+	}
+}
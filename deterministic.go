@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Runs one node at a time, picking among the ready nodes in an order derived from seed, so two
+// runs with the same seed start, finish and report nodes in the same order. Anything
+// nondeterministic inside the fns themselves is out of scope.
+func WithDeterministicScheduling(seed int64) ExecOption {
+	return func(c *ExecConfig) {
+		c.Deterministic = true
+		c.Seed = seed
+	}
+}
+
+// Takes the next node off the ready queue; see WithFairScheduling for the default order
+func (r *run) next() NodeID {
+	i := 0
+	if r.rand != nil {
+		r.sortReady()
+		i = r.rand.Intn(len(r.ready))
+	} else if r.prioritized {
+		i = r.nextPriority()
+	} else if r.cfg.Fair {
+		i = r.nextFair()
+	}
+
+	id := r.ready[i]
+	if i == 0 {
+		// The common FIFO case; shifting the rest down would make a wide ready set quadratic
+		r.ready = r.ready[1:]
+	} else {
+		r.ready = append(r.ready[:i], r.ready[i+1:]...)
+	}
+	if r.rand != nil {
+		r.sorted = len(r.ready)
+	}
+	return id
+}
+
+// Puts a node that was just taken off the ready queue back at the front of it, or back in its
+// sorted place under deterministic scheduling
+func (r *run) requeue(id NodeID) {
+	if r.rand == nil {
+		r.ready = append([]NodeID{id}, r.ready...)
+		return
+	}
+	i := sort.Search(len(r.ready), func(i int) bool { return r.ready[i] >= id })
+	r.ready = append(r.ready, "")
+	copy(r.ready[i+1:], r.ready[i:])
+	r.ready[i] = id
+	r.sorted = len(r.ready)
+}
+
+// Sorts the nodes queued since the last pick and merges them into the already sorted front of
+// the queue, so a wide ready set isn't sorted from scratch on every pick
+func (r *run) sortReady() {
+	if r.sorted == len(r.ready) {
+		return
+	}
+	fresh := append([]NodeID(nil), r.ready[r.sorted:]...)
+	sortIDs(fresh)
+
+	// Merge from the back so the sorted front only moves as far as the new nodes push it
+	i, j := r.sorted-1, len(fresh)-1
+	for k := len(r.ready) - 1; j >= 0; k-- {
+		if i >= 0 && r.ready[i] > fresh[j] {
+			r.ready[k] = r.ready[i]
+			i--
+		} else {
+			r.ready[k] = fresh[j]
+			j--
+		}
+	}
+	r.sorted = len(r.ready)
+}
+
+func newDeterministicRand(cfg ExecConfig) *rand.Rand {
+	if !cfg.Deterministic {
+		return nil
+	}
+	return rand.New(rand.NewSource(cfg.Seed))
+}
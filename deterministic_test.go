@@ -0,0 +1,99 @@
+package graph_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// width independent branches, each a chain of depth nodes
+func branches(t *testing.T, width, depth int) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("branches")
+	for b := 0; b < width; b++ {
+		for d := 0; d < depth; d++ {
+			var deps graph.NodeIDs
+			if d > 0 {
+				deps = graph.Deps(graph.NodeID(fmt.Sprintf("b%d_%d", b, d-1)))
+			}
+			if _, err := g.Add(graph.NewNode(fmt.Sprintf("b%d_%d", b, d), deps, graph.NoOp())); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return g
+}
+
+func runSeeded(t *testing.T, peg *graph.ParallelizedExecutableGraph, seed int64) []graph.Event {
+	t.Helper()
+
+	report, err := peg.Run(ctx(t), graph.WithDeterministicScheduling(seed), graph.WithEventLog(100_000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report.Events
+}
+
+// The run's events without their timestamps, which come from the real clock
+func eventSequence(t *testing.T, peg *graph.ParallelizedExecutableGraph, seed int64) []string {
+	t.Helper()
+
+	events := runSeeded(t, peg, seed)
+	seq := make([]string, len(events))
+	for i, e := range events {
+		seq[i] = fmt.Sprintf("%d %s %s %d %s", e.Seq, e.Kind, e.Node, e.Attempt, e.Status)
+	}
+	return seq
+}
+
+func TestDeterministicSameSeedSameEvents(t *testing.T) {
+	peg := branches(t, 8, 3).CompileToExecutable()
+
+	first := eventSequence(t, peg, 42)
+	for i := 0; i < 5; i++ {
+		if again := eventSequence(t, peg, 42); !reflect.DeepEqual(first, again) {
+			t.Fatalf("Expected run %d to repeat the events of the first, got\n%v\nthen\n%v", i+2, first, again)
+		}
+	}
+}
+
+func TestDeterministicSeedsDiffer(t *testing.T) {
+	peg := branches(t, 8, 3).CompileToExecutable()
+
+	base := eventSequence(t, peg, 1)
+	for seed := int64(2); seed < 10; seed++ {
+		if !reflect.DeepEqual(base, eventSequence(t, peg, seed)) {
+			return
+		}
+	}
+	t.Error("Expected some seed other than 1 to change the order")
+}
+
+func TestDeterministicWideReadySet(t *testing.T) {
+	// Every root is ready at once, and each finishing root adds its dependent to the queue
+	peg := branches(t, 2000, 2).CompileToExecutable()
+
+	if first, again := eventSequence(t, peg, 7), eventSequence(t, peg, 7); !reflect.DeepEqual(first, again) {
+		t.Error("Expected a wide ready set to be picked from in the same order")
+	}
+}
+
+func TestDeterministicRunsOneAtATime(t *testing.T) {
+	peg := branches(t, 4, 2).CompileToExecutable()
+
+	running := 0
+	for _, e := range runSeeded(t, peg, 3) {
+		switch e.Kind {
+		case graph.EventNodeStarted:
+			running++
+		case graph.EventNodeFinished:
+			running--
+		}
+		if running > 1 {
+			t.Fatalf("Expected one node running at a time, got %d when %s started", running, e.Node)
+		}
+	}
+}
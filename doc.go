@@ -0,0 +1,5 @@
+// Package graph builds graphs of dependent nodes, orders them and runs them in parallel.
+//
+// Build a Graph with NewGraph and Add, check it with Validate or Sort, then compile it with
+// CompileToExecutable and Run the result. cmd/example has a small demo.
+package graph
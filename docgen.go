@@ -0,0 +1,303 @@
+package graph
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Metadata keys WriteDoc gives sections of their own; tags and phases are comma separated
+const (
+	DocDescriptionKey = "description"
+	DocOwnerKey       = "owner"
+	DocTagsKey        = "tags"
+	DocPhaseKey       = "phase"
+)
+
+type DocFormat int
+
+const (
+	DocMarkdown DocFormat = iota
+	DocHTML
+)
+
+// Placeholder for nodes without a description
+const docMissingDescription = "No description. Add one with the description metadata key."
+
+// One node's section of the runbook
+type docNode struct {
+	id          NodeID
+	anchor      string
+	description string
+	// Label and value pairs, in the order written
+	facts [][2]string
+	// Links to other nodes' sections, or plain ids for ones not in the graph
+	dependencies []docLink
+	dependents   []docLink
+	// The node's other metadata, sorted by key
+	metadata [][2]string
+}
+
+type docLink struct {
+	id     NodeID
+	anchor string
+	note   string
+}
+
+// WriteDoc writes a runbook for the graph: the Mermaid diagram from ToMermaid, a table of
+// contents, then one section per node, dependencies first, with its description, owner, phases
+// and tags from metadata, its dependencies and dependents as links, its timeout and retry
+// settings and the rest of its metadata. Output is deterministic for a given graph.
+func (g *Graph) WriteDoc(w io.Writer, format DocFormat) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	sorted, err := g.sort()
+	if err != nil {
+		return err
+	}
+	order := g.docOrder(sorted)
+
+	anchors := docAnchors(order)
+	dependents := map[NodeID][]docLink{}
+	nodes := make([]docNode, 0, len(order))
+	for _, id := range order {
+		node := g.nodes[id]
+		d := docNode{id: id, anchor: anchors[id], description: node.Metadata[DocDescriptionKey]}
+
+		if owner := node.Metadata[DocOwnerKey]; owner != "" {
+			d.facts = append(d.facts, [2]string{"Owner", owner})
+		}
+		if phases := docList(node.Metadata[DocPhaseKey]); phases != "" {
+			d.facts = append(d.facts, [2]string{"Phases", phases})
+		}
+		if tags := docList(node.Metadata[DocTagsKey]); tags != "" {
+			d.facts = append(d.facts, [2]string{"Tags", tags})
+		}
+		if node.marker {
+			d.facts = append(d.facts, [2]string{"Kind", "marker"})
+		}
+		if node.approval {
+			d.facts = append(d.facts, [2]string{"Kind", "approval"})
+		}
+		if node.assertion {
+			d.facts = append(d.facts, [2]string{"Kind", "assertion"})
+		}
+		if node.timeout > 0 {
+			d.facts = append(d.facts, [2]string{"Timeout", fmt.Sprintf("%s, then %s", node.timeout, timeoutBehaviorNames[node.onTimeout])})
+		}
+		if node.retry.MaxAttempts > 1 {
+			retry := fmt.Sprintf("%d attempts", node.retry.MaxAttempts)
+			if node.retry.Backoff > 0 {
+				retry += fmt.Sprintf(", %s apart", node.retry.Backoff)
+			}
+			if len(node.retry.RetryOn) > 0 {
+				categories := make([]string, 0, len(node.retry.RetryOn))
+				for _, c := range node.retry.RetryOn {
+					categories = append(categories, string(c))
+				}
+				retry += ", on " + strings.Join(categories, ", ")
+			}
+			d.facts = append(d.facts, [2]string{"Retry", retry})
+		}
+		if node.allowFailure {
+			d.facts = append(d.facts, [2]string{"Failure", "allowed"})
+		}
+
+		for _, depId := range sortedIDs(g.dependencies(node)) {
+			link := docLink{id: depId, anchor: anchors[depId]}
+			if _, optional := node.optionalDependencies[depId]; optional {
+				link.note = "optional"
+			}
+			if node.DependencyKind(depId) == EdgeSoft {
+				link.note = "soft"
+			}
+			d.dependencies = append(d.dependencies, link)
+			dependents[depId] = append(dependents[depId], docLink{id: id, anchor: anchors[id], note: link.note})
+		}
+
+		for _, key := range sortedKeys(node.Metadata) {
+			switch key {
+			case DocDescriptionKey, DocOwnerKey, DocTagsKey, DocPhaseKey:
+				continue
+			}
+			d.metadata = append(d.metadata, [2]string{key, node.Metadata[key]})
+		}
+		nodes = append(nodes, d)
+	}
+	for i := range nodes {
+		nodes[i].dependents = dependents[nodes[i].id]
+	}
+
+	diagram := g.buildView(exportConfig{}).mermaid()
+	if format == DocHTML {
+		_, err = io.WriteString(w, docHTML(g.name, diagram, nodes))
+	} else {
+		_, err = io.WriteString(w, docMarkdown(g.name, diagram, nodes))
+	}
+	return err
+}
+
+// sorted rearranged so it's the same every time: by how deep each node sits below the nodes with
+// no dependencies, then by id. The caller holds the read lock.
+func (g *Graph) docOrder(sorted SortedNodeIDs) SortedNodeIDs {
+	depth := make(map[NodeID]int, len(sorted))
+	for _, id := range sorted {
+		d := 0
+		// Dependencies come first in sorted; missing ones, allowed by WithLazyAdd, don't count
+		for depId := range g.dependencies(g.nodes[id]) {
+			if below, ok := depth[depId]; ok && below+1 > d {
+				d = below + 1
+			}
+		}
+		depth[id] = d
+	}
+
+	order := append(SortedNodeIDs{}, sorted...)
+	sort.Slice(order, func(i, j int) bool {
+		if depth[order[i]] != depth[order[j]] {
+			return depth[order[i]] < depth[order[j]]
+		}
+		return order[i] < order[j]
+	})
+	return order
+}
+
+// A unique anchor per node, made of lowercase letters, digits and dashes
+func docAnchors(order SortedNodeIDs) map[NodeID]string {
+	anchors := make(map[NodeID]string, len(order))
+	used := map[string]bool{}
+	for _, id := range order {
+		slug := strings.Trim(strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return '-'
+		}, string(id)), "-")
+		anchor := "node-" + slug
+		for n := 2; used[anchor]; n++ {
+			anchor = fmt.Sprintf("node-%s-%d", slug, n)
+		}
+		used[anchor] = true
+		anchors[id] = anchor
+	}
+	return anchors
+}
+
+// A comma-separated metadata value, tidied
+func docList(value string) string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return strings.Join(items, ", ")
+}
+
+func docMarkdown(name, diagram string, nodes []docNode) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "# %s\n\n```mermaid\n%s```\n\n## Contents\n\n", name, diagram)
+	for _, d := range nodes {
+		fmt.Fprintf(b, "- [%s](#%s)\n", d.id, d.anchor)
+	}
+
+	links := func(links []docLink) string {
+		parts := make([]string, 0, len(links))
+		for _, l := range links {
+			part := fmt.Sprintf("`%s`", l.id)
+			if l.anchor != "" {
+				part = fmt.Sprintf("[%s](#%s)", l.id, l.anchor)
+			}
+			if l.note != "" {
+				part += " (" + l.note + ")"
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	for _, d := range nodes {
+		fmt.Fprintf(b, "\n<a id=\"%s\"></a>\n\n## %s\n\n", d.anchor, d.id)
+		if d.description != "" {
+			fmt.Fprintf(b, "%s\n\n", d.description)
+		} else {
+			fmt.Fprintf(b, "> **TODO:** %s\n\n", docMissingDescription)
+		}
+		for _, f := range d.facts {
+			fmt.Fprintf(b, "- **%s:** %s\n", f[0], f[1])
+		}
+		if len(d.dependencies) > 0 {
+			fmt.Fprintf(b, "- **Depends on:** %s\n", links(d.dependencies))
+		}
+		if len(d.dependents) > 0 {
+			fmt.Fprintf(b, "- **Needed by:** %s\n", links(d.dependents))
+		}
+		if len(d.metadata) > 0 {
+			b.WriteString("\n| Metadata | Value |\n| --- | --- |\n")
+			for _, m := range d.metadata {
+				fmt.Fprintf(b, "| %s | %s |\n", markdownCell(m[0]), markdownCell(m[1]))
+			}
+		}
+	}
+	return b.String()
+}
+
+func docHTML(name, diagram string, nodes []docNode) string {
+	esc := html.EscapeString
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n", esc(name))
+	fmt.Fprintf(b, "<h1>%s</h1>\n<pre class=\"mermaid\">\n%s</pre>\n<h2>Contents</h2>\n<ul>\n", esc(name), esc(diagram))
+	for _, d := range nodes {
+		fmt.Fprintf(b, "<li><a href=\"#%s\">%s</a></li>\n", d.anchor, esc(string(d.id)))
+	}
+	b.WriteString("</ul>\n")
+
+	links := func(links []docLink) string {
+		parts := make([]string, 0, len(links))
+		for _, l := range links {
+			part := fmt.Sprintf("<code>%s</code>", esc(string(l.id)))
+			if l.anchor != "" {
+				part = fmt.Sprintf("<a href=\"#%s\">%s</a>", l.anchor, esc(string(l.id)))
+			}
+			if l.note != "" {
+				part += " (" + l.note + ")"
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	for _, d := range nodes {
+		fmt.Fprintf(b, "<section id=\"%s\">\n<h2>%s</h2>\n", d.anchor, esc(string(d.id)))
+		if d.description != "" {
+			fmt.Fprintf(b, "<p>%s</p>\n", esc(d.description))
+		} else {
+			fmt.Fprintf(b, "<p class=\"missing\"><strong>TODO:</strong> %s</p>\n", docMissingDescription)
+		}
+		b.WriteString("<ul>\n")
+		for _, f := range d.facts {
+			fmt.Fprintf(b, "<li><strong>%s:</strong> %s</li>\n", f[0], esc(f[1]))
+		}
+		if len(d.dependencies) > 0 {
+			fmt.Fprintf(b, "<li><strong>Depends on:</strong> %s</li>\n", links(d.dependencies))
+		}
+		if len(d.dependents) > 0 {
+			fmt.Fprintf(b, "<li><strong>Needed by:</strong> %s</li>\n", links(d.dependents))
+		}
+		b.WriteString("</ul>\n")
+		if len(d.metadata) > 0 {
+			b.WriteString("<table>\n<tr><th>Metadata</th><th>Value</th></tr>\n")
+			for _, m := range d.metadata {
+				fmt.Fprintf(b, "<tr><td>%s</td><td>%s</td></tr>\n", esc(m[0]), esc(m[1]))
+			}
+			b.WriteString("</table>\n")
+		}
+		b.WriteString("</section>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package graph_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A small deploy runbook touching every part of a section
+func runbook() *graph.Graph {
+	g := graph.NewGraph("deploy-runbook", graph.WithLazyAdd())
+	g.Add(graph.NewNode("Build API", nil, graph.NoOp(),
+		graph.WithMetadata(graph.DocDescriptionKey, "Compiles the API image & pushes it."),
+		graph.WithMetadata(graph.DocOwnerKey, "platform"),
+		graph.WithMetadata(graph.DocTagsKey, "ci, docker ,"),
+		graph.WithMetadata(graph.DocPhaseKey, "build"),
+		graph.WithMetadata("runner", "large|x86"),
+		graph.WithRetry(graph.RetryPolicy{MaxAttempts: 3, Backoff: 10 * time.Second, RetryOn: []graph.ErrorCategory{graph.CategoryTimedOut}}),
+	))
+	g.Add(graph.NewNode("build-api", nil, graph.NoOp(), graph.WithMetadata(graph.DocDescriptionKey, "An id that slugs like Build API.")))
+	g.Add(graph.NewNode("migrate", graph.Deps("Build API"), graph.NoOp(),
+		graph.WithMetadata(graph.DocOwnerKey, "data"),
+		graph.WithMetadata(graph.DocPhaseKey, "deploy,database"),
+		graph.WithTimeout(5*time.Minute),
+		graph.WithOptionalDependency("seed"),
+	))
+	g.Add(graph.NewApprovalNode("sign-off", graph.Deps("migrate")))
+	g.Add(graph.NewNode("deploy", graph.Deps("sign-off", "build-api"), graph.NoOp(),
+		graph.WithMetadata(graph.DocDescriptionKey, "Rolls out <api> to production."),
+		graph.WithSoftDependency("smoke"),
+		graph.WithTimeout(time.Minute, graph.OnTimeoutSkip),
+		graph.AllowFailure(),
+	))
+	g.Add(graph.NewNode("smoke", nil, graph.NoOp()))
+	g.Add(graph.NewNode("seed", nil, graph.NoOp(), graph.WithMetadata(graph.DocDescriptionKey, "Loads fixtures when present.")))
+	g.Add(graph.NewNode("done", graph.Deps("deploy"), graph.NoOp(), graph.AsMarker()))
+	return g
+}
+
+func writeDoc(t *testing.T, g *graph.Graph, format graph.DocFormat) string {
+	t.Helper()
+
+	var b bytes.Buffer
+	if err := g.WriteDoc(&b, format); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestWriteDocMarkdownGolden(t *testing.T) {
+	golden(t, "runbook.md.golden", writeDoc(t, runbook(), graph.DocMarkdown))
+}
+
+func TestWriteDocHTMLGolden(t *testing.T) {
+	golden(t, "runbook.html.golden", writeDoc(t, runbook(), graph.DocHTML))
+}
+
+func TestWriteDocDeterministic(t *testing.T) {
+	for _, format := range []graph.DocFormat{graph.DocMarkdown, graph.DocHTML} {
+		first := writeDoc(t, runbook(), format)
+		for i := 0; i < 10; i++ {
+			if got := writeDoc(t, runbook(), format); got != first {
+				t.Fatalf("Expected the same document every time, got\n%s\nthen\n%s", first, got)
+			}
+		}
+	}
+}
+
+func TestWriteDocSections(t *testing.T) {
+	g := runbook()
+	doc := writeDoc(t, g, graph.DocMarkdown)
+
+	// One section per node, each after its dependencies'
+	section := map[graph.NodeID]int{}
+	for _, id := range mustSort(t, g) {
+		heading := "\n## " + string(id) + "\n"
+		if strings.Count(doc, heading) != 1 {
+			t.Fatalf("Expected one section for %s", id)
+		}
+		section[id] = strings.Index(doc, heading)
+	}
+	for _, e := range g.Edges() {
+		if section[e.From] < section[e.To] {
+			t.Errorf("Expected %s's section after %s's", e.From, e.To)
+		}
+	}
+	if diagram := strings.Index(doc, "```mermaid\n"); diagram < 0 || diagram > strings.Index(doc, "## Contents") {
+		t.Error("Expected the diagram ahead of the contents")
+	}
+
+	// Ids that slug alike still get their own anchors
+	for _, want := range []string{"(#node-build-api)", "(#node-build-api-2)"} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("Expected a link to %s", want)
+		}
+	}
+	if n := strings.Count(doc, "> **TODO:**"); n != 4 {
+		t.Errorf("Expected the placeholder for the 4 nodes without a description, got %d", n)
+	}
+	if !strings.Contains(doc, "[seed](#node-seed) (optional)") || !strings.Contains(doc, "[smoke](#node-smoke) (soft)") {
+		t.Error("Expected the edge kinds noted on the links")
+	}
+
+	// An optional dependency that's absent isn't an edge, so it isn't listed
+	g.Remove("seed")
+	if doc := writeDoc(t, g, graph.DocMarkdown); !strings.Contains(doc, "- **Depends on:** [Build API](#node-build-api)\n") {
+		t.Error("Expected the absent optional dependency left out")
+	}
+}
+
+func TestWriteDocHTMLEscapes(t *testing.T) {
+	doc := writeDoc(t, runbook(), graph.DocHTML)
+	if strings.Contains(doc, "<api>") || !strings.Contains(doc, "Rolls out &lt;api&gt; to production.") {
+		t.Error("Expected the description escaped")
+	}
+	if !strings.Contains(doc, "Compiles the API image &amp; pushes it.") {
+		t.Error("Expected ampersands escaped")
+	}
+}
+
+func TestWriteDocCycle(t *testing.T) {
+	g := graph.NewGraph("cyclic", graph.WithLazyAdd())
+	g.Add(graph.NewNode("a", graph.Deps("b"), graph.NoOp()))
+	g.Add(graph.NewNode("b", graph.Deps("a"), graph.NoOp()))
+
+	var b bytes.Buffer
+	if err := g.WriteDoc(&b, graph.DocMarkdown); err == nil {
+		t.Error("Expected a graph that can't be sorted to fail")
+	}
+	if b.Len() != 0 {
+		t.Errorf("Expected nothing written, got %q", b.String())
+	}
+}
@@ -0,0 +1,289 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A graph as the DOT and Mermaid exports draw it
+type graphView struct {
+	name  string
+	nodes []viewNode
+	edges []viewEdge
+}
+
+type viewNode struct {
+	id        NodeID
+	marker    bool
+	alias     bool
+	assertion bool
+	// Extra lines under the id
+	notes []string
+}
+
+type viewEdge struct {
+	Edge
+	soft     bool
+	optional bool
+	// Why the compiler added the edge; empty for edges drawn as declared
+	derived string
+	weight  float64
+}
+
+func (e viewEdge) labels() []string {
+	labels := []string{}
+	if e.optional {
+		labels = append(labels, "optional")
+	}
+	if e.derived != "" {
+		labels = append(labels, e.derived)
+	}
+	if e.weight != 0 {
+		labels = append(labels, "weight "+strconv.FormatFloat(e.weight, 'g', -1, 64))
+	}
+	return labels
+}
+
+// The graph as authored: edges point at the ids declared, aliases are nodes of their own with
+// an edge to their target, and selectors are listed under the node declaring them rather than
+// drawn. Optional dependencies are drawn only when the node they name exists. Markers are
+// diamonds, assertions hexagons, soft edges are dashed with hollow arrowheads and optional ones
+// are dotted. Edges with a nonzero weight are labeled with it. HideMarkers leaves markers out,
+// drawing their dependents' edges through them gray and labeled with the marker.
+func (g *Graph) ToDOT(opts ...ExportOption) string {
+	return g.view(newExportConfig(opts)).dot()
+}
+
+// ToDOT's view as a Mermaid flowchart. Soft edges end in a circle; optional ones are labeled.
+func (g *Graph) ToMermaid(opts ...ExportOption) string {
+	return g.view(newExportConfig(opts)).mermaid()
+}
+
+// The graph as it will execute: aliases resolved, optional dependencies bound or dropped and
+// selectors expanded into edges. Edges the compiler derived rather than copied are gray and
+// labeled with the alias or selector they came from; the rest is drawn as Graph.ToDOT draws it.
+func (peg *ParallelizedExecutableGraph) ToDOT() string {
+	return peg.view().dot()
+}
+
+// ToDOT's view as a Mermaid flowchart; derived edges are dotted and soft ones say so in their label
+func (peg *ParallelizedExecutableGraph) ToMermaid() string {
+	return peg.view().mermaid()
+}
+
+func (g *Graph) view(cfg exportConfig) graphView {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.buildView(cfg)
+}
+
+// The caller holds the read lock. Edges come from nodeEdges, as Graph.Edges does; those a
+// selector matched are left to the node's notes, and aliased ones point at the alias. Hidden
+// markers take their aliases with them, and edges through them are drawn as derived.
+func (g *Graph) buildView(cfg exportConfig) graphView {
+	v := graphView{name: g.name}
+	for _, id := range sortedIDs(g.nodes) {
+		node := g.nodes[id]
+		if node.marker && cfg.hideMarkers {
+			continue
+		}
+
+		n := viewNode{id: id, marker: node.marker, assertion: node.assertion}
+		for _, s := range node.selectors {
+			n.notes = append(n.notes, "selects "+s.String())
+		}
+		v.nodes = append(v.nodes, n)
+
+		declared := g.nodeEdges(id)
+		var collapsed map[NodeID]collapsedEdge
+		if cfg.hideMarkers {
+			declared, collapsed = g.collapseMarkers(node, declared)
+		}
+
+		edges := []viewEdge{}
+		for _, e := range declared {
+			_, hard := node.Dependencies[e.declared]
+			_, optional := node.optionalDependencies[e.declared]
+			if !hard && !optional {
+				continue
+			}
+			edges = append(edges, viewEdge{
+				Edge:     Edge{From: id, To: e.declared},
+				soft:     node.DependencyKind(e.declared) == EdgeSoft,
+				optional: optional && !hard,
+				weight:   g.weight(node, e.declared),
+			})
+		}
+		for target, c := range collapsed {
+			edges = append(edges, viewEdge{Edge: Edge{From: id, To: target}, soft: !c.hard, derived: "via " + string(c.via)})
+		}
+		// nodeEdges sorts by the resolved id
+		sort.Slice(edges, func(i, j int) bool { return edges[i].To < edges[j].To })
+		v.edges = append(v.edges, edges...)
+	}
+
+	for _, alias := range sortedIDs(g.aliases) {
+		if cfg.hideMarkers && g.isMarker(g.aliases[alias]) {
+			continue
+		}
+		v.nodes = append(v.nodes, viewNode{id: alias, alias: true})
+		v.edges = append(v.edges, viewEdge{Edge: Edge{From: alias, To: g.aliases[alias]}})
+	}
+	return v
+}
+
+func (peg *ParallelizedExecutableGraph) view() graphView {
+	v := graphView{name: peg.name}
+	for _, id := range sortedIDs(peg.nodes) {
+		node := peg.nodes[id]
+		v.nodes = append(v.nodes, viewNode{id: id, marker: node.marker, assertion: node.assertion})
+
+		// The alias each resolved dependency was declared through, unless it was also declared
+		// directly
+		via := map[NodeID]NodeID{}
+		for alias, target := range node.aliases {
+			if existing, ok := via[target]; !ok || alias < existing {
+				via[target] = alias
+			}
+		}
+
+		for _, depId := range sortedIDs(node.dependencies) {
+			_, soft := node.softIDs[depId]
+			e := viewEdge{Edge: Edge{From: id, To: depId}, soft: soft, weight: node.weights[depId]}
+			_, hard := node.declaredIDs[depId]
+			_, optional := node.optionalIDs[depId]
+			alias, aliased := via[depId]
+			switch {
+			case hard || optional:
+				e.optional = optional && !hard
+			case aliased:
+				_, declared := node.declaredIDs[alias]
+				e.optional = !declared
+				e.derived = "via " + string(alias)
+			default:
+				e.derived = "selector"
+			}
+			v.edges = append(v.edges, e)
+		}
+	}
+	return v
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func (v graphView) dot() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "digraph %s {\n", dotQuote(v.name))
+
+	for _, n := range v.nodes {
+		attrs := []string{}
+		if len(n.notes) > 0 {
+			attrs = append(attrs, "label="+dotQuote(strings.Join(append([]string{string(n.id)}, n.notes...), "\n")))
+		}
+		if n.marker {
+			attrs = append(attrs, "shape=diamond")
+		}
+		if n.alias {
+			attrs = append(attrs, "shape=note")
+		}
+		if n.assertion {
+			attrs = append(attrs, "shape=hexagon")
+		}
+		writeDOTStatement(b, dotQuote(string(n.id)), attrs)
+	}
+
+	for _, e := range v.edges {
+		attrs := []string{}
+		switch {
+		case e.optional:
+			attrs = append(attrs, "style=dotted")
+		case e.soft:
+			attrs = append(attrs, "style=dashed")
+		}
+		if e.soft {
+			attrs = append(attrs, "arrowhead=empty")
+		}
+		if e.derived != "" {
+			attrs = append(attrs, "color=gray")
+		}
+		if e.derived != "" || e.weight != 0 {
+			attrs = append(attrs, "label="+dotQuote(strings.Join(e.labels(), ", ")))
+		}
+		writeDOTStatement(b, dotQuote(string(e.From))+" -> "+dotQuote(string(e.To)), attrs)
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func writeDOTStatement(b *strings.Builder, statement string, attrs []string) {
+	b.WriteString("  " + statement)
+	if len(attrs) > 0 {
+		b.WriteString(" [" + strings.Join(attrs, ", ") + "]")
+	}
+	b.WriteString(";\n")
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "\n", "<br>").Replace(s) + `"`
+}
+
+// Mermaid ids can't hold arbitrary text, so nodes are numbered in the order they're drawn and
+// labeled with their id
+func (v graphView) mermaid() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "---\ntitle: %s\n---\nflowchart TD\n", mermaidQuote(v.name))
+
+	refs := map[NodeID]string{}
+	ref := func(id NodeID) string {
+		if r, ok := refs[id]; ok {
+			return r
+		}
+		r := fmt.Sprintf("n%d", len(refs))
+		refs[id] = r
+		return r
+	}
+
+	for _, n := range v.nodes {
+		label := mermaidQuote(strings.Join(append([]string{string(n.id)}, n.notes...), "\n"))
+		switch {
+		case n.marker:
+			fmt.Fprintf(b, "  %s{%s}\n", ref(n.id), label)
+		case n.alias:
+			fmt.Fprintf(b, "  %s>%s]\n", ref(n.id), label)
+		case n.assertion:
+			fmt.Fprintf(b, "  %s{{%s}}\n", ref(n.id), label)
+		default:
+			fmt.Fprintf(b, "  %s[%s]\n", ref(n.id), label)
+		}
+	}
+	// With WithLazyAdd, missing dependencies point at ids that aren't nodes
+	for _, e := range v.edges {
+		if _, ok := refs[e.To]; !ok {
+			fmt.Fprintf(b, "  %s[%s]\n", ref(e.To), mermaidQuote(string(e.To)))
+		}
+	}
+
+	for _, e := range v.edges {
+		arrow := "-->"
+		switch {
+		case e.derived != "":
+			arrow = "-.->"
+		case e.soft:
+			arrow = "--o"
+		}
+		if labels := e.labels(); len(labels) > 0 {
+			if e.soft && e.derived != "" {
+				labels = append([]string{"soft"}, labels...)
+			}
+			arrow += "|" + mermaidQuote(strings.Join(labels, ", ")) + "|"
+		}
+		fmt.Fprintf(b, "  %s %s %s\n", ref(e.From), arrow, ref(e.To))
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package graph_test
+
+import (
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Two extractors behind a phase barrier that selects them, a weighted hand-off from transform to
+// load, and a report that reaches load through the warehouse alias and optionally waits on audit,
+// which exists, and on lint, which doesn't
+func phased(t *testing.T) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("etl")
+	g.Add(graph.NewNode("extract-orders", nil, graph.NoOp(), graph.WithMetadata("phase", "extract")))
+	g.Add(graph.NewNode("extract-users", nil, graph.NoOp(), graph.WithMetadata("phase", "extract")))
+	g.Add(graph.NewNode("extracted", nil, graph.NoOp(), graph.AsMarker(), graph.WithDependencySelector("phase", "extract")))
+	g.Add(graph.NewNode("transform", graph.Deps("extracted"), graph.NoOp()))
+	g.Add(graph.NewNode("load", nil, graph.NoOp()))
+	if err := g.AddEdge("load", "transform", 2.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Alias("warehouse", "load"); err != nil {
+		t.Fatal(err)
+	}
+	g.Add(graph.NewNode("audit", nil, graph.NoOp()))
+	g.Add(graph.NewNode("report", graph.Deps("warehouse"), graph.NoOp(),
+		graph.WithSoftDependency("transform"), graph.WithOptionalDependency("audit"), graph.WithOptionalDependency("lint")))
+	return g
+}
+
+func TestDOTAuthoredAndCompiled(t *testing.T) {
+	g := phased(t)
+	peg := g.CompileToExecutable()
+
+	golden(t, "dot_authored.golden", g.ToDOT())
+	golden(t, "dot_compiled.golden", peg.ToDOT())
+	golden(t, "mermaid_authored.golden", g.ToMermaid())
+	golden(t, "mermaid_compiled.golden", peg.ToMermaid())
+}
+
+func TestDOTDeterministic(t *testing.T) {
+	authored, compiled := phased(t).ToDOT(), phased(t).CompileToExecutable().ToDOT()
+	for i := 0; i < 20; i++ {
+		g := phased(t)
+		if got := g.ToDOT(); got != authored {
+			t.Fatalf("Expected the same authored DOT every time, got\n%s\nthen\n%s", authored, got)
+		}
+		if got := g.CompileToExecutable().ToDOT(); got != compiled {
+			t.Fatalf("Expected the same compiled DOT every time, got\n%s\nthen\n%s", compiled, got)
+		}
+	}
+}
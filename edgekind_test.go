@@ -0,0 +1,103 @@
+package graph_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestSoftDependencyFailureDoesNotSkip(t *testing.T) {
+	g := graph.NewGraph("kinds")
+	g.Add(graph.NewNode("flaky", nil, failWith(errors.New("boom"))))
+	g.Add(graph.NewNode("build", nil, graph.NoOp()))
+	g.Add(graph.NewNode("report", graph.Deps("build"), graph.NoOp(), graph.WithSoftDependency("flaky")))
+
+	report, _ := g.CompileToExecutable().Run(ctx(t))
+
+	if status := report.Nodes["flaky"].Status; status != graph.StatusFailed {
+		t.Fatalf("Expected flaky to fail, got %s", status)
+	}
+	if status := report.Nodes["report"].Status; status != graph.StatusSucceeded {
+		t.Errorf("Expected report to run after its failed soft dependency, got %s", status)
+	}
+}
+
+func TestHardDependencyFailureSkips(t *testing.T) {
+	g := graph.NewGraph("kinds")
+	g.Add(graph.NewNode("flaky", nil, failWith(errors.New("boom"))))
+	g.Add(graph.NewNode("report", graph.Deps("flaky"), graph.NoOp()))
+
+	report, err := g.CompileToExecutable().Run(ctx(t))
+	if err == nil {
+		t.Fatal("Expected the run to fail")
+	}
+	if status := report.Nodes["report"].Status; status != graph.StatusSkipped {
+		t.Errorf("Expected report to be skipped, got %s", status)
+	}
+}
+
+func TestSoftDependencyStillOrders(t *testing.T) {
+	g := graph.NewGraph("kinds")
+	g.Add(graph.NewNode("first", nil, graph.NoOp()))
+	g.Add(graph.NewNode("second", nil, graph.NoOp()))
+	if err := g.AddEdgeKind("first", "second", graph.EdgeSoft); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := mustSort(t, g); !reflect.DeepEqual(ids, graph.SortedNodeIDs{"second", "first"}) {
+		t.Errorf("Expected the soft edge to order second first, got %v", ids)
+	}
+
+	node, _ := g.Get("first")
+	if kind := node.DependencyKind("second"); kind != graph.EdgeSoft {
+		t.Errorf("Expected a soft edge, got %s", kind)
+	}
+}
+
+func TestEdgeKindExports(t *testing.T) {
+	g := graph.NewGraph("kinds")
+	g.Add(graph.NewNode("a", nil, graph.NoOp()))
+	g.Add(graph.NewNode("b", nil, graph.NoOp()))
+	g.Add(graph.NewNode("c", graph.Deps("a"), graph.NoOp(), graph.WithSoftDependency("b")))
+
+	dot := g.ToDOT()
+	if !strings.Contains(dot, `"c" -> "b" [style=dashed, arrowhead=empty]`) {
+		t.Errorf("Expected the soft edge to be drawn dashed, got:\n%s", dot)
+	}
+	if !strings.Contains(dot, `"c" -> "a";`) {
+		t.Errorf("Expected the hard edge to be plain, got:\n%s", dot)
+	}
+
+	var b bytes.Buffer
+	if err := g.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Nodes []struct {
+			ID               graph.NodeID   `json:"id"`
+			Dependencies     []graph.NodeID `json:"dependencies"`
+			SoftDependencies []graph.NodeID `json:"softDependencies"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, n := range doc.Nodes {
+		if n.ID != "c" {
+			continue
+		}
+		found = true
+		if !reflect.DeepEqual(n.Dependencies, []graph.NodeID{"a"}) || !reflect.DeepEqual(n.SoftDependencies, []graph.NodeID{"b"}) {
+			t.Errorf("Expected c to list a as hard and b as soft, got %v and %v", n.Dependencies, n.SoftDependencies)
+		}
+	}
+	if !found {
+		t.Error("Expected c in the JSON")
+	}
+}
@@ -0,0 +1,220 @@
+package graph
+
+import (
+	"fmt"
+)
+
+type EdgeKind int
+
+const (
+	// The dependent is skipped when the dependency fails
+	EdgeHard EdgeKind = iota
+	// Ordering only; the dependent runs once the dependency finishes, whatever the outcome
+	EdgeSoft
+)
+
+func (k EdgeKind) String() string {
+	switch k {
+	case EdgeHard:
+		return "hard"
+	case EdgeSoft:
+		return "soft"
+	}
+	return fmt.Sprintf("EdgeKind(%d)", int(k))
+}
+
+func WithSoftDependency(id NodeID) NodeOption {
+	return func(n *Node) {
+		n.setDependency(id, EdgeSoft)
+	}
+}
+
+func (n *Node) setDependency(id NodeID, kind EdgeKind) {
+	if n.Dependencies == nil {
+		n.Dependencies = make(NodeIDs)
+	}
+	if _, ok := n.Dependencies[id]; ok {
+		n.countDuplicate(id)
+	}
+	n.Dependencies[id] = struct{}{}
+
+	if kind == EdgeHard {
+		delete(n.softDependencies, id)
+		return
+	}
+
+	if n.softDependencies == nil {
+		n.softDependencies = make(NodeIDs)
+	}
+	n.softDependencies[id] = struct{}{}
+}
+
+func (n *Node) DependencyKind(id NodeID) EdgeKind {
+	if _, ok := n.softDependencies[id]; ok {
+		return EdgeSoft
+	}
+	return EdgeHard
+}
+
+// AddEdge makes from depend on to. Both nodes must already be in the graph. An optional weight
+// replaces the graph's default weight for this edge.
+func (g *Graph) AddEdge(from, to NodeID, weight ...float64) error {
+	return g.AddEdgeKind(from, to, EdgeHard, weight...)
+}
+
+func (g *Graph) AddEdgeKind(from, to NodeID, kind EdgeKind, weight ...float64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	from, to = g.normalize(from), g.normalize(to)
+
+	if g.frozen {
+		return ErrGraphFrozen
+	}
+
+	node, ok := g.nodes[from]
+	if !ok {
+		return fmt.Errorf("Node %s does not exist", from)
+	}
+	if !g.exists(to) && !g.lazyAdd {
+		return fmt.Errorf("Node %s is missing dependency %s", from, to)
+	}
+
+	_, hard := node.Dependencies[to]
+	_, optional := node.optionalDependencies[to]
+	if !hard && !optional {
+		if err := g.checkEdgeLimit(node, 1); err != nil {
+			return err
+		}
+	}
+
+	if err := g.checkPolicies(node, NodeIDs{g.resolve(to): {}}, g.lookup); err != nil {
+		return err
+	}
+
+	if g.order != nil {
+		if _, ok := g.dependencies(node)[g.resolve(to)]; !ok {
+			if !g.exists(to) {
+				// Ordered once it's added
+				g.order.link(from, to)
+			} else if err := g.order.insert(from, g.resolve(to), g); err != nil {
+				return err
+			}
+		}
+	}
+
+	owned := g.ownNode(from)
+	owned.setDependency(to, kind)
+	if len(weight) > 0 {
+		if owned.weights == nil {
+			owned.weights = make(map[NodeID]float64)
+		}
+		owned.weights[to] = weight[0]
+	}
+	g.changed()
+	return nil
+}
+
+// Optional dependencies only become edges if the node exists when the graph is validated or compiled
+func WithOptionalDependency(id NodeID) NodeOption {
+	return func(n *Node) {
+		if n.optionalDependencies == nil {
+			n.optionalDependencies = make(NodeIDs)
+		}
+
+		_, hard := n.Dependencies[id]
+		_, optional := n.optionalDependencies[id]
+		if hard || optional {
+			n.countDuplicate(id)
+		}
+		n.optionalDependencies[id] = struct{}{}
+	}
+}
+
+// Declared dependencies plus the optional ones present in the graph and the nodes selectors
+// match, with aliases resolved
+func (g *Graph) dependencies(node *Node) NodeIDs {
+	if len(node.optionalDependencies) == 0 && len(g.aliases) == 0 && len(node.selectors) == 0 {
+		return node.Dependencies
+	}
+
+	deps := make(NodeIDs, len(node.Dependencies)+len(node.optionalDependencies))
+	for depId := range node.Dependencies {
+		deps[g.resolve(depId)] = struct{}{}
+	}
+	for depId := range node.optionalDependencies {
+		if g.exists(depId) {
+			deps[g.resolve(depId)] = struct{}{}
+		}
+	}
+	for _, s := range node.selectors {
+		for depId := range g.selected(node, s) {
+			deps[depId] = struct{}{}
+		}
+	}
+	return deps
+}
+
+// The dependencies a node actually has in this graph, with optional ones resolved
+func (g *Graph) ResolvedDependencies(id NodeID) (NodeIDs, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	id = g.normalize(id)
+
+	node, ok := g.nodes[id]
+	if !ok {
+		return nil, fmt.Errorf("Node %s does not exist", id)
+	}
+
+	resolved := make(NodeIDs)
+	for depId := range g.dependencies(node) {
+		resolved[depId] = struct{}{}
+	}
+	return resolved, nil
+}
+
+// From depends on To, so To runs first. Every export writes edges in this direction: GraphML
+// sources and DOT and Mermaid tails are From, and targets and heads To.
+type Edge struct {
+	From NodeID
+	To   NodeID
+}
+
+// An edge along with the dependency id its node declared, which differs from To for aliases
+type declaredEdge struct {
+	Edge
+	declared NodeID
+}
+
+// The node's edges with aliases resolved, sorted by To
+func (g *Graph) nodeEdges(id NodeID) []declaredEdge {
+	declared := g.declared(g.nodes[id])
+
+	edges := make([]declaredEdge, 0, len(declared))
+	for _, depId := range sortedIDs(declared) {
+		edges = append(edges, declaredEdge{Edge: Edge{From: id, To: depId}, declared: declared[depId]})
+	}
+	return edges
+}
+
+// Every edge in the graph with aliases resolved, sorted by From then To
+func (g *Graph) Edges() []Edge {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	edges := []Edge{}
+	for _, id := range sortedIDs(g.nodes) {
+		for _, e := range g.nodeEdges(id) {
+			edges = append(edges, e.Edge)
+		}
+	}
+	return edges
+}
+
+func (g *Graph) EdgeCount() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.edgeCount()
+}
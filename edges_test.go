@@ -0,0 +1,212 @@
+package graph_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestEdgesOrderAndDirection(t *testing.T) {
+	// Added out of order, so only sorting can put them in this order
+	g := graph.NewGraph("edges", graph.WithLazyAdd())
+	g.Add(graph.NewNode("d", graph.Deps("c", "b"), graph.NoOp()))
+	g.Add(graph.NewNode("b", graph.Deps("a"), graph.NoOp()))
+	g.Add(graph.NewNode("c", graph.Deps("a"), graph.NoOp(), graph.WithSoftDependency("b")))
+	g.Add(graph.NewNode("a", nil, graph.NoOp()))
+
+	want := []graph.Edge{
+		{From: "b", To: "a"},
+		{From: "c", To: "a"},
+		{From: "c", To: "b"},
+		{From: "d", To: "b"},
+		{From: "d", To: "c"},
+	}
+	if got := g.Edges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected edges %v, got %v", want, got)
+	}
+	if n := g.EdgeCount(); n != len(want) {
+		t.Errorf("Expected %d edges, got %d", len(want), n)
+	}
+
+	// From depends on To, so To comes first in the sort
+	ids := mustSort(t, g)
+	for _, e := range g.Edges() {
+		if !before(ids, e.To, e.From) {
+			t.Errorf("Expected %s before %s in %v", e.To, e.From, ids)
+		}
+	}
+}
+
+func TestEdgesResolveAliasesAndSelectors(t *testing.T) {
+	g := graph.NewGraph("edges")
+	g.Add(graph.NewNode("db", nil, graph.NoOp(), graph.WithMetadata("kind", "store")))
+	g.Add(graph.NewNode("cache", nil, graph.NoOp(), graph.WithMetadata("kind", "store")))
+	g.Alias("storage", "db")
+	g.Add(graph.NewNode("api", graph.Deps("storage"), graph.NoOp()))
+	g.Add(graph.NewNode("web", nil, graph.NoOp(), graph.WithDependencySelector("kind", "store")))
+
+	want := []graph.Edge{
+		{From: "api", To: "db"},
+		{From: "web", To: "cache"},
+		{From: "web", To: "db"},
+	}
+	if got := g.Edges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected edges %v, got %v", want, got)
+	}
+	if n := g.EdgeCount(); n != len(want) {
+		t.Errorf("Expected %d edges, got %d", len(want), n)
+	}
+
+	// The exports that resolve edges draw exactly these
+	for name, got := range map[string][]graph.Edge{
+		"GraphML":       graphMLEdgeList(t, g),
+		"WeightedEdges": weightedEdgeList(g),
+		"compiled DOT":  dotEdges(g.CompileToExecutable().ToDOT()),
+	} {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %s to draw %v, got %v", name, want, got)
+		}
+	}
+}
+
+// A graph without aliases, optional dependencies or selectors, where declared and resolved
+// edges are the same, so every export must agree with Edges
+func TestExportersAgreeWithEdges(t *testing.T) {
+	g := graph.NewGraph("release")
+	g.Add(graph.NewNode("build", nil, graph.NoOp()))
+	g.Add(graph.NewNode("lint", nil, graph.NoOp()))
+	g.Add(graph.NewNode("test", graph.Deps("build"), graph.NoOp()))
+	g.Add(graph.NewNode("deploy", graph.Deps("test", "build"), graph.NoOp(), graph.WithSoftDependency("lint")))
+	want := g.Edges()
+
+	var adj bytes.Buffer
+	if err := g.WriteAdjacency(&adj); err != nil {
+		t.Fatal(err)
+	}
+	peg := g.CompileToExecutable()
+
+	for name, got := range map[string][]graph.Edge{
+		"GraphML":          graphMLEdgeList(t, g),
+		"WeightedEdges":    weightedEdgeList(g),
+		"DOT":              dotEdges(g.ToDOT()),
+		"compiled DOT":     dotEdges(peg.ToDOT()),
+		"Mermaid":          mermaidEdges(t, g.ToMermaid()),
+		"compiled Mermaid": mermaidEdges(t, peg.ToMermaid()),
+		"JSON":             jsonEdges(t, g),
+		"adjacency":        adjacencyEdges(adj.String()),
+	} {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %s to draw %v, got %v", name, want, got)
+		}
+	}
+}
+
+func sortEdges(edges []graph.Edge) []graph.Edge {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+func graphMLEdgeList(t *testing.T, g *graph.Graph) []graph.Edge {
+	t.Helper()
+
+	hidden, _ := graphMLEdges(t, g)
+	edges := []graph.Edge{}
+	for _, e := range hidden {
+		edges = append(edges, graph.Edge{From: graph.NodeID(e.source), To: graph.NodeID(e.target)})
+	}
+	return edges
+}
+
+func weightedEdgeList(g *graph.Graph) []graph.Edge {
+	edges := []graph.Edge{}
+	for _, e := range g.WeightedEdges() {
+		edges = append(edges, e.Edge)
+	}
+	return edges
+}
+
+var dotEdge = regexp.MustCompile(`^\s*"([^"]*)" -> "([^"]*)"`)
+
+func dotEdges(dot string) []graph.Edge {
+	edges := []graph.Edge{}
+	for _, line := range strings.Split(dot, "\n") {
+		if m := dotEdge.FindStringSubmatch(line); m != nil {
+			edges = append(edges, graph.Edge{From: graph.NodeID(m[1]), To: graph.NodeID(m[2])})
+		}
+	}
+	return sortEdges(edges)
+}
+
+var (
+	mermaidNode = regexp.MustCompile(`^\s*(n\d+)[\[{>]+"([^"]*)"`)
+	mermaidEdge = regexp.MustCompile(`^\s*(n\d+) (?:-->|-\.->|--o)(?:\|[^|]*\|)? (n\d+)$`)
+)
+
+func mermaidEdges(t *testing.T, mermaid string) []graph.Edge {
+	t.Helper()
+
+	names := map[string]graph.NodeID{}
+	edges := []graph.Edge{}
+	for _, line := range strings.Split(mermaid, "\n") {
+		if m := mermaidNode.FindStringSubmatch(line); m != nil {
+			names[m[1]] = graph.NodeID(m[2])
+		}
+		if m := mermaidEdge.FindStringSubmatch(line); m != nil {
+			edges = append(edges, graph.Edge{From: names[m[1]], To: names[m[2]]})
+		}
+	}
+	return sortEdges(edges)
+}
+
+func jsonEdges(t *testing.T, g *graph.Graph) []graph.Edge {
+	t.Helper()
+
+	var b bytes.Buffer
+	if err := g.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	var def struct {
+		Nodes []struct {
+			ID               graph.NodeID   `json:"id"`
+			Dependencies     []graph.NodeID `json:"dependencies"`
+			SoftDependencies []graph.NodeID `json:"softDependencies"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &def); err != nil {
+		t.Fatal(err)
+	}
+
+	edges := []graph.Edge{}
+	for _, n := range def.Nodes {
+		for _, depId := range append(n.Dependencies, n.SoftDependencies...) {
+			edges = append(edges, graph.Edge{From: n.ID, To: depId})
+		}
+	}
+	return sortEdges(edges)
+}
+
+// Reads the id: deps lines, dropping the soft and optional markers
+func adjacencyEdges(adj string) []graph.Edge {
+	edges := []graph.Edge{}
+	for _, line := range strings.Split(adj, "\n") {
+		id, deps, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(id, "#") {
+			continue
+		}
+		for _, depId := range strings.Fields(deps) {
+			edges = append(edges, graph.Edge{From: graph.NodeID(id), To: graph.NodeID(strings.TrimLeft(depId, "~?"))})
+		}
+	}
+	return sortEdges(edges)
+}
@@ -0,0 +1,30 @@
+package graph
+
+import (
+	"errors"
+)
+
+var ErrEmptyGraph = errors.New("Graph has no nodes")
+
+// Lets Sort and Run accept a graph with no nodes: Sort returns an empty order and Run an empty
+// report. Without it both fail with ErrEmptyGraph, since an empty graph is usually a loader that
+// quietly produced nothing.
+func AllowEmpty() GraphOption {
+	return func(g *Graph) {
+		g.allowEmpty = true
+	}
+}
+
+func (g *Graph) checkEmpty() error {
+	if len(g.nodes) == 0 && !g.allowEmpty {
+		return ErrEmptyGraph
+	}
+	return nil
+}
+
+func (peg *ParallelizedExecutableGraph) checkEmpty() error {
+	if len(peg.nodes) == 0 && !peg.allowEmpty {
+		return ErrEmptyGraph
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package graph_test
+
+import (
+	"errors"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestEmptyGraphRejected(t *testing.T) {
+	g := graph.NewGraph("empty")
+
+	if _, err := g.Sort(); !errors.Is(err, graph.ErrEmptyGraph) {
+		t.Errorf("Expected Sort to fail with ErrEmptyGraph, got %v", err)
+	}
+	if _, err := g.CompileToExecutable().Run(ctx(t)); !errors.Is(err, graph.ErrEmptyGraph) {
+		t.Errorf("Expected Run to fail with ErrEmptyGraph, got %v", err)
+	}
+}
+
+func TestEmptyAfterRemove(t *testing.T) {
+	g := graph.NewGraph("emptied")
+	g.Add(graph.NewNode("a", nil, graph.NoOp()))
+	mustSort(t, g)
+
+	g.Remove("a")
+	if _, err := g.Sort(); !errors.Is(err, graph.ErrEmptyGraph) {
+		t.Errorf("Expected a graph emptied by Remove to be rejected, got %v", err)
+	}
+}
+
+func TestAllowEmpty(t *testing.T) {
+	g := graph.NewGraph("empty", graph.AllowEmpty())
+
+	ids, err := g.Sort()
+	if err != nil || len(ids) != 0 {
+		t.Errorf("Expected an empty order, got %v, %v", ids, err)
+	}
+
+	report, err := g.CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report == nil || len(report.Nodes) != 0 {
+		t.Errorf("Expected an empty report, got %v", report)
+	}
+}
@@ -0,0 +1,104 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// MarkEntryPoint declares id, a node or an alias of one, as a place runs of the graph start
+// from. Once a graph has entry points, Warnings flags the nodes no entry point reaches.
+func (g *Graph) MarkEntryPoint(id NodeID) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id = g.normalize(id)
+
+	if g.frozen {
+		return ErrGraphFrozen
+	}
+	if !g.exists(id) {
+		return &NodeNotFoundError{ID: id}
+	}
+
+	if g.entryPoints == nil {
+		g.entryPoints = make(NodeIDs)
+	}
+	g.entryPoints[g.resolve(id)] = struct{}{}
+	g.changed()
+	return nil
+}
+
+// Sorted by id
+func (g *Graph) EntryPoints() SortedNodeIDs {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return sortedIDs(g.entryPoints)
+}
+
+// The nodes that are neither an entry point nor a transitive dependency or dependent of one,
+// sorted by id; nil when the graph has no entry points
+func (g *Graph) unreachable() SortedNodeIDs {
+	if len(g.entryPoints) == 0 {
+		return nil
+	}
+
+	dependents := map[NodeID][]NodeID{}
+	for id, node := range g.nodes {
+		for depId := range g.dependencies(node) {
+			dependents[depId] = append(dependents[depId], id)
+		}
+	}
+
+	upstream := func(id NodeID) []NodeID {
+		node, ok := g.nodes[id]
+		if !ok {
+			return nil
+		}
+		return sortedIDs(g.dependencies(node))
+	}
+	downstream := func(id NodeID) []NodeID {
+		return dependents[id]
+	}
+
+	// Walked separately, so a dependent of one entry point's dependency isn't counted as reached
+	reached := reach(g.entryPoints, upstream)
+	for id := range reach(g.entryPoints, downstream) {
+		reached[id] = struct{}{}
+	}
+
+	unreachable := SortedNodeIDs{}
+	for _, id := range sortedIDs(g.nodes) {
+		if _, ok := reached[id]; !ok {
+			unreachable = append(unreachable, id)
+		}
+	}
+	return unreachable
+}
+
+// The ids in from and everything next leads to from them
+func reach(from NodeIDs, next func(id NodeID) []NodeID) NodeIDs {
+	reached := make(NodeIDs, len(from))
+	queue := sortedIDs(from)
+	for _, id := range queue {
+		reached[id] = struct{}{}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, other := range next(id) {
+			if _, ok := reached[other]; !ok {
+				reached[other] = struct{}{}
+				queue = append(queue, other)
+			}
+		}
+	}
+	return reached
+}
+
+func unreachableWarning(ids SortedNodeIDs) Warning {
+	return Warning{
+		Kind:    WarningUnreachable,
+		Nodes:   ids,
+		Message: fmt.Sprintf("No entry point reaches %s", joinIDs(ids, stringLimit)),
+	}
+}
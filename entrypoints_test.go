@@ -0,0 +1,115 @@
+package graph_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// nightly pulls in extract and transform, and publish runs after it; the legacy pair hangs off
+// nothing
+func withEntryPoint(t *testing.T) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("jobs")
+	g.Add(graph.NewNode("extract", nil, graph.NoOp()))
+	g.Add(graph.NewNode("transform", graph.Deps("extract"), graph.NoOp()))
+	g.Add(graph.NewNode("nightly", graph.Deps("transform"), graph.NoOp()))
+	g.Add(graph.NewNode("publish", graph.Deps("nightly"), graph.NoOp()))
+	g.Add(graph.NewNode("legacy-export", nil, graph.NoOp()))
+	g.Add(graph.NewNode("legacy-upload", graph.Deps("legacy-export"), graph.NoOp()))
+	if err := g.MarkEntryPoint("nightly"); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func unreachableNodes(g *graph.Graph) []graph.NodeID {
+	warnings := warningsOf(g, graph.WarningUnreachable)
+	if len(warnings) == 0 {
+		return nil
+	}
+	return warnings[0].Nodes
+}
+
+func TestUnreachableOrphanCluster(t *testing.T) {
+	g := withEntryPoint(t)
+
+	warnings := warningsOf(g, graph.WarningUnreachable)
+	if len(warnings) != 1 {
+		t.Fatalf("Expected one unreachable warning, got %v", warnings)
+	}
+	if want := []graph.NodeID{"legacy-export", "legacy-upload"}; !reflect.DeepEqual(warnings[0].Nodes, want) {
+		t.Errorf("Expected exactly %v flagged, got %v", want, warnings[0].Nodes)
+	}
+	if want := "No entry point reaches legacy-export legacy-upload"; warnings[0].Message != want {
+		t.Errorf("Expected %q, got %q", want, warnings[0].Message)
+	}
+}
+
+func TestUnreachableWalksEachDirectionAlone(t *testing.T) {
+	g := withEntryPoint(t)
+	// Depends on nightly's dependency but not on nightly, so no run from nightly needs it
+	g.Add(graph.NewNode("audit", graph.Deps("extract"), graph.NoOp()))
+
+	if want := []graph.NodeID{"audit", "legacy-export", "legacy-upload"}; !reflect.DeepEqual(unreachableNodes(g), want) {
+		t.Errorf("Expected %v flagged, got %v", want, unreachableNodes(g))
+	}
+}
+
+func TestUnreachableWithoutEntryPoints(t *testing.T) {
+	if got := warningsOf(diamond(t), graph.WarningUnreachable); len(got) != 0 {
+		t.Errorf("Expected the rule skipped without entry points, got %v", got)
+	}
+}
+
+func TestMarkEntryPoint(t *testing.T) {
+	g := withEntryPoint(t)
+	g.Alias("legacy", "legacy-upload")
+	if err := g.MarkEntryPoint("legacy"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (graph.SortedNodeIDs{"legacy-upload", "nightly"}); !reflect.DeepEqual(g.EntryPoints(), want) {
+		t.Errorf("Expected the alias resolved to its node, got %v", g.EntryPoints())
+	}
+	if got := unreachableNodes(g); got != nil {
+		t.Errorf("Expected every node reached, got %v", got)
+	}
+
+	var nerr *graph.NodeNotFoundError
+	if err := g.MarkEntryPoint("missing"); !errors.As(err, &nerr) || nerr.ID != "missing" {
+		t.Errorf("Expected a NodeNotFoundError, got %v", err)
+	}
+}
+
+func TestEntryPointsFollowTheGraph(t *testing.T) {
+	g := withEntryPoint(t)
+
+	clone := g.Clone()
+	if !reflect.DeepEqual(clone.EntryPoints(), g.EntryPoints()) {
+		t.Errorf("Expected the clone to keep the entry points, got %v", clone.EntryPoints())
+	}
+
+	if err := g.Remove("publish"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Remove("nightly"); err != nil {
+		t.Fatal(err)
+	}
+	if got := g.EntryPoints(); len(got) != 0 {
+		t.Errorf("Expected the entry point dropped with its node, got %v", got)
+	}
+	if got := unreachableNodes(g); got != nil {
+		t.Errorf("Expected the rule skipped once no entry points are left, got %v", got)
+	}
+	if got := clone.EntryPoints(); !reflect.DeepEqual(got, graph.SortedNodeIDs{"nightly"}) {
+		t.Errorf("Expected the clone unaffected, got %v", got)
+	}
+
+	g.Freeze()
+	if err := g.MarkEntryPoint("extract"); !errors.Is(err, graph.ErrGraphFrozen) {
+		t.Errorf("Expected a frozen graph to refuse, got %v", err)
+	}
+}
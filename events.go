@@ -0,0 +1,147 @@
+package graph
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type EventKind int
+
+const (
+	EventRunStarted EventKind = iota
+	// One per attempt
+	EventNodeStarted
+	EventNodeFinished
+	// The attempt failed and another follows
+	EventNodeRetrying
+	// The node's final result, including nodes that were skipped or never ran
+	EventNodeResult
+	EventRunCanceled
+	EventRunFinished
+	// In place of run-canceled when the run was stopped with RunHandle.Abort
+	EventRunAborted
+	// An approval node parked; the event carries its token
+	EventAwaitingApproval
+)
+
+var eventNames = map[EventKind]string{
+	EventRunStarted:       "run-started",
+	EventNodeStarted:      "node-started",
+	EventNodeFinished:     "node-finished",
+	EventNodeRetrying:     "node-retrying",
+	EventNodeResult:       "node-result",
+	EventRunCanceled:      "run-canceled",
+	EventRunFinished:      "run-finished",
+	EventRunAborted:       "run-aborted",
+	EventAwaitingApproval: "awaiting-approval",
+}
+
+func (k EventKind) String() string {
+	if name, ok := eventNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+func (k EventKind) MarshalText() ([]byte, error) {
+	if _, ok := eventNames[k]; !ok {
+		return nil, fmt.Errorf("Unknown event kind %d", int(k))
+	}
+	return []byte(k.String()), nil
+}
+
+func (k *EventKind) UnmarshalText(text []byte) error {
+	for kind, name := range eventNames {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown event kind %q", text)
+}
+
+// Node, Attempt, Status, Reason, Err and Token are set as they apply to the kind
+type Event struct {
+	// Increases by one per event, starting at 1
+	Seq     uint64
+	Time    time.Time
+	Kind    EventKind
+	Node    NodeID
+	Attempt int
+	Status  NodeStatus
+	Reason  StatusReason
+	Err     error
+	Token   string
+}
+
+// Records the run's events in the report, keeping the first limit of them; zero disables the log
+func WithEventLog(limit int) ExecOption {
+	return func(c *ExecConfig) {
+		c.EventLimit = limit
+	}
+}
+
+type eventLog struct {
+	mu      sync.Mutex
+	limit   int
+	seq     uint64
+	events  []Event
+	dropped int
+	// From WithListeners, with the logger their panics go to
+	listeners []Listener
+	logger    Logger
+}
+
+// Nil when the run neither keeps events nor has listeners
+func newEventLog(cfg ExecConfig) *eventLog {
+	if cfg.EventLimit <= 0 && len(cfg.Listeners) == 0 {
+		return nil
+	}
+	return &eventLog{limit: cfg.EventLimit, listeners: cfg.Listeners, logger: cfg.Logger}
+}
+
+// Safe on a nil log, which records nothing
+func (l *eventLog) record(clock Clock, e Event) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	e.Seq = l.seq
+	e.Time = clock.Now()
+	l.notify(e)
+
+	if l.limit <= 0 {
+		return
+	}
+	if len(l.events) >= l.limit {
+		l.dropped++
+		return
+	}
+	l.events = append(l.events, e)
+}
+
+func (r *run) event(e Event) {
+	r.events.record(r.clock(), e)
+}
+
+func (r *run) nodeEvent(kind EventKind, nr NodeReport) {
+	r.event(Event{Kind: kind, Node: nr.ID, Status: nr.Status, Reason: nr.Reason, Err: nr.Err})
+}
+
+// Copies the log into the report
+func (r *run) fillEvents() {
+	if r.events == nil || r.events.limit <= 0 {
+		return
+	}
+
+	r.events.mu.Lock()
+	defer r.events.mu.Unlock()
+
+	r.report.Events = append([]Event(nil), r.events.events...)
+	r.report.EventsDropped = r.events.dropped
+}
@@ -0,0 +1,103 @@
+package graph_test
+
+import (
+	"errors"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// a feeds b, which fails once before succeeding, and c, which fails; d needs both
+func eventful(t *testing.T) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("eventful")
+	g.Add(graph.NewNode("a", nil, graph.NoOp()))
+	g.Add(graph.NewNode("b", graph.Deps("a"), failTimes(1), graph.WithRetry(graph.RetryPolicy{MaxAttempts: 2})))
+	g.Add(graph.NewNode("c", graph.Deps("a"), failWith(errors.New("boom"))))
+	g.Add(graph.NewNode("d", graph.Deps("b", "c"), graph.NoOp()))
+	return g
+}
+
+func TestEventLogReplaysToReport(t *testing.T) {
+	report, _ := eventful(t).CompileToExecutable().Run(ctx(t), graph.WithEventLog(100))
+
+	events := report.Events
+	if len(events) == 0 {
+		t.Fatal("Expected events in the report")
+	}
+	if events[0].Kind != graph.EventRunStarted || events[len(events)-1].Kind != graph.EventRunFinished {
+		t.Errorf("Expected the log to open with run-started and close with run-finished, got %s ... %s", events[0].Kind, events[len(events)-1].Kind)
+	}
+
+	replayed := map[graph.NodeID]graph.NodeStatus{}
+	attempts := map[graph.NodeID]int{}
+	for i, e := range events {
+		if e.Seq != uint64(i+1) {
+			t.Fatalf("Expected event %d to have seq %d, got %d", i, i+1, e.Seq)
+		}
+		if i > 0 && e.Time.Before(events[i-1].Time) {
+			t.Errorf("Expected event %d no earlier than the one before", e.Seq)
+		}
+
+		switch e.Kind {
+		case graph.EventNodeStarted:
+			attempts[e.Node]++
+		case graph.EventNodeResult:
+			if _, ok := replayed[e.Node]; ok {
+				t.Errorf("Expected one result event for %s", e.Node)
+			}
+			replayed[e.Node] = e.Status
+		}
+	}
+
+	for id, nr := range report.Nodes {
+		if replayed[id] != nr.Status {
+			t.Errorf("Expected the log to end %s as %s, got %s", id, nr.Status, replayed[id])
+		}
+		if attempts[id] != len(nr.Attempts) {
+			t.Errorf("Expected %d started events for %s, got %d", len(nr.Attempts), id, attempts[id])
+		}
+	}
+	if replayed["d"] != graph.StatusSkipped {
+		t.Errorf("Expected d's skip in the log, got %s", replayed["d"])
+	}
+}
+
+func TestEventLogLimit(t *testing.T) {
+	full, _ := eventful(t).CompileToExecutable().Run(ctx(t), graph.WithEventLog(1000))
+	capped, _ := eventful(t).CompileToExecutable().Run(ctx(t), graph.WithEventLog(3))
+
+	if len(capped.Events) != 3 {
+		t.Fatalf("Expected 3 events kept, got %d", len(capped.Events))
+	}
+	if capped.EventsDropped != len(full.Events)-3 {
+		t.Errorf("Expected %d events dropped, got %d", len(full.Events)-3, capped.EventsDropped)
+	}
+	if full.EventsDropped != 0 {
+		t.Errorf("Expected nothing dropped under the limit, got %d", full.EventsDropped)
+	}
+}
+
+func TestEventLogOffByDefault(t *testing.T) {
+	report, _ := eventful(t).CompileToExecutable().Run(ctx(t))
+	if report.Events != nil || report.EventsDropped != 0 {
+		t.Errorf("Expected no event log, got %d events", len(report.Events))
+	}
+}
+
+func TestEventKindText(t *testing.T) {
+	for _, kind := range []graph.EventKind{graph.EventRunStarted, graph.EventNodeRetrying, graph.EventRunFinished} {
+		text, err := kind.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back graph.EventKind
+		if err := back.UnmarshalText(text); err != nil || back != kind {
+			t.Errorf("Expected %s to round-trip, got %s, %v", kind, back, err)
+		}
+	}
+	if _, err := graph.EventKind(99).MarshalText(); err == nil {
+		t.Error("Expected an error for an unknown kind")
+	}
+}
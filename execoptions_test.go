@@ -0,0 +1,83 @@
+package graph_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestRunOptionsOverrideCompileOptions(t *testing.T) {
+	peg := diamond(t).CompileToExecutable(graph.WithMaxConcurrency(2))
+
+	if limit := peg.Config().MaxConcurrency; limit != 2 {
+		t.Errorf("Expected the compiled default of 2, got %d", limit)
+	}
+	if limit := peg.Config(graph.WithMaxConcurrency(5)).MaxConcurrency; limit != 5 {
+		t.Errorf("Expected the run's 5 to win, got %d", limit)
+	}
+	if limit := peg.Config().MaxConcurrency; limit != 2 {
+		t.Errorf("Expected an override not to stick, got %d", limit)
+	}
+}
+
+func TestCompileHooksFireWhenRunAddsLogger(t *testing.T) {
+	var started atomic.Int32
+	hooks := graph.Hooks{OnNodeStart: func(id graph.NodeID, attempt int) { started.Add(1) }}
+	peg := diamond(t).CompileToExecutable(graph.WithHooks(hooks))
+
+	logger := &recordingLogger{}
+	if _, err := peg.Run(ctx(t), graph.WithLogger(logger)); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := started.Load(); n != 4 {
+		t.Errorf("Expected the compiled hooks to see 4 starts, got %d", n)
+	}
+	if cfg := peg.Config(graph.WithLogger(logger)); cfg.Logger != logger || cfg.Hooks.OnNodeStart == nil {
+		t.Error("Expected the effective config to carry both the hooks and the logger")
+	}
+}
+
+func TestCompiledConcurrencyLimitApplies(t *testing.T) {
+	var running, peak atomic.Int32
+	fn := func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil, nil
+	}
+
+	g := graph.NewGraph("wide")
+	for i := 0; i < 8; i++ {
+		g.Add(graph.NewNode(fmt.Sprintf("n%d", i), nil, fn))
+	}
+	if _, err := g.CompileToExecutable(graph.WithMaxConcurrency(2)).Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	if p := peak.Load(); p > 2 {
+		t.Errorf("Expected at most 2 nodes at once, saw %d", p)
+	}
+}
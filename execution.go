@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"time"
+)
+
+// Values returned by a node's dependencies, also keyed by any alias a dependency was declared
+// through. A dependency that produced no value (for example one skipped on timeout) has no entry.
+type Results map[NodeID]any
+
+// ExecutionContext describes the invocation to the fn. Metadata, Inputs, Dependencies, Results
+// and RunConfig are copies made for this invocation, so changing them affects neither the graph
+// nor other nodes; the values inside Inputs and Results are not copied.
+type ExecutionContext struct {
+	ID           NodeID
+	Metadata     map[string]string
+	Inputs       map[string]any
+	Dependencies NodeIDs
+	Results      Results
+	RunID        string
+	RunConfig    map[string]string
+	Attempt      int
+
+	output   io.Writer
+	services map[reflect.Type]any
+	// Bounds the attempt, by clock; see RemainingBudget
+	deadline time.Time
+	clock    Clock
+	// Nil unless the run keeps race hints
+	touches *touchLog
+}
+
+// Adapts the original func(id) error shape to a NodeFn
+func FromIDFunc(fn func(id NodeID) error) NodeFn {
+	return func(ctx context.Context, ec *ExecutionContext) (any, error) {
+		return nil, fn(ec.ID)
+	}
+}
+
+// For fns that take nothing and produce no result. The fn can't see the run's ctx, so it can
+// only be abandoned, not stopped, on timeout or cancellation.
+func FromFunc(fn func() error) NodeFn {
+	return func(ctx context.Context, ec *ExecutionContext) (any, error) {
+		return nil, fn()
+	}
+}
+
+func FromContextFunc(fn func(ctx context.Context) error) NodeFn {
+	return func(ctx context.Context, ec *ExecutionContext) (any, error) {
+		return nil, fn(ctx)
+	}
+}
+
+// The value becomes the node's result; dependents can read it back typed with ResultAs
+func FromResultFunc[T any](fn func(ctx context.Context) (T, error)) NodeFn {
+	return func(ctx context.Context, ec *ExecutionContext) (any, error) {
+		value, err := fn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+// Succeeds at once with no result
+func NoOp() NodeFn {
+	return func(ctx context.Context, ec *ExecutionContext) (any, error) {
+		return nil, nil
+	}
+}
+
+// A dependency's result as a T, loaded first if it was stored under ExternalResults; false when
+// the dependency left no result, it couldn't be loaded or it isn't a T
+func ResultAs[T any](ec *ExecutionContext, id NodeID) (T, bool) {
+	value := ec.Results[id]
+	if ref, ok := value.(ResultRef); ok {
+		if _, wantRef := value.(T); !wantRef {
+			var err error
+			if value, err = ref.Load(); err != nil {
+				var zero T
+				return zero, false
+			}
+		}
+	}
+	typed, ok := value.(T)
+	return typed, ok
+}
+
+func WithMetadata(key, value string) NodeOption {
+	return func(n *Node) {
+		if n.Metadata == nil {
+			n.Metadata = make(map[string]string)
+		}
+		n.Metadata[key] = value
+	}
+}
+
+// Sets the node's inputs, adding to any set before
+func WithInputs(inputs map[string]any) NodeOption {
+	return func(n *Node) {
+		if n.Inputs == nil {
+			n.Inputs = make(map[string]any, len(inputs))
+		}
+		for key, value := range inputs {
+			n.Inputs[key] = value
+		}
+	}
+}
+
+// A string-valued input, or "" when it's missing or not a string
+func (ec *ExecutionContext) Input(key string) string {
+	s, _ := ec.Inputs[key].(string)
+	return s
+}
+
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,210 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestGenericFnBranchesOnMetadata(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[graph.NodeID]string{}
+
+	notify := func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		deps := []string{}
+		for id := range ec.Dependencies {
+			deps = append(deps, string(id))
+		}
+		sort.Strings(deps)
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch ec.Metadata["channel"] {
+		case "email":
+			seen[ec.ID] = fmt.Sprintf("mailed after %v", deps)
+		case "pager":
+			seen[ec.ID] = fmt.Sprintf("paged after %v", deps)
+		}
+		return nil, nil
+	}
+
+	g := graph.NewGraph("notify")
+	g.Add(graph.NewNode("build", nil, graph.NoOp()))
+	g.Add(graph.NewNode("test", nil, graph.NoOp()))
+	g.Add(graph.NewNode("mail", graph.Deps("build"), notify, graph.WithMetadata("channel", "email")))
+	g.Add(graph.NewNode("page", graph.Deps("build", "test"), notify, graph.WithMetadata("channel", "pager")))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[graph.NodeID]string{
+		"mail": "mailed after [build]",
+		"page": "paged after [build test]",
+	}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("Expected %v, got %v", want, seen)
+	}
+}
+
+func TestExecutionContextResultsAndRun(t *testing.T) {
+	var got *graph.ExecutionContext
+
+	g := graph.NewGraph("ec")
+	g.Add(graph.NewNode("a", nil, returns(42)))
+	g.Add(graph.NewNode("b", graph.Deps("a"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		got = ec
+		return nil, nil
+	}))
+
+	report, err := g.CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.ID != "b" || got.Attempt != 1 || got.RunID != report.RunID {
+		t.Errorf("Expected b's first attempt in run %s, got %s attempt %d in %s", report.RunID, got.ID, got.Attempt, got.RunID)
+	}
+	if got.Results["a"] != 42 {
+		t.Errorf("Expected a's result, got %v", got.Results)
+	}
+}
+
+func TestFromIDFunc(t *testing.T) {
+	var called graph.NodeID
+
+	g := graph.NewGraph("compat")
+	g.Add(graph.NewNode("legacy", nil, graph.FromIDFunc(func(id graph.NodeID) error {
+		called = id
+		return nil
+	})))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+	if called != "legacy" {
+		t.Errorf("Expected the adapted fn to get its id, got %q", called)
+	}
+}
+
+// Fns get their own copies, so one writing to them races with nothing and leaves later runs alone
+func TestExecutionContextCopies(t *testing.T) {
+	var mu sync.Mutex
+	seen := []string{}
+
+	scribble := func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		mu.Lock()
+		seen = append(seen, ec.Metadata["k"]+" "+fmt.Sprint(ec.Inputs["in"])+" "+fmt.Sprint(len(ec.Dependencies)))
+		mu.Unlock()
+
+		ec.Metadata["k"] = "changed"
+		ec.Inputs["in"] = "changed"
+		ec.Dependencies["extra"] = struct{}{}
+		return nil, nil
+	}
+
+	g := graph.NewGraph("copies")
+	g.Add(graph.NewNode("root", nil, graph.NoOp()))
+	for _, id := range []string{"x", "y"} {
+		g.Add(graph.NewNode(id, graph.Deps("root"), scribble, graph.WithMetadata("k", "v"), graph.WithInputs(map[string]any{"in": 1})))
+	}
+
+	peg := g.CompileToExecutable()
+	for i := 0; i < 2; i++ {
+		if _, err := peg.Run(ctx(t)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, s := range seen {
+		if s != "v 1 1" {
+			t.Errorf("Expected every invocation to see the node as declared, got %q", s)
+		}
+	}
+	if len(seen) != 4 {
+		t.Errorf("Expected 4 invocations, got %d", len(seen))
+	}
+}
+
+type client struct {
+	name string
+}
+
+func TestAdaptersInARun(t *testing.T) {
+	var ran sync.Map
+	var sawCtx bool
+
+	g := graph.NewGraph("adapters")
+	g.Add(graph.NewNode("plain", nil, graph.FromFunc(func() error {
+		ran.Store("plain", true)
+		return nil
+	})))
+	g.Add(graph.NewNode("contextual", nil, graph.FromContextFunc(func(ctx context.Context) error {
+		sawCtx = ctx.Value(client{}) == "marked"
+		return nil
+	})))
+	g.Add(graph.NewNode("connect", nil, graph.FromResultFunc(func(ctx context.Context) (*client, error) {
+		return &client{name: "db"}, nil
+	})))
+	g.Add(graph.NewNode("count", nil, graph.FromResultFunc(func(ctx context.Context) (int, error) {
+		return 3, nil
+	}), graph.KeepResult()))
+	g.Add(graph.NewNode("use", graph.Deps("connect", "count", "plain"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		c, ok := graph.ResultAs[*client](ec, "connect")
+		if !ok || c.name != "db" {
+			return nil, fmt.Errorf("Expected the typed client, got %v", ec.Results["connect"])
+		}
+		n, ok := graph.ResultAs[int](ec, "count")
+		if !ok || n != 3 {
+			return nil, fmt.Errorf("Expected the typed count, got %v", ec.Results["count"])
+		}
+		// The wrong type, and a dependency with no result
+		if _, ok := graph.ResultAs[string](ec, "count"); ok {
+			return nil, fmt.Errorf("Expected an int result not to read as a string")
+		}
+		if _, ok := graph.ResultAs[int](ec, "plain"); ok {
+			return nil, fmt.Errorf("Expected no result from plain")
+		}
+		return nil, nil
+	}))
+	g.Add(graph.NewNode("done", graph.Deps("use", "contextual"), graph.NoOp()))
+
+	report, err := g.CompileToExecutable().Run(context.WithValue(ctx(t), client{}, "marked"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ran.Load("plain"); !ok {
+		t.Error("Expected FromFunc's fn to run")
+	}
+	if !sawCtx {
+		t.Error("Expected FromContextFunc's fn to get the run's ctx")
+	}
+	if report.Results["count"] != 3 {
+		t.Errorf("Expected FromResultFunc's value as the result, got %v", report.Results["count"])
+	}
+	if report.Nodes["done"].Status != graph.StatusSucceeded {
+		t.Errorf("Expected every node to succeed, got %s; use said %v", report.Nodes["done"].Status, report.Nodes["use"].Err)
+	}
+}
+
+func TestAdaptersKeepErrorCategories(t *testing.T) {
+	bad := graph.Permanent(errors.New("bad config"))
+	for name, fn := range map[string]graph.NodeFn{
+		"FromFunc":        graph.FromFunc(func() error { return bad }),
+		"FromContextFunc": graph.FromContextFunc(func(ctx context.Context) error { return bad }),
+		"FromResultFunc": graph.FromResultFunc(func(ctx context.Context) (int, error) {
+			return 1, bad
+		}),
+	} {
+		nr := runOne(t, fn, []graph.NodeOption{graph.WithRetry(graph.RetryPolicy{MaxAttempts: 3})})
+		if nr.Category != graph.CategoryPermanent || len(nr.Attempts) != 1 {
+			t.Errorf("%s: expected one permanent attempt, got %s after %d", name, nr.Category, len(nr.Attempts))
+		}
+	}
+}
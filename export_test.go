@@ -0,0 +1,7 @@
+package graph
+
+// Overrides how many dependencies a compiled node waits for, to build states the compiler never
+// would
+func (peg *ParallelizedExecutableGraph) SetRequired(id NodeID, n int) {
+	peg.nodes[id].required = n
+}
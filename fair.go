@@ -0,0 +1,60 @@
+package graph
+
+// By default ready nodes are dispatched first come, first served: the roots in id order, then
+// the targets each finished node made ready, in id order, behind everything already waiting. A
+// branch that fans out widely can then hold every slot while another branch waits.
+//
+// WithFairScheduling instead takes turns between groups of ready nodes, one node per group in
+// group order, oldest first within a group. Nodes are grouped by the value of the metadata key
+// when one is given, nodes without it forming one group; otherwise by lineage, the first root by
+// id a node descends from. Deterministic scheduling takes precedence.
+func WithFairScheduling(metadataKey ...string) ExecOption {
+	return func(c *ExecConfig) {
+		c.Fair = true
+		c.FairKey = ""
+		if len(metadataKey) > 0 {
+			c.FairKey = metadataKey[0]
+		}
+	}
+}
+
+// The index in the ready queue of the next group's oldest node
+func (r *run) nextFair() int {
+	first := map[string]int{}
+	for i, id := range r.ready {
+		if _, ok := first[r.group(id)]; !ok {
+			first[r.group(id)] = i
+		}
+	}
+
+	groups := sortedKeys(first)
+	pick := groups[0]
+	for _, group := range groups {
+		if group > r.lastGroup {
+			pick = group
+			break
+		}
+	}
+
+	r.lastGroup = pick
+	return first[pick]
+}
+
+func (r *run) group(id NodeID) string {
+	if r.cfg.FairKey != "" {
+		return r.peg.nodes[id].metadata[r.cfg.FairKey]
+	}
+
+	if lineage, ok := r.lineages[id]; ok {
+		return string(lineage)
+	}
+
+	lineage := id
+	for _, depId := range sortedIDs(r.peg.nodes[id].dependencies) {
+		if root := NodeID(r.group(depId)); lineage == id || root < lineage {
+			lineage = root
+		}
+	}
+	r.lineages[id] = lineage
+	return string(lineage)
+}
@@ -0,0 +1,98 @@
+package graph_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Branch a fans out into 1000 quick nodes; branch b is the chain b1, b2, b3. Until b3 has run
+// the a nodes take hold each, so that with more than one slot the slot they're given outlasts a
+// b node's goroutine being scheduled.
+func lopsided(t *testing.T, hold time.Duration, opts ...graph.NodeOption) *graph.Graph {
+	t.Helper()
+
+	bDone := make(chan struct{})
+	quick := func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		if hold > 0 {
+			select {
+			case <-bDone:
+			case <-time.After(hold):
+			}
+		}
+		return nil, nil
+	}
+
+	g := graph.NewGraph("lopsided")
+	g.Add(graph.NewNode("a", nil, graph.NoOp()))
+	for i := 0; i < 1000; i++ {
+		g.Add(graph.NewNode(fmt.Sprintf("a%04d", i), graph.Deps("a"), quick))
+	}
+	g.Add(graph.NewNode("b1", nil, graph.NoOp(), opts...))
+	g.Add(graph.NewNode("b2", graph.Deps("b1"), graph.NoOp(), opts...))
+	g.Add(graph.NewNode("b3", graph.Deps("b2"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		close(bDone)
+		return nil, nil
+	}, opts...))
+	return g
+}
+
+// How many of branch a's fan-out were dispatched before b3
+func startedBeforeB3(t *testing.T, g *graph.Graph, concurrency int, opts ...graph.ExecOption) int {
+	t.Helper()
+
+	opts = append(opts, graph.WithMaxConcurrency(concurrency), graph.WithEventLog(10_000))
+	report, err := g.CompileToExecutable().Run(ctx(t), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	for _, e := range report.Events {
+		switch {
+		case e.Kind != graph.EventNodeStarted:
+		case e.Node == "b3":
+			return count
+		case strings.HasPrefix(string(e.Node), "a0"):
+			count++
+		}
+	}
+	t.Fatal("Expected b3 to start")
+	return 0
+}
+
+func TestFairSchedulingByLineage(t *testing.T) {
+	// The other slot keeps taking a nodes while b2 and b3 run, so only a loose bound holds
+	if got := startedBeforeB3(t, lopsided(t, 100*time.Microsecond), 2, graph.WithFairScheduling()); got > 20 {
+		t.Errorf("Expected b3 to start long before a drains, but %d of a's nodes started first", got)
+	}
+
+	// With one slot the groups strictly take turns: a, b1, a0000, b2, a0001, b3
+	if got := startedBeforeB3(t, lopsided(t, 0), 1, graph.WithFairScheduling()); got != 2 {
+		t.Errorf("Expected 2 of a's nodes before b3, got %d", got)
+	}
+}
+
+func TestFairSchedulingByMetadata(t *testing.T) {
+	// The a nodes have no team and share one group
+	team := graph.WithMetadata("team", "b")
+
+	if got := startedBeforeB3(t, lopsided(t, 100*time.Microsecond, team), 2, graph.WithFairScheduling("team")); got > 20 {
+		t.Errorf("Expected b3 to start long before a drains, but %d of a's nodes started first", got)
+	}
+	// The no-team group sorts first but b1 is dispatched ahead of a: b1, a, b2, a0000, b3
+	if got := startedBeforeB3(t, lopsided(t, 0, team), 1, graph.WithFairScheduling("team")); got != 1 {
+		t.Errorf("Expected 1 of a's nodes before b3, got %d", got)
+	}
+}
+
+func TestDefaultSchedulingIsFirstComeFirstServed(t *testing.T) {
+	// b1 is queued ahead of a's fan-out, but b2 only joins the queue behind all of it
+	if got := startedBeforeB3(t, lopsided(t, 0), 1); got != 1000 {
+		t.Errorf("Expected all of a's fan-out before b3, got %d", got)
+	}
+}
@@ -0,0 +1,100 @@
+package graph_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// n roots all feeding one aggregate node
+func fanIn(n int, root, aggregate graph.NodeFn) *graph.Graph {
+	g := graph.NewGraph("fan-in")
+	deps := make(graph.NodeIDs, n)
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("n%d", i)
+		g.Add(graph.NewNode(id, nil, root))
+		deps[graph.NodeID(id)] = struct{}{}
+	}
+	g.Add(graph.NewNode("aggregate", deps, aggregate))
+	return g
+}
+
+func TestWideFanInAggregateFiresOnce(t *testing.T) {
+	n := 80000
+	if testing.Short() {
+		n = 8000
+	}
+
+	var done, fired, early int64
+	g := fanIn(n, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		atomic.AddInt64(&done, 1)
+		return nil, nil
+	}, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		atomic.AddInt64(&fired, 1)
+		if atomic.LoadInt64(&done) != int64(n) {
+			atomic.AddInt64(&early, 1)
+		}
+		return nil, nil
+	})
+
+	report, err := g.CompileToExecutable().Run(ctx(t), graph.WithMaxConcurrency(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fired != 1 || early != 0 {
+		t.Errorf("Expected the aggregate to fire once after all %d roots, fired %d times, %d early", n, fired, early)
+	}
+	if got := len(report.WithStatus(graph.StatusSucceeded)); got != n+1 {
+		t.Errorf("Expected %d nodes to succeed, got %d", n+1, got)
+	}
+}
+
+// Ten times the roots should take about ten times the allocations to compile and run; quadratic
+// bookkeeping would take a hundred
+func TestWideFanInScalesLinearly(t *testing.T) {
+	allocs := func(n int) (compile, run float64) {
+		g := fanIn(n, graph.NoOp(), graph.NoOp())
+		compile = testing.AllocsPerRun(1, func() { g.CompileToExecutable() })
+		peg := g.CompileToExecutable()
+		run = testing.AllocsPerRun(1, func() { peg.Run(context.Background()) })
+		return compile, run
+	}
+
+	smallCompile, smallRun := allocs(2000)
+	largeCompile, largeRun := allocs(20000)
+	if ratio := largeCompile / smallCompile; ratio > 12 {
+		t.Errorf("Expected compile allocations to grow linearly, grew %.1fx for 10x the nodes", ratio)
+	}
+	if ratio := largeRun / smallRun; ratio > 12 {
+		t.Errorf("Expected run allocations to grow linearly, grew %.1fx for 10x the nodes", ratio)
+	}
+}
+
+// Reports the cost per node, which should stay about the same from 10k to 100k nodes
+func BenchmarkWideFanIn(b *testing.B) {
+	for _, n := range []int{10000, 100000} {
+		g := fanIn(n, graph.NoOp(), graph.NoOp())
+
+		b.Run(fmt.Sprintf("compile-%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				g.CompileToExecutable()
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/node")
+		})
+
+		peg := g.CompileToExecutable()
+		b.Run(fmt.Sprintf("run-%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := peg.Run(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/node")
+		})
+	}
+}
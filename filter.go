@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// What compiling does with an edge from a node a WithNodeFilter keeps to one it drops
+type CutPolicy int
+
+const (
+	// The compiled graph is broken, failing every run
+	OnCutError CutPolicy = iota
+	// The kept node depends on the dropped node's dependencies instead, through any number of
+	// dropped nodes, so ordering is kept. An inherited edge is hard when some path it stands for
+	// is hard throughout, and soft otherwise.
+	OnCutSplice
+)
+
+// Compiles only the nodes keep returns true for; the rest are left out of the compiled graph,
+// its reports and its exports. keep is called with a copy of each node after any transforms. An
+// edge from a kept node to a dropped one is handled by policy, OnCutError unless given; optional
+// dependencies on dropped nodes just stay unbound. Only CompileToExecutable reads the filter,
+// and a filtered graph can't be recompiled.
+func WithNodeFilter(keep func(n *Node) bool, policy ...CutPolicy) ExecOption {
+	return func(c *ExecConfig) {
+		c.NodeFilter = keep
+		c.OnCut = OnCutError
+		if len(policy) > 0 {
+			c.OnCut = policy[0]
+		}
+	}
+}
+
+// g without the nodes keep rejects, or g itself when it keeps them all. The caller holds the
+// read lock.
+func (g *Graph) filtered(keep func(n *Node) bool, policy CutPolicy) (*Graph, error) {
+	kept, dropped := NodeIDs{}, NodeIDs{}
+	for id, node := range g.nodes {
+		if keep(node.clone()) {
+			kept[id] = struct{}{}
+		} else {
+			dropped[id] = struct{}{}
+		}
+	}
+	if len(dropped) == 0 {
+		return g, nil
+	}
+
+	out := g.subgraph(g.name, kept)
+	out.transforms = nil
+
+	// The kept nodes a dropped node waits on, through any other dropped ones, and how
+	inherited := map[NodeID]map[NodeID]EdgeKind{}
+	var through func(id NodeID) map[NodeID]EdgeKind
+	through = func(id NodeID) map[NodeID]EdgeKind {
+		if found, ok := inherited[id]; ok {
+			return found
+		}
+		found := map[NodeID]EdgeKind{}
+		inherited[id] = found
+
+		node := g.nodes[id]
+		edges := map[NodeID]EdgeKind{}
+		for depId := range node.Dependencies {
+			edges[g.resolve(depId)] = node.DependencyKind(depId)
+		}
+		for depId := range node.optionalDependencies {
+			if target := g.resolve(depId); g.exists(target) {
+				if _, ok := edges[target]; !ok {
+					edges[target] = EdgeHard
+				}
+			}
+		}
+
+		for target, kind := range edges {
+			if _, ok := dropped[target]; !ok {
+				strongest(found, target, kind)
+				continue
+			}
+			for next, nextKind := range through(target) {
+				if kind == EdgeSoft {
+					nextKind = EdgeSoft
+				}
+				strongest(found, next, nextKind)
+			}
+		}
+		return found
+	}
+
+	for _, id := range sortedIDs(kept) {
+		node := out.nodes[id]
+		for _, depId := range sortedIDs(node.Dependencies) {
+			target := g.resolve(depId)
+			if _, ok := dropped[target]; !ok {
+				continue
+			}
+			if policy != OnCutSplice {
+				return nil, fmt.Errorf("Node %s depends on %s, which the node filter drops", id, depId)
+			}
+
+			kind := node.DependencyKind(depId)
+			delete(node.Dependencies, depId)
+			delete(node.softDependencies, depId)
+			delete(node.weights, depId)
+			for next, nextKind := range through(target) {
+				if kind == EdgeSoft {
+					nextKind = EdgeSoft
+				}
+				if _, ok := node.Dependencies[next]; ok {
+					if nextKind == EdgeHard {
+						delete(node.softDependencies, next)
+					}
+					continue
+				}
+				node.setDependency(next, nextKind)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Records an edge to id, hard if it or one already recorded is
+func strongest(found map[NodeID]EdgeKind, id NodeID, kind EdgeKind) {
+	if existing, ok := found[id]; !ok || existing == EdgeSoft {
+		found[id] = kind
+	}
+}
@@ -0,0 +1,134 @@
+package graph_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func notBilling(n *graph.Node) bool {
+	return n.Metadata["env"] != "prod"
+}
+
+// checkout feeds charge and ledger, both prod only; invoice needs ledger, and receipt waits
+// softly on charge. audit optionally reads ledger.
+func canonical(l *runLog) *graph.Graph {
+	prod := graph.WithMetadata("env", "prod")
+	g := graph.NewGraph("shop")
+	g.Add(graph.NewNode("checkout", nil, l.fn))
+	g.Add(graph.NewNode("charge", graph.Deps("checkout"), l.fn, prod))
+	g.Add(graph.NewNode("ledger", graph.Deps("charge"), l.fn, prod))
+	g.Add(graph.NewNode("invoice", graph.Deps("ledger"), l.fn))
+	g.Add(graph.NewNode("receipt", nil, l.fn, graph.WithSoftDependency("charge")))
+	g.Add(graph.NewNode("audit", nil, l.fn, graph.WithOptionalDependency("ledger")))
+	return g
+}
+
+func TestNodeFilterCutIsAnError(t *testing.T) {
+	peg := canonical(&runLog{}).CompileToExecutable(graph.WithNodeFilter(notBilling))
+
+	_, err := peg.Run(ctx(t))
+	if err == nil || !strings.Contains(err.Error(), "Node invoice depends on ledger, which the node filter drops") {
+		t.Errorf("Expected the cut edge to break the compiled graph, got %v", err)
+	}
+}
+
+func TestNodeFilterSplice(t *testing.T) {
+	l := &runLog{}
+	g := canonical(l)
+	peg := g.CompileToExecutable(graph.WithNodeFilter(notBilling, graph.OnCutSplice))
+
+	// invoice inherits checkout through both dropped nodes; receipt's inherited edge stays soft
+	want := []graph.Edge{
+		{From: "invoice", To: "checkout"},
+		{From: "receipt", To: "checkout"},
+	}
+	if got := dotEdges(peg.ToDOT()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the compiled edges %v, got %v", want, got)
+	}
+	if dot := peg.ToDOT(); strings.Contains(dot, "charge") || strings.Contains(dot, "ledger") {
+		t.Errorf("Expected the dropped nodes left out of the export, got\n%s", dot)
+	}
+	if mermaid := peg.ToMermaid(); strings.Contains(mermaid, "charge") || strings.Contains(mermaid, "ledger") {
+		t.Errorf("Expected the dropped nodes left out of the Mermaid export, got\n%s", mermaid)
+	}
+
+	report, err := peg.Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := report.Nodes["charge"]; ok || len(report.Nodes) != 4 {
+		t.Errorf("Expected only the kept nodes reported, got %v", report.Nodes)
+	}
+	if len(l.ids) != 4 || !before(graph.SortedNodeIDs(l.ids), "checkout", "invoice") || !before(graph.SortedNodeIDs(l.ids), "checkout", "receipt") {
+		t.Errorf("Expected the ordering through the dropped nodes kept, got %v", l.ids)
+	}
+	if !strings.Contains(peg.ToDOT(), "arrowhead=empty") {
+		t.Error("Expected receipt's inherited edge drawn soft")
+	}
+
+	// The canonical graph is untouched
+	if !g.Has("charge") || !mustGet(t, g, "invoice").Dependencies.Has("ledger") {
+		t.Error("Expected the filter to leave the graph alone")
+	}
+}
+
+func TestNodeFilterSpliceKeepsHardPath(t *testing.T) {
+	g := graph.NewGraph("paths")
+	g.Add(graph.NewNode("a", nil, graph.NoOp()))
+	g.Add(graph.NewNode("hard", graph.Deps("a"), graph.NoOp()))
+	g.Add(graph.NewNode("soft", nil, graph.NoOp(), graph.WithSoftDependency("a")))
+	g.Add(graph.NewNode("z", graph.Deps("hard", "soft"), graph.NoOp()))
+	keep := func(n *graph.Node) bool { return n.Identifier() == "a" || n.Identifier() == "z" }
+
+	// One path to a is hard throughout, so the inherited edge is hard
+	want := graph.NewGraph("paths")
+	want.Add(graph.NewNode("a", nil, graph.NoOp()))
+	want.Add(graph.NewNode("z", graph.Deps("a"), graph.NoOp()))
+	if got := g.CompileToExecutable(graph.WithNodeFilter(keep, graph.OnCutSplice)).ToDOT(); got != want.CompileToExecutable().ToDOT() {
+		t.Errorf("Expected z to depend hard on a, got\n%s", got)
+	}
+}
+
+func TestNodeFilterKeepingEverything(t *testing.T) {
+	g := canonical(&runLog{})
+	keepAll := func(n *graph.Node) bool { return true }
+
+	filtered, plain := g.CompileToExecutable(graph.WithNodeFilter(keepAll)), g.CompileToExecutable()
+	if filtered.ToDOT() != plain.ToDOT() || filtered.ToMermaid() != plain.ToMermaid() {
+		t.Errorf("Expected a filter that keeps every node to change nothing, got\n%s\nwant\n%s", filtered.ToDOT(), plain.ToDOT())
+	}
+	report, err := filtered.Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Nodes) != 6 {
+		t.Errorf("Expected every node run, got %v", report.Nodes)
+	}
+}
+
+func TestNodeFilterGetsCopies(t *testing.T) {
+	g := canonical(&runLog{})
+	g.CompileToExecutable(graph.WithNodeFilter(func(n *graph.Node) bool {
+		if n.Metadata != nil {
+			n.Metadata["env"] = "tampered"
+		}
+		n.Dependencies = nil
+		return true
+	}))
+	if node := mustGet(t, g, "charge"); node.Metadata["env"] != "prod" || !node.Dependencies.Has("checkout") {
+		t.Errorf("Expected the filter to see copies, got %+v", node)
+	}
+}
+
+func TestNodeFilterRefusesRecompile(t *testing.T) {
+	g := canonical(&runLog{})
+	peg := g.CompileToExecutable(graph.WithNodeFilter(notBilling, graph.OnCutSplice))
+	g.Add(graph.NewNode("refund", graph.Deps("invoice"), graph.NoOp()))
+
+	if err := peg.Recompile(g, graph.Diff{Added: graph.Deps("refund")}); err == nil || !strings.Contains(err.Error(), "node filter") {
+		t.Errorf("Expected a filtered graph to need a full compile, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"context"
+)
+
+type FlagOffBehavior int
+
+const (
+	// Skip the node and let its dependents run without its result
+	OnFlagOffContinue FlagOffBehavior = iota
+	// Skip the node and its hard dependents, as if it had failed
+	OnFlagOffSkipDependents
+)
+
+// Gates the node behind a feature flag: when the run's flag provider says flag is off, the node
+// is Skipped with reason flag-disabled instead of running. Runs without a provider ignore flags.
+func WithFeatureFlag(flag string, behavior ...FlagOffBehavior) NodeOption {
+	return func(n *Node) {
+		n.flag = flag
+		n.onFlagOff = OnFlagOffContinue
+
+		if len(behavior) > 0 {
+			n.onFlagOff = behavior[0]
+		}
+	}
+}
+
+// Decides which feature flags are on. It's asked about each flag once per run, from the run's
+// goroutine, and the answers are kept in the report's Flags.
+func WithFlagProvider(provider func(flag string) bool) ExecOption {
+	return func(c *ExecConfig) {
+		c.FlagProvider = provider
+	}
+}
+
+// Whether the node's flag, if it has one, lets it run
+func (r *run) flagOn(node *executableNode) bool {
+	if node.flag == "" || r.cfg.FlagProvider == nil {
+		return true
+	}
+
+	on, ok := r.report.Flags[node.flag]
+	if !ok {
+		on = r.cfg.FlagProvider(node.flag)
+		if r.report.Flags == nil {
+			r.report.Flags = make(map[string]bool)
+		}
+		r.report.Flags[node.flag] = on
+	}
+	return on
+}
+
+func (r *run) settleFlagOff(ctx context.Context, id NodeID) {
+	now := r.clock().Now()
+	nr := NodeReport{ID: id, Status: StatusSkipped, Reason: ReasonFlagDisabled, Start: now, End: now}
+	*r.report.Nodes[id] = nr
+	r.cfg.State.record(nr, nil)
+	r.finished(nr)
+
+	r.release(ctx, id, r.satisfied(id))
+}
+
+// satisfies, taking the node's flag-off behavior into account
+func (r *run) satisfied(id NodeID) bool {
+	nr := r.report.Nodes[id]
+	if nr.Reason == ReasonFlagDisabled {
+		return r.peg.nodes[id].onFlagOff == OnFlagOffContinue
+	}
+	if nr.Reason == ReasonConditionFalse {
+		return true
+	}
+	return satisfies(*nr)
+}
@@ -0,0 +1,148 @@
+package graph_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Answers from flags and counts how often each flag is asked about
+type flagProvider struct {
+	mu    sync.Mutex
+	flags map[string]bool
+	asked map[string]int
+}
+
+func (p *flagProvider) on(flag string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.asked == nil {
+		p.asked = map[string]int{}
+	}
+	p.asked[flag]++
+	return p.flags[flag]
+}
+
+// index is gated and feeds publish; seen records what publish was given
+func gated(seen *sync.Map, behavior ...graph.FlagOffBehavior) *graph.Graph {
+	g := graph.NewGraph("search")
+	g.Add(graph.NewNode("crawl", nil, returns("pages")))
+	g.Add(graph.NewNode("index", graph.Deps("crawl"), returns("index"), graph.WithFeatureFlag("new-indexer", behavior...)))
+	g.Add(graph.NewNode("publish", graph.Deps("index"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		seen.Store("index", ec.Results["index"])
+		return nil, nil
+	}))
+	return g
+}
+
+func TestFeatureFlagOffSkipsNode(t *testing.T) {
+	seen := &sync.Map{}
+	provider := &flagProvider{}
+	report, err := gated(seen).CompileToExecutable().Run(ctx(t), graph.WithFlagProvider(provider.on))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nr := report.Nodes["index"]; nr.Status != graph.StatusSkipped || nr.Reason != graph.ReasonFlagDisabled {
+		t.Errorf("Expected index skipped with flag-disabled, got %s/%s", nr.Status, nr.Reason)
+	}
+	if nr := report.Nodes["publish"]; nr.Status != graph.StatusSucceeded {
+		t.Errorf("Expected publish to run, got %s/%s", nr.Status, nr.Reason)
+	}
+	if result, ok := seen.Load("index"); !ok || result != nil {
+		t.Errorf("Expected publish to get a nil result, got %v", result)
+	}
+	if want := map[string]bool{"new-indexer": false}; !reflect.DeepEqual(report.Flags, want) {
+		t.Errorf("Expected the answer %v in the report, got %v", want, report.Flags)
+	}
+}
+
+func TestFeatureFlagOffSkipsDependents(t *testing.T) {
+	g := gated(&sync.Map{}, graph.OnFlagOffSkipDependents)
+	g.Add(graph.NewNode("notify", nil, graph.NoOp(), graph.WithSoftDependency("index")))
+
+	provider := &flagProvider{}
+	report, _ := g.CompileToExecutable().Run(ctx(t), graph.WithFlagProvider(provider.on))
+
+	if nr := report.Nodes["publish"]; nr.Status != graph.StatusSkipped || nr.Reason != graph.ReasonUpstreamFailed {
+		t.Errorf("Expected the hard dependent skipped, got %s/%s", nr.Status, nr.Reason)
+	}
+	if nr := report.Nodes["notify"]; nr.Status != graph.StatusSucceeded {
+		t.Errorf("Expected the soft dependent to run, got %s/%s", nr.Status, nr.Reason)
+	}
+}
+
+func TestFeatureFlagOnRuns(t *testing.T) {
+	seen := &sync.Map{}
+	provider := &flagProvider{flags: map[string]bool{"new-indexer": true}}
+	report, err := gated(seen).CompileToExecutable().Run(ctx(t), graph.WithFlagProvider(provider.on))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nr := report.Nodes["index"]; nr.Status != graph.StatusSucceeded {
+		t.Errorf("Expected index to run, got %s/%s", nr.Status, nr.Reason)
+	}
+	if result, _ := seen.Load("index"); result != "index" {
+		t.Errorf("Expected publish to get index's result, got %v", result)
+	}
+	if want := map[string]bool{"new-indexer": true}; !reflect.DeepEqual(report.Flags, want) {
+		t.Errorf("Expected the answer %v in the report, got %v", want, report.Flags)
+	}
+}
+
+func TestFeatureFlagWithoutProvider(t *testing.T) {
+	report, err := gated(&sync.Map{}).CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nr := report.Nodes["index"]; nr.Status != graph.StatusSucceeded {
+		t.Errorf("Expected flags ignored without a provider, got %s/%s", nr.Status, nr.Reason)
+	}
+	if report.Flags != nil {
+		t.Errorf("Expected no flags recorded, got %v", report.Flags)
+	}
+}
+
+func TestFeatureFlagAskedOncePerRun(t *testing.T) {
+	g := graph.NewGraph("flags")
+	for _, id := range []string{"a", "b", "c"} {
+		g.Add(graph.NewNode(id, nil, graph.NoOp(), graph.WithFeatureFlag("shared")))
+	}
+	g.Add(graph.NewNode("d", nil, graph.NoOp(), graph.WithFeatureFlag("other")))
+	peg := g.CompileToExecutable()
+
+	provider := &flagProvider{flags: map[string]bool{"shared": true}}
+	for i := 0; i < 2; i++ {
+		if _, err := peg.Run(ctx(t), graph.WithFlagProvider(provider.on)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := map[string]int{"shared": 2, "other": 2}; !reflect.DeepEqual(provider.asked, want) {
+		t.Errorf("Expected each flag asked once per run, got %v", provider.asked)
+	}
+}
+
+func TestFeatureFlagsInReportJSON(t *testing.T) {
+	provider := &flagProvider{}
+	report, _ := gated(&sync.Map{}).CompileToExecutable().Run(ctx(t), graph.WithFlagProvider(provider.on))
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded graph.Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Flags, report.Flags) {
+		t.Errorf("Expected flags %v to survive JSON, got %v", report.Flags, decoded.Flags)
+	}
+	if nr := decoded.Nodes["index"]; nr.Reason != graph.ReasonFlagDisabled {
+		t.Errorf("Expected the flag-disabled reason to survive JSON, got %s", nr.Reason)
+	}
+}
@@ -0,0 +1,95 @@
+package graph_test
+
+import (
+	"errors"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestFreezeRejectsMutations(t *testing.T) {
+	g := diamond(t)
+	g.Freeze()
+
+	mutators := map[string]func() error{
+		"Add": func() error {
+			_, err := g.Add(graph.NewNode("e", nil, graph.NoOp()))
+			return err
+		},
+		"AddAll": func() error {
+			return g.AddAll([]*graph.Node{graph.NewNode("e", nil, graph.NoOp())})
+		},
+		"AddIdempotent": func() error {
+			_, err := g.AddIdempotent(graph.NewNode("e", nil, graph.NoOp()))
+			return err
+		},
+		"Ensure": func() error {
+			_, _, err := g.Ensure(graph.NewNode("e", nil, graph.NoOp()))
+			return err
+		},
+		"Remove":         func() error { return g.Remove("d") },
+		"AddEdge":        func() error { return g.AddEdge("d", "a") },
+		"AddEdgeKind":    func() error { return g.AddEdgeKind("d", "a", graph.EdgeSoft) },
+		"Merge":          func() error { return g.Merge(graph.NewGraph("other")) },
+		"Alias":          func() error { return g.Alias("first", "a") },
+		"MarkEntryPoint": func() error { return g.MarkEntryPoint("a") },
+		"Rename":         func() error { return g.Rename("a", "z") },
+	}
+
+	for name, mutate := range mutators {
+		if err := mutate(); !errors.Is(err, graph.ErrGraphFrozen) {
+			t.Errorf("Expected %s to fail with ErrGraphFrozen, got %v", name, err)
+		}
+	}
+	if n := len(mustSort(t, g)); n != 4 {
+		t.Errorf("Expected the frozen graph to be unchanged, got %d nodes", n)
+	}
+}
+
+func TestFreezeKeepsReads(t *testing.T) {
+	g := diamond(t)
+	g.Freeze()
+	g.Freeze()
+
+	if !g.Frozen() {
+		t.Fatal("Expected the graph to be frozen")
+	}
+	if err := g.Validate(); err != nil {
+		t.Errorf("Expected Validate to work, got %v", err)
+	}
+	if !g.Has("a") {
+		t.Error("Expected Has to work")
+	}
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Errorf("Expected a frozen graph to compile and run, got %v", err)
+	}
+}
+
+func TestFreezeSurvivesPassingByPointer(t *testing.T) {
+	g := diamond(t)
+	freeze := func(g *graph.Graph) { g.Freeze() }
+	freeze(g)
+
+	if _, err := g.Add(graph.NewNode("e", nil, graph.NoOp())); !errors.Is(err, graph.ErrGraphFrozen) {
+		t.Errorf("Expected ErrGraphFrozen, got %v", err)
+	}
+}
+
+func TestCloneOfFrozenIsMutable(t *testing.T) {
+	g := diamond(t)
+	g.Freeze()
+
+	c := g.Clone()
+	if c.Frozen() {
+		t.Fatal("Expected the clone not to be frozen")
+	}
+	if _, err := c.Add(graph.NewNode("e", graph.Deps("d"), graph.NoOp())); err != nil {
+		t.Fatalf("Expected the clone to take new nodes, got %v", err)
+	}
+	if err := c.Remove("e"); err != nil {
+		t.Fatalf("Expected the clone to give up nodes, got %v", err)
+	}
+	if g.Has("e") {
+		t.Error("Expected the original to be unaffected by the clone")
+	}
+}
@@ -0,0 +1,185 @@
+package graph
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A Frontier hands out the compiled graph's nodes in dependency order for callers that run
+// them themselves. Failure follows the same rules as Run: a node whose hard dependency failed
+// becomes unreachable once all its dependencies have finished, and so do its hard dependents.
+// It does no locking; use SyncFrontier to share one between goroutines.
+type Frontier struct {
+	peg         *ParallelizedExecutableGraph
+	pending     map[NodeID]int
+	blocked     NodeIDs
+	ready       SortedNodeIDs
+	running     NodeIDs
+	done        NodeIDs
+	failed      NodeIDs
+	unreachable NodeIDs
+}
+
+// Fails with the error Run would for a graph that was broken when compiled
+func (peg *ParallelizedExecutableGraph) NewFrontier() (*Frontier, error) {
+	if peg.broken != nil {
+		return nil, peg.broken
+	}
+
+	f := &Frontier{
+		peg:         peg,
+		pending:     make(map[NodeID]int, len(peg.nodes)),
+		blocked:     make(NodeIDs),
+		ready:       peg.Roots(),
+		running:     make(NodeIDs),
+		done:        make(NodeIDs),
+		failed:      make(NodeIDs),
+		unreachable: make(NodeIDs),
+	}
+
+	for id, node := range peg.nodes {
+		f.pending[id] = node.required
+	}
+
+	return f, nil
+}
+
+// Ready returns the nodes that became ready since the last call. They count as running until
+// they're marked done or failed.
+func (f *Frontier) Ready() SortedNodeIDs {
+	ready := f.ready
+	f.ready = SortedNodeIDs{}
+
+	for _, id := range ready {
+		f.running[id] = struct{}{}
+	}
+	sortIDs(ready)
+	return ready
+}
+
+func (f *Frontier) MarkDone(id NodeID) error {
+	if err := f.finish(id); err != nil {
+		return err
+	}
+
+	f.done[id] = struct{}{}
+	f.release(id, true)
+	return nil
+}
+
+func (f *Frontier) MarkFailed(id NodeID) error {
+	if err := f.finish(id); err != nil {
+		return err
+	}
+
+	f.failed[id] = struct{}{}
+	f.release(id, false)
+	return nil
+}
+
+func (f *Frontier) finish(id NodeID) error {
+	if _, ok := f.peg.nodes[id]; !ok {
+		return fmt.Errorf("Node %s does not exist", id)
+	}
+	if _, ok := f.running[id]; !ok {
+		return fmt.Errorf("Node %s is not running", id)
+	}
+
+	delete(f.running, id)
+	return nil
+}
+
+func (f *Frontier) release(id NodeID, satisfied bool) {
+	for _, target := range sortedIDs(f.peg.nodes[id].targetIDs) {
+		_, soft := f.peg.nodes[target].softIDs[id]
+		if !satisfied && !soft {
+			f.blocked[target] = struct{}{}
+		}
+
+		f.pending[target]--
+		if f.pending[target] > 0 {
+			continue
+		}
+
+		if _, ok := f.blocked[target]; ok {
+			f.unreachable[target] = struct{}{}
+			f.release(target, false)
+			continue
+		}
+		f.ready = append(f.ready, target)
+	}
+}
+
+// Nodes that haven't been marked done or failed and aren't unreachable, including running ones
+func (f *Frontier) Remaining() int {
+	return len(f.peg.nodes) - len(f.done) - len(f.failed) - len(f.unreachable)
+}
+
+// Nodes that can no longer run because a hard dependency failed or was itself unreachable
+func (f *Frontier) Unreachable() SortedNodeIDs {
+	return sortedIDs(f.unreachable)
+}
+
+func (f *Frontier) Failed() SortedNodeIDs {
+	return sortedIDs(f.failed)
+}
+
+func (f *Frontier) Running() SortedNodeIDs {
+	return sortedIDs(f.running)
+}
+
+// A Frontier safe for concurrent use
+type SyncFrontier struct {
+	mu sync.Mutex
+	f  *Frontier
+}
+
+func (peg *ParallelizedExecutableGraph) NewSyncFrontier() (*SyncFrontier, error) {
+	f, err := peg.NewFrontier()
+	if err != nil {
+		return nil, err
+	}
+	return &SyncFrontier{f: f}, nil
+}
+
+func (s *SyncFrontier) Ready() SortedNodeIDs {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Ready()
+}
+
+func (s *SyncFrontier) MarkDone(id NodeID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.MarkDone(id)
+}
+
+func (s *SyncFrontier) MarkFailed(id NodeID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.MarkFailed(id)
+}
+
+func (s *SyncFrontier) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Remaining()
+}
+
+func (s *SyncFrontier) Unreachable() SortedNodeIDs {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Unreachable()
+}
+
+func (s *SyncFrontier) Failed() SortedNodeIDs {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Failed()
+}
+
+func (s *SyncFrontier) Running() SortedNodeIDs {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Running()
+}
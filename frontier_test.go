@@ -0,0 +1,154 @@
+package graph_test
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func newFrontier(t *testing.T, peg *graph.ParallelizedExecutableGraph) *graph.Frontier {
+	t.Helper()
+
+	f, err := peg.NewFrontier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func expectReady(t *testing.T, f interface{ Ready() graph.SortedNodeIDs }, want ...graph.NodeID) {
+	t.Helper()
+
+	got := f.Ready()
+	if len(want) == 0 {
+		want = graph.SortedNodeIDs{}
+	}
+	if !reflect.DeepEqual(got, graph.SortedNodeIDs(want)) {
+		t.Fatalf("Expected ready %v, got %v", want, got)
+	}
+}
+
+func TestFrontierDrivesDiamond(t *testing.T) {
+	f := newFrontier(t, diamond(t).CompileToExecutable())
+
+	expectReady(t, f, "a")
+	// Nothing new until a finishes
+	expectReady(t, f)
+	if f.Remaining() != 4 {
+		t.Errorf("Expected 4 remaining, got %d", f.Remaining())
+	}
+
+	if err := f.MarkDone("a"); err != nil {
+		t.Fatal(err)
+	}
+	expectReady(t, f, "b", "c")
+
+	f.MarkDone("b")
+	// d still waits on c
+	expectReady(t, f)
+	f.MarkDone("c")
+	expectReady(t, f, "d")
+
+	f.MarkDone("d")
+	if f.Remaining() != 0 {
+		t.Errorf("Expected nothing remaining, got %d", f.Remaining())
+	}
+	expectReady(t, f)
+}
+
+func TestFrontierFailureMakesDescendantsUnreachable(t *testing.T) {
+	f := newFrontier(t, diamond(t).CompileToExecutable())
+
+	expectReady(t, f, "a")
+	f.MarkDone("a")
+	expectReady(t, f, "b", "c")
+
+	if err := f.MarkFailed("b"); err != nil {
+		t.Fatal(err)
+	}
+	// d only becomes unreachable once c has finished too, as in Run
+	if got := f.Unreachable(); len(got) != 0 {
+		t.Errorf("Expected nothing unreachable while c runs, got %v", got)
+	}
+	f.MarkDone("c")
+	expectReady(t, f)
+
+	if got := f.Unreachable(); !reflect.DeepEqual(got, graph.SortedNodeIDs{"d"}) {
+		t.Errorf("Expected d unreachable, got %v", got)
+	}
+	if got := f.Failed(); !reflect.DeepEqual(got, graph.SortedNodeIDs{"b"}) {
+		t.Errorf("Expected b failed, got %v", got)
+	}
+	if f.Remaining() != 0 {
+		t.Errorf("Expected nothing remaining, got %d", f.Remaining())
+	}
+}
+
+func TestFrontierRejectsNodesNotRunning(t *testing.T) {
+	f := newFrontier(t, diamond(t).CompileToExecutable())
+
+	if err := f.MarkDone("a"); err == nil {
+		t.Error("Expected an error marking a node that was never handed out")
+	}
+	if err := f.MarkDone("missing"); err == nil {
+		t.Error("Expected an error marking a node not in the graph")
+	}
+
+	f.Ready()
+	f.MarkDone("a")
+	if err := f.MarkFailed("a"); err == nil {
+		t.Error("Expected an error finishing a node twice")
+	}
+}
+
+func TestSyncFrontierConcurrentWorkers(t *testing.T) {
+	f, err := diamond(t).CompileToExecutable().NewSyncFrontier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	seen := make(chan graph.NodeID, 4)
+	for f.Remaining() > 0 {
+		for _, id := range f.Ready() {
+			wg.Add(1)
+			go func(id graph.NodeID) {
+				defer wg.Done()
+				seen <- id
+				if err := f.MarkDone(id); err != nil {
+					t.Error(err)
+				}
+			}(id)
+		}
+		wg.Wait()
+	}
+	close(seen)
+
+	count := 0
+	for range seen {
+		count++
+	}
+	if count != 4 {
+		t.Errorf("Expected every node handed out once, got %d", count)
+	}
+}
+
+func TestFrontierRefusesBrokenGraph(t *testing.T) {
+	g := graph.NewGraph("broken", graph.WithLazyAdd())
+	g.Add(graph.NewNode("a", graph.Deps("missing"), graph.NoOp()))
+	peg := g.CompileToExecutable()
+
+	_, runErr := peg.Run(ctx(t))
+	if runErr == nil {
+		t.Fatal("Expected the graph to be broken")
+	}
+	if f, err := peg.NewFrontier(); f != nil || err == nil || err.Error() != runErr.Error() {
+		t.Errorf("Expected Run's error %q, got %v", runErr, err)
+	}
+	if f, err := peg.NewSyncFrontier(); f != nil || err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Expected the sync frontier refused too, got %v", err)
+	}
+}
@@ -0,0 +1,776 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type NodeID string
+type NodeIDs map[NodeID]struct{}
+type SortedNodeIDs []NodeID
+type NodeFn func(ctx context.Context, ec *ExecutionContext) (any, error)
+
+type Node struct {
+	Name         string
+	Fn           NodeFn
+	Dependencies NodeIDs
+	Metadata     map[string]string
+	// Parameters handed to the fn, so one fn can back several nodes
+	Inputs    map[string]any
+	timeout   time.Duration
+	onTimeout TimeoutBehavior
+	retry     RetryPolicy
+	onCancel  CancelFn
+	// Alternative fns by variant name
+	variants map[string]NodeFn
+	// Time OnCancel gets to finish
+	cancelGrace   time.Duration
+	beforeAttempt BeforeAttemptFn
+	afterAttempt  AfterAttemptFn
+	// Subset of Dependencies that only constrain ordering
+	softDependencies     NodeIDs
+	optionalDependencies NodeIDs
+	// How many extra times each dependency was declared
+	duplicates map[NodeID]int
+	// Edge weights given to AddEdge, by declared dependency id
+	weights map[NodeID]float64
+	marker  bool
+	// Failures don't fail the run or stop dependents
+	allowFailure bool
+	// Feature flag gating the node; empty if none
+	flag      string
+	onFlagOff FlagOffBehavior
+	// Set by WithCondition
+	conditionName string
+	condition     ConditionFn
+	priority      int
+	// Taken from the run's budget; zero means 1
+	cost       int
+	startDelay time.Duration
+	// Replaces the run's WithStartJitter when ownJitter is set
+	startJitter time.Duration
+	ownJitter   bool
+	keepResult  bool
+	// Set by NewApprovalNode
+	approval          bool
+	approvalTimeout   time.Duration
+	onApprovalTimeout ApprovalTimeoutBehavior
+	// Set by NewAssertionNode
+	assertion bool
+	// Dependencies on every node with matching metadata
+	selectors []selector
+	// Settings filled in from the graph's default node options
+	inherited []string
+}
+
+func NewNode(name string, dependencies NodeIDs, fn NodeFn, opts ...NodeOption) *Node {
+	n := &Node{
+		Name:         name,
+		Fn:           fn,
+		Dependencies: dependencies,
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	return n
+}
+
+func (n *Node) Identifier() NodeID {
+	return NodeID(n.Name)
+}
+
+func (n *Node) clone() *Node {
+	c := *n
+	// Empty sets stay nil; everything that adds to them creates them first
+	c.Dependencies = copyMap(n.Dependencies)
+	c.softDependencies = copyMap(n.softDependencies)
+	c.Metadata = copyMap(n.Metadata)
+	c.Inputs = copyMap(n.Inputs)
+	c.variants = copyMap(n.variants)
+	c.optionalDependencies = copyMap(n.optionalDependencies)
+	c.duplicates = copyMap(n.duplicates)
+	c.weights = copyMap(n.weights)
+	c.selectors = append([]selector(nil), n.selectors...)
+	return &c
+}
+
+type Nodes map[NodeID]*Node
+
+var ErrGraphFrozen = errors.New("Graph is frozen")
+
+// Mutators, Sort and compiling are safe for concurrent use
+type Graph struct {
+	mu     sync.RWMutex
+	name   string
+	nodes  Nodes
+	frozen bool
+	// Empty outside any namespace
+	namespace  string
+	allowEmpty bool
+	policies   []EdgePolicy
+	limits     graphLimits
+	// Applied to nodes as they're added
+	nodeDefaults []NodeOption
+	// Dependencies may name nodes not added yet
+	lazyAdd bool
+	// Weight of edges not given one
+	defaultWeight float64
+	// Kept only with WithIncrementalCycleCheck
+	order *topoOrder
+	// Alias id to the id it stands for
+	aliases       map[NodeID]NodeID
+	maxAliasDepth int
+	// Memoized by SortCached; any mutation clears it
+	sorted *sortResult
+	// Counts mutations, so a compiled graph can tell it's stale
+	version uint64
+	// The most nodes the graph has held since it was made or compacted
+	peakNodes int
+	// How many nodes depend on each id, for Remove; built on demand, kept up to date by Add and
+	// Remove and dropped by every other mutation
+	dependents map[NodeID]int
+	// Run by Close, last first
+	closers []func() error
+	// Declared with MarkEntryPoint
+	entryPoints NodeIDs
+	// Applied by CompileToExecutable
+	transforms []Transform
+	// Nil leaves ids as given
+	normalizer func(id string) NodeID
+	// Set by WithSharedFnWarnings
+	sharedFnWarnings bool
+
+	// Copy-on-write state shared with snapshots
+	shared   bool
+	borrowed NodeIDs
+}
+
+func NewGraph(name string, opts ...GraphOption) *Graph {
+	g := &Graph{
+		name:  name,
+		nodes: make(Nodes),
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// Rejects all further mutations with ErrGraphFrozen; reads, Sort, Clone and compiling keep working
+func (g *Graph) Freeze() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.frozen = true
+}
+
+func (g *Graph) Frozen() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.frozen
+}
+
+// Copies the graph, including each node's dependency set. The copy is never frozen.
+func (g *Graph) Clone() *Graph {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.clone()
+}
+
+func (g *Graph) clone() *Graph {
+	return g.subgraph(g.name, nil)
+}
+
+// A copy of the graph holding only the nodes in ids, or every node when ids is nil, and the
+// aliases and entry points that lead to them
+func (g *Graph) subgraph(name string, ids NodeIDs) *Graph {
+	keep := func(id NodeID) bool {
+		_, ok := ids[id]
+		return ids == nil || ok
+	}
+
+	c := NewGraph(name)
+	c.namespace = g.namespace
+	c.allowEmpty = g.allowEmpty
+	c.policies = g.policies
+	c.nodeDefaults = g.nodeDefaults
+	c.lazyAdd = g.lazyAdd
+	c.limits = g.limits
+	c.defaultWeight = g.defaultWeight
+	c.maxAliasDepth = g.maxAliasDepth
+	c.transforms = g.transforms
+	c.normalizer = g.normalizer
+	c.sharedFnWarnings = g.sharedFnWarnings
+	for id := range g.entryPoints {
+		if keep(id) {
+			if c.entryPoints == nil {
+				c.entryPoints = make(NodeIDs)
+			}
+			c.entryPoints[id] = struct{}{}
+		}
+	}
+	for alias, target := range g.aliases {
+		if keep(g.resolve(alias)) {
+			if c.aliases == nil {
+				c.aliases = make(map[NodeID]NodeID)
+			}
+			c.aliases[alias] = target
+		}
+	}
+	for id, node := range g.nodes {
+		if keep(id) {
+			c.nodes[id] = node.clone()
+		}
+	}
+	if g.order != nil {
+		c.rebuildOrder()
+	}
+	return c
+}
+
+// The graph keeps node itself unless it has default node options to fill in, so change it only
+// through the graph once added; Get and All hand out copies and views instead
+func (g *Graph) Add(node *Node) (NodeID, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.frozen {
+		return "", ErrGraphFrozen
+	}
+
+	return g.add(g.withDefaults(node))
+}
+
+// The caller holds the lock and has applied the defaults
+func (g *Graph) add(node *Node) (NodeID, error) {
+	id := node.Identifier()
+	if _, ok := g.nodes[id]; ok {
+		return "", fmt.Errorf("Node with id %s already exists", id)
+	}
+	if _, ok := g.aliases[id]; ok {
+		return "", fmt.Errorf("Node %s collides with an alias of the same id", id)
+	}
+
+	for depId := range node.Dependencies {
+		if !g.exists(depId) && !g.lazyAdd {
+			return "", fmt.Errorf("Node %s is missing dependency %s", id, depId)
+		}
+	}
+
+	if err := g.checkNodeLimit(id, len(g.nodes)+1); err != nil {
+		return "", err
+	}
+	if err := g.checkEdgeLimit(node, 0); err != nil {
+		return "", err
+	}
+
+	if err := g.checkPolicies(node, g.dependencies(node), g.lookup); err != nil {
+		return "", err
+	}
+	if err := g.checkWaiting(node); err != nil {
+		return "", err
+	}
+
+	g.own()
+	g.nodes[id] = node
+	if g.order != nil {
+		if err := g.orderAdd(node); err != nil {
+			delete(g.nodes, id)
+			return "", err
+		}
+	}
+	dependents := g.dependents
+	g.changed()
+	g.dependents = dependents
+	g.countDependents(node, 1)
+	return id, nil
+}
+
+// Removes a node nothing else depends on
+func (g *Graph) Remove(id NodeID) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id = g.normalize(id)
+
+	if g.frozen {
+		return ErrGraphFrozen
+	}
+
+	if _, ok := g.nodes[id]; !ok {
+		return fmt.Errorf("Node %s does not exist", id)
+	}
+
+	for _, alias := range sortedIDs(g.aliases) {
+		if g.aliases[alias] == id {
+			return fmt.Errorf("Node %s is the target of alias %s", id, alias)
+		}
+	}
+
+	if g.dependentCounts()[id] > 0 {
+		dependents := SortedNodeIDs{}
+		for other, node := range g.nodes {
+			if _, ok := node.Dependencies[id]; ok {
+				dependents = append(dependents, other)
+			}
+		}
+		sortIDs(dependents)
+		return fmt.Errorf("Node %s is a dependency of %s", id, joinIDs(dependents, stringLimit))
+	}
+
+	node := g.nodes[id]
+	g.own()
+	dependents := g.dependents
+	g.changed()
+	g.dependents = dependents
+	if g.order != nil {
+		g.orderRemove(node)
+	}
+	g.countDependents(node, -1)
+	delete(g.nodes, id)
+	delete(g.borrowed, id)
+	delete(g.entryPoints, id)
+	return nil
+}
+
+// How many nodes depend on each id, counted on the first Remove after any mutation other than Add
+// and Remove. Scanning every node instead would make removing nodes one at a time quadratic, all
+// the more since a map keeps iterating at the size it grew to. The caller holds the write lock.
+func (g *Graph) dependentCounts() map[NodeID]int {
+	if g.dependents == nil {
+		g.dependents = make(map[NodeID]int)
+		for _, node := range g.nodes {
+			g.countDependents(node, 1)
+		}
+	}
+	return g.dependents
+}
+
+// Adds delta for each of node's dependencies, when the counts have been built
+func (g *Graph) countDependents(node *Node, delta int) {
+	if g.dependents == nil {
+		return
+	}
+	for depId := range node.Dependencies {
+		if g.dependents[depId] += delta; g.dependents[depId] == 0 {
+			delete(g.dependents, depId)
+		}
+	}
+}
+
+// Copies every node of other into g. A node whose id is already in g is left out if the two are
+// equivalent and is an error otherwise; see WithEquivalence. Nothing is added unless the whole
+// merge succeeds.
+func (g *Graph) Merge(other *Graph, opts ...MergeOption) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.frozen {
+		return ErrGraphFrozen
+	}
+	if other != g {
+		other.mu.RLock()
+		defer other.mu.RUnlock()
+	}
+
+	cfg := newMergeConfig(opts)
+	if err := g.checkNamespace(other, cfg); err != nil {
+		return err
+	}
+	nodes, aliases, err := g.normalizedAll(other.nodes, other.aliases)
+	if err != nil {
+		return err
+	}
+	return g.merge(nodes, aliases, cfg, !g.lazyAdd)
+}
+
+// Called by every mutation, after any nodes are added
+func (g *Graph) changed() {
+	g.sorted = nil
+	g.dependents = nil
+	g.version++
+	if len(g.nodes) > g.peakNodes {
+		g.peakNodes = len(g.nodes)
+	}
+}
+
+type sortResult struct {
+	ids SortedNodeIDs
+	err error
+}
+
+// Sort only reads the graph, so it's safe to call while the graph is read or compiled elsewhere
+func (g *Graph) Sort() (SortedNodeIDs, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if err := g.checkEmpty(); err != nil {
+		return nil, err
+	}
+	return g.sort()
+}
+
+// Like Sort, but remembers the result until the graph next changes. Callers get their own copy.
+func (g *Graph) SortCached() (SortedNodeIDs, error) {
+	g.mu.RLock()
+	cached := g.sorted
+	g.mu.RUnlock()
+
+	if cached == nil {
+		g.mu.Lock()
+		if g.sorted == nil {
+			ids, err := g.sort()
+			if empty := g.checkEmpty(); empty != nil {
+				err = empty
+			}
+			g.sorted = &sortResult{ids: ids, err: err}
+		}
+		cached = g.sorted
+		g.mu.Unlock()
+	}
+
+	if cached.err != nil {
+		return nil, cached.err
+	}
+	return append(SortedNodeIDs(nil), cached.ids...), nil
+}
+
+func (g *Graph) sort() (SortedNodeIDs, error) {
+	if err := g.checkSelectors(); err != nil {
+		return nil, err
+	}
+
+	visited := map[NodeID]bool{}
+	results := make(SortedNodeIDs, 0, len(g.nodes))
+
+	for n, node := range g.nodes {
+		if !visited[n] {
+			stack := map[NodeID]bool{}
+			if err := g.visit(n, g.dependencies(node), stack, visited, &results); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// Checks every dependency exists, there are no cycles and the graph is within its depth limit
+func (g *Graph) Validate() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if err := g.checkDepth(); err != nil {
+		return err
+	}
+	_, err := g.sort()
+	return err
+}
+
+func (g *Graph) visit(name NodeID, neighbors NodeIDs, stack map[NodeID]bool, visited map[NodeID]bool, results *SortedNodeIDs) error {
+	visited[name] = true
+	stack[name] = true
+
+	for n := range neighbors {
+		if !visited[n] {
+			// Child node doesn't exist
+			if _, ok := g.nodes[n]; !ok {
+				return fmt.Errorf("Node %s does not exist", n)
+			}
+
+			// Propagate errors from recursive calls
+			if err := g.visit(n, g.dependencies(g.nodes[n]), stack, visited, results); err != nil {
+				return err
+			}
+		} else if stack[n] {
+			return fmt.Errorf("Detected cycle on %s", n)
+		}
+	}
+
+	*results = append(*results, name)
+
+	stack[name] = false
+	return nil
+}
+
+// Make it a parallelize workflow
+type executableNode struct {
+	targetIDs     NodeIDs
+	required      int
+	fn            NodeFn
+	timeout       time.Duration
+	onTimeout     TimeoutBehavior
+	retry         RetryPolicy
+	onCancel      CancelFn
+	cancelGrace   time.Duration
+	beforeAttempt BeforeAttemptFn
+	afterAttempt  AfterAttemptFn
+	variants      map[string]NodeFn
+	softIDs       NodeIDs
+	dependencies  NodeIDs
+	optionalIDs   NodeIDs
+	metadata      map[string]string
+	inputs        map[string]any
+	// Hard and soft dependencies as declared, before aliases and selectors
+	declaredIDs NodeIDs
+	// Dependencies declared through an alias, mapped to the node they resolve to
+	aliases map[NodeID]NodeID
+	// Nonzero edge weights by resolved dependency
+	weights      map[NodeID]float64
+	marker       bool
+	allowFailure bool
+	flag         string
+	onFlagOff    FlagOffBehavior
+	condition    ConditionFn
+	priority     int
+	cost         int
+	startDelay   time.Duration
+	startJitter  time.Duration
+	ownJitter    bool
+	keepResult   bool
+	approval     bool
+	// Zero waits for a decision however long it takes
+	approvalTimeout   time.Duration
+	onApprovalTimeout ApprovalTimeoutBehavior
+	assertion         bool
+}
+
+func (exn *executableNode) AddTargets(nodeIds ...NodeID) {
+	if exn.targetIDs == nil {
+		targets := make(NodeIDs, len(nodeIds))
+
+		for nidx := range nodeIds {
+			id := nodeIds[nidx]
+			targets[id] = struct{}{}
+		}
+
+		exn.targetIDs = targets
+		return
+	}
+
+	// Add to the existing map
+	for nidx := range nodeIds {
+		id := nodeIds[nidx]
+		exn.targetIDs[id] = struct{}{}
+	}
+}
+
+// Copies everything but the targets, which belong to the dependents
+func (exn *executableNode) load(node *Node, deps NodeIDs, aliases map[NodeID]NodeID, weights map[NodeID]float64) {
+	exn.fn = node.Fn
+	exn.required = len(deps)
+	exn.timeout = node.timeout
+	exn.onTimeout = node.onTimeout
+	exn.retry = node.retry
+	exn.onCancel = node.onCancel
+	exn.variants = node.variants
+	exn.cancelGrace = node.cancelGrace
+	exn.beforeAttempt = node.beforeAttempt
+	exn.afterAttempt = node.afterAttempt
+	// AddEdge changes a node's edge sets in place, so the compiled node keeps its own copies
+	exn.softIDs = copyMap(node.softDependencies)
+	if len(aliases) > 0 {
+		exn.softIDs = make(NodeIDs, len(node.softDependencies))
+		for depId := range node.softDependencies {
+			if target, ok := aliases[depId]; ok {
+				depId = target
+			}
+			exn.softIDs[depId] = struct{}{}
+		}
+	}
+	exn.aliases = aliases
+	exn.weights = weights
+	exn.marker = node.marker
+	exn.allowFailure = node.allowFailure
+	exn.flag, exn.onFlagOff = node.flag, node.onFlagOff
+	exn.condition = node.condition
+	exn.priority = node.priority
+	exn.cost = node.cost
+	exn.startDelay = node.startDelay
+	exn.startJitter, exn.ownJitter = node.startJitter, node.ownJitter
+	exn.keepResult = node.keepResult
+	exn.approval, exn.approvalTimeout, exn.onApprovalTimeout = node.approval, node.approvalTimeout, node.onApprovalTimeout
+	exn.assertion = node.assertion
+	exn.dependencies = copyMap(deps)
+	exn.optionalIDs = node.optionalDependencies
+	exn.declaredIDs = copyMap(node.Dependencies)
+	exn.metadata = node.Metadata
+	exn.inputs = node.Inputs
+}
+
+type executableNodes map[NodeID]*executableNode
+
+func (en executableNodes) RootIds() []NodeID {
+	rootIds := []NodeID{}
+
+	for id, node := range en {
+		if node.required == 0 {
+			rootIds = append(rootIds, id)
+		}
+	}
+
+	return rootIds
+}
+
+func (en executableNodes) GetOrCreate(id NodeID) *executableNode {
+	n, ok := en[id]
+	if !ok {
+		n = &executableNode{}
+		en[id] = n
+	}
+	return n
+}
+
+type ParallelizedExecutableGraph struct {
+	name       string
+	namespace  string
+	allowEmpty bool
+	nodes      executableNodes
+	defaults   []ExecOption
+
+	// Nodes declaring each optional dependency, so recompiling knows who to rebind
+	optionalRefs map[NodeID]NodeIDs
+	// The aliases as compiled, which Diff can't describe changes to
+	aliases map[NodeID]NodeID
+	// What was wrong with the graph when compiled, which fails every run
+	broken error
+	// The graph, its version and its fingerprint as of the last compile
+	source            *Graph
+	version           uint64
+	sourceFingerprint string
+	// Names of the source's transforms that ran
+	transforms []string
+
+	mu      sync.Mutex
+	flights map[string]*flight
+
+	historyMu sync.Mutex
+	history   reportRing
+}
+
+func (g *Graph) CompileToExecutable(opts ...ExecOption) *ParallelizedExecutableGraph {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	cfg := ExecConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if len(g.transforms) == 0 && cfg.NodeFilter == nil {
+		return g.compile(g, opts)
+	}
+
+	out, applied, err := g, []string(nil), error(nil)
+	if len(g.transforms) > 0 {
+		out, applied, err = g.transformed()
+		if out != g {
+			out.mu.RLock()
+			defer out.mu.RUnlock()
+		}
+	}
+	if err == nil && cfg.NodeFilter != nil {
+		var filtered *Graph
+		if filtered, err = out.filtered(cfg.NodeFilter, cfg.OnCut); err == nil && filtered != out {
+			filtered.mu.RLock()
+			defer filtered.mu.RUnlock()
+			out = filtered
+		}
+	}
+	peg := out.compile(g, opts)
+	peg.transforms = applied
+	if err != nil {
+		peg.broken = err
+	}
+	return peg
+}
+
+// Compiles g, recording source as what it was compiled from. Both are read locked.
+func (g *Graph) compile(source *Graph, opts []ExecOption) *ParallelizedExecutableGraph {
+	nodes := make(executableNodes, len(g.nodes))
+
+	for id, node := range g.nodes {
+		deps := g.dependencies(node)
+		for depId := range deps {
+			dep := nodes.GetOrCreate(depId)
+			dep.AddTargets(id)
+		}
+
+		nodes.GetOrCreate(id).load(node, deps, g.aliased(node), g.edgeWeights(node, deps))
+	}
+
+	peg := &ParallelizedExecutableGraph{
+		name:              g.name,
+		namespace:         g.namespace,
+		allowEmpty:        g.allowEmpty,
+		nodes:             nodes,
+		defaults:          opts,
+		source:            source,
+		version:           source.version,
+		sourceFingerprint: source.fingerprint(),
+		aliases:           copyMap(g.aliases),
+	}
+	peg.broken = g.broken()
+	peg.indexOptional(g)
+
+	return peg
+}
+
+// Whether g has changed since this graph was compiled or last recompiled from it. A graph
+// compiled from anything other than g is always stale.
+func (peg *ParallelizedExecutableGraph) Stale(g *Graph) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return peg.source != g || peg.version != g.version
+}
+
+// The configuration a Run with these options would use
+func (peg *ParallelizedExecutableGraph) Config(opts ...ExecOption) ExecConfig {
+	cfg := ExecConfig{}
+	for _, opt := range peg.defaults {
+		opt(&cfg)
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// Introspection; everything returned is a copy
+func (peg *ParallelizedExecutableGraph) Roots() []NodeID {
+	rootIds := peg.nodes.RootIds()
+	sortIDs(rootIds)
+	return rootIds
+}
+
+func (peg *ParallelizedExecutableGraph) Targets(id NodeID) (NodeIDs, error) {
+	node, ok := peg.nodes[id]
+	if !ok {
+		return nil, fmt.Errorf("Node %s does not exist", id)
+	}
+
+	targets := make(NodeIDs, len(node.targetIDs))
+	for target := range node.targetIDs {
+		targets[target] = struct{}{}
+	}
+	return targets, nil
+}
+
+func (peg *ParallelizedExecutableGraph) DependencyCount(id NodeID) (int, error) {
+	node, ok := peg.nodes[id]
+	if !ok {
+		return 0, fmt.Errorf("Node %s does not exist", id)
+	}
+	return node.required, nil
+}
+
+// Driver
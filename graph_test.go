@@ -0,0 +1,201 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func Example() {
+	g := graph.NewGraph("release")
+	g.Add(graph.NewNode("build", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		return "app.tar", nil
+	}))
+	g.Add(graph.NewNode("deploy", graph.Deps("build"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		fmt.Println("deploying", ec.Results["build"])
+		return nil, nil
+	}))
+
+	order, err := g.Sort()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(order)
+
+	if _, err := g.CompileToExecutable().Run(context.Background()); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// [build deploy]
+	// deploying app.tar
+}
+
+func TestSortPutsDependenciesFirst(t *testing.T) {
+	ids := mustSort(t, diamond(t))
+
+	if len(ids) != 4 || ids[0] != "a" || ids[3] != "d" {
+		t.Errorf("Expected a first and d last, got %v", ids)
+	}
+	for _, pair := range [][2]graph.NodeID{{"a", "b"}, {"a", "c"}, {"b", "d"}, {"c", "d"}} {
+		if !before(ids, pair[0], pair[1]) {
+			t.Errorf("Expected %s before %s, got %v", pair[0], pair[1], ids)
+		}
+	}
+}
+
+func TestSortDetectsCycles(t *testing.T) {
+	g := diamond(t)
+	if err := g.AddEdge("a", "d"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.Sort(); err == nil {
+		t.Error("Expected Sort to fail on a cycle")
+	}
+	if err := g.Validate(); err == nil {
+		t.Error("Expected Validate to fail on a cycle")
+	}
+}
+
+func TestAddRejectsBadNodes(t *testing.T) {
+	g := diamond(t)
+
+	if _, err := g.Add(graph.NewNode("a", nil, graph.NoOp())); err == nil {
+		t.Error("Expected an error adding a duplicate id")
+	}
+	if _, err := g.Add(graph.NewNode("e", graph.Deps("missing"), graph.NoOp())); err == nil {
+		t.Error("Expected an error adding a node with a missing dependency")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	g := diamond(t)
+
+	if err := g.Remove("a"); err == nil {
+		t.Error("Expected an error removing a node others depend on")
+	}
+	if err := g.Remove("missing"); err == nil {
+		t.Error("Expected an error removing a node that doesn't exist")
+	}
+	if err := g.Remove("d"); err != nil {
+		t.Fatal(err)
+	}
+	if ids := mustSort(t, g); len(ids) != 3 {
+		t.Errorf("Expected 3 nodes after the remove, got %v", ids)
+	}
+}
+
+// Remove counts dependents once and keeps the counts through Adds and Removes; every other
+// mutation has them counted again
+func TestRemoveAfterOtherMutations(t *testing.T) {
+	g := diamond(t)
+	expectDependency := func(id graph.NodeID, of string) {
+		t.Helper()
+		if err := g.Remove(id); err == nil || err.Error() != fmt.Sprintf("Node %s is a dependency of %s", id, of) {
+			t.Errorf("Expected %s to be kept for %s, got %v", id, of, err)
+		}
+	}
+
+	expectDependency("a", "b c")
+	g.Add(graph.NewNode("e", graph.Deps("d"), graph.NoOp()))
+	expectDependency("d", "e")
+	if err := g.Remove("e"); err != nil {
+		t.Fatal(err)
+	}
+
+	g.Add(graph.NewNode("f", nil, graph.NoOp()))
+	if err := g.AddEdge("f", "d"); err != nil {
+		t.Fatal(err)
+	}
+	expectDependency("d", "f")
+
+	other := graph.NewGraph("other", graph.WithLazyAdd())
+	other.Add(graph.NewNode("g", graph.Deps("f"), graph.NoOp()))
+	if err := g.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	expectDependency("f", "g")
+
+	for _, id := range []graph.NodeID{"g", "f", "d", "c", "b", "a"} {
+		if err := g.Remove(id); err != nil {
+			t.Fatalf("Expected %s removable once its dependents are gone, got %v", id, err)
+		}
+	}
+	if g.Has("a") || g.EdgeCount() != 0 {
+		t.Errorf("Expected an empty graph, got %v", g)
+	}
+}
+
+func TestCompiledGraphShape(t *testing.T) {
+	peg := diamond(t).CompileToExecutable()
+
+	if roots := peg.Roots(); !reflect.DeepEqual(roots, []graph.NodeID{"a"}) {
+		t.Errorf("Expected a as the only root, got %v", roots)
+	}
+	targets, err := peg.Targets("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(targets, graph.Deps("b", "c")) {
+		t.Errorf("Expected a to feed b and c, got %v", targets)
+	}
+	if n, _ := peg.DependencyCount("d"); n != 2 {
+		t.Errorf("Expected d to wait on 2 nodes, got %d", n)
+	}
+	if _, err := peg.Targets("missing"); err == nil {
+		t.Error("Expected an error for a node not in the graph")
+	}
+}
+
+func TestRunPassesResultsDownstream(t *testing.T) {
+	g := graph.NewGraph("sum")
+	g.Add(graph.NewNode("one", nil, returns(1)))
+	g.Add(graph.NewNode("two", nil, returns(2)))
+	var sum int
+	g.Add(graph.NewNode("sum", graph.Deps("one", "two"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		sum = ec.Results["one"].(int) + ec.Results["two"].(int)
+		return sum, nil
+	}))
+
+	report, err := g.CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != 3 {
+		t.Errorf("Expected sum to see both results, got %d", sum)
+	}
+	for id, nr := range report.Nodes {
+		if nr.Status != graph.StatusSucceeded {
+			t.Errorf("Expected %s to succeed, got %s", id, nr.Status)
+		}
+	}
+}
+
+func TestRunFailureSkipsDependents(t *testing.T) {
+	boom := errors.New("boom")
+	g := graph.NewGraph("failing")
+	g.Add(graph.NewNode("a", nil, graph.NoOp()))
+	g.Add(graph.NewNode("b", graph.Deps("a"), failWith(boom)))
+	g.Add(graph.NewNode("c", graph.Deps("b"), graph.NoOp()))
+
+	report, err := g.CompileToExecutable().Run(ctx(t))
+	if !errors.Is(err, boom) {
+		t.Fatalf("Expected the run to fail with the node's error, got %v", err)
+	}
+	var nerr *graph.NodeError
+	if !errors.As(err, &nerr) || nerr.ID != "b" {
+		t.Errorf("Expected a NodeError for b, got %v", err)
+	}
+
+	if got := report.Nodes["c"]; got.Status != graph.StatusSkipped || got.Reason != graph.ReasonUpstreamFailed {
+		t.Errorf("Expected c skipped for its failed upstream, got %s %s", got.Status, got.Reason)
+	}
+	if got := report.Nodes["a"].Status; got != graph.StatusSucceeded {
+		t.Errorf("Expected a to succeed, got %s", got)
+	}
+}
@@ -0,0 +1,211 @@
+package graph
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+const graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Changes how Graph.WriteGraphML, Graph.ToDOT and Graph.ToMermaid draw the graph
+type ExportOption func(*exportConfig)
+
+// The name ExportOption had when only GraphML took options
+type GraphMLOption = ExportOption
+
+type exportConfig struct {
+	hideMarkers bool
+}
+
+func newExportConfig(opts []ExportOption) exportConfig {
+	cfg := exportConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// Leaves marker nodes out of the view. Their dependents get edges straight to the nodes the
+// markers depended on, carrying the skipped marker as data in GraphML and as a label in DOT and
+// Mermaid, so the ordering stays visible. The graph itself is unchanged.
+func HideMarkers() ExportOption {
+	return func(c *exportConfig) {
+		c.hideMarkers = true
+	}
+}
+
+// Edges point from a node to the node it depends on, with the edge kind and any nonzero weight
+// as data. Edges declared through an alias point at the resolved node and carry the alias as data.
+// String-valued inputs are written as node data named input.<key>; other inputs are left out.
+// Markers are flagged with marker data.
+func (g *Graph) WriteGraphML(w io.Writer, opts ...ExportOption) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	cfg := newExportConfig(opts)
+
+	metaKeys := map[string]struct{}{}
+	inputKeys := map[string]struct{}{}
+	for _, node := range g.nodes {
+		for key := range node.Metadata {
+			metaKeys[key] = struct{}{}
+		}
+		for key, value := range node.Inputs {
+			if _, ok := value.(string); ok {
+				inputKeys[key] = struct{}{}
+			}
+		}
+	}
+	names := sortedKeys(metaKeys)
+	inputs := sortedKeys(inputKeys)
+
+	doc := graphMLDocument{
+		XMLNS: graphMLNamespace,
+		Graph: graphMLGraph{ID: g.name, EdgeDefault: "directed"},
+	}
+
+	keyIDs := make(map[string]string, len(names))
+	for i, name := range names {
+		id := fmt.Sprintf("n%d", i)
+		keyIDs[name] = id
+		doc.Keys = append(doc.Keys, graphMLKey{ID: id, For: "node", AttrName: name, AttrType: "string"})
+	}
+	inputIDs := make(map[string]string, len(inputs))
+	for i, name := range inputs {
+		id := fmt.Sprintf("i%d", i)
+		inputIDs[name] = id
+		doc.Keys = append(doc.Keys, graphMLKey{ID: id, For: "node", AttrName: "input." + name, AttrType: "string"})
+	}
+	doc.Keys = append(doc.Keys, graphMLKey{ID: "kind", For: "edge", AttrName: "kind", AttrType: "string"})
+	if len(g.aliases) > 0 {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: "alias", For: "edge", AttrName: "alias", AttrType: "string"})
+	}
+	doc.Keys = append(doc.Keys, graphMLKey{ID: "weight", For: "edge", AttrName: "weight", AttrType: "double"})
+	if cfg.hideMarkers {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: "via", For: "edge", AttrName: "via", AttrType: "string"})
+	} else {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: "marker", For: "node", AttrName: "marker", AttrType: "boolean"})
+	}
+
+	for _, id := range sortedIDs(g.nodes) {
+		node := g.nodes[id]
+		if node.marker && cfg.hideMarkers {
+			continue
+		}
+
+		n := graphMLNode{ID: string(id)}
+		for _, name := range names {
+			if value, ok := node.Metadata[name]; ok {
+				n.Data = append(n.Data, graphMLData{Key: keyIDs[name], Value: value})
+			}
+		}
+		for _, name := range inputs {
+			if value, ok := node.Inputs[name].(string); ok {
+				n.Data = append(n.Data, graphMLData{Key: inputIDs[name], Value: value})
+			}
+		}
+		if node.marker {
+			n.Data = append(n.Data, graphMLData{Key: "marker", Value: "true"})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, n)
+
+		edges := g.nodeEdges(id)
+		var collapsed map[NodeID]collapsedEdge
+		if cfg.hideMarkers {
+			edges, collapsed = g.collapseMarkers(node, edges)
+		}
+		for _, e := range edges {
+			edge := graphMLEdge{
+				ID:     fmt.Sprintf("e%d", len(doc.Graph.Edges)),
+				Source: string(e.From),
+				Target: string(e.To),
+				Data:   []graphMLData{{Key: "kind", Value: node.DependencyKind(e.declared).String()}},
+			}
+			if e.declared != e.To {
+				edge.Data = append(edge.Data, graphMLData{Key: "alias", Value: string(e.declared)})
+			}
+			if weight := g.weight(node, e.declared); weight != 0 {
+				edge.Data = append(edge.Data, graphMLData{Key: "weight", Value: strconv.FormatFloat(weight, 'g', -1, 64)})
+			}
+			doc.Graph.Edges = append(doc.Graph.Edges, edge)
+		}
+
+		for _, target := range sortedIDs(collapsed) {
+			kind := EdgeSoft
+			if collapsed[target].hard {
+				kind = EdgeHard
+			}
+			doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+				ID:     fmt.Sprintf("e%d", len(doc.Graph.Edges)),
+				Source: string(id),
+				Target: string(target),
+				Data: []graphMLData{
+					{Key: "kind", Value: kind.String()},
+					{Key: "via", Value: string(collapsed[target].via)},
+				},
+			})
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
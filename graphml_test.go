@@ -0,0 +1,101 @@
+package graph_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func graphMLFixture(t *testing.T) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("etl")
+	g.Add(graph.NewNode("extract", nil, graph.NoOp(), graph.WithMetadata("team", "data & <ml>")))
+	g.Add(graph.NewNode(`load "raw"`, graph.Deps("extract"), graph.NoOp(), graph.WithMetadata("env", "prod")))
+	g.Add(graph.NewNode("report", graph.Deps(`load "raw"`), graph.NoOp(), graph.WithSoftDependency("extract")))
+	return g
+}
+
+func TestGraphMLGolden(t *testing.T) {
+	var b bytes.Buffer
+	if err := graphMLFixture(t).WriteGraphML(&b); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "etl.graphml", b.String())
+}
+
+func TestGraphMLParsesBack(t *testing.T) {
+	var b bytes.Buffer
+	if err := graphMLFixture(t).WriteGraphML(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"graphml"`
+		Keys    []struct {
+			ID   string `xml:"id,attr"`
+			For  string `xml:"for,attr"`
+			Name string `xml:"attr.name,attr"`
+		} `xml:"key"`
+		Graph struct {
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []struct {
+				ID   string `xml:"id,attr"`
+				Data []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected well-formed XML, got %v", err)
+	}
+
+	if doc.XMLName.Space != "http://graphml.graphdrawing.org/xmlns" {
+		t.Errorf("Expected the GraphML namespace, got %q", doc.XMLName.Space)
+	}
+	if doc.Graph.EdgeDefault != "directed" {
+		t.Errorf("Expected a directed graph, got %q", doc.Graph.EdgeDefault)
+	}
+
+	keys := map[string]string{}
+	for _, k := range doc.Keys {
+		keys[k.ID] = k.Name
+	}
+	values := map[string]map[string]string{}
+	for _, n := range doc.Graph.Nodes {
+		values[n.ID] = map[string]string{}
+		for _, d := range n.Data {
+			if _, ok := keys[d.Key]; !ok {
+				t.Errorf("Node %s uses undeclared key %s", n.ID, d.Key)
+			}
+			values[n.ID][keys[d.Key]] = d.Value
+		}
+	}
+	if len(values) != 3 {
+		t.Errorf("Expected 3 nodes, got %v", values)
+	}
+	if got := values["extract"]["team"]; got != "data & <ml>" {
+		t.Errorf("Expected metadata to survive escaping, got %q", got)
+	}
+	if _, ok := values[`load "raw"`]; !ok {
+		t.Errorf("Expected the quoted id to survive escaping, got %v", values)
+	}
+
+	edges := map[[2]string]bool{}
+	for _, e := range doc.Graph.Edges {
+		edges[[2]string{e.Source, e.Target}] = true
+	}
+	for _, want := range [][2]string{{`load "raw"`, "extract"}, {"report", `load "raw"`}, {"report", "extract"}} {
+		if !edges[want] {
+			t.Errorf("Expected an edge %s -> %s, got %v", want[0], want[1], edges)
+		}
+	}
+}
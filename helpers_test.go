@@ -0,0 +1,187 @@
+package graph_test
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A Clock that only moves when Advance is called
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Moves the clock forward and fires every timer that has come due
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	waiting := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiting = append(waiting, w)
+		} else {
+			w.ch <- c.now
+		}
+	}
+	c.waiters = waiting
+}
+
+// How many timers haven't fired yet
+func (c *fakeClock) armed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// Blocks until at least n timers are armed, so an Advance doesn't race the code arming them
+func (c *fakeClock) waitFor(t *testing.T, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		armed := c.armed()
+		if armed >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d timers, have %d", n, armed)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// a feeds b and c, which both feed d
+func diamond(t *testing.T, opts ...graph.GraphOption) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("diamond", opts...)
+	for _, node := range []*graph.Node{
+		graph.NewNode("a", nil, graph.NoOp()),
+		graph.NewNode("b", graph.Deps("a"), graph.NoOp()),
+		graph.NewNode("c", graph.Deps("a"), graph.NoOp()),
+		graph.NewNode("d", graph.Deps("b", "c"), graph.NoOp()),
+	} {
+		if _, err := g.Add(node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// Compares got with testdata/name, or rewrites the file under -update
+func golden(t *testing.T, name string, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Reading %s: %v; run with -update to create it", path, err)
+	}
+	if got != string(want) {
+		t.Errorf("Output doesn't match %s; run with -update if the change is intended\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func mustSort(t *testing.T, g *graph.Graph) graph.SortedNodeIDs {
+	t.Helper()
+
+	ids, err := g.Sort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+// A context canceled when the test ends
+func ctx(t *testing.T) context.Context {
+	c, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return c
+}
+
+// A fn that fails with err
+func failWith(err error) graph.NodeFn {
+	return func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		return nil, err
+	}
+}
+
+// A fn that returns value
+func returns(value any) graph.NodeFn {
+	return func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		return value, nil
+	}
+}
+
+// Whether first comes before second in ids
+func before(ids graph.SortedNodeIDs, first, second graph.NodeID) bool {
+	for _, id := range ids {
+		switch id {
+		case first:
+			return true
+		case second:
+			return false
+		}
+	}
+	return false
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
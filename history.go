@@ -0,0 +1,78 @@
+package graph
+
+// Keeps the reports of the last n runs, canceled ones included, for History and LastReport.
+// Give it to CompileToExecutable; a run given a different size resizes the history, keeping
+// the newest reports.
+func WithHistory(n int) ExecOption {
+	return func(c *ExecConfig) {
+		c.History = n
+	}
+}
+
+// A fixed-size ring of reports, oldest at start
+type reportRing struct {
+	reports []*Report
+	start   int
+	count   int
+}
+
+func (h *reportRing) add(report *Report, size int) {
+	if size != len(h.reports) {
+		kept := h.newest(size)
+		h.reports = make([]*Report, size)
+		h.start, h.count = 0, len(kept)
+		for i, r := range kept {
+			h.reports[len(kept)-1-i] = r
+		}
+	}
+
+	if h.count < size {
+		h.reports[(h.start+h.count)%size] = report
+		h.count++
+		return
+	}
+	h.reports[h.start] = report
+	h.start = (h.start + 1) % size
+}
+
+// Up to n reports, newest first
+func (h *reportRing) newest(n int) []*Report {
+	if n > h.count {
+		n = h.count
+	}
+
+	reports := make([]*Report, 0, n)
+	for i := 0; i < n; i++ {
+		reports = append(reports, h.reports[(h.start+h.count-1-i)%len(h.reports)])
+	}
+	return reports
+}
+
+func (peg *ParallelizedExecutableGraph) remember(report *Report, size int) {
+	if size <= 0 || report == nil {
+		return
+	}
+
+	peg.historyMu.Lock()
+	defer peg.historyMu.Unlock()
+	peg.history.add(report, size)
+}
+
+// Reports of the most recent runs, newest first; empty unless the graph keeps a history
+func (peg *ParallelizedExecutableGraph) History() []*Report {
+	peg.historyMu.Lock()
+	defer peg.historyMu.Unlock()
+
+	return peg.history.newest(peg.history.count)
+}
+
+// The most recent run's report, or nil
+func (peg *ParallelizedExecutableGraph) LastReport() *Report {
+	peg.historyMu.Lock()
+	defer peg.historyMu.Unlock()
+
+	if reports := peg.history.newest(1); len(reports) > 0 {
+		return reports[0]
+	}
+	return nil
+}
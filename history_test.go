@@ -0,0 +1,110 @@
+package graph_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func runIDs(reports []*graph.Report) []string {
+	ids := []string{}
+	for _, r := range reports {
+		ids = append(ids, r.RunID)
+	}
+	return ids
+}
+
+func TestHistoryKeepsNewest(t *testing.T) {
+	peg := diamond(t).CompileToExecutable(graph.WithHistory(3))
+	if peg.LastReport() != nil || len(peg.History()) != 0 {
+		t.Fatal("Expected no history before the first run")
+	}
+
+	ran := []string{}
+	for i := 0; i < 5; i++ {
+		report, err := peg.Run(ctx(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ran = append(ran, report.RunID)
+	}
+
+	want := []string{ran[4], ran[3], ran[2]}
+	if got := runIDs(peg.History()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the last 3 runs newest first, %v, got %v", want, got)
+	}
+	if last := peg.LastReport(); last == nil || last.RunID != ran[4] {
+		t.Errorf("Expected the last run's report, got %v", last)
+	}
+}
+
+func TestHistoryKeepsCanceledRuns(t *testing.T) {
+	peg := diamond(t).CompileToExecutable(graph.WithHistory(2))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := peg.Run(canceled)
+	if err == nil {
+		t.Fatal("Expected the canceled run to fail")
+	}
+	if last := peg.LastReport(); last == nil || last.RunID != report.RunID {
+		t.Errorf("Expected the canceled run's report kept, got %v", last)
+	}
+}
+
+func TestHistoryResize(t *testing.T) {
+	peg := diamond(t).CompileToExecutable(graph.WithHistory(4))
+	ran := []string{}
+	for i := 0; i < 4; i++ {
+		report, _ := peg.Run(ctx(t))
+		ran = append(ran, report.RunID)
+	}
+
+	// Shrinking keeps the newest, then the new run goes in front
+	report, _ := peg.Run(ctx(t), graph.WithHistory(2))
+	want := []string{report.RunID, ran[3]}
+	if got := runIDs(peg.History()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v after shrinking, got %v", want, got)
+	}
+}
+
+func TestHistoryOffByDefault(t *testing.T) {
+	peg := diamond(t).CompileToExecutable()
+	peg.Run(ctx(t))
+	if peg.LastReport() != nil || len(peg.History()) != 0 {
+		t.Error("Expected no history without WithHistory")
+	}
+}
+
+func TestHistoryConcurrentRuns(t *testing.T) {
+	peg := diamond(t).CompileToExecutable(graph.WithHistory(5))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			peg.Run(context.Background())
+			peg.History()
+		}()
+	}
+	wg.Wait()
+
+	reports := peg.History()
+	if len(reports) != 5 {
+		t.Fatalf("Expected 5 reports, got %d", len(reports))
+	}
+	seen := map[string]bool{}
+	for _, r := range reports {
+		if r == nil {
+			t.Fatal("Expected no empty slots")
+		}
+		if seen[r.RunID] {
+			t.Errorf("Expected 5 distinct reports, got %v", runIDs(reports))
+		}
+		seen[r.RunID] = true
+	}
+}
@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	idSeparator = "/"
+	idEscape    = `\`
+)
+
+var idEscaper = strings.NewReplacer(idEscape, idEscape+idEscape, idSeparator, idEscape+idSeparator)
+
+// Joins parts with "/", escaping "/" and "\" inside them, so different parts never produce
+// the same id: NodeIDFrom("a/b", "c") and NodeIDFrom("a", "b/c") differ.
+func NodeIDFrom(parts ...string) NodeID {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = idEscaper.Replace(part)
+	}
+	return NodeID(strings.Join(escaped, idSeparator))
+}
+
+// Derives an id from the SHA-256 of v's JSON encoding, which sorts map keys, so equal values
+// always hash the same. Values with the same encoding share an id even if they differ in Go,
+// for example in unexported fields; otherwise ids only collide if SHA-256 does.
+func NodeIDHash(v any) (NodeID, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return NodeID(hex.EncodeToString(sum[:])), nil
+}
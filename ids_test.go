@@ -0,0 +1,66 @@
+package graph_test
+
+import (
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestNodeIDFromPartitionsDontCollide(t *testing.T) {
+	partitions := [][]string{
+		{"a/b", "c"},
+		{"a", "b/c"},
+		{"a", "b", "c"},
+		{"a/b/c"},
+		{`a\`, "b"},
+		{`a\/b`},
+		{"a", `\b`},
+	}
+
+	seen := map[graph.NodeID][]string{}
+	for _, parts := range partitions {
+		id := graph.NodeIDFrom(parts...)
+		if other, ok := seen[id]; ok {
+			t.Errorf("Expected %q and %q to differ, both gave %s", other, parts, id)
+		}
+		seen[id] = parts
+	}
+}
+
+func TestNodeIDFromPlainParts(t *testing.T) {
+	if got := graph.NodeIDFrom("repo", "path", "target"); got != "repo/path/target" {
+		t.Errorf("Expected parts without separators joined as is, got %s", got)
+	}
+}
+
+type target struct {
+	Repo string
+	Path string
+	Tags map[string]string
+}
+
+func TestNodeIDHashStable(t *testing.T) {
+	v := target{Repo: "go_graph", Path: "cmd/example", Tags: map[string]string{"a": "1", "b": "2", "c": "3"}}
+
+	first, err := graph.NodeIDHash(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		again, _ := graph.NodeIDHash(target{Repo: "go_graph", Path: "cmd/example", Tags: map[string]string{"c": "3", "b": "2", "a": "1"}})
+		if again != first {
+			t.Fatalf("Expected equal values to hash the same, got %s and %s", first, again)
+		}
+	}
+
+	other, _ := graph.NodeIDHash(target{Repo: "go_graph", Path: "cmd"})
+	if other == first {
+		t.Error("Expected different values to hash differently")
+	}
+}
+
+func TestNodeIDHashUnencodable(t *testing.T) {
+	if _, err := graph.NodeIDHash(make(chan int)); err == nil {
+		t.Error("Expected an error for a value JSON can't encode")
+	}
+}
@@ -0,0 +1,112 @@
+package graph_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func download(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+	return "fetched " + ec.Input("url"), nil
+}
+
+func downloads(t *testing.T) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("downloads")
+	g.Add(graph.NewNode("docs", nil, download, graph.WithInputs(map[string]any{"url": "https://example.com/docs", "retries": 3})))
+	g.Add(graph.NewNode("blog", nil, download, graph.WithInputs(map[string]any{"url": "https://example.com/blog"})))
+	return g
+}
+
+func TestInputsParameterizeSharedFn(t *testing.T) {
+	g := downloads(t)
+	var seen graph.Results
+	g.Add(graph.NewNode("collect", graph.Deps("docs", "blog"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		seen = ec.Results
+		return nil, nil
+	}))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	if seen["docs"] != "fetched https://example.com/docs" || seen["blog"] != "fetched https://example.com/blog" {
+		t.Errorf("Expected each node to fetch its own url, got %v", seen)
+	}
+}
+
+func TestInputsNonStringValues(t *testing.T) {
+	g := graph.NewGraph("inputs")
+	var retries any
+	var missing string
+	g.Add(graph.NewNode("docs", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		retries = ec.Inputs["retries"]
+		missing = ec.Input("retries")
+		return nil, nil
+	}, graph.WithInputs(map[string]any{"retries": 3})))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+	if retries != 3 {
+		t.Errorf("Expected the int input as is, got %v", retries)
+	}
+	if missing != "" {
+		t.Errorf("Expected Input to return \"\" for a non-string, got %q", missing)
+	}
+}
+
+func TestInputsAddUp(t *testing.T) {
+	node := graph.NewNode("n", nil, graph.NoOp(),
+		graph.WithInputs(map[string]any{"a": "1", "b": "2"}),
+		graph.WithInputs(map[string]any{"b": "3"}))
+
+	if node.Inputs["a"] != "1" || node.Inputs["b"] != "3" {
+		t.Errorf("Expected later inputs to add to and override earlier ones, got %v", node.Inputs)
+	}
+}
+
+func TestInputsSurviveCloneAndMerge(t *testing.T) {
+	g := downloads(t)
+
+	clone := g.Clone()
+	node, _ := clone.Get("docs")
+	if node.Inputs["url"] != "https://example.com/docs" {
+		t.Fatalf("Expected the clone to keep inputs, got %v", node.Inputs)
+	}
+	// The clone's inputs are its own
+	node.Inputs["url"] = "changed"
+	if original, _ := g.Get("docs"); original.Inputs["url"] != "https://example.com/docs" {
+		t.Errorf("Expected changing the clone to leave the original alone, got %v", original.Inputs)
+	}
+
+	merged := graph.NewGraph("merged")
+	if err := merged.Merge(downloads(t)); err != nil {
+		t.Fatal(err)
+	}
+	if node, _ := merged.Get("blog"); node.Inputs["url"] != "https://example.com/blog" {
+		t.Errorf("Expected the merged node to keep inputs, got %v", node.Inputs)
+	}
+}
+
+func TestInputsInGraphML(t *testing.T) {
+	var b bytes.Buffer
+	if err := downloads(t).WriteGraphML(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	if !strings.Contains(out, `attr.name="input.url"`) {
+		t.Errorf("Expected a key for the url input, got %s", out)
+	}
+	if !strings.Contains(out, "https://example.com/blog") {
+		t.Errorf("Expected the url values, got %s", out)
+	}
+	if strings.Contains(out, "input.retries") {
+		t.Errorf("Expected non-string inputs left out, got %s", out)
+	}
+}
@@ -0,0 +1,68 @@
+package graph_test
+
+import (
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestIntrospectDiamond(t *testing.T) {
+	peg := diamond(t).CompileToExecutable()
+
+	if roots := peg.Roots(); !reflect.DeepEqual(roots, []graph.NodeID{"a"}) {
+		t.Errorf("Expected the one root a, got %v", roots)
+	}
+
+	targets := map[graph.NodeID]graph.NodeIDs{
+		"a": graph.Deps("b", "c"),
+		"b": graph.Deps("d"),
+		"c": graph.Deps("d"),
+		"d": {},
+	}
+	counts := map[graph.NodeID]int{"a": 0, "b": 1, "c": 1, "d": 2}
+
+	for id, want := range targets {
+		got, err := peg.Targets(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %s to target %v, got %v", id, want, got)
+		}
+
+		count, err := peg.DependencyCount(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != counts[id] {
+			t.Errorf("Expected %s to require %d, got %d", id, counts[id], count)
+		}
+	}
+}
+
+func TestIntrospectReturnsCopies(t *testing.T) {
+	peg := diamond(t).CompileToExecutable()
+
+	targets, _ := peg.Targets("a")
+	delete(targets, "b")
+	peg.Roots()[0] = "z"
+
+	if again, _ := peg.Targets("a"); len(again) != 2 {
+		t.Errorf("Expected changing the returned targets to leave the graph alone, got %v", again)
+	}
+	if roots := peg.Roots(); roots[0] != "a" {
+		t.Errorf("Expected changing the returned roots to leave the graph alone, got %v", roots)
+	}
+}
+
+func TestIntrospectUnknownNode(t *testing.T) {
+	peg := diamond(t).CompileToExecutable()
+
+	if _, err := peg.Targets("missing"); err == nil {
+		t.Error("Expected an error for an unknown node's targets")
+	}
+	if _, err := peg.DependencyCount("missing"); err == nil {
+		t.Error("Expected an error for an unknown node's count")
+	}
+}
@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrLimitExceeded = errors.New("Graph limit exceeded")
+
+type Limit int
+
+const (
+	LimitNodes Limit = iota
+	LimitDepth
+	LimitEdgesPerNode
+)
+
+func (l Limit) String() string {
+	switch l {
+	case LimitNodes:
+		return "max nodes"
+	case LimitDepth:
+		return "max depth"
+	case LimitEdgesPerNode:
+		return "max edges per node"
+	}
+	return fmt.Sprintf("Limit(%d)", int(l))
+}
+
+type LimitError struct {
+	Limit Limit
+	// The node that went over the limit
+	Node   NodeID
+	Max    int
+	Actual int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: node %s exceeds %s of %d with %d", ErrLimitExceeded, e.Node, e.Limit, e.Max, e.Actual)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// Zero means no limit
+type graphLimits struct {
+	nodes        int
+	depth        int
+	edgesPerNode int
+}
+
+// Checked on Add and Merge
+func WithMaxNodes(n int) GraphOption {
+	return func(g *Graph) {
+		g.limits.nodes = n
+	}
+}
+
+// The longest chain of dependencies, counting the nodes on it. Checked by Validate.
+func WithMaxDepth(d int) GraphOption {
+	return func(g *Graph) {
+		g.limits.depth = d
+	}
+}
+
+// Declared dependencies, optional ones included. Checked on Add, AddEdge and Merge.
+func WithMaxEdgesPerNode(m int) GraphOption {
+	return func(g *Graph) {
+		g.limits.edgesPerNode = m
+	}
+}
+
+func (g *Graph) checkNodeLimit(id NodeID, count int) error {
+	if g.limits.nodes > 0 && count > g.limits.nodes {
+		return &LimitError{Limit: LimitNodes, Node: id, Max: g.limits.nodes, Actual: count}
+	}
+	return nil
+}
+
+func (g *Graph) checkEdgeLimit(node *Node, extra int) error {
+	count := len(node.Dependencies) + extra
+	for depId := range node.optionalDependencies {
+		if _, ok := node.Dependencies[depId]; !ok {
+			count++
+		}
+	}
+
+	if g.limits.edgesPerNode > 0 && count > g.limits.edgesPerNode {
+		return &LimitError{Limit: LimitEdgesPerNode, Node: node.Identifier(), Max: g.limits.edgesPerNode, Actual: count}
+	}
+	return nil
+}
+
+// Finds the longest chain without recursion so deep graphs can't exhaust the stack.
+// Nodes on a cycle are never reached; Sort reports those.
+func (g *Graph) checkDepth() error {
+	if g.limits.depth <= 0 {
+		return nil
+	}
+
+	pending := make(map[NodeID]int, len(g.nodes))
+	dependents := make(map[NodeID]SortedNodeIDs, len(g.nodes))
+	depth := make(map[NodeID]int, len(g.nodes))
+	queue := SortedNodeIDs{}
+
+	for _, id := range sortedIDs(g.nodes) {
+		deps := g.dependencies(g.nodes[id])
+		pending[id] = len(deps)
+		for depId := range deps {
+			dependents[depId] = append(dependents[depId], id)
+		}
+		if len(deps) == 0 {
+			depth[id] = 1
+			queue = append(queue, id)
+		}
+	}
+
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		if depth[id] > g.limits.depth {
+			return &LimitError{Limit: LimitDepth, Node: id, Max: g.limits.depth, Actual: depth[id]}
+		}
+
+		for _, target := range dependents[id] {
+			if depth[id]+1 > depth[target] {
+				depth[target] = depth[id] + 1
+			}
+			pending[target]--
+			if pending[target] == 0 {
+				queue = append(queue, target)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package graph_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func expectLimit(t *testing.T, err error, limit graph.Limit, node graph.NodeID) {
+	t.Helper()
+
+	if !errors.Is(err, graph.ErrLimitExceeded) {
+		t.Fatalf("Expected ErrLimitExceeded, got %v", err)
+	}
+	var lerr *graph.LimitError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("Expected a LimitError, got %T", err)
+	}
+	if lerr.Limit != limit || lerr.Node != node {
+		t.Errorf("Expected %s exceeded by %s, got %s by %s", limit, node, lerr.Limit, lerr.Node)
+	}
+}
+
+// A chain of n nodes, each depending on the one before
+func chain(t *testing.T, n int, opts ...graph.GraphOption) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("chain", opts...)
+	g.Add(graph.NewNode("n0", nil, graph.NoOp()))
+	for i := 1; i < n; i++ {
+		if _, err := g.Add(graph.NewNode(fmt.Sprintf("n%d", i), graph.Deps(graph.NodeID(fmt.Sprintf("n%d", i-1))), graph.NoOp())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+func TestMaxNodesOnAdd(t *testing.T) {
+	g := diamond(t, graph.WithMaxNodes(4))
+
+	_, err := g.Add(graph.NewNode("e", nil, graph.NoOp()))
+	expectLimit(t, err, graph.LimitNodes, "e")
+	if _, ok := g.Get("e"); ok {
+		t.Error("Expected the rejected node left out")
+	}
+}
+
+func TestMaxNodesOnMerge(t *testing.T) {
+	g := graph.NewGraph("small", graph.WithMaxNodes(3))
+	g.Add(graph.NewNode("x", nil, graph.NoOp()))
+
+	err := g.Merge(diamond(t))
+	if !errors.Is(err, graph.ErrLimitExceeded) {
+		t.Fatalf("Expected ErrLimitExceeded, got %v", err)
+	}
+	if g.Has("a") {
+		t.Error("Expected the failed merge to add nothing")
+	}
+}
+
+func TestMaxEdgesPerNode(t *testing.T) {
+	g := diamond(t, graph.WithMaxEdgesPerNode(2))
+
+	_, err := g.Add(graph.NewNode("e", graph.Deps("a", "b", "c"), graph.NoOp()))
+	expectLimit(t, err, graph.LimitEdgesPerNode, "e")
+
+	// d already has two
+	expectLimit(t, g.AddEdge("d", "a"), graph.LimitEdgesPerNode, "d")
+
+	_, err = g.Add(graph.NewNode("f", graph.Deps("a"), graph.NoOp(), graph.WithOptionalDependency("b"), graph.WithOptionalDependency("c")))
+	expectLimit(t, err, graph.LimitEdgesPerNode, "f")
+}
+
+func TestMaxDepthOnValidate(t *testing.T) {
+	g := chain(t, 10, graph.WithMaxDepth(5))
+
+	expectLimit(t, g.Validate(), graph.LimitDepth, "n5")
+
+	ok := chain(t, 5, graph.WithMaxDepth(5))
+	if err := ok.Validate(); err != nil {
+		t.Errorf("Expected a chain at the limit to pass, got %v", err)
+	}
+}
+
+func TestMaxDepthDeepChain(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Builds a 200k node chain")
+	}
+
+	g := chain(t, 200_000, graph.WithMaxDepth(1000))
+	expectLimit(t, g.Validate(), graph.LimitDepth, "n1000")
+}
+
+func TestLimitsOffByDefault(t *testing.T) {
+	g := chain(t, 2000)
+	for i := 0; i < 50; i++ {
+		g.AddEdge("n1999", graph.NodeID(fmt.Sprintf("n%d", i)))
+	}
+
+	if err := g.Validate(); err != nil {
+		t.Errorf("Expected no limits by default, got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package graph
+
+// Receives every event of a run, the same ones WithEventLog records. Calls are made one at a
+// time in Seq order, so a listener needn't be safe for concurrent use, but they hold up the
+// node that caused the event until they return.
+type Listener interface {
+	OnEvent(e Event)
+}
+
+// Adds listeners to the run, called in the order they were added. Given at compile time and
+// again at run, the compile listeners come first. A listener that panics is recovered and
+// reported through the run's Logger; the run and the other listeners carry on.
+func WithListeners(listeners ...Listener) ExecOption {
+	return func(c *ExecConfig) {
+		c.Listeners = append(append([]Listener(nil), c.Listeners...), listeners...)
+	}
+}
+
+// The caller holds the log's lock
+func (l *eventLog) notify(e Event) {
+	for i, listener := range l.listeners {
+		l.call(i, listener, e)
+	}
+}
+
+func (l *eventLog) call(i int, listener Listener, e Event) {
+	defer func() {
+		if p := recover(); p != nil && l.logger != nil {
+			l.logger.Printf("Listener %d panicked on %s event %d: %v", i, e.Kind, e.Seq, p)
+		}
+	}()
+	listener.OnEvent(e)
+}
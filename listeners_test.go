@@ -0,0 +1,113 @@
+package graph_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Keeps what it's told, tagging each event with its name in a log the listeners share
+type listening struct {
+	name   string
+	events []graph.Event
+	order  *[]string
+}
+
+func (l *listening) OnEvent(e graph.Event) {
+	l.events = append(l.events, e)
+	if l.order != nil {
+		*l.order = append(*l.order, l.name)
+	}
+}
+
+type panicking struct{}
+
+func (panicking) OnEvent(e graph.Event) {
+	panic("listener blew up")
+}
+
+func TestListenersReceiveEveryEvent(t *testing.T) {
+	order := []string{}
+	metrics, progress, audit := &listening{name: "metrics", order: &order}, &listening{name: "progress", order: &order}, &listening{name: "audit", order: &order}
+
+	report, _ := eventful(t).CompileToExecutable().Run(ctx(t), graph.WithEventLog(1000), graph.WithListeners(metrics, progress), graph.WithListeners(audit))
+
+	for _, l := range []*listening{metrics, progress, audit} {
+		if !reflect.DeepEqual(l.events, report.Events) {
+			t.Errorf("Expected %s to get the %d events the log kept, got %d", l.name, len(report.Events), len(l.events))
+		}
+	}
+	for i := 0; i < len(order); i += 3 {
+		if got := order[i : i+3]; !reflect.DeepEqual(got, []string{"metrics", "progress", "audit"}) {
+			t.Fatalf("Expected each event handed out in registration order, got %v", got)
+		}
+	}
+}
+
+func TestListenersWithoutEventLog(t *testing.T) {
+	l := &listening{}
+	report, _ := eventful(t).CompileToExecutable().Run(ctx(t), graph.WithListeners(l))
+
+	if report.Events != nil {
+		t.Errorf("Expected no events kept in the report, got %d", len(report.Events))
+	}
+	if len(l.events) == 0 || l.events[0].Kind != graph.EventRunStarted || l.events[len(l.events)-1].Kind != graph.EventRunFinished {
+		t.Fatalf("Expected the whole run, got %v", l.events)
+	}
+	for i, e := range l.events {
+		if e.Seq != uint64(i+1) || e.Time.IsZero() {
+			t.Fatalf("Expected event %d numbered and timed, got %+v", i, e)
+		}
+	}
+}
+
+func TestListenersCompileTimeFirst(t *testing.T) {
+	order := []string{}
+	compiled, run := &listening{name: "compiled", order: &order}, &listening{name: "run", order: &order}
+
+	eventful(t).CompileToExecutable(graph.WithListeners(compiled)).Run(ctx(t), graph.WithListeners(run))
+	if len(order) < 2 || order[0] != "compiled" || order[1] != "run" {
+		t.Errorf("Expected the compile-time listener called first, got %v", order)
+	}
+	if len(compiled.events) != len(run.events) {
+		t.Errorf("Expected both to get every event, got %d and %d", len(compiled.events), len(run.events))
+	}
+}
+
+func TestListenerPanicIsolated(t *testing.T) {
+	before, after := &listening{}, &listening{}
+	logger := &recordingLogger{}
+
+	report, err := diamond(t).CompileToExecutable().Run(ctx(t), graph.WithListeners(before, panicking{}, after), graph.WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Expected the run unaffected, got %v", err)
+	}
+	for id, nr := range report.Nodes {
+		if nr.Status != graph.StatusSucceeded {
+			t.Errorf("Expected %s to succeed, got %s", id, nr.Status)
+		}
+	}
+	if len(before.events) == 0 || !reflect.DeepEqual(before.events, after.events) {
+		t.Errorf("Expected the listeners either side to get every event, got %d and %d", len(before.events), len(after.events))
+	}
+
+	panics := []string{}
+	for _, line := range logger.lines {
+		if strings.HasPrefix(line, "Listener ") {
+			panics = append(panics, line)
+		}
+	}
+	if len(panics) != len(before.events) {
+		t.Fatalf("Expected a log line per panic, got %v", panics)
+	}
+	if want := "Listener 1 panicked on run-started event 1: listener blew up"; panics[0] != want {
+		t.Errorf("Expected %q, got %q", want, panics[0])
+	}
+	for _, line := range panics {
+		if !strings.HasPrefix(line, "Listener 1 panicked") {
+			t.Errorf("Expected only the panicking listener logged, got %q", line)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package graph
+
+import (
+	"context"
+)
+
+// Markers are ordering-only nodes such as barriers and phase anchors. The executor settles a
+// marker as soon as it's ready, without a goroutine or calling its fn, and leaves it out of
+// hooks, logs, events and metrics unless the run opts in with WithMarkerHooks. Markers add
+// nothing to TotalWork; the critical path runs through them without listing them.
+func AsMarker() NodeOption {
+	return func(n *Node) {
+		n.marker = true
+	}
+}
+
+func (n *Node) IsMarker() bool {
+	return n.marker
+}
+
+// Reports markers to hooks, the logger, the event log and metrics like any other node
+func WithMarkerHooks() ExecOption {
+	return func(c *ExecConfig) {
+		c.MarkerHooks = true
+	}
+}
+
+func (r *run) settleMarker(ctx context.Context, id NodeID) {
+	now := r.clock().Now()
+	nr := NodeReport{ID: id, Status: StatusSucceeded, Reason: ReasonMarker, Start: now, End: now}
+	*r.report.Nodes[id] = nr
+	r.cfg.State.record(nr, nil)
+	r.finished(nr)
+
+	r.release(ctx, id, true)
+}
+
+func (r *run) silent(id NodeID) bool {
+	return r.peg.nodes[id].marker && !r.cfg.MarkerHooks
+}
+
+// False for ids not in the graph, which WithLazyAdd allows dependencies on
+func (g *Graph) isMarker(id NodeID) bool {
+	node, ok := g.nodes[id]
+	return ok && node.marker
+}
+
+// The node's edges with the ones to markers taken out, and the non-marker nodes those markers
+// stood for, leaving out any the node also has a direct edge to
+func (g *Graph) collapseMarkers(node *Node, edges []declaredEdge) ([]declaredEdge, map[NodeID]collapsedEdge) {
+	direct := make(NodeIDs, len(edges))
+	kept := make([]declaredEdge, 0, len(edges))
+	collapsed := map[NodeID]collapsedEdge{}
+	seen := map[NodeID]bool{}
+	for _, e := range edges {
+		direct[e.To] = struct{}{}
+		if !g.isMarker(e.To) {
+			kept = append(kept, e)
+			continue
+		}
+		g.collapse(e.To, e.To, node.DependencyKind(e.declared) == EdgeHard, collapsed, seen)
+	}
+
+	for target := range collapsed {
+		if _, ok := direct[target]; ok {
+			delete(collapsed, target)
+		}
+	}
+	return kept, collapsed
+}
+
+type collapsedEdge struct {
+	// Every hop on some path was hard
+	hard bool
+	// The first marker on the path
+	via NodeID
+}
+
+// Adds the non-marker nodes reached from marker through markers only
+func (g *Graph) collapse(marker NodeID, via NodeID, hard bool, into map[NodeID]collapsedEdge, seen map[NodeID]bool) {
+	// Markers already walked with at least this strong a path add nothing new
+	if wasHard, ok := seen[marker]; ok && (wasHard || !hard) {
+		return
+	}
+	seen[marker] = hard
+
+	node := g.nodes[marker]
+	declared := g.declared(node)
+
+	for _, depId := range sortedIDs(declared) {
+		hopHard := hard && node.DependencyKind(declared[depId]) == EdgeHard
+		if g.isMarker(depId) {
+			g.collapse(depId, via, hopHard, into, seen)
+			continue
+		}
+
+		edge, ok := into[depId]
+		if !ok {
+			edge.via = via
+		}
+		edge.hard = edge.hard || hopHard
+		into[depId] = edge
+	}
+}
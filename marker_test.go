@@ -0,0 +1,265 @@
+package graph_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A fn a marker must never call
+func mustNotRun(t *testing.T) graph.NodeFn {
+	return func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		t.Errorf("Expected marker %s not to run", ec.ID)
+		return nil, nil
+	}
+}
+
+func TestMarkerLeftOutOfTotalWork(t *testing.T) {
+	clock := newFakeClock()
+	g := graph.NewGraph("phases")
+	g.Add(graph.NewNode("a", nil, takes(clock, 10*time.Second)))
+	g.Add(graph.NewNode("barrier", graph.Deps("a"), mustNotRun(t), graph.AsMarker()))
+	g.Add(graph.NewNode("b", graph.Deps("barrier"), takes(clock, 5*time.Second)))
+	hooks, finished := finishes()
+	done := runAsync(g.CompileToExecutable(), graph.WithClock(clock), hooks)
+
+	clock.waitFor(t, 1)
+	clock.Advance(10 * time.Second)
+	awaitFinish(t, finished, "a")
+	clock.waitFor(t, 1)
+	clock.Advance(5 * time.Second)
+
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	report := res.report
+
+	nr := report.Nodes["barrier"]
+	if nr.Status != graph.StatusSucceeded || nr.Reason != graph.ReasonMarker {
+		t.Errorf("Expected the marker to succeed with reason marker, got %s/%s", nr.Status, nr.Reason)
+	}
+	if len(nr.Attempts) != 0 {
+		t.Errorf("Expected no attempts for the marker, got %d", len(nr.Attempts))
+	}
+	if report.TotalWork != 15*time.Second {
+		t.Errorf("Expected 15s of work, got %s", report.TotalWork)
+	}
+	if want := []graph.NodeID{"a", "b"}; !reflect.DeepEqual(report.CriticalPath, want) {
+		t.Errorf("Expected the critical path to run through the marker as %v, got %v", want, report.CriticalPath)
+	}
+	if report.CriticalPathDuration != 15*time.Second {
+		t.Errorf("Expected a 15s critical path, got %s", report.CriticalPathDuration)
+	}
+}
+
+func TestMarkerSilentUnlessOptedIn(t *testing.T) {
+	build := func() *graph.Graph {
+		g := graph.NewGraph("phases")
+		g.Add(graph.NewNode("a", nil, graph.NoOp()))
+		g.Add(graph.NewNode("barrier", graph.Deps("a"), mustNotRun(t), graph.AsMarker()))
+		g.Add(graph.NewNode("b", graph.Deps("barrier"), graph.NoOp()))
+		return g
+	}
+	observe := func(opts ...graph.ExecOption) (hooked, logged bool) {
+		var mu sync.Mutex
+		seen := map[graph.NodeID]bool{}
+		opts = append(opts, graph.WithEventLog(100), graph.WithHooks(graph.Hooks{
+			OnNodeResult: func(id graph.NodeID, result graph.NodeReport) {
+				mu.Lock()
+				defer mu.Unlock()
+				seen[id] = true
+			},
+		}))
+
+		report, err := build().CompileToExecutable().Run(ctx(t), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range report.Events {
+			if e.Node == "barrier" {
+				logged = true
+			}
+		}
+		return seen["barrier"], logged
+	}
+
+	if hooked, logged := observe(); hooked || logged {
+		t.Errorf("Expected the marker to stay out of hooks and events, got hooked %t, logged %t", hooked, logged)
+	}
+	if hooked, logged := observe(graph.WithMarkerHooks()); !hooked || !logged {
+		t.Errorf("Expected WithMarkerHooks to report the marker, got hooked %t, logged %t", hooked, logged)
+	}
+}
+
+func TestMarkerStillOrders(t *testing.T) {
+	g := graph.NewGraph("phases")
+	var mu sync.Mutex
+	order := []graph.NodeID{}
+	record := func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, ec.ID)
+		return nil, nil
+	}
+	g.Add(graph.NewNode("a", nil, record))
+	g.Add(graph.NewNode("barrier", graph.Deps("a"), mustNotRun(t), graph.AsMarker()))
+	g.Add(graph.NewNode("b", graph.Deps("barrier"), record))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+	if want := []graph.NodeID{"a", "b"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Expected %v, got %v", want, order)
+	}
+}
+
+type hiddenEdge struct {
+	source, target, kind, via string
+}
+
+// The edges of a GraphML export and the ids of its nodes
+func graphMLEdges(t *testing.T, g *graph.Graph, opts ...graph.GraphMLOption) ([]hiddenEdge, map[string]bool) {
+	t.Helper()
+
+	var b bytes.Buffer
+	if err := g.WriteGraphML(&b, opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Graph struct {
+			Nodes []struct {
+				ID string `xml:"id,attr"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+				Data   []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := map[string]bool{}
+	for _, n := range doc.Graph.Nodes {
+		nodes[n.ID] = true
+	}
+	edges := []hiddenEdge{}
+	for _, e := range doc.Graph.Edges {
+		edge := hiddenEdge{source: e.Source, target: e.Target}
+		for _, d := range e.Data {
+			switch d.Key {
+			case "kind":
+				edge.kind = d.Value
+			case "via":
+				edge.via = d.Value
+			}
+		}
+		edges = append(edges, edge)
+	}
+	return edges, nodes
+}
+
+func TestGraphMLHideMarkersCollapsesEdges(t *testing.T) {
+	// build and lint meet at the first barrier, which the second barrier waits on; deploy waits on
+	// the second barrier and notify only softly
+	g := graph.NewGraph("release")
+	g.Add(graph.NewNode("build", nil, graph.NoOp()))
+	g.Add(graph.NewNode("lint", nil, graph.NoOp()))
+	g.Add(graph.NewNode("checked", graph.Deps("build", "lint"), graph.NoOp(), graph.AsMarker()))
+	g.Add(graph.NewNode("ready", graph.Deps("checked"), graph.NoOp(), graph.AsMarker()))
+	g.Add(graph.NewNode("deploy", graph.Deps("ready"), graph.NoOp()))
+	g.Add(graph.NewNode("notify", nil, graph.NoOp(), graph.WithSoftDependency("ready")))
+
+	edges, nodes := graphMLEdges(t, g, graph.HideMarkers())
+	if nodes["checked"] || nodes["ready"] {
+		t.Errorf("Expected the markers to be hidden, got nodes %v", nodes)
+	}
+	want := []hiddenEdge{
+		{source: "deploy", target: "build", kind: "hard", via: "ready"},
+		{source: "deploy", target: "lint", kind: "hard", via: "ready"},
+		{source: "notify", target: "build", kind: "soft", via: "ready"},
+		{source: "notify", target: "lint", kind: "soft", via: "ready"},
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Errorf("Expected the collapsed edges\n%v\ngot\n%v", want, edges)
+	}
+
+	// Without the option the markers are drawn with their own edges
+	edges, nodes = graphMLEdges(t, g)
+	if !nodes["checked"] || !nodes["ready"] {
+		t.Errorf("Expected the markers to be drawn, got nodes %v", nodes)
+	}
+	if len(edges) != 5 {
+		t.Errorf("Expected the 5 declared edges, got %v", edges)
+	}
+}
+
+func TestGraphMLHideMarkersKeepsDirectEdges(t *testing.T) {
+	// deploy depends on build both directly and through the marker; the direct edge wins
+	g := graph.NewGraph("release")
+	g.Add(graph.NewNode("build", nil, graph.NoOp()))
+	g.Add(graph.NewNode("ready", graph.Deps("build"), graph.NoOp(), graph.AsMarker()))
+	g.Add(graph.NewNode("deploy", graph.Deps("ready", "build"), graph.NoOp()))
+
+	edges, _ := graphMLEdges(t, g, graph.HideMarkers())
+	want := []hiddenEdge{{source: "deploy", target: "build", kind: "hard"}}
+	if !reflect.DeepEqual(edges, want) {
+		t.Errorf("Expected only the direct edge %v, got %v", want, edges)
+	}
+}
+
+func TestDOTHideMarkersCollapsesEdges(t *testing.T) {
+	g := graph.NewGraph("release")
+	g.Add(graph.NewNode("build", nil, graph.NoOp()))
+	g.Add(graph.NewNode("lint", nil, graph.NoOp()))
+	g.Add(graph.NewNode("checked", graph.Deps("build", "lint"), graph.NoOp(), graph.AsMarker()))
+	g.Add(graph.NewNode("ready", graph.Deps("checked"), graph.NoOp(), graph.AsMarker()))
+	g.Add(graph.NewNode("deploy", graph.Deps("ready", "lint"), graph.NoOp()))
+	g.Add(graph.NewNode("notify", nil, graph.NoOp(), graph.WithSoftDependency("ready")))
+	if err := g.Alias("release-ready", "ready"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `digraph "release" {
+  "build";
+  "deploy";
+  "lint";
+  "notify";
+  "deploy" -> "build" [color=gray, label="via ready"];
+  "deploy" -> "lint";
+  "notify" -> "build" [style=dashed, arrowhead=empty, color=gray, label="via ready"];
+  "notify" -> "lint" [style=dashed, arrowhead=empty, color=gray, label="via ready"];
+}
+`
+	if got := g.ToDOT(graph.HideMarkers()); got != want {
+		t.Errorf("Expected the collapsed edges\n%s\ngot\n%s", want, got)
+	}
+
+	mermaid := g.ToMermaid(graph.HideMarkers())
+	edges := []graph.Edge{{From: "deploy", To: "build"}, {From: "deploy", To: "lint"}, {From: "notify", To: "build"}, {From: "notify", To: "lint"}}
+	if got := mermaidEdges(t, mermaid); !reflect.DeepEqual(got, edges) {
+		t.Errorf("Expected the Mermaid edges %v, got %v", edges, got)
+	}
+	if !strings.Contains(mermaid, `"soft, via ready"`) || strings.Contains(mermaid, `"checked"`) || strings.Contains(mermaid, `"release-ready"`) {
+		t.Errorf("Expected the markers and their alias hidden but named on the edges, got\n%s", mermaid)
+	}
+
+	// Without the option the markers are drawn
+	if dot := g.ToDOT(); !strings.Contains(dot, `"ready" [shape=diamond]`) || !strings.Contains(dot, `"release-ready" -> "ready"`) {
+		t.Errorf("Expected the markers drawn, got\n%s", dot)
+	}
+}
@@ -0,0 +1,355 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var ErrNodeConflict = errors.New("Conflicting node definitions")
+
+type NodeConflictError struct {
+	ID NodeID
+	// How the incoming node differs from the existing one
+	Differences []string
+}
+
+func (e *NodeConflictError) Error() string {
+	if len(e.Differences) == 0 {
+		return fmt.Sprintf("%s: node %s is defined twice and the definitions aren't equivalent", ErrNodeConflict, e.ID)
+	}
+	return fmt.Sprintf("%s: node %s is defined twice: %s", ErrNodeConflict, e.ID, strings.Join(e.Differences, ", "))
+}
+
+func (e *NodeConflictError) Is(target error) bool {
+	return target == ErrNodeConflict
+}
+
+type MergeOption func(*mergeConfig)
+
+type mergeConfig struct {
+	equivalent func(a, b *Node) bool
+	// Nodes with different fns are never equivalent
+	compareFns bool
+	// Accept nodes from a graph in another namespace
+	renamespace bool
+}
+
+// Decides whether two nodes with the same id are one node defined twice. The default compares
+// dependencies, including optional and soft ones, selectors and metadata. The nodes may be the
+// graph's own and must only be read.
+func WithEquivalence(equivalent func(a, b *Node) bool) MergeOption {
+	return func(c *mergeConfig) {
+		c.equivalent = equivalent
+	}
+}
+
+// Also requires equivalent nodes to share a fn. Fns are compared by their code, so two closures
+// made by the same func literal count as the same fn whatever they captured.
+func CompareFns() MergeOption {
+	return func(c *mergeConfig) {
+		c.compareFns = true
+	}
+}
+
+func sameFn(a, b NodeFn) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+func newMergeConfig(opts []MergeOption) mergeConfig {
+	cfg := mergeConfig{equivalent: sameDefinition}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+func sameDefinition(a, b *Node) bool {
+	return len(nodeDiff(a, b)) == 0
+}
+
+// What's in b but not a and the other way around, for dependencies, selectors and metadata
+func nodeDiff(a, b *Node) []string {
+	diff := []string{}
+	for _, set := range []struct {
+		name string
+		a, b NodeIDs
+	}{
+		{"dependency", a.Dependencies, b.Dependencies},
+		{"soft dependency", a.softDependencies, b.softDependencies},
+		{"optional dependency", a.optionalDependencies, b.optionalDependencies},
+	} {
+		for _, id := range sortedIDs(set.b.Difference(set.a)) {
+			diff = append(diff, fmt.Sprintf("+%s %s", set.name, id))
+		}
+		for _, id := range sortedIDs(set.a.Difference(set.b)) {
+			diff = append(diff, fmt.Sprintf("-%s %s", set.name, id))
+		}
+	}
+
+	as, bs := selectorSet(a), selectorSet(b)
+	for _, s := range sortedKeys(bs) {
+		if _, ok := as[s]; !ok {
+			diff = append(diff, "+selector "+s)
+		}
+	}
+	for _, s := range sortedKeys(as) {
+		if _, ok := bs[s]; !ok {
+			diff = append(diff, "-selector "+s)
+		}
+	}
+
+	keys := map[string]struct{}{}
+	for key := range a.Metadata {
+		keys[key] = struct{}{}
+	}
+	for key := range b.Metadata {
+		keys[key] = struct{}{}
+	}
+	for _, key := range sortedKeys(keys) {
+		av, inA := a.Metadata[key]
+		bv, inB := b.Metadata[key]
+		switch {
+		case !inA:
+			diff = append(diff, fmt.Sprintf("+metadata %s=%q", key, bv))
+		case !inB:
+			diff = append(diff, fmt.Sprintf("-metadata %s=%q", key, av))
+		case av != bv:
+			diff = append(diff, fmt.Sprintf("metadata %s %q -> %q", key, av, bv))
+		}
+	}
+	return diff
+}
+
+func (g *Graph) conflict(existing, incoming *Node, cfg mergeConfig) error {
+	fnMatches := !cfg.compareFns || sameFn(existing.Fn, incoming.Fn)
+	if fnMatches && cfg.equivalent(existing, incoming) {
+		return nil
+	}
+
+	diff := nodeDiff(existing, incoming)
+	if !fnMatches {
+		diff = append(diff, "fn")
+	}
+	return &NodeConflictError{ID: incoming.Identifier(), Differences: diff}
+}
+
+// Like Add, except that adding a node equivalent to the one already there, as Merge decides, does
+// nothing and returns its id. A node that isn't equivalent fails with a NodeConflictError.
+func (g *Graph) AddIdempotent(node *Node, opts ...MergeOption) (NodeID, error) {
+	id, _, err := g.Ensure(node, opts...)
+	return id, err
+}
+
+// Ensure is AddIdempotent that also says whether node was added. The check and the add happen
+// under one lock, so of several callers ensuring the same node at once exactly one sees created.
+func (g *Graph) Ensure(node *Node, opts ...MergeOption) (id NodeID, created bool, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.frozen {
+		return "", false, ErrGraphFrozen
+	}
+
+	node = g.withDefaults(node)
+	if existing, ok := g.nodes[node.Identifier()]; ok {
+		if err := g.conflict(existing, node, newMergeConfig(opts)); err != nil {
+			return "", false, err
+		}
+		return existing.Identifier(), false, nil
+	}
+	id, err = g.add(node)
+	return id, err == nil, err
+}
+
+// Whether the graph has a node with id; aliases don't count
+func (g *Graph) Has(id NodeID) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	id = g.normalize(id)
+
+	_, ok := g.nodes[id]
+	return ok
+}
+
+// A copy of the node with id, so changing it leaves the graph alone
+func (g *Graph) Get(id NodeID) (*Node, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	id = g.normalize(id)
+
+	node, ok := g.nodes[id]
+	if !ok {
+		return nil, false
+	}
+	return node.clone(), true
+}
+
+// Adds the nodes as one change: their dependencies may be among them or already in g, and
+// nothing is added unless every node can be. Nodes already in g, or listed twice, are treated as
+// in Merge.
+func (g *Graph) AddAll(nodes []*Node, opts ...MergeOption) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.frozen {
+		return ErrGraphFrozen
+	}
+
+	cfg := newMergeConfig(opts)
+	batch := make(Nodes, len(nodes))
+	for _, node := range nodes {
+		node = g.withDefaults(node)
+		id := node.Identifier()
+		if first, ok := batch[id]; ok {
+			if err := g.conflict(first, node, cfg); err != nil {
+				return err
+			}
+			continue
+		}
+		batch[id] = node
+	}
+
+	return g.merge(batch, nil, cfg, !g.lazyAdd)
+}
+
+// Adds nodes and aliases to g, leaving out nodes equivalent to ones already there; the caller
+// holds the lock
+func (g *Graph) merge(nodes Nodes, aliases map[NodeID]NodeID, cfg mergeConfig, checkDeps bool) error {
+	incoming := make(Nodes, len(nodes))
+	for _, id := range sortedIDs(nodes) {
+		if existing, ok := g.nodes[id]; ok {
+			if err := g.conflict(existing, nodes[id], cfg); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := g.aliases[id]; ok {
+			return fmt.Errorf("Node %s collides with an alias of the same id", id)
+		}
+		incoming[id] = nodes[id]
+	}
+
+	for _, alias := range sortedIDs(aliases) {
+		if _, ok := g.nodes[alias]; ok {
+			return fmt.Errorf("Alias %s collides with a node of the same id", alias)
+		}
+		if existing, ok := g.aliases[alias]; ok && existing != aliases[alias] {
+			return fmt.Errorf("Alias %s already points to %s", alias, existing)
+		}
+	}
+
+	// Stage the incoming nodes and aliases in g itself rather than in a copy of the whole graph;
+	// nothing can see g while the caller holds the lock, and unstage takes them out again if they
+	// don't validate
+	g.own()
+	previous := g.aliases
+	if len(aliases) > 0 {
+		g.aliases = make(map[NodeID]NodeID, len(previous)+len(aliases))
+		for alias, target := range previous {
+			g.aliases[alias] = target
+		}
+		for alias, target := range aliases {
+			g.aliases[alias] = target
+		}
+	}
+	ids := sortedIDs(incoming)
+	unstage := func() {
+		for _, id := range ids {
+			delete(g.nodes, id)
+		}
+		g.aliases = previous
+	}
+
+	if err := g.stage(incoming, ids, checkDeps); err != nil {
+		unstage()
+		return err
+	}
+	if g.order != nil {
+		if err := g.orderMerge(ids); err != nil {
+			// Nodes placed before the failing one are still in the order
+			unstage()
+			g.rebuildOrder()
+			return err
+		}
+	}
+
+	g.changed()
+	return nil
+}
+
+// Placing a node in the order costs a pass over the graph, so larger batches are cheaper to
+// order from scratch
+const orderAddBatch = 16
+
+// Adds the incoming nodes to g and checks them, along with the optional edges of existing nodes
+// that now bind to them
+func (g *Graph) stage(incoming Nodes, ids SortedNodeIDs, checkDeps bool) error {
+	for _, id := range ids {
+		g.nodes[id] = incoming[id].clone()
+
+		if err := g.checkNodeLimit(id, len(g.nodes)); err != nil {
+			return err
+		}
+		if err := g.checkEdgeLimit(g.nodes[id], 0); err != nil {
+			return err
+		}
+	}
+
+	if checkDeps {
+		for _, id := range ids {
+			for depId := range g.nodes[id].Dependencies {
+				if !g.exists(depId) {
+					return fmt.Errorf("Node %s is missing dependency %s", id, depId)
+				}
+			}
+		}
+	}
+
+	if len(g.policies) == 0 {
+		return nil
+	}
+
+	checks := make(map[NodeID]NodeIDs, len(ids))
+	for id, node := range g.nodes {
+		deps := g.dependencies(node)
+		if _, ok := incoming[id]; ok {
+			checks[id] = deps
+			continue
+		}
+
+		crossing := NodeIDs{}
+		for depId := range deps {
+			if _, ok := incoming[depId]; ok {
+				crossing[depId] = struct{}{}
+			}
+		}
+		if len(crossing) > 0 {
+			checks[id] = crossing
+		}
+	}
+
+	errs := []error{}
+	for _, id := range sortedIDs(checks) {
+		if err := g.checkPolicies(g.nodes[id], checks[id], g.lookup); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Places the staged nodes in the order one at a time for a small batch, otherwise rebuilds it
+func (g *Graph) orderMerge(ids SortedNodeIDs) error {
+	if len(ids) > orderAddBatch {
+		return g.rebuildOrder()
+	}
+	for _, id := range ids {
+		if err := g.orderAdd(g.nodes[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,340 @@
+package graph_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A graph of a, z and b, with b depending on a and any of bDeps and tagged with team
+func teamGraph(t *testing.T, team string, bDeps ...graph.NodeID) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("team")
+	g.Add(graph.NewNode("a", nil, graph.NoOp()))
+	g.Add(graph.NewNode("z", nil, graph.NoOp()))
+	if _, err := g.Add(graph.NewNode("b", graph.Deps(append([]graph.NodeID{"a"}, bDeps...)...), graph.NoOp(), graph.WithMetadata("team", team))); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func expectConflict(t *testing.T, err error, id graph.NodeID, differences ...string) {
+	t.Helper()
+
+	var cerr *graph.NodeConflictError
+	if !errors.As(err, &cerr) || !errors.Is(err, graph.ErrNodeConflict) {
+		t.Fatalf("Expected a NodeConflictError, got %v", err)
+	}
+	if cerr.ID != id {
+		t.Errorf("Expected the conflict on %s, got %s", id, cerr.ID)
+	}
+	if !reflect.DeepEqual(append([]string{}, cerr.Differences...), append([]string{}, differences...)) {
+		t.Errorf("Expected differences %q, got %q", differences, cerr.Differences)
+	}
+	for _, d := range differences {
+		if !strings.Contains(err.Error(), d) {
+			t.Errorf("Expected the message to show %s, got %v", d, err)
+		}
+	}
+}
+
+func TestMergeIdenticalDuplicates(t *testing.T) {
+	g := teamGraph(t, "data")
+	other := teamGraph(t, "data")
+	other.Add(graph.NewNode("c", graph.Deps("b"), graph.NoOp()))
+
+	if err := g.Merge(other); err != nil {
+		t.Fatalf("Expected identical duplicates to merge, got %v", err)
+	}
+	ids := mustSort(t, g)
+	if len(ids) != 4 || !before(ids, "b", "c") {
+		t.Errorf("Expected a, b and z once each plus c after b, got %v", ids)
+	}
+}
+
+func TestMergeConflictingDependency(t *testing.T) {
+	g := teamGraph(t, "data")
+	other := teamGraph(t, "data", "z")
+	other.Add(graph.NewNode("c", nil, graph.NoOp()))
+
+	expectConflict(t, g.Merge(other), "b", "+dependency z")
+
+	// Nothing from other was added
+	if g.Has("c") {
+		t.Error("Expected a failed merge to add nothing")
+	}
+	if deps, _ := g.ResolvedDependencies("b"); len(deps) != 1 {
+		t.Errorf("Expected b to keep its dependencies, got %v", deps)
+	}
+}
+
+func TestMergeConflictingMetadata(t *testing.T) {
+	g := teamGraph(t, "data")
+	other := teamGraph(t, "ml")
+	expectConflict(t, g.Merge(other), "b", `metadata team "data" -> "ml"`)
+}
+
+func TestMergeMissingAndExtraDependencies(t *testing.T) {
+	g := teamGraph(t, "data", "z")
+	other := graph.NewGraph("other")
+	other.Add(graph.NewNode("a", nil, graph.NoOp()))
+	other.Add(graph.NewNode("b", graph.Deps("a"), graph.NoOp(), graph.WithSoftDependency("a"), graph.WithMetadata("team", "data"), graph.WithMetadata("tier", "1")))
+
+	expectConflict(t, g.Merge(other), "b", "-dependency z", "+soft dependency a", `+metadata tier="1"`)
+}
+
+func TestMergeWithEquivalence(t *testing.T) {
+	// Same id is same node, whatever else differs
+	sameID := graph.WithEquivalence(func(a, b *graph.Node) bool { return true })
+
+	g := teamGraph(t, "data")
+	if err := g.Merge(teamGraph(t, "ml", "z"), sameID); err != nil {
+		t.Fatalf("Expected the custom equivalence to accept b, got %v", err)
+	}
+	if got := mustGet(t, g, "b").Metadata["team"]; got != "data" {
+		t.Errorf("Expected g's own b kept, got team %s", got)
+	}
+
+	never := graph.WithEquivalence(func(a, b *graph.Node) bool { return false })
+	err := teamGraph(t, "data").Merge(teamGraph(t, "data"), never)
+	// The nodes don't differ in anything the diff looks at
+	expectConflict(t, err, "a")
+	if !strings.Contains(err.Error(), "aren't equivalent") {
+		t.Errorf("Expected a message for a conflict with no listed differences, got %v", err)
+	}
+}
+
+func mustGet(t *testing.T, g *graph.Graph, id graph.NodeID) *graph.Node {
+	t.Helper()
+
+	node, ok := g.Get(id)
+	if !ok {
+		t.Fatalf("Expected node %s", id)
+	}
+	return node
+}
+
+func TestAddAll(t *testing.T) {
+	g := teamGraph(t, "data")
+	err := g.AddAll([]*graph.Node{
+		graph.NewNode("d", graph.Deps("c"), graph.NoOp()),
+		graph.NewNode("c", graph.Deps("b"), graph.NoOp()),
+		// Already in g, and listed twice
+		graph.NewNode("a", nil, graph.NoOp()),
+		graph.NewNode("d", graph.Deps("c"), graph.NoOp()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := mustSort(t, g); len(ids) != 5 || !before(ids, "c", "d") {
+		t.Errorf("Expected c and d added in order, got %v", ids)
+	}
+}
+
+func TestAddAllIsAtomic(t *testing.T) {
+	g := teamGraph(t, "data")
+
+	err := g.AddAll([]*graph.Node{
+		graph.NewNode("c", graph.Deps("b"), graph.NoOp()),
+		graph.NewNode("d", graph.Deps("missing"), graph.NoOp()),
+	})
+	if err == nil {
+		t.Fatal("Expected a missing dependency to fail the batch")
+	}
+	if g.Has("c") || g.Has("d") {
+		t.Error("Expected nothing from the failed batch added")
+	}
+
+	err = g.AddAll([]*graph.Node{
+		graph.NewNode("c", nil, graph.NoOp()),
+		graph.NewNode("c", graph.Deps("a"), graph.NoOp()),
+	})
+	expectConflict(t, err, "c", "+dependency a")
+	if g.Has("c") {
+		t.Error("Expected a conflict within the batch to add nothing")
+	}
+}
+
+func TestMergeCycleLeavesGraphAlone(t *testing.T) {
+	g := diamond(t, graph.WithIncrementalCycleCheck())
+	other := graph.NewGraph("other", graph.WithLazyAdd())
+	other.Add(graph.NewNode("e", graph.Deps("d"), graph.NoOp()))
+	other.Add(graph.NewNode("f", graph.Deps("e"), graph.NoOp()))
+	if err := g.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+
+	cyclic := graph.NewGraph("cyclic", graph.WithLazyAdd())
+	cyclic.Add(graph.NewNode("x", graph.Deps("y", "f"), graph.NoOp()))
+	cyclic.Add(graph.NewNode("y", graph.Deps("x"), graph.NoOp()))
+	if err := g.Merge(cyclic); !errors.Is(err, graph.ErrCycle) {
+		t.Fatalf("Expected the merge to be rejected as a cycle, got %v", err)
+	}
+	if g.Has("x") || g.Has("y") {
+		t.Error("Expected the rejected nodes left out")
+	}
+	if ids := mustSort(t, g); len(ids) != 6 {
+		t.Errorf("Expected the six nodes from before, got %v", ids)
+	}
+}
+
+func TestAddIdempotentNoOp(t *testing.T) {
+	g := teamGraph(t, "data")
+	g.Add(graph.NewNode("first", nil, returns("first")))
+	fingerprint := g.Fingerprint()
+
+	id, err := g.AddIdempotent(graph.NewNode("b", graph.Deps("a"), graph.NoOp(), graph.WithMetadata("team", "data")))
+	if err != nil || id != "b" {
+		t.Fatalf("Expected re-adding an identical b to succeed, got %q, %v", id, err)
+	}
+	g.AddIdempotent(graph.NewNode("first", nil, returns("second")))
+	if value, _ := mustGet(t, g, "first").Fn(ctx(t), &graph.ExecutionContext{}); value != "first" {
+		t.Errorf("Expected the node already in the graph kept, got one returning %v", value)
+	}
+	if g.Fingerprint() != fingerprint {
+		t.Error("Expected the no-op adds to leave the graph as it was")
+	}
+
+	if _, err := g.AddIdempotent(graph.NewNode("c", graph.Deps("b"), graph.NoOp())); err != nil || !g.Has("c") {
+		t.Errorf("Expected a new node added as by Add, got %v", err)
+	}
+
+	// Plain Add still refuses any duplicate
+	if _, err := g.Add(graph.NewNode("a", nil, graph.NoOp())); err == nil {
+		t.Error("Expected Add to reject a duplicate")
+	}
+}
+
+func TestAddIdempotentMismatch(t *testing.T) {
+	g := teamGraph(t, "data")
+	for _, tc := range []struct {
+		name string
+		node *graph.Node
+		diff []string
+	}{
+		{"dependency", graph.NewNode("b", graph.Deps("a", "z"), graph.NoOp(), graph.WithMetadata("team", "data")), []string{"+dependency z"}},
+		{"metadata", graph.NewNode("b", graph.Deps("a"), graph.NoOp(), graph.WithMetadata("team", "ml")), []string{`metadata team "data" -> "ml"`}},
+		{"soft", graph.NewNode("b", nil, graph.NoOp(), graph.WithMetadata("team", "data"), graph.WithSoftDependency("a")), []string{"+soft dependency a"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := g.AddIdempotent(tc.node)
+			expectConflict(t, err, "b", tc.diff...)
+		})
+	}
+	if deps := mustGet(t, g, "b").Dependencies; !deps.Equal(graph.Deps("a")) {
+		t.Errorf("Expected b left as it was, got %v", deps)
+	}
+}
+
+func TestAddIdempotentCompareFns(t *testing.T) {
+	g := graph.NewGraph("fns")
+	g.Add(graph.NewNode("a", nil, returns(1)))
+
+	// Two closures from the same literal share their code, whatever they captured
+	if _, err := g.AddIdempotent(graph.NewNode("a", nil, returns(2)), graph.CompareFns()); err != nil {
+		t.Errorf("Expected closures of the same func to match, got %v", err)
+	}
+	if _, err := g.AddIdempotent(graph.NewNode("a", nil, graph.NoOp())); err != nil {
+		t.Errorf("Expected fns ignored without CompareFns, got %v", err)
+	}
+	_, err := g.AddIdempotent(graph.NewNode("a", nil, graph.NoOp()), graph.CompareFns())
+	expectConflict(t, err, "a", "fn")
+}
+
+func TestAddIdempotentAppliesDefaults(t *testing.T) {
+	g := graph.NewGraph("defaults", graph.WithDefaultNodeOptions(graph.WithMetadata("team", "data")))
+	g.Add(graph.NewNode("a", nil, graph.NoOp()))
+
+	// Both copies get the default before they're compared
+	if _, err := g.AddIdempotent(graph.NewNode("a", nil, graph.NoOp())); err != nil {
+		t.Errorf("Expected the defaults applied before comparing, got %v", err)
+	}
+}
+
+// AddAll already treats nodes equivalent to ones in the graph as no-ops, and takes the same
+// options
+func TestAddIdempotentAndAddAll(t *testing.T) {
+	g := graph.NewGraph("batch")
+	g.AddIdempotent(graph.NewNode("a", nil, returns(1)))
+
+	if err := g.AddAll([]*graph.Node{graph.NewNode("a", nil, returns(1)), graph.NewNode("b", graph.Deps("a"), graph.NoOp())}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.AddIdempotent(graph.NewNode("b", graph.Deps("a"), graph.NoOp())); err != nil {
+		t.Errorf("Expected a node from AddAll to be re-added as a no-op, got %v", err)
+	}
+
+	err := g.AddAll([]*graph.Node{graph.NewNode("a", nil, graph.NoOp()), graph.NewNode("c", nil, graph.NoOp())}, graph.CompareFns())
+	expectConflict(t, err, "a", "fn")
+	if g.Has("c") {
+		t.Error("Expected the batch with a mismatched duplicate to add nothing")
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	g := graph.NewGraph("loader")
+	g.Add(graph.NewNode("a", nil, graph.NoOp()))
+
+	id, created, err := g.Ensure(graph.NewNode("b", graph.Deps("a"), graph.NoOp()))
+	if err != nil || id != "b" || !created {
+		t.Fatalf("Expected b created, got %s, %t, %v", id, created, err)
+	}
+	id, created, err = g.Ensure(graph.NewNode("b", graph.Deps("a"), graph.NoOp()))
+	if err != nil || id != "b" || created {
+		t.Errorf("Expected the existing b returned, got %s, %t, %v", id, created, err)
+	}
+
+	_, created, err = g.Ensure(graph.NewNode("b", nil, graph.NoOp()))
+	if created {
+		t.Error("Expected a mismatch not to count as created")
+	}
+	expectConflict(t, err, "b", "-dependency a")
+
+	g.Freeze()
+	if _, _, err := g.Ensure(graph.NewNode("c", nil, graph.NoOp())); !errors.Is(err, graph.ErrGraphFrozen) {
+		t.Errorf("Expected a frozen graph to refuse, got %v", err)
+	}
+}
+
+func TestEnsureConcurrent(t *testing.T) {
+	g := graph.NewGraph("loader")
+	g.Add(graph.NewNode("root", nil, graph.NoOp()))
+
+	const loaders = 32
+	var created atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < loaders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Every loader ensures the shared node and one of its own
+			for _, node := range []*graph.Node{
+				graph.NewNode("shared", graph.Deps("root"), graph.NoOp(), graph.WithMetadata("team", "data")),
+				graph.NewNode(fmt.Sprintf("own-%d", i), graph.Deps("shared"), graph.NoOp()),
+			} {
+				_, ok, err := g.Ensure(node)
+				if err != nil {
+					t.Error(err)
+				}
+				if ok {
+					created.Add(1)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := created.Load(); n != loaders+1 {
+		t.Errorf("Expected the shared node created once and each own node once, got %d creations", n)
+	}
+	if n := g.EdgeCount(); n != loaders+1 {
+		t.Errorf("Expected %d edges, got %d", loaders+1, n)
+	}
+}
@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const unknownLabel = "unknown"
+
+// Receives one observation per node per run, from the goroutine that finished the node, so
+// implementations must be safe for concurrent use. attempts is how many times the fn was
+// called, the last attempt's number; zero for nodes that never ran.
+type MetricsSink interface {
+	ObserveNode(id NodeID, labels map[string]string, status NodeStatus, attempts int, duration time.Duration)
+}
+
+// Sends node observations to sink, labeled with the node metadata under labelKeys. Nodes
+// without one of the keys get the value "unknown", so label sets stay bounded. Namespaced
+// graphs add their namespace under NamespaceLabel.
+func WithMetrics(sink MetricsSink, labelKeys ...string) ExecOption {
+	return func(c *ExecConfig) {
+		c.Metrics = sink
+		c.MetricLabels = labelKeys
+	}
+}
+
+func (r *run) observe(nr NodeReport) {
+	if r.cfg.Metrics == nil {
+		return
+	}
+
+	var metadata map[string]string
+	if node, ok := r.peg.nodes[nr.ID]; ok {
+		metadata = node.metadata
+	}
+
+	labels := make(map[string]string, len(r.cfg.MetricLabels))
+	for _, key := range r.cfg.MetricLabels {
+		value, ok := metadata[key]
+		if !ok {
+			value = unknownLabel
+		}
+		labels[key] = value
+	}
+	if r.peg.namespace != "" {
+		labels[NamespaceLabel] = r.peg.namespace
+	}
+
+	r.cfg.Metrics.ObserveNode(nr.ID, labels, nr.Status, len(nr.Attempts), nr.Duration())
+}
+
+// Totals for one combination of label values
+type LabelStats struct {
+	Labels   map[string]string
+	Count    int
+	Statuses map[NodeStatus]int
+	// Summed over the nodes, so Attempts less Count is the number of retries
+	Attempts int
+	Duration time.Duration
+}
+
+// A MetricsSink that totals observations by label combination instead of by node
+type AggregatingSink struct {
+	mu    sync.Mutex
+	stats map[string]*LabelStats
+}
+
+func NewAggregatingSink() *AggregatingSink {
+	return &AggregatingSink{stats: make(map[string]*LabelStats)}
+}
+
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Quoted so separators inside keys and values can't make two label sets collide
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = strconv.Quote(key) + "=" + strconv.Quote(labels[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (s *AggregatingSink) ObserveNode(id NodeID, labels map[string]string, status NodeStatus, attempts int, duration time.Duration) {
+	key := labelKey(labels)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.stats[key]
+	if !ok {
+		copied := make(map[string]string, len(labels))
+		for k, v := range labels {
+			copied[k] = v
+		}
+		stats = &LabelStats{Labels: copied, Statuses: make(map[NodeStatus]int)}
+		s.stats[key] = stats
+	}
+
+	stats.Count++
+	stats.Statuses[status]++
+	stats.Attempts += attempts
+	stats.Duration += duration
+}
+
+// A copy of the totals, sorted by label values
+func (s *AggregatingSink) Stats() []LabelStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.stats))
+	for key := range s.stats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make([]LabelStats, 0, len(keys))
+	for _, key := range keys {
+		stats := *s.stats[key]
+		stats.Statuses = make(map[NodeStatus]int, len(s.stats[key].Statuses))
+		for status, count := range s.stats[key].Statuses {
+			stats.Statuses[status] = count
+		}
+		out = append(out, stats)
+	}
+	return out
+}
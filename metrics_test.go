@@ -0,0 +1,136 @@
+package graph_test
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A MetricsSink keeping every observation's labels and attempts by node
+type labelSink struct {
+	mu       sync.Mutex
+	labels   map[graph.NodeID]map[string]string
+	attempts map[graph.NodeID]int
+}
+
+func (s *labelSink) ObserveNode(id graph.NodeID, labels map[string]string, status graph.NodeStatus, attempts int, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.labels[id]; ok {
+		panic("observed twice: " + string(id))
+	}
+	s.labels[id] = labels
+	if s.attempts != nil {
+		s.attempts[id] = attempts
+	}
+}
+
+// Two nodes owned by data, one failing, one by web and one with no team
+func teams() *graph.Graph {
+	g := graph.NewGraph("teams")
+	g.Add(graph.NewNode("ingest", nil, graph.NoOp(), graph.WithMetadata("team", "data"), graph.WithMetadata("tier", "1")))
+	g.Add(graph.NewNode("clean", graph.Deps("ingest"), failWith(errors.New("boom")), graph.WithMetadata("team", "data"), graph.WithMetadata("tier", "2")))
+	g.Add(graph.NewNode("site", nil, graph.NoOp(), graph.WithMetadata("team", "web")))
+	g.Add(graph.NewNode("misc", nil, graph.NoOp()))
+	return g
+}
+
+func TestAggregatingSinkByTeam(t *testing.T) {
+	sink := graph.NewAggregatingSink()
+	teams().CompileToExecutable().Run(ctx(t), graph.WithMetrics(sink, "team"))
+
+	stats := sink.Stats()
+	if len(stats) != 3 {
+		t.Fatalf("Expected one entry per team, got %v", stats)
+	}
+
+	// Sorted by label values
+	want := []struct {
+		team     string
+		count    int
+		statuses map[graph.NodeStatus]int
+	}{
+		{"data", 2, map[graph.NodeStatus]int{graph.StatusSucceeded: 1, graph.StatusFailed: 1}},
+		{"unknown", 1, map[graph.NodeStatus]int{graph.StatusSucceeded: 1}},
+		{"web", 1, map[graph.NodeStatus]int{graph.StatusSucceeded: 1}},
+	}
+	for i, w := range want {
+		got := stats[i]
+		if got.Labels["team"] != w.team || got.Count != w.count || !reflect.DeepEqual(got.Statuses, w.statuses) {
+			t.Errorf("Expected %s with %d nodes %v, got %v", w.team, w.count, w.statuses, got)
+		}
+	}
+}
+
+func TestMetricsLabelsPerNode(t *testing.T) {
+	sink := &labelSink{labels: map[graph.NodeID]map[string]string{}}
+	teams().CompileToExecutable().Run(ctx(t), graph.WithMetrics(sink, "team", "tier"))
+
+	want := map[graph.NodeID]map[string]string{
+		"ingest": {"team": "data", "tier": "1"},
+		"clean":  {"team": "data", "tier": "2"},
+		"site":   {"team": "web", "tier": "unknown"},
+		"misc":   {"team": "unknown", "tier": "unknown"},
+	}
+	if !reflect.DeepEqual(sink.labels, want) {
+		t.Errorf("Expected labels %v, got %v", want, sink.labels)
+	}
+}
+
+func TestAggregatingSinkStatsAreCopies(t *testing.T) {
+	sink := graph.NewAggregatingSink()
+	teams().CompileToExecutable().Run(ctx(t), graph.WithMetrics(sink, "team"))
+
+	stats := sink.Stats()
+	stats[0].Statuses[graph.StatusFailed] = 100
+	if sink.Stats()[0].Statuses[graph.StatusFailed] != 1 {
+		t.Error("Expected changing the returned stats to leave the sink alone")
+	}
+}
+
+// flaky fails twice before it succeeds, steady runs once and the marker never runs
+func retried() *graph.Graph {
+	g := graph.NewGraph("retries")
+	g.Add(graph.NewNode("flaky", nil, failTimes(2), graph.WithRetry(graph.RetryPolicy{MaxAttempts: 3}), graph.WithMetadata("team", "data")))
+	g.Add(graph.NewNode("steady", nil, graph.NoOp(), graph.WithMetadata("team", "data")))
+	g.Add(graph.NewNode("gate", graph.Deps("flaky"), graph.NoOp(), graph.AsMarker(), graph.WithMetadata("team", "data")))
+	return g
+}
+
+func TestMetricsAttempts(t *testing.T) {
+	sink := &labelSink{labels: map[graph.NodeID]map[string]string{}, attempts: map[graph.NodeID]int{}}
+	if _, err := retried().CompileToExecutable().Run(ctx(t), graph.WithMetrics(sink, "team"), graph.WithMarkerHooks()); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[graph.NodeID]int{"flaky": 3, "steady": 1, "gate": 0}; !reflect.DeepEqual(sink.attempts, want) {
+		t.Errorf("Expected attempts %v, got %v", want, sink.attempts)
+	}
+
+	aggregated := graph.NewAggregatingSink()
+	retried().CompileToExecutable().Run(ctx(t), graph.WithMetrics(aggregated, "team"), graph.WithMarkerHooks())
+	if stats := aggregated.Stats(); len(stats) != 1 || stats[0].Count != 3 || stats[0].Attempts != 4 {
+		t.Errorf("Expected the team's attempts totaled, got %+v", stats)
+	}
+}
+
+func TestAggregatingSinkLabelsCantCollide(t *testing.T) {
+	sink := graph.NewAggregatingSink()
+	sink.ObserveNode("a", map[string]string{"a": "x,b=y"}, graph.StatusSucceeded, 1, time.Second)
+	sink.ObserveNode("b", map[string]string{"a": "x", "b": "y"}, graph.StatusSucceeded, 1, time.Second)
+	sink.ObserveNode("c", map[string]string{"a": `x", "b": "y`}, graph.StatusSucceeded, 1, time.Second)
+
+	stats := sink.Stats()
+	if len(stats) != 3 {
+		t.Fatalf("Expected three label sets, got %v", stats)
+	}
+	for _, s := range stats {
+		if s.Count != 1 {
+			t.Errorf("Expected each label set kept apart, got %v", s)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package graph_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Whole groups of four merged at once: a reader that sees part of a group saw a half-applied merge
+func checkGroups(t *testing.T, ids []graph.NodeID) {
+	t.Helper()
+
+	at := map[graph.NodeID]int{}
+	for i, id := range ids {
+		at[id] = i
+	}
+	for _, id := range ids {
+		var w, i, j int
+		if _, err := fmt.Sscanf(string(id), "m%d-%d-%d", &w, &i, &j); err != nil {
+			continue
+		}
+		for k := 0; k < 4; k++ {
+			if _, ok := at[graph.NodeID(fmt.Sprintf("m%d-%d-%d", w, i, k))]; !ok {
+				t.Errorf("Saw %s without the rest of its merge", id)
+				return
+			}
+		}
+		if j > 0 && at[graph.NodeID(fmt.Sprintf("m%d-%d-%d", w, i, j-1))] > at[id] {
+			t.Errorf("Saw %s before its dependency", id)
+		}
+	}
+}
+
+func group(w, i int) *graph.Graph {
+	g := graph.NewGraph("group")
+	for j := 0; j < 4; j++ {
+		var deps graph.NodeIDs
+		if j > 0 {
+			deps = graph.Deps(graph.NodeID(fmt.Sprintf("m%d-%d-%d", w, i, j-1)))
+		}
+		g.Add(graph.NewNode(fmt.Sprintf("m%d-%d-%d", w, i, j), deps, graph.NoOp()))
+	}
+	return g
+}
+
+func TestConcurrentMutationWithSortAndCompile(t *testing.T) {
+	g := diamond(t)
+
+	const writers, rounds = 4, 25
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				if err := g.Merge(group(w, i)); err != nil {
+					t.Error(err)
+					return
+				}
+				id := fmt.Sprintf("tmp%d-%d", w, i)
+				if _, err := g.Add(graph.NewNode(id, graph.Deps("d"), graph.NoOp())); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := g.AddEdge(graph.NodeID(id), "a"); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := g.Remove(graph.NodeID(id)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+
+	// Each reader makes a fixed number of passes while the writers run
+	read := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 30; i++ {
+				f()
+			}
+		}()
+	}
+	read(func() {
+		ids, err := g.Sort()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		checkGroups(t, ids)
+	})
+	read(func() {
+		report, err := g.CompileToExecutable().Run(ctx(t))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		ids := []graph.NodeID{}
+		for id := range report.Nodes {
+			ids = append(ids, id)
+		}
+		for _, id := range ids {
+			if strings.HasPrefix(string(id), "m") && report.Nodes[id].Status != graph.StatusSucceeded {
+				t.Errorf("Expected %s to succeed, got %s", id, report.Nodes[id].Status)
+			}
+		}
+	})
+	read(func() {
+		var b bytes.Buffer
+		if err := g.WriteGraphML(&b); err != nil {
+			t.Error(err)
+		}
+		_ = g.String()
+	})
+
+	wg.Wait()
+
+	ids := mustSort(t, g)
+	checkGroups(t, ids)
+	if want := 4 + writers*rounds*4; len(ids) != want {
+		t.Errorf("Expected %d nodes, got %d", want, len(ids))
+	}
+}
+
+func TestStaleAfterEdgeChange(t *testing.T) {
+	g := diamond(t)
+	g.Add(graph.NewNode("e", graph.Deps("a"), graph.NoOp()))
+	peg := g.CompileToExecutable()
+	if peg.Stale(g) {
+		t.Fatal("Expected a fresh compile not to be stale")
+	}
+	compiled := peg.ToDOT()
+
+	// Reads don't count
+	mustSort(t, g)
+	_ = g.String()
+	if peg.Stale(g) {
+		t.Error("Expected reads to leave the compile fresh")
+	}
+
+	if err := g.AddEdge("e", "d"); err != nil {
+		t.Fatal(err)
+	}
+	if !peg.Stale(g) {
+		t.Error("Expected an edge change to make the compile stale")
+	}
+	if got := peg.ToDOT(); got != compiled {
+		t.Errorf("Expected the compiled graph to keep its edges until recompiled, got\n%s", got)
+	}
+
+	if err := peg.Recompile(g, graph.Diff{Changed: graph.Deps("e")}); err != nil {
+		t.Fatal(err)
+	}
+	if peg.Stale(g) {
+		t.Error("Expected Recompile to catch the compile up")
+	}
+	if peg.ToDOT() == compiled {
+		t.Error("Expected Recompile to pick up the new edge")
+	}
+
+	if !peg.Stale(diamond(t)) {
+		t.Error("Expected a compile to be stale against a different graph")
+	}
+}
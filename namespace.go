@@ -0,0 +1,53 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNamespaceMismatch = errors.New("Graphs are in different namespaces")
+
+// The metric label a namespaced graph's observations carry its namespace under
+const NamespaceLabel = "namespace"
+
+// Puts the graph in a tenant's namespace. Node ids stay as they are; the namespace prefixes the
+// RunIDs and single-flight keys of its compiled graphs and labels their metrics under
+// NamespaceLabel. Merge refuses graphs from other namespaces unless given Renamespace.
+func WithNamespace(ns string) GraphOption {
+	return func(g *Graph) {
+		g.namespace = ns
+	}
+}
+
+func (g *Graph) Namespace() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.namespace
+}
+
+func (peg *ParallelizedExecutableGraph) Namespace() string {
+	return peg.namespace
+}
+
+// Lets Merge take in a graph from another namespace; its nodes join this graph's namespace
+func Renamespace() MergeOption {
+	return func(c *mergeConfig) {
+		c.renamespace = true
+	}
+}
+
+func (g *Graph) checkNamespace(other *Graph, cfg mergeConfig) error {
+	if cfg.renamespace || g.namespace == other.namespace {
+		return nil
+	}
+	return fmt.Errorf("%w: %q and %q", ErrNamespaceMismatch, g.namespace, other.namespace)
+}
+
+// key qualified by ns, or key itself outside any namespace
+func namespaced(ns, key string) string {
+	if ns == "" {
+		return key
+	}
+	return ns + "/" + key
+}
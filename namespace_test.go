@@ -0,0 +1,110 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// gatedGraph's graph in tenant ns
+func tenant(ns string, calls *atomic.Int32, started chan<- struct{}, release <-chan struct{}) *graph.ParallelizedExecutableGraph {
+	g := graph.NewGraph("gated", graph.WithNamespace(ns))
+	g.Add(graph.NewNode("work", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		calls.Add(1)
+		started <- struct{}{}
+		<-release
+		return nil, nil
+	}))
+	return g.CompileToExecutable()
+}
+
+func TestNamespacesKeepSingleFlightKeysApart(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}, 2), make(chan struct{})
+
+	a := runAsync(tenant("acme", &calls, started, release), graph.WithSingleFlight("deploy"))
+	b := runAsync(tenant("globex", &calls, started, release), graph.WithSingleFlight("deploy"))
+
+	// Both tenants' runs get going at once; neither waits on the other's key
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected both tenants to run concurrently under the same key")
+		}
+	}
+	close(release)
+
+	ra, rb := <-a, <-b
+	if ra.err != nil || rb.err != nil {
+		t.Fatalf("Expected both runs to succeed, got %v and %v", ra.err, rb.err)
+	}
+	if ra.report == rb.report || calls.Load() != 2 {
+		t.Errorf("Expected two separate runs, got %d calls", calls.Load())
+	}
+}
+
+func TestNamespaceKeepsNodeIDs(t *testing.T) {
+	g := graph.NewGraph("etl", graph.WithNamespace("acme"))
+	g.Add(graph.NewNode("extract", nil, graph.NoOp()))
+	g.Add(graph.NewNode("load", graph.Deps("extract"), graph.NoOp()))
+
+	if ids := mustSort(t, g); !reflect.DeepEqual(ids, graph.SortedNodeIDs{"extract", "load"}) {
+		t.Errorf("Expected the ids as given, got %v", ids)
+	}
+
+	peg := g.CompileToExecutable()
+	if peg.Namespace() != "acme" || g.Namespace() != "acme" {
+		t.Errorf("Expected the namespace on both graphs, got %q and %q", g.Namespace(), peg.Namespace())
+	}
+	report, err := peg.Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(report.RunID, "acme/") {
+		t.Errorf("Expected the RunID prefixed with the namespace, got %s", report.RunID)
+	}
+	if _, ok := report.Nodes["load"]; !ok {
+		t.Errorf("Expected the report keyed by the plain ids, got %v", report.Nodes)
+	}
+}
+
+func TestNamespaceMetricLabel(t *testing.T) {
+	g := graph.NewGraph("etl", graph.WithNamespace("acme"))
+	g.Add(graph.NewNode("extract", nil, graph.NoOp(), graph.WithMetadata("team", "data")))
+
+	sink := &labelSink{labels: map[graph.NodeID]map[string]string{}}
+	g.CompileToExecutable().Run(ctx(t), graph.WithMetrics(sink, "team"))
+
+	want := map[string]string{"team": "data", graph.NamespaceLabel: "acme"}
+	if got := sink.labels["extract"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected labels %v, got %v", want, got)
+	}
+}
+
+func TestMergeAcrossNamespaces(t *testing.T) {
+	acme := graph.NewGraph("etl", graph.WithNamespace("acme"))
+	acme.Add(graph.NewNode("extract", nil, graph.NoOp()))
+	globex := graph.NewGraph("etl", graph.WithNamespace("globex"))
+	globex.Add(graph.NewNode("load", nil, graph.NoOp()))
+
+	if err := acme.Merge(globex); !errors.Is(err, graph.ErrNamespaceMismatch) {
+		t.Fatalf("Expected ErrNamespaceMismatch, got %v", err)
+	}
+	if acme.Has("load") {
+		t.Error("Expected the rejected merge to add nothing")
+	}
+
+	if err := acme.Merge(globex, graph.Renamespace()); err != nil {
+		t.Fatal(err)
+	}
+	if !acme.Has("load") || acme.Namespace() != "acme" {
+		t.Errorf("Expected load to join acme, got namespace %q", acme.Namespace())
+	}
+}
@@ -0,0 +1,71 @@
+package graph_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func runWithin(t *testing.T, limit time.Duration, peg *graph.ParallelizedExecutableGraph) (*graph.Report, error) {
+	t.Helper()
+
+	done := make(chan runResult, 1)
+	go func() {
+		report, err := peg.Run(ctx(t))
+		done <- runResult{report, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.report, r.err
+	case <-time.After(limit):
+		t.Fatalf("Expected the run to end within %s", limit)
+		return nil, nil
+	}
+}
+
+func TestNoProgressWrongRequiredCount(t *testing.T) {
+	peg := diamond(t).CompileToExecutable()
+	// d only has two dependencies to wait for
+	peg.SetRequired("d", 3)
+
+	report, err := runWithin(t, 5*time.Second, peg)
+	if !errors.Is(err, graph.ErrNoProgress) {
+		t.Fatalf("Expected ErrNoProgress, got %v", err)
+	}
+
+	var stuck *graph.NoProgressError
+	if !errors.As(err, &stuck) {
+		t.Fatalf("Expected a *NoProgressError, got %T", err)
+	}
+	if !reflect.DeepEqual(stuck.Unmet, map[graph.NodeID]graph.NodeIDs{"d": {}}) {
+		t.Errorf("Expected d stuck with its dependencies finished, got %v", stuck.Unmet)
+	}
+	if !strings.Contains(err.Error(), "never became ready") {
+		t.Errorf("Expected the error to explain d, got %q", err)
+	}
+	if nr := report.Nodes["d"]; nr.Status != graph.StatusNotRun || nr.Reason != graph.ReasonNoProgress {
+		t.Errorf("Expected d NotRun for no progress, got %s (%s)", nr.Status, nr.Reason)
+	}
+	if len(report.WithStatus(graph.StatusSucceeded)) != 3 {
+		t.Error("Expected the rest of the graph to run")
+	}
+}
+
+func TestNoProgressListsUnmetDependencies(t *testing.T) {
+	peg := diamond(t).CompileToExecutable()
+	peg.SetRequired("b", 2)
+
+	_, err := runWithin(t, 5*time.Second, peg)
+	var stuck *graph.NoProgressError
+	if !errors.As(err, &stuck) {
+		t.Fatalf("Expected a *NoProgressError, got %v", err)
+	}
+	if want := (map[graph.NodeID]graph.NodeIDs{"b": {}, "d": graph.Deps("b")}); !reflect.DeepEqual(stuck.Unmet, want) {
+		t.Errorf("Expected %v, got %v", want, stuck.Unmet)
+	}
+}
@@ -0,0 +1,104 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Puts every id the graph is given through normalize: node names and dependencies as nodes are
+// added or merged, aliases, and the ids passed to edge, lookup and removal methods. Ids that
+// normalize alike name one node, so adding a second such node fails like any duplicate id, and
+// exports write the normalized form. normalize must return its own output unchanged.
+//
+// Nodes keep the names they were made with until they're added; compiled graphs, reports and
+// run options such as WithPrecompleted use the normalized ids.
+func WithIDNormalizer(normalize func(id string) NodeID) GraphOption {
+	return func(g *Graph) {
+		g.normalizer = normalize
+	}
+}
+
+// An id normalizer for WithIDNormalizer that ignores case and surrounding space
+func LowercaseID(id string) NodeID {
+	return NodeID(strings.ToLower(strings.TrimSpace(id)))
+}
+
+func (g *Graph) normalize(id NodeID) NodeID {
+	if g.normalizer == nil {
+		return id
+	}
+	return g.normalizer(string(id))
+}
+
+func (g *Graph) normalizeIDs(ids NodeIDs) NodeIDs {
+	if g.normalizer == nil || ids == nil {
+		return ids
+	}
+	normalized := make(NodeIDs, len(ids))
+	for id := range ids {
+		normalized[g.normalize(id)] = struct{}{}
+	}
+	return normalized
+}
+
+// A copy of node with its id and every id it refers to normalized, or node itself if the graph
+// has no normalizer
+func (g *Graph) normalized(node *Node) *Node {
+	if g.normalizer == nil {
+		return node
+	}
+
+	n := node.clone()
+	n.Name = string(g.normalize(node.Identifier()))
+	n.Dependencies, n.softDependencies, n.duplicates = nil, nil, nil
+	// Dependencies that normalize alike count as duplicates
+	for _, depId := range sortedIDs(node.Dependencies) {
+		n.setDependency(g.normalize(depId), node.DependencyKind(depId))
+	}
+	for depId, count := range node.duplicates {
+		for i := 0; i < count; i++ {
+			n.countDuplicate(g.normalize(depId))
+		}
+	}
+	n.optionalDependencies = g.normalizeIDs(node.optionalDependencies)
+	n.weights = nil
+	for depId, weight := range node.weights {
+		if n.weights == nil {
+			n.weights = make(map[NodeID]float64, len(node.weights))
+		}
+		n.weights[g.normalize(depId)] = weight
+	}
+	return n
+}
+
+// nodes and aliases with their ids normalized, failing if two nodes or two aliases normalize alike
+func (g *Graph) normalizedAll(nodes Nodes, aliases map[NodeID]NodeID) (Nodes, map[NodeID]NodeID, error) {
+	if g.normalizer == nil {
+		return nodes, aliases, nil
+	}
+
+	byID := make(Nodes, len(nodes))
+	from := map[NodeID]NodeID{}
+	for _, id := range sortedIDs(nodes) {
+		node := g.normalized(nodes[id])
+		if other, ok := from[node.Identifier()]; ok {
+			return nil, nil, fmt.Errorf("Nodes %s and %s normalize to the same id %s", other, id, node.Identifier())
+		}
+		byID[node.Identifier()], from[node.Identifier()] = node, id
+	}
+
+	var targets map[NodeID]NodeID
+	declared := map[NodeID]NodeID{}
+	for _, alias := range sortedIDs(aliases) {
+		normalized := g.normalize(alias)
+		if other, ok := declared[normalized]; ok {
+			return nil, nil, fmt.Errorf("Aliases %s and %s normalize to the same id %s", other, alias, normalized)
+		}
+		declared[normalized] = alias
+		if targets == nil {
+			targets = make(map[NodeID]NodeID, len(aliases))
+		}
+		targets[normalized] = g.normalize(aliases[alias])
+	}
+	return byID, targets, nil
+}
@@ -0,0 +1,149 @@
+package graph_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestLowercaseID(t *testing.T) {
+	for in, want := range map[string]graph.NodeID{
+		"Build-API":    "build-api",
+		"  build-api ": "build-api",
+		"build-api":    "build-api",
+	} {
+		if got := graph.LowercaseID(in); got != want {
+			t.Errorf("Expected %q to normalize to %s, got %s", in, want, got)
+		}
+	}
+}
+
+func TestNormalizerResolvesMixedCaseDependency(t *testing.T) {
+	g := graph.NewGraph("ci", graph.WithIDNormalizer(graph.LowercaseID))
+	if _, err := g.Add(graph.NewNode("Build-API", nil, graph.NoOp())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Add(graph.NewNode("Deploy", graph.Deps("build-api "), graph.NoOp())); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []graph.Edge{{From: "deploy", To: "build-api"}}; !reflect.DeepEqual(g.Edges(), want) {
+		t.Errorf("Expected %v, got %v", want, g.Edges())
+	}
+	g.Add(graph.NewNode("Smoke", nil, graph.NoOp()))
+	if err := g.AddEdge("SMOKE", "Deploy", 3); err != nil {
+		t.Fatal(err)
+	}
+	if w, err := g.EdgeWeight("smoke", " DEPLOY"); err != nil || w != 3 {
+		t.Errorf("Expected the weight found under any case, got %v, %v", w, err)
+	}
+	for _, id := range []graph.NodeID{"build-api", "BUILD-API", " Build-API"} {
+		if !g.Has(id) {
+			t.Errorf("Expected Has(%q)", id)
+		}
+		if node, ok := g.Get(id); !ok || node.Identifier() != "build-api" {
+			t.Errorf("Expected Get(%q) to find build-api, got %v", id, node)
+		}
+	}
+
+	report, err := g.CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := report.Nodes["build-api"]; !ok || len(report.Nodes) != 3 {
+		t.Errorf("Expected the report to use the normalized ids, got %v", report.Nodes)
+	}
+}
+
+func TestNormalizerCollisions(t *testing.T) {
+	g := graph.NewGraph("ci", graph.WithIDNormalizer(graph.LowercaseID))
+	g.Add(graph.NewNode("build-api", nil, graph.NoOp()))
+
+	if _, err := g.Add(graph.NewNode("Build-API", nil, graph.NoOp())); err == nil || err.Error() != "Node with id build-api already exists" {
+		t.Errorf("Expected a duplicate error, got %v", err)
+	}
+
+	other := graph.NewGraph("other")
+	other.Add(graph.NewNode("Lint", nil, graph.NoOp()))
+	other.Add(graph.NewNode("lint", nil, graph.NoOp()))
+	if err := g.Merge(other); err == nil || err.Error() != "Nodes Lint and lint normalize to the same id lint" {
+		t.Errorf("Expected the merge to refuse folding two nodes together, got %v", err)
+	}
+	if g.Has("lint") {
+		t.Error("Expected the refused merge to add nothing")
+	}
+
+	// Without a normalizer they stay apart
+	plain := graph.NewGraph("plain")
+	plain.Add(graph.NewNode("build-api", nil, graph.NoOp()))
+	if _, err := plain.Add(graph.NewNode("Build-API", nil, graph.NoOp())); err != nil {
+		t.Errorf("Expected ids compared as given by default, got %v", err)
+	}
+}
+
+func TestNormalizerCoversEdgesAliasesAndRemoval(t *testing.T) {
+	g := graph.NewGraph("ci", graph.WithIDNormalizer(graph.LowercaseID))
+	g.Add(graph.NewNode("Build", nil, graph.NoOp()))
+	g.Add(graph.NewNode("Test", nil, graph.NoOp()))
+	if err := g.AddEdge("TEST", "build"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Alias("Compile", "BUILD"); err != nil {
+		t.Fatal(err)
+	}
+	if got := g.ResolveAlias("compile"); got != "build" {
+		t.Errorf("Expected the alias stored normalized, got %s", got)
+	}
+	if err := g.Rename("TEST", "Verify"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Remove("VERIFY"); err != nil {
+		t.Errorf("Expected Remove to find verify, got %v", err)
+	}
+	if ids := mustSort(t, g); !reflect.DeepEqual(ids, graph.SortedNodeIDs{"build"}) {
+		t.Errorf("Expected only build left, got %v", ids)
+	}
+}
+
+func TestNormalizerExportsNormalizedIDs(t *testing.T) {
+	g := graph.NewGraph("ci", graph.WithIDNormalizer(graph.LowercaseID))
+	g.Add(graph.NewNode("Build", nil, graph.NoOp()))
+	g.Add(graph.NewNode("Deploy", graph.Deps("BUILD"), graph.NoOp()))
+
+	var adj bytes.Buffer
+	if err := g.WriteAdjacency(&adj); err != nil {
+		t.Fatal(err)
+	}
+	def := writeJSON(t, g)
+	for name, out := range map[string]string{"adjacency": adj.String(), "JSON": def, "DOT": g.CompileToExecutable().ToDOT()} {
+		if strings.Contains(out, "Build") || strings.Contains(out, "BUILD") || strings.Contains(out, "Deploy") {
+			t.Errorf("Expected the %s export to use the normalized ids, got\n%s", name, out)
+		}
+	}
+}
+
+func TestNormalizerAppliesToLoadedDefinitions(t *testing.T) {
+	def := `{"schemaVersion": 2, "name": "ci", "nodes": [
+		{"id": "Build"},
+		{"id": "Deploy", "dependencies": ["BUILD"]}
+	], "aliases": {"Compile": "build"}}`
+	g := parseJSON(t, def, graph.LoadGraphOptions(graph.WithIDNormalizer(graph.LowercaseID)))
+
+	if want := []graph.Edge{{From: "deploy", To: "build"}}; !reflect.DeepEqual(g.Edges(), want) {
+		t.Errorf("Expected %v, got %v", want, g.Edges())
+	}
+	if got := g.ResolveAlias("COMPILE"); got != "build" {
+		t.Errorf("Expected the loaded alias normalized, got %s", got)
+	}
+
+	dup := `{"schemaVersion": 2, "name": "ci", "nodes": [{"id": "Build"}, {"id": "build"}]}`
+	if _, err := graph.ParseJSON(strings.NewReader(dup), noOps, graph.LoadGraphOptions(graph.WithIDNormalizer(graph.LowercaseID))); err == nil {
+		t.Error("Expected a definition with ids that normalize alike to be refused")
+	}
+	if g := parseJSON(t, dup); len(mustSort(t, g)) != 2 {
+		t.Error("Expected both ids kept without a normalizer")
+	}
+}
@@ -0,0 +1,76 @@
+package graph_test
+
+import (
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A reusable sub-graph whose service runs after db-migrate when the composed graph has one
+func serviceGraph(t *testing.T, withMigrate bool) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("composed")
+	if withMigrate {
+		g.Add(graph.NewNode("db-migrate", nil, graph.NoOp()))
+	}
+	g.Add(graph.NewNode("config", nil, graph.NoOp()))
+	if _, err := g.Add(graph.NewNode("service", graph.Deps("config"), graph.NoOp(), graph.WithOptionalDependency("db-migrate"))); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestOptionalDependencyBindsWhenPresent(t *testing.T) {
+	g := serviceGraph(t, true)
+
+	deps, err := g.ResolvedDependencies("service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(deps, graph.Deps("config", "db-migrate")) {
+		t.Errorf("Expected the optional edge to bind, got %v", deps)
+	}
+
+	targets, _ := g.CompileToExecutable().Targets("db-migrate")
+	if _, ok := targets["service"]; !ok {
+		t.Errorf("Expected db-migrate to feed service when compiled, got %v", targets)
+	}
+}
+
+func TestOptionalDependencyDroppedWhenAbsent(t *testing.T) {
+	g := serviceGraph(t, false)
+	if err := g.Validate(); err != nil {
+		t.Fatalf("Expected a missing optional dependency to validate, got %v", err)
+	}
+
+	deps, err := g.ResolvedDependencies("service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(deps, graph.Deps("config")) {
+		t.Errorf("Expected only the declared edge, got %v", deps)
+	}
+
+	peg := g.CompileToExecutable()
+	if count, _ := peg.DependencyCount("service"); count != 1 {
+		t.Errorf("Expected service to wait on one node, got %d", count)
+	}
+	if _, err := peg.Run(ctx(t)); err != nil {
+		t.Errorf("Expected the run to succeed, got %v", err)
+	}
+}
+
+func TestOptionalDependencyBindsWhenAddedLater(t *testing.T) {
+	g := serviceGraph(t, false)
+	g.Add(graph.NewNode("db-migrate", nil, graph.NoOp()))
+
+	deps, _ := g.ResolvedDependencies("service")
+	if _, ok := deps["db-migrate"]; !ok {
+		t.Errorf("Expected the optional edge to bind once db-migrate is added, got %v", deps)
+	}
+	if ids := mustSort(t, g); !before(ids, "db-migrate", "service") {
+		t.Errorf("Expected db-migrate to sort before service, got %v", ids)
+	}
+}
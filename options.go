@@ -0,0 +1,154 @@
+package graph
+
+import (
+	"io"
+	"reflect"
+	"time"
+)
+
+type NodeOption func(*Node)
+
+type GraphOption func(*Graph)
+
+type TimeoutBehavior int
+
+const (
+	// Fail the node (and skip its dependents) when it runs past its timeout
+	OnTimeoutFail TimeoutBehavior = iota
+	// Mark the node skipped and let its dependents run without it
+	OnTimeoutSkip
+)
+
+func WithTimeout(d time.Duration, behavior ...TimeoutBehavior) NodeOption {
+	return func(n *Node) {
+		n.timeout = d
+		n.onTimeout = OnTimeoutFail
+
+		if len(behavior) > 0 {
+			n.onTimeout = behavior[0]
+		}
+	}
+}
+
+type RetryPolicy struct {
+	// Total attempts including the first; zero or one means no retries
+	MaxAttempts int
+	// Wait between attempts
+	Backoff time.Duration
+	// Error categories worth retrying; empty means all but canceled and permanent
+	RetryOn []ErrorCategory
+}
+
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func WithRetry(policy RetryPolicy) NodeOption {
+	return func(n *Node) {
+		n.retry = policy
+	}
+}
+
+// Hooks are called from the goroutines running the nodes, so they must be safe for concurrent use
+type Hooks struct {
+	// Called before and after every attempt
+	OnNodeStart  func(id NodeID, attempt int)
+	OnNodeFinish func(id NodeID, attempt AttemptReport)
+	// Called once per node with its final result, including nodes that never ran
+	OnNodeResult func(id NodeID, result NodeReport)
+	// Called when an approval node parks, with the token to approve or reject it by
+	OnAwaitingApproval func(id NodeID, token string)
+}
+
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// ExecConfig is the effective execution configuration for a run. Options given to
+// CompileToExecutable are applied first and the ones given to Run on top, so run beats compile.
+type ExecConfig struct {
+	// Zero means no limit
+	MaxConcurrency int
+	Hooks          Hooks
+	Logger         Logger
+	// Empty means every run executes
+	SingleFlightKey string
+	NodeOutput      func(id NodeID) io.Writer
+	// Bytes of output kept per node in the report; zero disables capture
+	CaptureOutput int
+	SlowNode      SlowNodeWarning
+	Precompleted  map[NodeID]any
+	// Take turns between groups of ready nodes, keyed by the FairKey metadata or else lineage
+	Fair    bool
+	FairKey string
+	// Run one node at a time in an order derived from Seed
+	Deterministic bool
+	Seed          int64
+	// Events kept in the report; zero disables the log
+	EventLimit int
+	Listeners  []Listener
+	// Empty runs every node's default fn
+	Variant string
+	// Longest random wait before each node starts; zero means none
+	StartJitter time.Duration
+	// Zero means the run has no deadline of its own
+	RunTimeout time.Duration
+	// Zero means the run dispatches until it's done
+	SoftDeadline time.Time
+	Metrics      MetricsSink
+	// Node metadata keys that become metric labels
+	MetricLabels []string
+	// Time each unit of edge weight adds to the critical path; zero leaves weights out
+	EdgeWeightCost time.Duration
+	// Whether marker nodes reach hooks, the logger, events and metrics
+	MarkerHooks bool
+	RaceHints   bool
+	// Total cost of the nodes running at once; zero means no budget
+	Budget int
+	// How long results are held, and where under ExternalResults
+	ResultRetention ResultRetention
+	ResultStore     ResultStore
+	// Nil runs every node whatever its feature flag
+	FlagProvider func(flag string) bool
+	// Read-only settings for every fn in the run
+	RunConfig map[string]string
+	// Nil skips the staleness check
+	StaleSource *Graph
+	OnStale     StaleBehavior
+	// Reports the compiled graph keeps; zero keeps none
+	History int
+	// By static type, for Service
+	Services map[reflect.Type]any
+	// Kept up to date for SnapshotState when set
+	State *RunState
+	// Nil means the system clock
+	Clock Clock
+	// Nil compiles every node; read by CompileToExecutable only
+	NodeFilter func(n *Node) bool
+	OnCut      CutPolicy
+	// Decisions for approval nodes, set by Start
+	approvals chan approvalDecision
+}
+
+type ExecOption func(*ExecConfig)
+
+func WithHooks(hooks Hooks) ExecOption {
+	return func(c *ExecConfig) {
+		c.Hooks = hooks
+	}
+}
+
+func WithMaxConcurrency(n int) ExecOption {
+	return func(c *ExecConfig) {
+		c.MaxConcurrency = n
+	}
+}
+
+func WithLogger(logger Logger) ExecOption {
+	return func(c *ExecConfig) {
+		c.Logger = logger
+	}
+}
@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// How many inversions CompareOrders lists unless given a limit
+const DefaultInversionLimit = 100
+
+// First came before Second in the old order and comes after it in the new one
+type Inversion struct {
+	First  NodeID
+	Second NodeID
+}
+
+func (i Inversion) String() string {
+	return fmt.Sprintf("%s before %s", i.Second, i.First)
+}
+
+type OrderComparison struct {
+	// In the new order only, in that order
+	Added SortedNodeIDs
+	// In the old order only, in that order
+	Removed SortedNodeIDs
+	// Pairs of nodes in both orders whose relative order flipped, sorted by where First and then
+	// Second were in the old order
+	Inversions []Inversion
+	// Inversions past the limit
+	InversionsOmitted int
+}
+
+func (c *OrderComparison) Changed() bool {
+	return len(c.Added) > 0 || len(c.Removed) > 0 || len(c.Inversions) > 0 || c.InversionsOmitted > 0
+}
+
+// CompareOrders says how the execution order shifted between two Sort results. At most limit
+// inversions are listed, DefaultInversionLimit if it isn't given; the rest are only counted.
+// Ids listed twice count from where they first appear.
+func CompareOrders(old, new SortedNodeIDs, limit ...int) *OrderComparison {
+	max := DefaultInversionLimit
+	if len(limit) > 0 {
+		max = limit[0]
+	}
+
+	c := &OrderComparison{Added: SortedNodeIDs{}, Removed: SortedNodeIDs{}, Inversions: []Inversion{}}
+
+	oldAt, newAt := positions(old), positions(new)
+	for i, id := range new {
+		if _, ok := oldAt[id]; !ok && newAt[id] == i {
+			c.Added = append(c.Added, id)
+		}
+	}
+	for i, id := range old {
+		if _, ok := newAt[id]; !ok && oldAt[id] == i {
+			c.Removed = append(c.Removed, id)
+		}
+	}
+
+	// The shared nodes in old order, each with its rank among them in the new order
+	shared := SortedNodeIDs{}
+	for i, id := range old {
+		if _, ok := newAt[id]; ok && oldAt[id] == i {
+			shared = append(shared, id)
+		}
+	}
+	rank := make(map[NodeID]int, len(shared))
+	for i, id := range new {
+		if _, ok := oldAt[id]; ok && newAt[id] == i {
+			rank[id] = len(rank)
+		}
+	}
+
+	// later[i] counts the shared nodes after shared[i] in the old order that are ranked before it
+	// in the new one. A Fenwick tree over ranks keeps this at n log n.
+	later := make([]int, len(shared))
+	tree := make([]int, len(shared)+1)
+	total := 0
+	for i := len(shared) - 1; i >= 0; i-- {
+		r := rank[shared[i]]
+		for k := r; k > 0; k -= k & -k {
+			later[i] += tree[k]
+		}
+		for k := r + 1; k < len(tree); k += k & -k {
+			tree[k]++
+		}
+		total += later[i]
+	}
+
+	for i := 0; i < len(shared) && len(c.Inversions) < max; i++ {
+		found := 0
+		for j := i + 1; found < later[i] && len(c.Inversions) < max; j++ {
+			if rank[shared[j]] < rank[shared[i]] {
+				c.Inversions = append(c.Inversions, Inversion{First: shared[i], Second: shared[j]})
+				found++
+			}
+		}
+	}
+	c.InversionsOmitted = total - len(c.Inversions)
+
+	return c
+}
+
+// Where each id first appears
+func positions(order SortedNodeIDs) map[NodeID]int {
+	at := make(map[NodeID]int, len(order))
+	for i, id := range order {
+		if _, ok := at[id]; !ok {
+			at[id] = i
+		}
+	}
+	return at
+}
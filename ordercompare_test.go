@@ -0,0 +1,135 @@
+package graph_test
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestCompareOrdersUnchanged(t *testing.T) {
+	order := graph.SortedNodeIDs{"build", "test", "deploy"}
+	c := graph.CompareOrders(order, order)
+	if c.Changed() || len(c.Added) != 0 || len(c.Removed) != 0 || len(c.Inversions) != 0 || c.InversionsOmitted != 0 {
+		t.Errorf("Expected no changes, got %+v", c)
+	}
+}
+
+func TestCompareOrdersAddedAndRemoved(t *testing.T) {
+	c := graph.CompareOrders(
+		graph.SortedNodeIDs{"build", "lint", "test", "deploy"},
+		graph.SortedNodeIDs{"build", "test", "scan", "deploy", "notify"},
+	)
+	if !reflect.DeepEqual(c.Added, graph.SortedNodeIDs{"scan", "notify"}) {
+		t.Errorf("Expected scan and notify added in new order, got %v", c.Added)
+	}
+	if !reflect.DeepEqual(c.Removed, graph.SortedNodeIDs{"lint"}) {
+		t.Errorf("Expected lint removed, got %v", c.Removed)
+	}
+	if len(c.Inversions) != 0 || !c.Changed() {
+		t.Errorf("Expected a change with no inversions, got %+v", c)
+	}
+}
+
+func TestCompareOrdersInversions(t *testing.T) {
+	// migrate moves ahead of test and backup; lint, only in old, is after migrate there but
+	// can't count as an inversion
+	c := graph.CompareOrders(
+		graph.SortedNodeIDs{"build", "test", "backup", "lint", "migrate", "deploy"},
+		graph.SortedNodeIDs{"build", "migrate", "test", "backup", "deploy"},
+	)
+	want := []graph.Inversion{
+		{First: "test", Second: "migrate"},
+		{First: "backup", Second: "migrate"},
+	}
+	if !reflect.DeepEqual(c.Inversions, want) {
+		t.Errorf("Expected inversions %v, got %v", want, c.Inversions)
+	}
+	if got := c.Inversions[0].String(); got != "migrate before test" {
+		t.Errorf("Expected the inversion to read as the new order, got %q", got)
+	}
+}
+
+func TestCompareOrdersLimit(t *testing.T) {
+	// Reversing five nodes inverts all ten pairs
+	old := graph.SortedNodeIDs{"a", "b", "c", "d", "e"}
+	reversed := old.Reverse()
+
+	c := graph.CompareOrders(old, reversed, 3)
+	want := []graph.Inversion{{First: "a", Second: "b"}, {First: "a", Second: "c"}, {First: "a", Second: "d"}}
+	if !reflect.DeepEqual(c.Inversions, want) || c.InversionsOmitted != 7 {
+		t.Errorf("Expected the first three inversions and seven omitted, got %v and %d", c.Inversions, c.InversionsOmitted)
+	}
+
+	if c := graph.CompareOrders(old, reversed, 0); len(c.Inversions) != 0 || c.InversionsOmitted != 10 || !c.Changed() {
+		t.Errorf("Expected a zero limit to only count, got %v and %d", c.Inversions, c.InversionsOmitted)
+	}
+	if c := graph.CompareOrders(old, reversed); len(c.Inversions) != 10 {
+		t.Errorf("Expected the default limit to list all ten, got %d", len(c.Inversions))
+	}
+}
+
+func TestCompareOrdersDuplicates(t *testing.T) {
+	// Each id counts from where it first appears
+	c := graph.CompareOrders(graph.SortedNodeIDs{"a", "b", "a"}, graph.SortedNodeIDs{"b", "a", "b"})
+	if len(c.Added) != 0 || len(c.Removed) != 0 || !reflect.DeepEqual(c.Inversions, []graph.Inversion{{First: "a", Second: "b"}}) {
+		t.Errorf("Expected one inversion of a and b, got %+v", c)
+	}
+}
+
+// Checks the counting against every pair, on random orders sharing some of their nodes
+func TestCompareOrdersMatchesPairwise(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 200; round++ {
+		ids := graph.SortedNodeIDs{}
+		for i := 0; i < 2+rng.Intn(30); i++ {
+			ids = append(ids, graph.NodeID(fmt.Sprintf("n%d", i)))
+		}
+		shuffled := func() graph.SortedNodeIDs {
+			s := graph.SortedNodeIDs{}
+			for _, i := range rng.Perm(len(ids)) {
+				if rng.Intn(5) > 0 {
+					s = append(s, ids[i])
+				}
+			}
+			return s
+		}
+		old, new := shuffled(), shuffled()
+
+		want := []graph.Inversion{}
+		for i := range old {
+			for j := i + 1; j < len(old); j++ {
+				a, b := new.Index(old[i]), new.Index(old[j])
+				if a >= 0 && b >= 0 && b < a {
+					want = append(want, graph.Inversion{First: old[i], Second: old[j]})
+				}
+			}
+		}
+
+		limit := rng.Intn(len(want) + 2)
+		c := graph.CompareOrders(old, new, limit)
+		listed := want
+		if len(listed) > limit {
+			listed = listed[:limit]
+		}
+		if !reflect.DeepEqual(c.Inversions, listed) || c.InversionsOmitted != len(want)-len(listed) {
+			t.Fatalf("%v -> %v with limit %d: expected %v and %d omitted, got %v and %d",
+				old, new, limit, listed, len(want)-len(listed), c.Inversions, c.InversionsOmitted)
+		}
+	}
+}
+
+func BenchmarkCompareOrders(b *testing.B) {
+	old := graph.SortedNodeIDs{}
+	for i := 0; i < 10000; i++ {
+		old = append(old, graph.NodeID(fmt.Sprintf("n%d", i)))
+	}
+	reversed := old.Reverse()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		graph.CompareOrders(old, reversed)
+	}
+}
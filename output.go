@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"io"
+	"sync"
+)
+
+// The factory is called once per node per run
+func WithNodeOutput(factory func(id NodeID) io.Writer) ExecOption {
+	return func(c *ExecConfig) {
+		c.NodeOutput = factory
+	}
+}
+
+// Buffers what each node writes to its Output, up to limit bytes, into its report entry
+func WithCapturedOutput(limit int) ExecOption {
+	return func(c *ExecConfig) {
+		c.CaptureOutput = limit
+	}
+}
+
+// The writer for this node's output; discards unless the run configured one
+func (ec *ExecutionContext) Output() io.Writer {
+	if ec.output == nil {
+		return io.Discard
+	}
+	return ec.output
+}
+
+type cappedBuffer struct {
+	mu        sync.Mutex
+	limit     int
+	buf       []byte
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	room := b.limit - len(b.buf)
+	if room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf = append(b.buf, p[:room]...)
+		}
+	} else {
+		b.buf = append(b.buf, p...)
+	}
+
+	// Report the full length so writers don't fail once the cap is hit
+	return len(p), nil
+}
+
+func (b *cappedBuffer) contents() (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf), b.truncated
+}
+
+func (r *run) output(id NodeID) (io.Writer, *cappedBuffer) {
+	writers := []io.Writer{}
+
+	if r.cfg.NodeOutput != nil {
+		if w := r.cfg.NodeOutput(id); w != nil {
+			writers = append(writers, w)
+		}
+	}
+
+	var captured *cappedBuffer
+	if r.cfg.CaptureOutput > 0 {
+		captured = &cappedBuffer{limit: r.cfg.CaptureOutput}
+		writers = append(writers, captured)
+	}
+
+	switch len(writers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return writers[0], captured
+	}
+	return io.MultiWriter(writers...), captured
+}
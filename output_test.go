@@ -0,0 +1,107 @@
+package graph_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestCapturedOutputIsAttributed(t *testing.T) {
+	// Both fns are running before either writes, and they take turns line by line
+	var started sync.WaitGroup
+	started.Add(2)
+	turns := map[graph.NodeID]chan struct{}{"left": make(chan struct{}), "right": make(chan struct{})}
+	other := map[graph.NodeID]graph.NodeID{"left": "right", "right": "left"}
+
+	write := func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		started.Done()
+		started.Wait()
+		for i := 0; i < 3; i++ {
+			<-turns[ec.ID]
+			fmt.Fprintf(ec.Output(), "%s line %d\n", ec.ID, i)
+			if ec.ID == "left" || i < 2 {
+				turns[other[ec.ID]] <- struct{}{}
+			}
+		}
+		return nil, nil
+	}
+
+	g := graph.NewGraph("output")
+	g.Add(graph.NewNode("left", nil, write))
+	g.Add(graph.NewNode("right", nil, write))
+
+	go func() { turns["left"] <- struct{}{} }()
+	report, err := g.CompileToExecutable().Run(ctx(t), graph.WithCapturedOutput(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []graph.NodeID{"left", "right"} {
+		want := fmt.Sprintf("%s line 0\n%s line 1\n%s line 2\n", id, id, id)
+		if got := report.Nodes[id].Output; got != want {
+			t.Errorf("Expected %s's output to be its own, got %q", id, got)
+		}
+	}
+}
+
+func TestCapturedOutputIsCapped(t *testing.T) {
+	g := graph.NewGraph("output")
+	g.Add(graph.NewNode("chatty", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		_, err := io.WriteString(ec.Output(), strings.Repeat("x", 100))
+		return nil, err
+	}))
+
+	report, err := g.CompileToExecutable().Run(ctx(t), graph.WithCapturedOutput(10))
+	if err != nil {
+		t.Fatalf("Expected writes past the cap not to fail, got %v", err)
+	}
+	if nr := report.Nodes["chatty"]; nr.Output != strings.Repeat("x", 10) || !nr.OutputTruncated {
+		t.Errorf("Expected 10 bytes and a truncation flag, got %d bytes, truncated %v", len(nr.Output), nr.OutputTruncated)
+	}
+}
+
+func TestNodeOutputFactory(t *testing.T) {
+	var mu sync.Mutex
+	buffers := map[graph.NodeID]*bytes.Buffer{}
+	factory := func(id graph.NodeID) io.Writer {
+		mu.Lock()
+		defer mu.Unlock()
+		buffers[id] = &bytes.Buffer{}
+		return buffers[id]
+	}
+
+	g := graph.NewGraph("output")
+	g.Add(graph.NewNode("a", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		fmt.Fprint(ec.Output(), "hello from a")
+		return nil, nil
+	}))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t), graph.WithNodeOutput(factory)); err != nil {
+		t.Fatal(err)
+	}
+	if got := buffers["a"].String(); got != "hello from a" {
+		t.Errorf("Expected the factory's writer to get the output, got %q", got)
+	}
+}
+
+func TestOutputDiscardedByDefault(t *testing.T) {
+	g := graph.NewGraph("output")
+	g.Add(graph.NewNode("a", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		_, err := fmt.Fprint(ec.Output(), "nobody listens")
+		return nil, err
+	}))
+
+	report, err := g.CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Nodes["a"].Output != "" {
+		t.Error("Expected nothing captured without WithCapturedOutput")
+	}
+}
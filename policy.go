@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrPolicyViolation = errors.New("Edge policy violation")
+
+// An EdgePolicy returns an error to forbid from depending on to. The nodes are the graph's own,
+// shared with it, and must only be read.
+type EdgePolicy func(from, to *Node) error
+
+type PolicyViolationError struct {
+	From NodeID
+	To   NodeID
+	Err  error
+}
+
+func (e *PolicyViolationError) Error() string {
+	return fmt.Sprintf("%s: %s may not depend on %s: %s", ErrPolicyViolation, e.From, e.To, e.Err)
+}
+
+func (e *PolicyViolationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *PolicyViolationError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+// Policies are checked on Add, AddEdge and Merge; every policy is evaluated and all violations returned
+func WithEdgePolicy(policy EdgePolicy) GraphOption {
+	return func(g *Graph) {
+		g.policies = append(g.policies, policy)
+	}
+}
+
+func (g *Graph) checkPolicies(from *Node, deps NodeIDs, lookup func(id NodeID) *Node) error {
+	if len(g.policies) == 0 {
+		return nil
+	}
+
+	errs := []error{}
+	for _, depId := range sortedIDs(deps) {
+		to := lookup(depId)
+		if to == nil {
+			continue
+		}
+
+		for _, policy := range g.policies {
+			if err := policy(from, to); err != nil {
+				errs = append(errs, &PolicyViolationError{From: from.Identifier(), To: depId, Err: err})
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (g *Graph) lookup(id NodeID) *Node {
+	return g.nodes[id]
+}
@@ -0,0 +1,97 @@
+package graph_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func noPaymentsOnExperimental(from, to *graph.Node) error {
+	if from.Metadata["team"] == "payments" && to.Metadata["env"] == "experimental" {
+		return errors.New("payments may not depend on experimental nodes")
+	}
+	return nil
+}
+
+func policyGraph(t *testing.T, policies ...graph.EdgePolicy) *graph.Graph {
+	t.Helper()
+
+	opts := []graph.GraphOption{}
+	for _, p := range policies {
+		opts = append(opts, graph.WithEdgePolicy(p))
+	}
+	g := graph.NewGraph("payments", opts...)
+	g.Add(graph.NewNode("ledger", nil, graph.NoOp(), graph.WithMetadata("env", "prod")))
+	g.Add(graph.NewNode("beta-fraud", nil, graph.NoOp(), graph.WithMetadata("env", "experimental")))
+	return g
+}
+
+func assertViolation(t *testing.T, err error, from, to graph.NodeID) {
+	t.Helper()
+
+	if !errors.Is(err, graph.ErrPolicyViolation) {
+		t.Fatalf("Expected ErrPolicyViolation, got %v", err)
+	}
+	var violation *graph.PolicyViolationError
+	if !errors.As(err, &violation) {
+		t.Fatalf("Expected a *PolicyViolationError, got %T", err)
+	}
+	if violation.From != from || violation.To != to {
+		t.Errorf("Expected the violation to name %s -> %s, got %s -> %s", from, to, violation.From, violation.To)
+	}
+}
+
+func TestPolicyRejectsViolatingAdd(t *testing.T) {
+	g := policyGraph(t, noPaymentsOnExperimental)
+
+	_, err := g.Add(graph.NewNode("charge", graph.Deps("beta-fraud"), graph.NoOp(), graph.WithMetadata("team", "payments")))
+	assertViolation(t, err, "charge", "beta-fraud")
+	if g.Has("charge") {
+		t.Error("Expected the rejected node not to be added")
+	}
+}
+
+func TestPolicyPermitsCompliantEdges(t *testing.T) {
+	g := policyGraph(t, noPaymentsOnExperimental)
+
+	if _, err := g.Add(graph.NewNode("charge", graph.Deps("ledger"), graph.NoOp(), graph.WithMetadata("team", "payments"))); err != nil {
+		t.Fatalf("Expected a compliant node to be added, got %v", err)
+	}
+	if _, err := g.Add(graph.NewNode("analytics", graph.Deps("beta-fraud"), graph.NoOp(), graph.WithMetadata("team", "data"))); err != nil {
+		t.Fatalf("Expected another team to use the experimental node, got %v", err)
+	}
+	assertViolation(t, g.AddEdge("charge", "beta-fraud"), "charge", "beta-fraud")
+}
+
+func TestPolicyEnforcedAcrossMerge(t *testing.T) {
+	g := policyGraph(t, noPaymentsOnExperimental)
+
+	other := graph.NewGraph("checkout")
+	other.Add(graph.NewNode("beta-fraud", nil, graph.NoOp(), graph.WithMetadata("env", "experimental")))
+	other.Add(graph.NewNode("charge", graph.Deps("beta-fraud"), graph.NoOp(), graph.WithMetadata("team", "payments")))
+
+	assertViolation(t, g.Merge(other), "charge", "beta-fraud")
+	if g.Has("charge") {
+		t.Error("Expected a rejected merge to add nothing")
+	}
+}
+
+func TestPoliciesCompose(t *testing.T) {
+	calls := 0
+	counting := func(from, to *graph.Node) error {
+		calls++
+		return fmt.Errorf("no edges into %s", to.Name)
+	}
+	g := policyGraph(t, noPaymentsOnExperimental, counting)
+
+	_, err := g.Add(graph.NewNode("charge", graph.Deps("beta-fraud"), graph.NoOp(), graph.WithMetadata("team", "payments")))
+	if calls != 1 {
+		t.Errorf("Expected every policy to be checked, the second ran %d times", calls)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "experimental") || !strings.Contains(msg, "no edges into beta-fraud") {
+		t.Errorf("Expected both violations to be reported, got %q", msg)
+	}
+}
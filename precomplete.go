@@ -0,0 +1,68 @@
+package graph
+
+// Starts the run with these nodes already succeeded, without running them; the values are
+// handed to dependents as the nodes' results. Nodes needed only by precompleted nodes are
+// skipped as not needed.
+func WithPrecompleted(results map[NodeID]any) ExecOption {
+	return func(c *ExecConfig) {
+		c.Precompleted = results
+	}
+}
+
+// Marks the precompleted and unneeded nodes finished and returns the nodes ready to run
+func (r *run) precomplete() []NodeID {
+	needed := make(map[NodeID]bool, len(r.peg.nodes))
+
+	var need func(id NodeID) bool
+	need = func(id NodeID) bool {
+		if n, ok := needed[id]; ok {
+			return n
+		}
+
+		n := false
+		if _, ok := r.cfg.Precompleted[id]; !ok {
+			targets := r.peg.nodes[id].targetIDs
+			n = len(targets) == 0
+			for target := range targets {
+				if need(target) {
+					n = true
+				}
+			}
+		}
+
+		needed[id] = n
+		return n
+	}
+
+	now := r.clock().Now()
+	ready := []NodeID{}
+	for _, id := range sortedIDs(r.peg.nodes) {
+		if need(id) {
+			continue
+		}
+
+		nr := NodeReport{ID: id, Status: StatusSkipped, Reason: ReasonNotNeeded, Start: now, End: now}
+		if value, ok := r.cfg.Precompleted[id]; ok {
+			nr.Status, nr.Reason = StatusSucceeded, ReasonPrecompleted
+			if _, ok := value.(resultUnavailable); ok {
+				nr.Reason = ReasonResultUnavailable
+			} else {
+				r.retain(id, value)
+			}
+		}
+		*r.report.Nodes[id] = nr
+		r.cfg.State.record(nr, r.results[id])
+		r.finished(nr)
+
+		for target := range r.peg.nodes[id].targetIDs {
+			r.pending[target]--
+		}
+	}
+
+	for _, id := range sortedIDs(r.peg.nodes) {
+		if needed[id] && r.pending[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	return ready
+}
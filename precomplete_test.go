@@ -0,0 +1,92 @@
+package graph_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A fn that records that it ran and returns what its dependencies returned
+type recorder struct {
+	mu  sync.Mutex
+	ran []graph.NodeID
+}
+
+func (r *recorder) fn(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ran = append(r.ran, ec.ID)
+
+	seen := map[graph.NodeID]any{}
+	for id, value := range ec.Results {
+		seen[id] = value
+	}
+	return seen, nil
+}
+
+func TestPrecompletedMiddleOfChain(t *testing.T) {
+	var rec recorder
+	var leafSaw graph.Results
+	g := graph.NewGraph("chain")
+	g.Add(graph.NewNode("extract", nil, rec.fn))
+	g.Add(graph.NewNode("transform", graph.Deps("extract"), rec.fn))
+	g.Add(graph.NewNode("load", graph.Deps("transform"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		leafSaw = ec.Results
+		return rec.fn(ctx, ec)
+	}))
+
+	report, err := g.CompileToExecutable().Run(ctx(t), graph.WithPrecompleted(map[graph.NodeID]any{"transform": "rows"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rec.ran) != 1 || rec.ran[0] != "load" {
+		t.Errorf("Expected only load to run, got %v", rec.ran)
+	}
+	if leafSaw["transform"] != "rows" {
+		t.Errorf("Expected load to see the provided result, got %v", leafSaw)
+	}
+
+	transform := report.Nodes["transform"]
+	if transform.Status != graph.StatusSucceeded || transform.Reason != graph.ReasonPrecompleted {
+		t.Errorf("Expected transform precompleted, got %s %s", transform.Status, transform.Reason)
+	}
+	if len(transform.Attempts) != 0 {
+		t.Errorf("Expected transform to have no attempts, got %d", len(transform.Attempts))
+	}
+	extract := report.Nodes["extract"]
+	if extract.Status != graph.StatusSkipped || extract.Reason != graph.ReasonNotNeeded {
+		t.Errorf("Expected extract skipped as not needed, got %s %s", extract.Status, extract.Reason)
+	}
+}
+
+func TestPrecompletedKeepsSharedDependencies(t *testing.T) {
+	var rec recorder
+	g := graph.NewGraph("diamond")
+	g.Add(graph.NewNode("a", nil, rec.fn))
+	g.Add(graph.NewNode("b", graph.Deps("a"), rec.fn))
+	g.Add(graph.NewNode("c", graph.Deps("a"), rec.fn))
+	g.Add(graph.NewNode("d", graph.Deps("b", "c"), rec.fn))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t), graph.WithPrecompleted(map[graph.NodeID]any{"b": nil})); err != nil {
+		t.Fatal(err)
+	}
+
+	// c still needs a
+	ran := map[graph.NodeID]bool{}
+	for _, id := range rec.ran {
+		ran[id] = true
+	}
+	if !ran["a"] || ran["b"] || !ran["c"] || !ran["d"] {
+		t.Errorf("Expected a, c and d to run, got %v", rec.ran)
+	}
+}
+
+func TestPrecompletedUnknownNode(t *testing.T) {
+	_, err := diamond(t).CompileToExecutable().Run(ctx(t), graph.WithPrecompleted(map[graph.NodeID]any{"missing": 1}))
+	if err == nil {
+		t.Error("Expected an error precompleting a node not in the graph")
+	}
+}
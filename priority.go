@@ -0,0 +1,22 @@
+package graph
+
+// Among the nodes ready at the same time, those of higher priority are dispatched first; nodes of
+// equal priority keep the default order. Priorities only reorder the ready queue, so a node
+// still waits for its dependencies. The default is 0, and negative priorities go after it.
+// Priorities take precedence over fair scheduling, and deterministic scheduling ignores them.
+func WithPriority(priority int) NodeOption {
+	return func(n *Node) {
+		n.priority = priority
+	}
+}
+
+// The index in the ready queue of the oldest node of the highest priority
+func (r *run) nextPriority() int {
+	pick := 0
+	for i, id := range r.ready {
+		if r.peg.nodes[id].priority > r.peg.nodes[r.ready[pick]].priority {
+			pick = i
+		}
+	}
+	return pick
+}
@@ -0,0 +1,54 @@
+package graph_test
+
+import (
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestPriorityOrdersReadyNodes(t *testing.T) {
+	l := &runLog{}
+	g := graph.NewGraph("queue")
+	g.Add(graph.NewNode("a", nil, l.fn))
+	g.Add(graph.NewNode("b", nil, l.fn, graph.WithPriority(1)))
+	g.Add(graph.NewNode("c", nil, l.fn, graph.WithPriority(2)))
+	g.Add(graph.NewNode("d", nil, l.fn, graph.WithPriority(-1)))
+	// Still waits for a, however urgent
+	g.Add(graph.NewNode("e", graph.Deps("a"), l.fn, graph.WithPriority(10)))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t), graph.WithMaxConcurrency(1)); err != nil {
+		t.Fatal(err)
+	}
+	if want := []graph.NodeID{"c", "b", "a", "e", "d"}; !reflect.DeepEqual(l.ids, want) {
+		t.Errorf("Expected %v, got %v", want, l.ids)
+	}
+}
+
+func TestPriorityTiesKeepDefaultOrder(t *testing.T) {
+	l := &runLog{}
+	g := graph.NewGraph("queue")
+	g.Add(graph.NewNode("a", nil, l.fn, graph.WithPriority(1)))
+	g.Add(graph.NewNode("b", nil, l.fn, graph.WithPriority(1)))
+	g.Add(graph.NewNode("c", nil, l.fn))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t), graph.WithMaxConcurrency(1)); err != nil {
+		t.Fatal(err)
+	}
+	if want := []graph.NodeID{"a", "b", "c"}; !reflect.DeepEqual(l.ids, want) {
+		t.Errorf("Expected %v, got %v", want, l.ids)
+	}
+}
+
+func TestPriorityFromDefaults(t *testing.T) {
+	g := graph.NewGraph("queue", graph.WithDefaultNodeOptions(graph.WithPriority(5)))
+	g.Add(graph.NewNode("a", nil, graph.NoOp()))
+	g.Add(graph.NewNode("b", nil, graph.NoOp(), graph.WithPriority(1)))
+
+	if settings := effective(t, g, "a"); settings.Priority != 5 || !reflect.DeepEqual(settings.Inherited, []string{"priority"}) {
+		t.Errorf("Expected a to inherit priority 5, got %+v", settings)
+	}
+	if settings := effective(t, g, "b"); settings.Priority != 1 || len(settings.Inherited) != 0 {
+		t.Errorf("Expected b to keep its own priority, got %+v", settings)
+	}
+}
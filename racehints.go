@@ -0,0 +1,104 @@
+package graph
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Records the resources fns say they touched with ExecutionContext.Touch, and flags in the
+// report's RaceHints every pair of nodes that touched the same one without either being ordered
+// before the other. The hints are advisory; the run is otherwise unchanged.
+func WithRaceHints() ExecOption {
+	return func(c *ExecConfig) {
+		c.RaceHints = true
+	}
+}
+
+// Two nodes touched Key with nothing ordering them, which may mean a missing edge. First sorts
+// before Second.
+type RaceHint struct {
+	Key    string
+	First  NodeID
+	Second NodeID
+}
+
+func (h RaceHint) String() string {
+	return fmt.Sprintf("%s and %s both touched %s with no edge ordering them", h.First, h.Second, h.Key)
+}
+
+// Says the running node used the resource named key, such as "bucket:x". Does nothing unless
+// the run was given WithRaceHints.
+func (ec *ExecutionContext) Touch(key string) {
+	if ec.touches == nil {
+		return
+	}
+	ec.touches.add(key, ec.ID)
+}
+
+// Which nodes touched each key
+type touchLog struct {
+	mu   sync.Mutex
+	keys map[string]NodeIDs
+}
+
+func newTouchLog(cfg ExecConfig) *touchLog {
+	if !cfg.RaceHints {
+		return nil
+	}
+	return &touchLog{keys: make(map[string]NodeIDs)}
+}
+
+func (l *touchLog) add(key string, id NodeID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.keys[key] == nil {
+		l.keys[key] = make(NodeIDs)
+	}
+	l.keys[key][id] = struct{}{}
+}
+
+// Fills in the report's RaceHints, sorted by key and then by node
+func (r *run) raceHints() {
+	if r.touches == nil {
+		return
+	}
+
+	r.touches.mu.Lock()
+	defer r.touches.mu.Unlock()
+
+	// Everything each node waits on, hard or soft
+	upstream := map[NodeID]NodeIDs{}
+	var ancestors func(id NodeID) NodeIDs
+	ancestors = func(id NodeID) NodeIDs {
+		if found, ok := upstream[id]; ok {
+			return found
+		}
+		found := NodeIDs{}
+		upstream[id] = found
+		for depId := range r.peg.nodes[id].dependencies {
+			found[depId] = struct{}{}
+			for a := range ancestors(depId) {
+				found[a] = struct{}{}
+			}
+		}
+		return found
+	}
+	ordered := func(a, b NodeID) bool {
+		_, before := ancestors(b)[a]
+		_, after := ancestors(a)[b]
+		return before || after
+	}
+
+	r.report.RaceHints = []RaceHint{}
+	for _, key := range sortedKeys(r.touches.keys) {
+		ids := sortedIDs(r.touches.keys[key])
+		for i, first := range ids {
+			for _, second := range ids[i+1:] {
+				if !ordered(first, second) {
+					r.report.RaceHints = append(r.report.RaceHints, RaceHint{Key: key, First: first, Second: second})
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package graph_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func touching(keys ...string) graph.NodeFn {
+	return func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		for _, key := range keys {
+			ec.Touch(key)
+		}
+		return nil, nil
+	}
+}
+
+// writer and cleaner both touch bucket:x with nothing between them; reader is ordered after
+// writer, and archiver after reader
+func buckets() *graph.Graph {
+	g := graph.NewGraph("buckets")
+	g.Add(graph.NewNode("writer", nil, touching("bucket:x")))
+	g.Add(graph.NewNode("cleaner", nil, touching("bucket:x")))
+	g.Add(graph.NewNode("reader", graph.Deps("writer"), touching("bucket:y")))
+	g.Add(graph.NewNode("archiver", graph.Deps("reader"), touching("bucket:y", "bucket:z")))
+	return g
+}
+
+func TestRaceHintsFlagUnorderedPair(t *testing.T) {
+	report, err := buckets().CompileToExecutable().Run(ctx(t), graph.WithRaceHints())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []graph.RaceHint{{Key: "bucket:x", First: "cleaner", Second: "writer"}}
+	if !reflect.DeepEqual(report.RaceHints, want) {
+		t.Fatalf("Expected %v, got %v", want, report.RaceHints)
+	}
+	if got, want := report.RaceHints[0].String(), "cleaner and writer both touched bucket:x with no edge ordering them"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestRaceHintsSkipOrderedPairs(t *testing.T) {
+	g := graph.NewGraph("ordered")
+	g.Add(graph.NewNode("writer", nil, touching("bucket:x")))
+	g.Add(graph.NewNode("middle", graph.Deps("writer"), graph.NoOp()))
+	// Ordered only through middle
+	g.Add(graph.NewNode("reader", graph.Deps("middle"), touching("bucket:x")))
+	// A soft edge orders too
+	g.Add(graph.NewNode("auditor", nil, touching("bucket:x"), graph.WithSoftDependency("reader")))
+
+	report, err := g.CompileToExecutable().Run(ctx(t), graph.WithRaceHints())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.RaceHints == nil || len(report.RaceHints) != 0 {
+		t.Errorf("Expected an empty list of hints, got %#v", report.RaceHints)
+	}
+}
+
+func TestRaceHintsOffByDefault(t *testing.T) {
+	report, err := buckets().CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.RaceHints != nil {
+		t.Errorf("Expected Touch to do nothing without WithRaceHints, got %v", report.RaceHints)
+	}
+}
+
+func TestRaceHintsOrderStable(t *testing.T) {
+	g := graph.NewGraph("many")
+	for _, id := range []string{"d", "b", "a", "c"} {
+		g.Add(graph.NewNode(id, nil, touching("queue", "bucket")))
+	}
+	peg := g.CompileToExecutable()
+
+	var first []graph.RaceHint
+	for i := 0; i < 5; i++ {
+		report, err := peg.Run(ctx(t), graph.WithRaceHints())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.RaceHints) != 12 {
+			t.Fatalf("Expected every pair flagged for both keys, got %v", report.RaceHints)
+		}
+		if h := report.RaceHints[0]; h != (graph.RaceHint{Key: "bucket", First: "a", Second: "b"}) {
+			t.Errorf("Expected the hints sorted by key and then node, got %v first", h)
+		}
+		if first == nil {
+			first = report.RaceHints
+		} else if !reflect.DeepEqual(report.RaceHints, first) {
+			t.Errorf("Expected the same hints every run, got %v and %v", first, report.RaceHints)
+		}
+	}
+}
+
+func TestRaceHintsJSON(t *testing.T) {
+	report, err := buckets().CompileToExecutable().Run(ctx(t), graph.WithRaceHints())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded graph.Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.RaceHints, report.RaceHints) {
+		t.Errorf("Expected the hints to survive JSON, got %v", decoded.RaceHints)
+	}
+}
@@ -0,0 +1,151 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// The structural changes made to a graph since it was compiled
+type Diff struct {
+	Added   NodeIDs
+	Removed NodeIDs
+	// Nodes whose dependencies, fn or options changed
+	Changed NodeIDs
+}
+
+func sameAliases(a, b map[NodeID]NodeID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for alias, target := range a {
+		if other, ok := b[alias]; !ok || other != target {
+			return false
+		}
+	}
+	return true
+}
+
+func (peg *ParallelizedExecutableGraph) indexOptional(g *Graph) {
+	peg.optionalRefs = make(map[NodeID]NodeIDs)
+	for id, node := range g.nodes {
+		peg.addOptionalRefs(id, node)
+	}
+}
+
+func (peg *ParallelizedExecutableGraph) addOptionalRefs(id NodeID, node *Node) {
+	for depId := range node.optionalDependencies {
+		refs, ok := peg.optionalRefs[depId]
+		if !ok {
+			refs = make(NodeIDs)
+			peg.optionalRefs[depId] = refs
+		}
+		refs[id] = struct{}{}
+	}
+}
+
+func (peg *ParallelizedExecutableGraph) removeOptionalRefs(id NodeID, exn *executableNode) {
+	for depId := range exn.optionalIDs {
+		refs := peg.optionalRefs[depId]
+		delete(refs, id)
+		if len(refs) == 0 {
+			delete(peg.optionalRefs, depId)
+		}
+	}
+}
+
+// Recompile updates the compiled graph in place to match g, touching only the nodes named in
+// changes and the nodes whose edges they affect. Diff has no way to name an alias, so when g's
+// aliases differ from those compiled every node is reloaded, as a full compile would. It must not
+// be called while the graph is running.
+// Graphs with transforms or compiled with WithNodeFilter can't be recompiled, as either may change
+// any part of the graph; compile them again instead.
+func (peg *ParallelizedExecutableGraph) Recompile(g *Graph, changes Diff) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if len(g.transforms) > 0 {
+		return fmt.Errorf("Graph %s has transforms, so it must be compiled again rather than recompiled", g.name)
+	}
+	if peg.Config().NodeFilter != nil {
+		return fmt.Errorf("Graph %s was compiled with a node filter, so it must be compiled again rather than recompiled", g.name)
+	}
+
+	for id := range changes.Removed {
+		if _, ok := g.nodes[id]; ok {
+			return fmt.Errorf("Node %s was removed but is still in the graph", id)
+		}
+	}
+
+	// Adding or removing a node can bind or unbind optional dependencies elsewhere
+	affected := make(NodeIDs, len(changes.Added)+len(changes.Changed))
+	for _, set := range []NodeIDs{changes.Added, changes.Changed, changes.Removed} {
+		for id := range set {
+			for ref := range peg.optionalRefs[id] {
+				affected[ref] = struct{}{}
+			}
+		}
+	}
+	// Selectors are matched against the whole graph, so any change can alter their edges
+	if len(changes.Added)+len(changes.Changed)+len(changes.Removed) > 0 {
+		for id, node := range g.nodes {
+			if len(node.selectors) > 0 {
+				affected[id] = struct{}{}
+			}
+		}
+	}
+	for _, set := range []NodeIDs{changes.Added, changes.Changed} {
+		for id := range set {
+			if _, ok := g.nodes[id]; !ok {
+				return fmt.Errorf("Node %s does not exist", id)
+			}
+			affected[id] = struct{}{}
+		}
+	}
+	if !sameAliases(peg.aliases, g.aliases) {
+		for id := range g.nodes {
+			affected[id] = struct{}{}
+		}
+	}
+
+	for id := range changes.Removed {
+		exn, ok := peg.nodes[id]
+		if !ok {
+			continue
+		}
+
+		for depId := range exn.dependencies {
+			if dep, ok := peg.nodes[depId]; ok {
+				delete(dep.targetIDs, id)
+			}
+		}
+		delete(peg.nodes, id)
+		peg.removeOptionalRefs(id, exn)
+	}
+
+	for id := range affected {
+		node, ok := g.nodes[id]
+		if !ok {
+			continue
+		}
+
+		exn := peg.nodes.GetOrCreate(id)
+		for depId := range exn.dependencies {
+			if dep, ok := peg.nodes[depId]; ok {
+				delete(dep.targetIDs, id)
+			}
+		}
+
+		peg.removeOptionalRefs(id, exn)
+
+		deps := g.dependencies(node)
+		for depId := range deps {
+			peg.nodes.GetOrCreate(depId).AddTargets(id)
+		}
+		exn.load(node, deps, g.aliased(node), g.edgeWeights(node, deps))
+		peg.addOptionalRefs(id, node)
+	}
+
+	peg.aliases = copyMap(g.aliases)
+	peg.broken = g.broken()
+	peg.source, peg.version, peg.sourceFingerprint = g, g.version, g.fingerprint()
+	return nil
+}
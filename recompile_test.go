@@ -0,0 +1,193 @@
+package graph_test
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Fails unless the two compiled graphs have the same roots, edges, targets and counts
+func assertEquivalent(t *testing.T, step string, got, want *graph.ParallelizedExecutableGraph, ids graph.SortedNodeIDs) {
+	t.Helper()
+
+	if got.Fingerprint() != want.Fingerprint() {
+		t.Fatalf("%s: fingerprints differ\ngot:\n%s\nwant:\n%s", step, got.ToDOT(), want.ToDOT())
+	}
+	if got.ToDOT() != want.ToDOT() {
+		t.Fatalf("%s: compiled views differ\ngot:\n%s\nwant:\n%s", step, got.ToDOT(), want.ToDOT())
+	}
+	if !reflect.DeepEqual(got.Roots(), want.Roots()) {
+		t.Fatalf("%s: roots %v, want %v", step, got.Roots(), want.Roots())
+	}
+	for _, id := range ids {
+		gotTargets, err := got.Targets(id)
+		if err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+		wantTargets, _ := want.Targets(id)
+		if !reflect.DeepEqual(gotTargets, wantTargets) {
+			t.Fatalf("%s: %s targets %v, want %v", step, id, gotTargets, wantTargets)
+		}
+
+		gotCount, _ := got.DependencyCount(id)
+		wantCount, _ := want.DependencyCount(id)
+		if gotCount != wantCount {
+			t.Fatalf("%s: %s requires %d, want %d", step, id, gotCount, wantCount)
+		}
+	}
+}
+
+// Tracks the changes made since the last recompile the way a caller would
+type changeLog struct {
+	diff graph.Diff
+}
+
+func newChangeLog() *changeLog {
+	return &changeLog{diff: graph.Diff{Added: graph.NodeIDs{}, Removed: graph.NodeIDs{}, Changed: graph.NodeIDs{}}}
+}
+
+func (c *changeLog) added(id graph.NodeID) {
+	// Taken out and put back since the last compile, so to the compiled graph it only changed
+	if _, ok := c.diff.Removed[id]; ok {
+		delete(c.diff.Removed, id)
+		c.diff.Changed[id] = struct{}{}
+		return
+	}
+	c.diff.Added[id] = struct{}{}
+}
+
+func (c *changeLog) removed(id graph.NodeID) {
+	delete(c.diff.Changed, id)
+	if _, ok := c.diff.Added[id]; ok {
+		delete(c.diff.Added, id)
+		return
+	}
+	c.diff.Removed[id] = struct{}{}
+}
+
+func (c *changeLog) changed(id graph.NodeID) {
+	if _, ok := c.diff.Added[id]; !ok {
+		c.diff.Changed[id] = struct{}{}
+	}
+}
+
+// Makes one random change: a node added with dependencies, soft and optional ones among them, an
+// edge added, or a node nothing depends on removed. Edges only point at earlier nodes, and
+// optional ones at opt nodes, which have no dependencies of their own and may come later, so the
+// graph stays acyclic.
+func randomChange(t *testing.T, rng *rand.Rand, g *graph.Graph, next *int, log *changeLog) {
+	t.Helper()
+
+	// By id, as Sort's order among unrelated nodes varies, and the seed should replay the same changes
+	ids := mustSort(t, g)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	switch op := rng.Intn(10); {
+	case op < 5 || len(ids) < 2:
+		id := fmt.Sprintf("n%03d", *next)
+		*next++
+
+		deps := graph.NodeIDs{}
+		opts := []graph.NodeOption{}
+		for i := rng.Intn(3); i > 0 && len(ids) > 0; i-- {
+			deps[ids[rng.Intn(len(ids))]] = struct{}{}
+		}
+		if rng.Intn(3) == 0 && len(ids) > 0 {
+			opts = append(opts, graph.WithSoftDependency(ids[rng.Intn(len(ids))]))
+		}
+		if rng.Intn(3) == 0 {
+			opts = append(opts, graph.WithOptionalDependency(graph.NodeID(fmt.Sprintf("opt%d", rng.Intn(4)))))
+		}
+		if _, err := g.Add(graph.NewNode(id, deps, graph.NoOp(), opts...)); err != nil {
+			t.Fatal(err)
+		}
+		log.added(graph.NodeID(id))
+	case op < 6:
+		id := fmt.Sprintf("opt%d", rng.Intn(4))
+		if _, err := g.Add(graph.NewNode(id, nil, graph.NoOp())); err == nil {
+			log.added(graph.NodeID(id))
+		}
+	case op < 8:
+		from, to := ids[rng.Intn(len(ids))], ids[rng.Intn(len(ids))]
+		if from[0] != 'n' || to[0] != 'n' || from <= to {
+			return
+		}
+		kind := graph.EdgeHard
+		if rng.Intn(2) == 0 {
+			kind = graph.EdgeSoft
+		}
+		if err := g.AddEdgeKind(from, to, kind); err == nil {
+			log.changed(from)
+		}
+	default:
+		id := ids[rng.Intn(len(ids))]
+		if err := g.Remove(id); err == nil {
+			log.removed(id)
+		}
+	}
+}
+
+func TestRecompileMatchesFreshCompile(t *testing.T) {
+	for seed := int64(1); seed <= 25; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		g := graph.NewGraph("random")
+		g.Add(graph.NewNode("n000", nil, graph.NoOp()))
+		next := 1
+
+		peg := g.CompileToExecutable()
+		for batch := 0; batch < 20; batch++ {
+			log := newChangeLog()
+			for i := rng.Intn(5) + 1; i > 0; i-- {
+				randomChange(t, rng, g, &next, log)
+			}
+
+			if err := peg.Recompile(g, log.diff); err != nil {
+				t.Fatalf("seed %d batch %d: %v", seed, batch, err)
+			}
+			step := fmt.Sprintf("seed %d batch %d", seed, batch)
+			assertEquivalent(t, step, peg, g.CompileToExecutable(), mustSort(t, g))
+		}
+
+		report, err := peg.Run(ctx(t))
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if n := len(report.WithStatus(graph.StatusSucceeded)); n != len(mustSort(t, g)) {
+			t.Errorf("seed %d: expected every node to run, %d of %d did", seed, n, len(mustSort(t, g)))
+		}
+	}
+}
+
+func TestRecompileRejectsRemovedNodeStillPresent(t *testing.T) {
+	g := diamond(t)
+	peg := g.CompileToExecutable()
+
+	if err := peg.Recompile(g, graph.Diff{Removed: graph.Deps("d")}); err == nil {
+		t.Error("Expected a removed node still in the graph to be rejected")
+	}
+}
+
+func TestRecompileAfterAliasChange(t *testing.T) {
+	g := graph.NewGraph("aliases")
+	g.Add(graph.NewNode("a", nil, graph.NoOp()))
+	g.Add(graph.NewNode("b", nil, graph.NoOp(), graph.WithOptionalDependency("source")))
+	peg := g.CompileToExecutable()
+
+	// Binds b's optional dependency, which no Diff can say
+	if err := g.Alias("source", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if !peg.Stale(g) {
+		t.Fatal("Expected the alias to make the compiled graph stale")
+	}
+	if err := peg.Recompile(g, graph.Diff{}); err != nil {
+		t.Fatal(err)
+	}
+	assertEquivalent(t, "alias added", peg, g.CompileToExecutable(), mustSort(t, g))
+	if count, _ := peg.DependencyCount("b"); count != 1 || peg.Stale(g) {
+		t.Errorf("Expected b to wait on a once recompiled, got %d dependencies", count)
+	}
+}
@@ -0,0 +1,124 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// Rename gives the node old the id new, keeping its settings, and rewrites every reference to
+// old: dependencies of every kind, edge weights, entry points and the targets of aliases, which
+// follow the node rather than blocking the rename. Dependencies declared through an alias are
+// left naming the alias. Edge policies are checked against the renamed node; nothing changes
+// unless the whole rename succeeds.
+func (g *Graph) Rename(old, new NodeID) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	old, new = g.normalize(old), g.normalize(new)
+
+	if g.frozen {
+		return ErrGraphFrozen
+	}
+	if _, ok := g.nodes[old]; !ok {
+		return &NodeNotFoundError{ID: old}
+	}
+	if old == new {
+		return nil
+	}
+	if _, ok := g.nodes[new]; ok {
+		return fmt.Errorf("Node with id %s already exists", new)
+	}
+	if _, ok := g.aliases[new]; ok {
+		return fmt.Errorf("Node %s would collide with an alias of the same id", new)
+	}
+
+	renamed := g.nodes[old].clone()
+	renamed.Name = string(new)
+	staged := Nodes{new: renamed}
+	for id, node := range g.nodes {
+		if id != old && node.references(old) {
+			staged[id] = node.clone()
+			staged[id].renameDependency(old, new)
+		}
+	}
+
+	lookup := func(id NodeID) *Node {
+		if node, ok := staged[id]; ok {
+			return node
+		}
+		if id == old {
+			return nil
+		}
+		return g.lookup(id)
+	}
+	resolved := func(id NodeID) NodeID {
+		if id = g.resolve(id); id == old {
+			return new
+		}
+		return id
+	}
+	for _, id := range sortedIDs(staged) {
+		// Resolved against the graph as it is, so optional dependencies on old, which doesn't
+		// exist under new yet, are still checked
+		current := g.nodes[id]
+		if id == new {
+			current = g.nodes[old]
+		}
+		node := staged[id]
+		deps := NodeIDs{}
+		for depId := range g.dependencies(current) {
+			if depId = resolved(depId); id == new || depId == new {
+				deps[depId] = struct{}{}
+			}
+		}
+		if err := g.checkPolicies(node, deps, lookup); err != nil {
+			return err
+		}
+	}
+
+	g.own()
+	delete(g.nodes, old)
+	delete(g.borrowed, old)
+	for id, node := range staged {
+		g.nodes[id] = node
+		delete(g.borrowed, id)
+	}
+	for alias, target := range g.aliases {
+		if target == old {
+			g.aliases[alias] = new
+		}
+	}
+	if _, ok := g.entryPoints[old]; ok {
+		delete(g.entryPoints, old)
+		g.entryPoints[new] = struct{}{}
+	}
+
+	g.changed()
+	if g.order != nil {
+		return g.rebuildOrder()
+	}
+	return nil
+}
+
+// Whether the node declares a dependency on id, of any kind
+func (n *Node) references(id NodeID) bool {
+	_, hard := n.Dependencies[id]
+	_, optional := n.optionalDependencies[id]
+	return hard || optional
+}
+
+func (n *Node) renameDependency(old, new NodeID) {
+	for _, set := range []NodeIDs{n.Dependencies, n.softDependencies, n.optionalDependencies} {
+		if _, ok := set[old]; ok {
+			delete(set, old)
+			set[new] = struct{}{}
+		}
+	}
+	if count, ok := n.duplicates[old]; ok {
+		delete(n.duplicates, old)
+		n.duplicates[new] = count
+	}
+	if weight, ok := n.weights[old]; ok {
+		delete(n.weights, old)
+		n.weights[new] = weight
+	}
+}
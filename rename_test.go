@@ -0,0 +1,187 @@
+package graph_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// extract -> transform -> load, with report soft on transform and audit optionally on it
+func etlChain(t *testing.T, opts ...graph.GraphOption) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("etl", opts...)
+	g.Add(graph.NewNode("extract", nil, graph.NoOp()))
+	g.Add(graph.NewNode("transform", graph.Deps("extract"), graph.NoOp(), graph.WithMetadata("team", "data")))
+	g.Add(graph.NewNode("load", nil, graph.NoOp()))
+	if err := g.AddEdge("load", "transform", 2); err != nil {
+		t.Fatal(err)
+	}
+	g.Add(graph.NewNode("report", nil, graph.NoOp(), graph.WithSoftDependency("transform")))
+	g.Add(graph.NewNode("audit", nil, graph.NoOp(), graph.WithOptionalDependency("transform")))
+	return g
+}
+
+func TestRenameMidChain(t *testing.T) {
+	g := etlChain(t)
+	if err := g.Rename("transform", "clean"); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Has("transform") || !g.Has("clean") {
+		t.Fatal("Expected only the new id left")
+	}
+	want := []graph.Edge{
+		{From: "audit", To: "clean"},
+		{From: "clean", To: "extract"},
+		{From: "load", To: "clean"},
+		{From: "report", To: "clean"},
+	}
+	if got := g.Edges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected edges %v, got %v", want, got)
+	}
+	ids := mustSort(t, g)
+	if ids.Contains("transform") || !before(ids, "extract", "clean") || !before(ids, "clean", "load") {
+		t.Errorf("Expected the sort to use the new id, got %v", ids)
+	}
+
+	// Settings and edge kinds come along
+	if node := mustGet(t, g, "clean"); node.Metadata["team"] != "data" || node.Identifier() != "clean" {
+		t.Errorf("Expected the node's settings kept, got %v", node.Metadata)
+	}
+	for id, kind := range map[graph.NodeID]graph.EdgeKind{"load": graph.EdgeHard, "report": graph.EdgeSoft} {
+		if got := mustGet(t, g, id).DependencyKind("clean"); got != kind {
+			t.Errorf("Expected %s's edge to stay %v, got %v", id, kind, got)
+		}
+	}
+	var adj bytes.Buffer
+	if err := g.WriteAdjacency(&adj); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(adj.String(), "audit: ?clean\n") {
+		t.Errorf("Expected audit's edge to stay optional, got\n%s", adj.String())
+	}
+	if w, err := g.EdgeWeight("load", "clean"); err != nil || w != 2 {
+		t.Errorf("Expected the weight kept, got %v, %v", w, err)
+	}
+
+	// Its dependents are known by the new id
+	if err := g.Remove("clean"); err == nil || !strings.Contains(err.Error(), "Node clean is a dependency of") {
+		t.Errorf("Expected the dependents to block removing clean, got %v", err)
+	}
+	if err := g.Remove("transform"); err == nil || err.Error() != "Node transform does not exist" {
+		t.Errorf("Expected the old id gone, got %v", err)
+	}
+}
+
+func TestRenameRuns(t *testing.T) {
+	g := etlChain(t)
+	g.Rename("transform", "clean")
+
+	l := &runLog{}
+	for _, id := range mustSort(t, g) {
+		node := mustGet(t, g, id)
+		node.Fn = l.fn
+		g.Remove(id)
+		g.Add(node)
+	}
+	report, err := g.CompileToExecutable().Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := report.Nodes["clean"]; !ok || len(report.Nodes) != 5 {
+		t.Errorf("Expected the run to report clean, got %v", report.Nodes)
+	}
+}
+
+func TestRenameFollowsAliasesAndEntryPoints(t *testing.T) {
+	g := etlChain(t)
+	g.Alias("tx", "transform")
+	g.Add(graph.NewNode("notify", graph.Deps("tx"), graph.NoOp()))
+	g.MarkEntryPoint("transform")
+
+	if err := g.Rename("transform", "clean"); err != nil {
+		t.Fatal(err)
+	}
+	if got := g.ResolveAlias("tx"); got != "clean" {
+		t.Errorf("Expected the alias to follow the node, got %s", got)
+	}
+	if !mustGet(t, g, "notify").Dependencies.Has("tx") {
+		t.Error("Expected a dependency through the alias left naming the alias")
+	}
+	if deps, _ := g.ResolvedDependencies("notify"); !deps.Equal(graph.Deps("clean")) {
+		t.Errorf("Expected notify to resolve to clean, got %v", deps)
+	}
+	if got := g.EntryPoints(); !reflect.DeepEqual(got, graph.SortedNodeIDs{"clean"}) {
+		t.Errorf("Expected the entry point renamed, got %v", got)
+	}
+}
+
+func TestRenameErrors(t *testing.T) {
+	g := etlChain(t)
+	g.Alias("tx", "transform")
+	fingerprint := g.Fingerprint()
+
+	for _, tc := range []struct {
+		old, new graph.NodeID
+		err      string
+	}{
+		{"transform", "load", "Node with id load already exists"},
+		{"transform", "tx", "Node tx would collide with an alias of the same id"},
+	} {
+		if err := g.Rename(tc.old, tc.new); err == nil || err.Error() != tc.err {
+			t.Errorf("Renaming %s to %s: expected %q, got %v", tc.old, tc.new, tc.err, err)
+		}
+	}
+	var nerr *graph.NodeNotFoundError
+	if err := g.Rename("missing", "clean"); !errors.As(err, &nerr) || nerr.ID != "missing" {
+		t.Errorf("Expected a NodeNotFoundError for missing, got %v", err)
+	}
+	if err := g.Rename("transform", "transform"); err != nil {
+		t.Errorf("Expected renaming to the same id to do nothing, got %v", err)
+	}
+	if g.Fingerprint() != fingerprint {
+		t.Error("Expected the failed renames to leave the graph alone")
+	}
+
+	g.Freeze()
+	if err := g.Rename("transform", "clean"); !errors.Is(err, graph.ErrGraphFrozen) {
+		t.Errorf("Expected a frozen graph to refuse, got %v", err)
+	}
+}
+
+func TestRenameChecksPolicies(t *testing.T) {
+	noBeta := func(from, to *graph.Node) error {
+		if strings.HasPrefix(string(to.Identifier()), "beta-") {
+			return errors.New("nothing may depend on beta nodes")
+		}
+		return nil
+	}
+	g := etlChain(t, graph.WithEdgePolicy(noBeta))
+	fingerprint := g.Fingerprint()
+
+	// audit's optional edge is checked too, and comes first
+	assertViolation(t, g.Rename("transform", "beta-transform"), "audit", "beta-transform")
+	if g.Fingerprint() != fingerprint || !g.Has("transform") {
+		t.Error("Expected the rejected rename to leave the graph alone")
+	}
+}
+
+func TestRenameKeepsIncrementalOrder(t *testing.T) {
+	g := etlChain(t, graph.WithIncrementalCycleCheck())
+	if err := g.Rename("transform", "clean"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddEdge("extract", "load"); err == nil {
+		t.Error("Expected the cycle through the renamed node caught")
+	}
+	ids := mustSort(t, g)
+	if !before(ids, "clean", "load") || !before(ids, "extract", "clean") {
+		t.Errorf("Expected the order rebuilt with the new id, got %v", ids)
+	}
+}
@@ -0,0 +1,175 @@
+package graph
+
+import (
+	"fmt"
+	"time"
+)
+
+type NodeStatus int
+
+const (
+	StatusPending NodeStatus = iota
+	StatusRunning
+	StatusSucceeded
+	StatusFailed
+	StatusTimedOut
+	StatusSkipped
+	StatusCanceled
+	StatusNotRun
+	// Failed or timed out, but the node was given AllowFailure
+	StatusFailedAllowed
+	// An approval node parked until it's decided on
+	StatusAwaitingApproval
+)
+
+var statusNames = map[NodeStatus]string{
+	StatusPending:          "Pending",
+	StatusRunning:          "Running",
+	StatusSucceeded:        "Succeeded",
+	StatusFailed:           "Failed",
+	StatusTimedOut:         "TimedOut",
+	StatusSkipped:          "Skipped",
+	StatusCanceled:         "Canceled",
+	StatusNotRun:           "NotRun",
+	StatusFailedAllowed:    "FailedAllowed",
+	StatusAwaitingApproval: "AwaitingApproval",
+}
+
+func (s NodeStatus) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("NodeStatus(%d)", int(s))
+}
+
+// Why a node ended up Skipped (or NotRun, or Succeeded without running)
+type StatusReason string
+
+const (
+	ReasonTimeout StatusReason = "timeout"
+	// TimedOut because the whole run hit its WithRunTimeout
+	ReasonRunTimeout     StatusReason = "run-timeout"
+	ReasonUpstreamFailed StatusReason = "upstream-failed"
+	ReasonNoProgress     StatusReason = "no-progress"
+	// Succeeded without running because the run was given its result
+	ReasonPrecompleted StatusReason = "precompleted"
+	// Only precompleted nodes depended on it
+	ReasonNotNeeded StatusReason = "not-needed"
+	// Canceled because the run was aborted
+	ReasonAborted StatusReason = "aborted"
+	// NotRun because the run reached its soft deadline first
+	ReasonDeadlineReached StatusReason = "deadline-reached"
+	// Succeeded without running because the node is a marker
+	ReasonMarker StatusReason = "marker"
+	// Succeeded in the run a state was resumed from, but its result couldn't be saved
+	ReasonResultUnavailable StatusReason = "result-unavailable"
+	// Skipped because its feature flag was off
+	ReasonFlagDisabled StatusReason = "flag-disabled"
+	// Skipped because its WithCondition said not to run
+	ReasonConditionFalse StatusReason = "condition-false"
+	// How an approval node was decided on
+	ReasonApproved        StatusReason = "approved"
+	ReasonRejected        StatusReason = "rejected"
+	ReasonApprovalTimeout StatusReason = "approval-timeout"
+)
+
+type AttemptReport struct {
+	// Counting from 1
+	Attempt int
+	Status  NodeStatus
+	Reason  StatusReason
+	// Empty unless the attempt failed
+	Category ErrorCategory
+	Err      error
+	Start    time.Time
+	End      time.Time
+}
+
+func (a AttemptReport) Duration() time.Duration {
+	return a.End.Sub(a.Start)
+}
+
+// The node's Status, Reason, Category and Err are those of its last attempt
+type NodeReport struct {
+	ID       NodeID
+	Status   NodeStatus
+	Reason   StatusReason
+	Category ErrorCategory
+	Err      error
+	Start    time.Time
+	End      time.Time
+	// The fn variant that ran; empty for the default
+	Variant string
+	// Every attempt in order; empty for nodes that never ran
+	Attempts []AttemptReport
+	// What the node's OnCancel cleanup returned; never changes Status
+	CleanupErr error
+	// Elapsed time at the last slow-node warning; zero if none fired
+	SlowElapsed time.Duration
+	// When every dependency had settled, and when the node got a slot to run, by the run's clock;
+	// zero for nodes that never got that far
+	ReadyAt      time.Time
+	DispatchedAt time.Time
+	// Time from the run's start until ReadyAt, waiting on dependencies, and from ReadyAt until
+	// Start less StartDelay, waiting for a concurrency slot or budget
+	ReadyWait time.Duration
+	QueueWait time.Duration
+	// Time spent waiting out WithStartDelay and the start jitter before Start
+	StartDelay time.Duration
+	// The random part of StartDelay
+	StartJitter time.Duration
+	// Captured output, when the run enabled it
+	Output          string
+	OutputTruncated bool
+}
+
+func (nr NodeReport) Duration() time.Duration {
+	return nr.End.Sub(nr.Start)
+}
+
+type Report struct {
+	Graph string
+	RunID string
+	// The run's WithRunConfig settings
+	Config map[string]string
+	Start  time.Time
+	End    time.Time
+	Nodes  map[NodeID]*NodeReport
+	// Sum of the durations of the nodes that ran
+	TotalWork time.Duration
+	// The longest chain of dependent nodes by measured duration, in run order
+	CriticalPath         []NodeID
+	CriticalPathDuration time.Duration
+	// In order, when the run kept an event log
+	Events []Event
+	// Events past the log's limit
+	EventsDropped int
+	// The run stopped at its soft deadline with nodes left to run
+	Incomplete bool
+	// Why the run was aborted; empty unless it was
+	AbortReason string
+	// The feature flags the run asked its provider about, and the answers
+	Flags map[string]bool
+	// Results of the succeeded nodes given KeepResult; not written to JSON
+	Results Results
+	// Nodes that touched the same resource unordered, when the run looked for them
+	RaceHints []RaceHint
+}
+
+func (r *Report) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// Nodes in the given status, sorted by id
+func (r *Report) WithStatus(status NodeStatus) SortedNodeIDs {
+	ids := SortedNodeIDs{}
+
+	for id, nr := range r.Nodes {
+		if nr.Status == status {
+			ids = append(ids, id)
+		}
+	}
+
+	sortIDs(ids)
+	return ids
+}
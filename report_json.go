@@ -0,0 +1,360 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const ReportSchemaVersion = 1
+
+func (s NodeStatus) MarshalText() ([]byte, error) {
+	if _, ok := statusNames[s]; !ok {
+		return nil, fmt.Errorf("Unknown node status %d", int(s))
+	}
+	return []byte(s.String()), nil
+}
+
+func (s *NodeStatus) UnmarshalText(text []byte) error {
+	for status, name := range statusNames {
+		if name == string(text) {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown node status %q", text)
+}
+
+type reportJSON struct {
+	SchemaVersion        int               `json:"schemaVersion"`
+	Graph                string            `json:"graph"`
+	RunID                string            `json:"runId"`
+	Config               map[string]string `json:"config,omitempty"`
+	Start                time.Time         `json:"start"`
+	End                  time.Time         `json:"end"`
+	Nodes                []nodeReportJSON  `json:"nodes"`
+	TotalWork            time.Duration     `json:"totalWorkNs,omitempty"`
+	CriticalPath         []NodeID          `json:"criticalPath,omitempty"`
+	CriticalPathDuration time.Duration     `json:"criticalPathNs,omitempty"`
+	Events               []eventJSON       `json:"events,omitempty"`
+	EventsDropped        int               `json:"eventsDropped,omitempty"`
+	Incomplete           bool              `json:"incomplete,omitempty"`
+	AbortReason          string            `json:"abortReason,omitempty"`
+	Flags                map[string]bool   `json:"flags,omitempty"`
+	RaceHints            []raceHintJSON    `json:"raceHints,omitempty"`
+}
+
+type raceHintJSON struct {
+	Key    string `json:"key"`
+	First  NodeID `json:"first"`
+	Second NodeID `json:"second"`
+}
+
+type eventJSON struct {
+	Seq     uint64       `json:"seq"`
+	Time    time.Time    `json:"time"`
+	Kind    EventKind    `json:"kind"`
+	Node    NodeID       `json:"node,omitempty"`
+	Attempt int          `json:"attempt,omitempty"`
+	Status  NodeStatus   `json:"status"`
+	Reason  StatusReason `json:"reason,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Token   string       `json:"token,omitempty"`
+}
+
+type nodeReportJSON struct {
+	ID              NodeID              `json:"id"`
+	Status          NodeStatus          `json:"status"`
+	Reason          StatusReason        `json:"reason,omitempty"`
+	Category        ErrorCategory       `json:"category,omitempty"`
+	Variant         string              `json:"variant,omitempty"`
+	Error           string              `json:"error,omitempty"`
+	Start           time.Time           `json:"start"`
+	End             time.Time           `json:"end"`
+	Attempts        []attemptReportJSON `json:"attempts,omitempty"`
+	CleanupError    string              `json:"cleanupError,omitempty"`
+	SlowElapsed     time.Duration       `json:"slowElapsedNs,omitempty"`
+	StartDelay      time.Duration       `json:"startDelayNs,omitempty"`
+	StartJitter     time.Duration       `json:"startJitterNs,omitempty"`
+	ReadyAt         *time.Time          `json:"readyAt,omitempty"`
+	DispatchedAt    *time.Time          `json:"dispatchedAt,omitempty"`
+	ReadyWait       time.Duration       `json:"readyWaitNs,omitempty"`
+	QueueWait       time.Duration       `json:"queueWaitNs,omitempty"`
+	Output          string              `json:"output,omitempty"`
+	OutputTruncated bool                `json:"outputTruncated,omitempty"`
+}
+
+type attemptReportJSON struct {
+	Attempt  int           `json:"attempt"`
+	Status   NodeStatus    `json:"status"`
+	Reason   StatusReason  `json:"reason,omitempty"`
+	Category ErrorCategory `json:"category,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func stringError(s string) error {
+	if s == "" {
+		return nil
+	}
+	return errors.New(s)
+}
+
+func (r *Report) MarshalJSON() ([]byte, error) {
+	out := reportJSON{
+		SchemaVersion:        ReportSchemaVersion,
+		Graph:                r.Graph,
+		RunID:                r.RunID,
+		Config:               r.Config,
+		Start:                r.Start,
+		End:                  r.End,
+		Nodes:                make([]nodeReportJSON, 0, len(r.Nodes)),
+		TotalWork:            r.TotalWork,
+		CriticalPath:         r.CriticalPath,
+		CriticalPathDuration: r.CriticalPathDuration,
+		EventsDropped:        r.EventsDropped,
+		Incomplete:           r.Incomplete,
+		AbortReason:          r.AbortReason,
+		Flags:                r.Flags,
+	}
+	for _, h := range r.RaceHints {
+		out.RaceHints = append(out.RaceHints, raceHintJSON(h))
+	}
+	for _, e := range r.Events {
+		out.Events = append(out.Events, eventJSON{
+			Seq:     e.Seq,
+			Time:    e.Time,
+			Kind:    e.Kind,
+			Node:    e.Node,
+			Attempt: e.Attempt,
+			Status:  e.Status,
+			Reason:  e.Reason,
+			Error:   errorString(e.Err),
+			Token:   e.Token,
+		})
+	}
+
+	for _, id := range sortedIDs(r.Nodes) {
+		nr := r.Nodes[id]
+		node := nodeReportJSON{
+			ID:              id,
+			Status:          nr.Status,
+			Reason:          nr.Reason,
+			Category:        nr.Category,
+			Variant:         nr.Variant,
+			Start:           nr.Start,
+			End:             nr.End,
+			Error:           errorString(nr.Err),
+			CleanupError:    errorString(nr.CleanupErr),
+			SlowElapsed:     nr.SlowElapsed,
+			StartDelay:      nr.StartDelay,
+			StartJitter:     nr.StartJitter,
+			ReadyAt:         optionalTime(nr.ReadyAt),
+			DispatchedAt:    optionalTime(nr.DispatchedAt),
+			ReadyWait:       nr.ReadyWait,
+			QueueWait:       nr.QueueWait,
+			Output:          nr.Output,
+			OutputTruncated: nr.OutputTruncated,
+		}
+		for _, a := range nr.Attempts {
+			node.Attempts = append(node.Attempts, attemptReportJSON{
+				Attempt:  a.Attempt,
+				Status:   a.Status,
+				Reason:   a.Reason,
+				Category: a.Category,
+				Error:    errorString(a.Err),
+				Start:    a.Start,
+				End:      a.End,
+			})
+		}
+		out.Nodes = append(out.Nodes, node)
+	}
+
+	return json.Marshal(out)
+}
+
+// Errors come back as plain errors carrying the original message
+func (r *Report) UnmarshalJSON(data []byte) error {
+	var in reportJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	if in.SchemaVersion > ReportSchemaVersion {
+		return fmt.Errorf("Report schema version %d is newer than the supported version %d", in.SchemaVersion, ReportSchemaVersion)
+	}
+
+	*r = Report{
+		Graph:                in.Graph,
+		RunID:                in.RunID,
+		Config:               in.Config,
+		Start:                in.Start,
+		End:                  in.End,
+		Nodes:                make(map[NodeID]*NodeReport, len(in.Nodes)),
+		TotalWork:            in.TotalWork,
+		CriticalPath:         in.CriticalPath,
+		CriticalPathDuration: in.CriticalPathDuration,
+		EventsDropped:        in.EventsDropped,
+		Incomplete:           in.Incomplete,
+		AbortReason:          in.AbortReason,
+		Flags:                in.Flags,
+	}
+	for _, h := range in.RaceHints {
+		r.RaceHints = append(r.RaceHints, RaceHint(h))
+	}
+	for _, e := range in.Events {
+		r.Events = append(r.Events, Event{
+			Seq:     e.Seq,
+			Time:    e.Time,
+			Kind:    e.Kind,
+			Node:    e.Node,
+			Attempt: e.Attempt,
+			Status:  e.Status,
+			Reason:  e.Reason,
+			Err:     stringError(e.Error),
+			Token:   e.Token,
+		})
+	}
+	for _, node := range in.Nodes {
+		if _, ok := r.Nodes[node.ID]; ok {
+			return fmt.Errorf("Report lists node %s more than once", node.ID)
+		}
+
+		nr := &NodeReport{
+			ID:              node.ID,
+			Status:          node.Status,
+			Reason:          node.Reason,
+			Category:        node.Category,
+			Variant:         node.Variant,
+			Start:           node.Start,
+			End:             node.End,
+			Err:             stringError(node.Error),
+			CleanupErr:      stringError(node.CleanupError),
+			SlowElapsed:     node.SlowElapsed,
+			StartDelay:      node.StartDelay,
+			StartJitter:     node.StartJitter,
+			ReadyWait:       node.ReadyWait,
+			QueueWait:       node.QueueWait,
+			Output:          node.Output,
+			OutputTruncated: node.OutputTruncated,
+		}
+		if node.ReadyAt != nil {
+			nr.ReadyAt = *node.ReadyAt
+		}
+		if node.DispatchedAt != nil {
+			nr.DispatchedAt = *node.DispatchedAt
+		}
+		for _, a := range node.Attempts {
+			nr.Attempts = append(nr.Attempts, AttemptReport{
+				Attempt:  a.Attempt,
+				Status:   a.Status,
+				Reason:   a.Reason,
+				Category: a.Category,
+				Err:      stringError(a.Error),
+				Start:    a.Start,
+				End:      a.End,
+			})
+		}
+		r.Nodes[node.ID] = nr
+	}
+
+	return nil
+}
+
+func LoadReport(data []byte) (*Report, error) {
+	r := &Report{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type NodeComparison struct {
+	ID             NodeID
+	PreviousStatus NodeStatus
+	Status         NodeStatus
+	// Current minus previous duration
+	DurationDelta time.Duration
+}
+
+func (nc NodeComparison) StatusChanged() bool {
+	return nc.PreviousStatus != nc.Status
+}
+
+// A node that succeeded before and now failed or timed out
+func (nc NodeComparison) Regressed() bool {
+	return nc.PreviousStatus == StatusSucceeded && (nc.Status == StatusFailed || nc.Status == StatusTimedOut)
+}
+
+type ReportComparison struct {
+	// In the current report only
+	Added SortedNodeIDs
+	// In the previous report only
+	Removed SortedNodeIDs
+	// Nodes in both, sorted by id
+	Nodes []NodeComparison
+}
+
+func (c *ReportComparison) Regressions() []NodeComparison {
+	regressions := []NodeComparison{}
+	for _, nc := range c.Nodes {
+		if nc.Regressed() {
+			regressions = append(regressions, nc)
+		}
+	}
+	return regressions
+}
+
+// Nodes whose duration grew by more than threshold
+func (c *ReportComparison) Slower(threshold time.Duration) []NodeComparison {
+	slower := []NodeComparison{}
+	for _, nc := range c.Nodes {
+		if nc.DurationDelta > threshold {
+			slower = append(slower, nc)
+		}
+	}
+	return slower
+}
+
+// Compare describes how this report differs from previous, which may be nil
+func (r *Report) Compare(previous *Report) *ReportComparison {
+	c := &ReportComparison{Added: SortedNodeIDs{}, Removed: SortedNodeIDs{}, Nodes: []NodeComparison{}}
+
+	prevNodes := map[NodeID]*NodeReport{}
+	if previous != nil {
+		prevNodes = previous.Nodes
+	}
+
+	for _, id := range sortedIDs(r.Nodes) {
+		cur := r.Nodes[id]
+		prev, ok := prevNodes[id]
+		if !ok {
+			c.Added = append(c.Added, id)
+			continue
+		}
+
+		c.Nodes = append(c.Nodes, NodeComparison{
+			ID:             id,
+			PreviousStatus: prev.Status,
+			Status:         cur.Status,
+			DurationDelta:  cur.Duration() - prev.Duration(),
+		})
+	}
+
+	for _, id := range sortedIDs(prevNodes) {
+		if _, ok := r.Nodes[id]; !ok {
+			c.Removed = append(c.Removed, id)
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,96 @@
+package graph_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+var reportStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func syntheticNode(id graph.NodeID, status graph.NodeStatus, took time.Duration) *graph.NodeReport {
+	return &graph.NodeReport{ID: id, Status: status, Start: reportStart, End: reportStart.Add(took)}
+}
+
+func syntheticReport(nodes ...*graph.NodeReport) *graph.Report {
+	r := &graph.Report{Graph: "nightly", RunID: "run", Start: reportStart, End: reportStart.Add(time.Minute), Nodes: map[graph.NodeID]*graph.NodeReport{}}
+	for _, n := range nodes {
+		r.Nodes[n.ID] = n
+	}
+	return r
+}
+
+func TestCompareFlagsRegression(t *testing.T) {
+	previous := syntheticReport(
+		syntheticNode("extract", graph.StatusSucceeded, 10*time.Second),
+		syntheticNode("load", graph.StatusSucceeded, 5*time.Second),
+		syntheticNode("retired", graph.StatusSucceeded, time.Second),
+	)
+	current := syntheticReport(
+		syntheticNode("extract", graph.StatusSucceeded, 40*time.Second),
+		syntheticNode("load", graph.StatusFailed, 5*time.Second),
+		syntheticNode("new", graph.StatusSucceeded, time.Second),
+	)
+
+	c := current.Compare(previous)
+	if !reflect.DeepEqual(c.Added, graph.SortedNodeIDs{"new"}) || !reflect.DeepEqual(c.Removed, graph.SortedNodeIDs{"retired"}) {
+		t.Errorf("Expected new added and retired removed, got %v and %v", c.Added, c.Removed)
+	}
+
+	regressions := c.Regressions()
+	if len(regressions) != 1 || regressions[0].ID != "load" || regressions[0].PreviousStatus != graph.StatusSucceeded {
+		t.Errorf("Expected load's Succeeded->Failed to be flagged, got %+v", regressions)
+	}
+
+	slower := c.Slower(10 * time.Second)
+	if len(slower) != 1 || slower[0].ID != "extract" || slower[0].DurationDelta != 30*time.Second {
+		t.Errorf("Expected extract to be 30s slower, got %+v", slower)
+	}
+}
+
+func TestCompareWithoutPrevious(t *testing.T) {
+	current := syntheticReport(syntheticNode("a", graph.StatusSucceeded, time.Second))
+
+	c := current.Compare(nil)
+	if !reflect.DeepEqual(c.Added, graph.SortedNodeIDs{"a"}) || len(c.Nodes) != 0 || len(c.Regressions()) != 0 {
+		t.Errorf("Expected every node to be new, got %+v", c)
+	}
+}
+
+func TestReportJSONRoundTrip(t *testing.T) {
+	failed := syntheticNode("load", graph.StatusFailed, 5*time.Second)
+	failed.Err = errors.New("connection refused")
+	report := syntheticReport(syntheticNode("extract", graph.StatusSucceeded, 10*time.Second), failed)
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "report.json", string(data)+"\n")
+
+	loaded, err := graph.LoadReport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Nodes["load"].Status != graph.StatusFailed || loaded.Nodes["load"].Err.Error() != "connection refused" {
+		t.Errorf("Expected the failure to survive, got %+v", loaded.Nodes["load"])
+	}
+	if loaded.Nodes["extract"].Duration() != 10*time.Second || loaded.Duration() != time.Minute {
+		t.Error("Expected the timings to survive")
+	}
+	if c := loaded.Compare(report); len(c.Added)+len(c.Removed)+len(c.Regressions()) != 0 {
+		t.Errorf("Expected the loaded report to compare equal, got %+v", c)
+	}
+}
+
+func TestLoadReportRejectsNewerSchema(t *testing.T) {
+	_, err := graph.LoadReport([]byte(`{"schemaVersion": 99, "graph": "g"}`))
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Expected a newer schema to be refused, got %v", err)
+	}
+}
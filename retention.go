@@ -0,0 +1,124 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// How long a run holds on to the results of the nodes that succeeded
+type ResultRetention int
+
+const (
+	// Until the run ends
+	RetainAllResults ResultRetention = iota
+	// Until every dependent of the node has settled
+	ReleaseConsumedResults
+	// In the store given to WithResultRetention, with dependents handed a ResultRef to load each
+	// one with. A result the store can't take fails its node.
+	ExternalResults
+)
+
+// ExternalResults needs a store; without one results stay in memory as under RetainAllResults
+func WithResultRetention(policy ResultRetention, store ...ResultStore) ExecOption {
+	return func(c *ExecConfig) {
+		c.ResultRetention = policy
+		if len(store) > 0 {
+			c.ResultStore = store[0]
+		}
+	}
+}
+
+// Keeps results outside the run, such as on disk, keyed by run id and node. Both methods are
+// called from the goroutines running the nodes, so they must be safe for concurrent use.
+type ResultStore interface {
+	Put(runID string, id NodeID, value any) error
+	Get(runID string, id NodeID) (any, error)
+}
+
+// Stands in for a result kept in a ResultStore
+type ResultRef struct {
+	RunID string
+	ID    NodeID
+	store ResultStore
+}
+
+func (ref ResultRef) Load() (any, error) {
+	return ref.store.Get(ref.RunID, ref.ID)
+}
+
+// The stored value, so SnapshotState saves the result rather than the reference
+func (ref ResultRef) MarshalJSON() ([]byte, error) {
+	value, err := ref.Load()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// Keeps the node's result in memory and in the report's Results whatever the run's retention
+// policy
+func KeepResult() NodeOption {
+	return func(n *Node) {
+		n.keepResult = true
+	}
+}
+
+// Puts value in the run's store under ExternalResults and returns what dependents get instead
+func (r *run) storeResult(id NodeID, node *executableNode, value any) (any, error) {
+	if r.cfg.ResultRetention != ExternalResults || r.cfg.ResultStore == nil || node.keepResult {
+		return value, nil
+	}
+	if err := r.cfg.ResultStore.Put(r.report.RunID, id, value); err != nil {
+		return nil, fmt.Errorf("Storing result: %w", err)
+	}
+	return ResultRef{RunID: r.report.RunID, ID: id, store: r.cfg.ResultStore}, nil
+}
+
+// Holds a succeeded node's result for its dependents
+func (r *run) retain(id NodeID, value any) {
+	node := r.peg.nodes[id]
+	if node.keepResult {
+		if r.report.Results == nil {
+			r.report.Results = make(Results)
+		}
+		r.report.Results[id] = value
+	}
+
+	r.results[id] = value
+	if r.cfg.ResultRetention != ReleaseConsumedResults || node.keepResult {
+		return
+	}
+
+	waiting := 0
+	for target := range node.targetIDs {
+		if r.report.Nodes[target].Status == StatusPending {
+			waiting++
+		}
+	}
+	if waiting == 0 {
+		delete(r.results, id)
+		return
+	}
+	if r.consumers == nil {
+		r.consumers = make(map[NodeID]int)
+	}
+	r.consumers[id] = waiting
+}
+
+// Releases the results id was the last dependent still waiting on
+func (r *run) consumed(id NodeID) {
+	if len(r.consumers) == 0 {
+		return
+	}
+
+	for depId := range r.peg.nodes[id].dependencies {
+		if _, ok := r.consumers[depId]; !ok {
+			continue
+		}
+		r.consumers[depId]--
+		if r.consumers[depId] == 0 {
+			delete(r.consumers, depId)
+			delete(r.results, depId)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+type dataset struct {
+	rows []byte
+}
+
+// Returns a dataset that closes freed once it's garbage collected
+func parsed(freed chan struct{}) graph.NodeFn {
+	return func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		d := &dataset{rows: make([]byte, 1<<20)}
+		runtime.SetFinalizer(d, func(*dataset) { close(freed) })
+		return d, nil
+	}
+}
+
+func consumes(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+	d, ok := graph.ResultAs[*dataset](ec, "parse")
+	if !ok {
+		return nil, errors.New("no dataset")
+	}
+	return len(d.rows), nil
+}
+
+// Collects garbage until freed closes or within runs out
+func collected(freed chan struct{}, within time.Duration) bool {
+	deadline := time.Now().Add(within)
+	for time.Now().Before(deadline) {
+		runtime.GC()
+		select {
+		case <-freed:
+			return true
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	return false
+}
+
+// parse feeds consume; check runs after consume and reports whether parse's result was freed
+func consumedGraph(freed chan struct{}, within time.Duration, seen *bool) *graph.Graph {
+	g := graph.NewGraph("retention")
+	g.Add(graph.NewNode("parse", nil, parsed(freed)))
+	g.Add(graph.NewNode("consume", graph.Deps("parse"), consumes))
+	g.Add(graph.NewNode("check", graph.Deps("consume"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		*seen = collected(freed, within)
+		return nil, nil
+	}))
+	return g
+}
+
+func TestReleaseConsumedResults(t *testing.T) {
+	freed, released := make(chan struct{}), false
+	report, err := consumedGraph(freed, 5*time.Second, &released).CompileToExecutable().Run(ctx(t), graph.WithResultRetention(graph.ReleaseConsumedResults))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !released {
+		t.Error("Expected parse's result released once consume finished")
+	}
+	if nr := report.Nodes["consume"]; nr.Status != graph.StatusSucceeded {
+		t.Errorf("Expected consume to get the dataset, got %s: %v", nr.Status, nr.Err)
+	}
+}
+
+func TestRetainAllResultsByDefault(t *testing.T) {
+	freed, released := make(chan struct{}), false
+	peg := consumedGraph(freed, 200*time.Millisecond, &released).CompileToExecutable()
+	if _, err := peg.Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+	if released {
+		t.Error("Expected parse's result held until the run ended")
+	}
+}
+
+func TestReleaseWaitsForEveryDependent(t *testing.T) {
+	freed := make(chan struct{})
+	slow := make(chan struct{})
+	var early, late bool
+
+	g := graph.NewGraph("retention")
+	g.Add(graph.NewNode("parse", nil, parsed(freed)))
+	g.Add(graph.NewNode("fast", graph.Deps("parse"), consumes))
+	g.Add(graph.NewNode("slow", graph.Deps("parse"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		<-slow
+		return consumes(ctx, ec)
+	}))
+	g.Add(graph.NewNode("after-fast", graph.Deps("fast"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		early = collected(freed, 200*time.Millisecond)
+		close(slow)
+		return nil, nil
+	}))
+	g.Add(graph.NewNode("after-slow", graph.Deps("slow"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		late = collected(freed, 5*time.Second)
+		return nil, nil
+	}))
+
+	report, err := g.CompileToExecutable().Run(ctx(t), graph.WithResultRetention(graph.ReleaseConsumedResults))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if early {
+		t.Error("Expected the result held while slow still needed it")
+	}
+	if !late {
+		t.Error("Expected the result released after slow finished")
+	}
+	if nr := report.Nodes["slow"]; nr.Status != graph.StatusSucceeded {
+		t.Errorf("Expected slow to get the dataset, got %s: %v", nr.Status, nr.Err)
+	}
+}
+
+func TestKeepResultSurvivesRelease(t *testing.T) {
+	g := graph.NewGraph("retention")
+	g.Add(graph.NewNode("summary", nil, returns("12 rows"), graph.KeepResult()))
+	g.Add(graph.NewNode("rows", nil, returns(12)))
+	g.Add(graph.NewNode("publish", graph.Deps("summary", "rows"), graph.NoOp()))
+
+	report, err := g.CompileToExecutable().Run(ctx(t), graph.WithResultRetention(graph.ReleaseConsumedResults))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := report.Results["summary"]; got != "12 rows" {
+		t.Errorf("Expected the kept result in the report, got %v", got)
+	}
+	if _, ok := report.Results["rows"]; ok {
+		t.Error("Expected only the kept result in the report")
+	}
+}
+
+// Keeps results in memory, counting the calls
+type memStore struct {
+	mu    sync.Mutex
+	items map[string]any
+	gets  int
+	fail  error
+}
+
+func (s *memStore) Put(runID string, id graph.NodeID, value any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		return s.fail
+	}
+	if s.items == nil {
+		s.items = map[string]any{}
+	}
+	s.items[runID+"/"+string(id)] = value
+	return nil
+}
+
+func (s *memStore) Get(runID string, id graph.NodeID) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gets++
+	value, ok := s.items[runID+"/"+string(id)]
+	if !ok {
+		return nil, fmt.Errorf("No result for %s", id)
+	}
+	return value, nil
+}
+
+func TestExternalResults(t *testing.T) {
+	store := &memStore{}
+	var ref graph.ResultRef
+	var loaded string
+
+	g := graph.NewGraph("retention")
+	g.Add(graph.NewNode("extract", nil, returns("rows")))
+	g.Add(graph.NewNode("load", graph.Deps("extract"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		ref, _ = graph.ResultAs[graph.ResultRef](ec, "extract")
+		loaded, _ = graph.ResultAs[string](ec, "extract")
+		return nil, nil
+	}))
+
+	report, err := g.CompileToExecutable().Run(ctx(t), graph.WithResultRetention(graph.ExternalResults, store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.ID != "extract" || ref.RunID != report.RunID {
+		t.Errorf("Expected a reference to extract in run %s, got %+v", report.RunID, ref)
+	}
+	if loaded != "rows" || store.gets != 1 {
+		t.Errorf("Expected ResultAs to load rows from the store once, got %q after %d gets", loaded, store.gets)
+	}
+	if got := store.items[report.RunID+"/extract"]; got != "rows" {
+		t.Errorf("Expected the result put in the store, got %v", got)
+	}
+}
+
+func TestExternalResultsPutFailure(t *testing.T) {
+	store := &memStore{fail: errors.New("disk full")}
+	g := graph.NewGraph("retention")
+	g.Add(graph.NewNode("extract", nil, returns("rows")))
+
+	report, _ := g.CompileToExecutable().Run(ctx(t), graph.WithResultRetention(graph.ExternalResults, store))
+	nr := report.Nodes["extract"]
+	if nr.Status != graph.StatusFailed || nr.Err == nil || !strings.Contains(nr.Err.Error(), "Storing result: disk full") {
+		t.Errorf("Expected the node failed by the store, got %s: %v", nr.Status, nr.Err)
+	}
+}
+
+func TestExternalResultsWithoutStore(t *testing.T) {
+	var got any
+	g := graph.NewGraph("retention")
+	g.Add(graph.NewNode("extract", nil, returns("rows")))
+	g.Add(graph.NewNode("load", graph.Deps("extract"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		got = ec.Results["extract"]
+		return nil, nil
+	}))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t), graph.WithResultRetention(graph.ExternalResults)); err != nil {
+		t.Fatal(err)
+	}
+	if got != "rows" {
+		t.Errorf("Expected the result in memory without a store, got %v", got)
+	}
+}
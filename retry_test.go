@@ -0,0 +1,131 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A fn that fails its first n calls and then returns "ok"
+func failTimes(n int32) graph.NodeFn {
+	var calls int32
+	return func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		if atomic.AddInt32(&calls, 1) <= n {
+			return nil, errors.New("flaky")
+		}
+		return "ok", nil
+	}
+}
+
+func TestRetryRecordsEveryAttempt(t *testing.T) {
+	g := graph.NewGraph("retries")
+	g.Add(graph.NewNode("flaky", nil, failTimes(2), graph.WithRetry(graph.RetryPolicy{MaxAttempts: 3})))
+
+	var mu sync.Mutex
+	var started []int
+	var finished []graph.AttemptReport
+	var results []graph.NodeReport
+	hooks := graph.Hooks{
+		OnNodeStart: func(id graph.NodeID, attempt int) {
+			mu.Lock()
+			defer mu.Unlock()
+			started = append(started, attempt)
+		},
+		OnNodeFinish: func(id graph.NodeID, attempt graph.AttemptReport) {
+			mu.Lock()
+			defer mu.Unlock()
+			finished = append(finished, attempt)
+		},
+		OnNodeResult: func(id graph.NodeID, result graph.NodeReport) {
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, result)
+		},
+	}
+
+	report, err := g.CompileToExecutable().Run(ctx(t), graph.WithHooks(hooks), graph.WithEventLog(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nr := report.Nodes["flaky"]
+	if nr.Status != graph.StatusSucceeded {
+		t.Fatalf("Expected the node to succeed, got %s", nr.Status)
+	}
+	if len(nr.Attempts) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", len(nr.Attempts))
+	}
+	want := []graph.NodeStatus{graph.StatusFailed, graph.StatusFailed, graph.StatusSucceeded}
+	for i, a := range nr.Attempts {
+		if a.Attempt != i+1 || a.Status != want[i] {
+			t.Errorf("Expected attempt %d to be %s, got attempt %d %s", i+1, want[i], a.Attempt, a.Status)
+		}
+		if (a.Err != nil) != (want[i] == graph.StatusFailed) {
+			t.Errorf("Expected attempt %d's error to match its status, got %v", i+1, a.Err)
+		}
+		if a.End.Before(a.Start) {
+			t.Errorf("Expected attempt %d to end after it started", i+1)
+		}
+	}
+
+	var nerr *graph.NodeError
+	if !errors.As(nr.Attempts[1].Err, &nerr) || nerr.Attempt != 2 {
+		t.Errorf("Expected the second attempt's error to carry attempt 2, got %v", nr.Attempts[1].Err)
+	}
+
+	if len(started) != 3 || started[0] != 1 || started[2] != 3 {
+		t.Errorf("Expected OnNodeStart for attempts 1 to 3, got %v", started)
+	}
+	if len(finished) != 3 || finished[2].Status != graph.StatusSucceeded {
+		t.Errorf("Expected OnNodeFinish per attempt ending in success, got %v", finished)
+	}
+	if len(results) != 1 || len(results[0].Attempts) != 3 {
+		t.Errorf("Expected one OnNodeResult with every attempt, got %v", results)
+	}
+
+	var retrying []int
+	for _, e := range report.Events {
+		if e.Kind == graph.EventNodeRetrying {
+			retrying = append(retrying, e.Attempt)
+		}
+	}
+	if len(retrying) != 2 || retrying[0] != 1 || retrying[1] != 2 {
+		t.Errorf("Expected retrying events after attempts 1 and 2, got %v", retrying)
+	}
+}
+
+func TestRetryFinalErrorNamesTheAttempt(t *testing.T) {
+	g := graph.NewGraph("retries")
+	g.Add(graph.NewNode("broken", nil, failTimes(5), graph.WithRetry(graph.RetryPolicy{MaxAttempts: 3})))
+
+	report, err := g.CompileToExecutable().Run(ctx(t))
+	if err == nil {
+		t.Fatal("Expected the run to fail")
+	}
+
+	var nerr *graph.NodeError
+	if !errors.As(report.Nodes["broken"].Err, &nerr) {
+		t.Fatalf("Expected a NodeError, got %v", report.Nodes["broken"].Err)
+	}
+	if nerr.Attempt != 3 {
+		t.Errorf("Expected the final error from attempt 3, got %d", nerr.Attempt)
+	}
+	if !strings.Contains(nerr.Error(), "attempt 3") {
+		t.Errorf("Expected the error to mention attempt 3, got %q", nerr.Error())
+	}
+}
+
+func TestNoRetryByDefault(t *testing.T) {
+	g := graph.NewGraph("retries")
+	g.Add(graph.NewNode("flaky", nil, failTimes(1)))
+
+	report, _ := g.CompileToExecutable().Run(ctx(t))
+	if got := len(report.Nodes["flaky"].Attempts); got != 1 {
+		t.Errorf("Expected a single attempt, got %d", got)
+	}
+}
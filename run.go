@@ -0,0 +1,670 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+type NodeError struct {
+	ID NodeID
+	// Which attempt failed, counting from 1
+	Attempt  int
+	Category ErrorCategory
+	Err      error
+}
+
+func (e *NodeError) Error() string {
+	if e.Attempt > 1 {
+		return fmt.Sprintf("Node %s failed on attempt %d: %s", e.ID, e.Attempt, e.Err)
+	}
+	return fmt.Sprintf("Node %s failed: %s", e.ID, e.Err)
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+var ErrNoProgress = errors.New("No progress possible")
+
+// Nodes that could never become ready, with the dependencies they were still waiting on
+type NoProgressError struct {
+	Unmet map[NodeID]NodeIDs
+}
+
+func (e *NoProgressError) Error() string {
+	parts := []string{}
+	for _, id := range sortedIDs(e.Unmet) {
+		if len(e.Unmet[id]) == 0 {
+			parts = append(parts, fmt.Sprintf("%s (dependencies finished but it never became ready)", id))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s (waiting on %s)", id, joinIDs(sortedIDs(e.Unmet[id]), stringLimit)))
+	}
+	return fmt.Sprintf("%s: %s", ErrNoProgress, strings.Join(parts, ", "))
+}
+
+func (e *NoProgressError) Is(target error) bool {
+	return target == ErrNoProgress
+}
+
+func sortIDs(ids []NodeID) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
+
+func sortedIDs[V any](m map[NodeID]V) SortedNodeIDs {
+	ids := make(SortedNodeIDs, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+
+	sortIDs(ids)
+	return ids
+}
+
+type completion struct {
+	report NodeReport
+	value  any
+	// Whether dependents may still run
+	satisfied bool
+}
+
+// Everything scoped to a single Run, so one compiled graph can be run many times
+type run struct {
+	peg      *ParallelizedExecutableGraph
+	cfg      ExecConfig
+	report   *Report
+	pending  map[NodeID]int
+	blocked  NodeIDs
+	results  Results
+	ready    []NodeID
+	done     chan completion
+	inflight int
+	// Set under deterministic scheduling, along with how much of ready is already sorted
+	rand   *rand.Rand
+	sorted int
+	// Nil unless the run keeps an event log
+	events *eventLog
+	// Fires at the soft deadline; draining is set once it has
+	softDeadline <-chan time.Time
+	draining     bool
+	// When WithRunTimeout ends the run, by the run's clock; zero without one
+	runDeadline time.Time
+	// The deadline of the ctx Run was given, in real time; zero without one
+	callerDeadline time.Time
+	// Nodes waiting out their start delay, and how long each one that has finished waited
+	delays   chan delayed
+	delaying int
+	waited   map[NodeID]time.Duration
+	// When each node that became ready did
+	readyAt map[NodeID]time.Time
+	// The jitter drawn for each node that had some, and what it's drawn from when deterministic
+	jitters    map[NodeID]time.Duration
+	jitterRand *rand.Rand
+	// Under ReleaseConsumedResults, how many dependents each held result is still waiting for
+	consumers map[NodeID]int
+	// What fns touched, under WithRaceHints
+	touches *touchLog
+	// Parked approval nodes by token, and their timeouts as they run out
+	gates     map[string]gate
+	decisions chan approvalDecision
+	// Under a budget, the cost of the running nodes, the node waiting for room and how many of the
+	// others may still go ahead of it
+	spent    int
+	held     NodeID
+	bypasses int
+	// Under fair scheduling, the group served last and each node's root lineage
+	lastGroup string
+	lineages  map[NodeID]NodeID
+	// Some node has a nonzero priority
+	prioritized bool
+}
+
+// Run always returns a report in which every node has settled. When ctx is canceled it waits for
+// the running nodes to return or be abandoned, then returns ctx's error alongside a report of
+// what finished; interrupted nodes are Canceled and undispatched ones NotRun.
+//
+// A node's fn is never invoked once any of its hard dependencies has failed, directly or through
+// a chain of hard dependencies; the dependent is Skipped as upstream-failed instead. Soft
+// dependencies only have to have finished.
+func (peg *ParallelizedExecutableGraph) Run(ctx context.Context, opts ...ExecOption) (*Report, error) {
+	cfg := peg.Config(opts...)
+	if cfg.SingleFlightKey != "" {
+		return peg.runOnce(ctx, namespaced(peg.namespace, cfg.SingleFlightKey), func() (*Report, error) {
+			return peg.run(ctx, cfg)
+		})
+	}
+	return peg.run(ctx, cfg)
+}
+
+func (peg *ParallelizedExecutableGraph) run(ctx context.Context, cfg ExecConfig) (*Report, error) {
+	if err := peg.checkStale(cfg); err != nil {
+		return nil, err
+	}
+	if err := peg.checkEmpty(); err != nil {
+		return nil, err
+	}
+	if peg.broken != nil {
+		return nil, peg.broken
+	}
+	if err := peg.checkVariant(cfg.Variant); err != nil {
+		return nil, err
+	}
+	for _, id := range sortedIDs(cfg.Precompleted) {
+		if _, ok := peg.nodes[id]; !ok {
+			return nil, fmt.Errorf("Node %s does not exist", id)
+		}
+	}
+
+	r := &run{
+		peg:      peg,
+		cfg:      cfg,
+		report:   &Report{Graph: peg.name, RunID: namespaced(peg.namespace, newRunID()), Config: cfg.RunConfig, Nodes: make(map[NodeID]*NodeReport, len(peg.nodes))},
+		pending:  make(map[NodeID]int, len(peg.nodes)),
+		blocked:  make(NodeIDs),
+		results:  make(Results),
+		done:     make(chan completion, len(peg.nodes)),
+		delays:   make(chan delayed, len(peg.nodes)),
+		rand:     newDeterministicRand(cfg),
+		events:   newEventLog(cfg),
+		lineages: make(map[NodeID]NodeID),
+	}
+
+	r.touches = newTouchLog(cfg)
+
+	ctx, release := r.deadline(ctx)
+	defer release()
+	cfg.State.reset(peg)
+
+	for id, node := range peg.nodes {
+		r.pending[id] = node.required
+		r.report.Nodes[id] = &NodeReport{ID: id}
+		r.prioritized = r.prioritized || node.priority != 0
+	}
+
+	report, err := r.execute(ctx)
+	peg.remember(report, cfg.History)
+	return report, err
+}
+
+func (r *run) execute(ctx context.Context) (*Report, error) {
+	r.report.Start = r.clock().Now()
+	r.event(Event{Kind: EventRunStarted})
+	r.ready = r.peg.Roots()
+	if len(r.cfg.Precompleted) > 0 {
+		r.ready = r.precomplete()
+	}
+	for _, id := range r.ready {
+		r.becameReady(id)
+	}
+
+	r.armSoftDeadline()
+
+	for {
+		for (len(r.ready) > 0 || r.held != "") && ctx.Err() == nil && !r.drained() && r.hasCapacity() {
+			id, ok := r.admit()
+			if !ok {
+				break
+			}
+			r.dispatch(ctx, id)
+		}
+
+		if r.inflight == 0 && ((r.delaying == 0 && len(r.gates) == 0) || r.drained() || ctx.Err() != nil) {
+			break
+		}
+
+		// Parked gates, unlike running nodes, don't settle when the ctx ends, so wake up for it
+		var canceled <-chan struct{}
+		if len(r.gates) > 0 && ctx.Err() == nil {
+			canceled = ctx.Done()
+		}
+
+		select {
+		case <-canceled:
+		case d := <-r.delays:
+			r.delayOver(ctx, d)
+		case d := <-r.decisions:
+			r.decided(ctx, d)
+		case d := <-r.cfg.approvals:
+			r.decided(ctx, d)
+		case c := <-r.done:
+			r.inflight--
+			r.spent -= r.peg.nodes[c.report.ID].costs()
+			r.complete(ctx, c)
+		case <-r.softDeadline:
+			r.draining = true
+		}
+	}
+	r.unpark(ctx)
+
+	// Nothing running and nothing ready: anything still pending can never start
+	var stuck error
+	if ctx.Err() == nil && !r.draining {
+		stuck = r.stuck()
+	}
+
+	for _, id := range sortedIDs(r.report.Nodes) {
+		nr := r.report.Nodes[id]
+		if nr.Status == StatusPending {
+			nr.Status = StatusNotRun
+			if r.draining {
+				nr.Reason = ReasonDeadlineReached
+				r.report.Incomplete = true
+			}
+		}
+		if nr.ReadyAt.IsZero() {
+			// Nodes that ended without a fn running, or never ran at all
+			nr.ReadyAt = r.readyAt[id]
+			nr.fillWaits(r.report.Start)
+		}
+		if nr.Status == StatusNotRun {
+			r.finished(*nr)
+		}
+	}
+
+	if ctx.Err() != nil {
+		cause := context.Cause(ctx)
+		var abort *AbortError
+		if errors.As(cause, &abort) {
+			r.report.AbortReason = abort.Reason
+			r.event(Event{Kind: EventRunAborted, Err: cause})
+		} else {
+			r.event(Event{Kind: EventRunCanceled, Err: cause})
+		}
+	}
+	r.event(Event{Kind: EventRunFinished})
+	r.report.End = r.clock().Now()
+	r.analyze()
+	r.raceHints()
+	r.fillEvents()
+
+	if ctx.Err() != nil {
+		return r.report, context.Cause(ctx)
+	}
+	if stuck != nil {
+		return r.report, errors.Join(stuck, r.err())
+	}
+	return r.report, r.err()
+}
+
+func (r *run) hasCapacity() bool {
+	if r.cfg.Deterministic {
+		return r.inflight == 0
+	}
+	return r.cfg.MaxConcurrency <= 0 || r.inflight < r.cfg.MaxConcurrency
+}
+
+// Whether dependents may run after this result
+func satisfies(nr NodeReport) bool {
+	return nr.Status == StatusSucceeded || nr.Status == StatusFailedAllowed || nr.Reason == ReasonTimeout
+}
+
+// Rechecks the hard dependencies right before dispatch, so a node queued ahead of a failure
+// can't slip through. A hard dependency that is satisfied had all of its own hard dependencies
+// satisfied, so checking direct ones covers the whole chain.
+func (r *run) upstreamOK(id NodeID) bool {
+	node := r.peg.nodes[id]
+	for depId := range node.dependencies {
+		if _, soft := node.softIDs[depId]; !soft && !r.satisfied(depId) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *run) dispatch(ctx context.Context, id NodeID) {
+	if !r.upstreamOK(id) {
+		r.skip(ctx, id, ReasonUpstreamFailed)
+		return
+	}
+	if !r.flagOn(r.peg.nodes[id]) {
+		r.settleFlagOff(ctx, id)
+		return
+	}
+	if !r.conditionMet(id) {
+		r.settleConditionFalse(ctx, id)
+		return
+	}
+	if r.peg.nodes[id].marker {
+		r.settleMarker(ctx, id)
+		return
+	}
+	if r.pendingDelay(id) {
+		r.delay(ctx, id)
+		return
+	}
+	if r.peg.nodes[id].approval {
+		r.park(id)
+		return
+	}
+
+	r.inflight++
+	r.spent += r.peg.nodes[id].costs()
+	r.report.Nodes[id].Status = StatusRunning
+	node := r.peg.nodes[id]
+
+	results := r.dependencyResults(node)
+
+	output, captured := r.output(id)
+	ec := &ExecutionContext{
+		ID:           id,
+		Metadata:     copyMap(node.metadata),
+		Inputs:       copyMap(node.inputs),
+		Dependencies: copyMap(node.dependencies),
+		Results:      results,
+		RunID:        r.report.RunID,
+		RunConfig:    copyMap(r.cfg.RunConfig),
+		Attempt:      1,
+		output:       output,
+		services:     r.cfg.Services,
+		touches:      r.touches,
+	}
+
+	waited, jitter := r.waited[id], r.jitters[id]
+	readyAt, dispatched := r.readyAt[id], r.clock().Now()
+	go func() {
+		c := r.invoke(ctx, ec, node)
+		c.report.StartDelay, c.report.StartJitter = waited, jitter
+		c.report.ReadyAt, c.report.DispatchedAt = readyAt, dispatched
+		c.report.fillWaits(r.report.Start)
+		if captured != nil {
+			c.report.Output, c.report.OutputTruncated = captured.contents()
+		}
+		r.done <- c
+	}()
+}
+
+// The results of node's dependencies, under the ids it declared aliased ones by as well
+func (r *run) dependencyResults(node *executableNode) Results {
+	results := make(Results, len(node.dependencies)+len(node.aliases))
+	for depId := range node.dependencies {
+		if value, ok := r.results[depId]; ok {
+			results[depId] = value
+		}
+	}
+	for alias, target := range node.aliases {
+		if value, ok := r.results[target]; ok {
+			results[alias] = value
+		}
+	}
+	return results
+}
+
+// Runs the node's attempts until one succeeds, the run is canceled or the retry policy gives up
+func (r *run) invoke(ctx context.Context, ec *ExecutionContext, node *executableNode) completion {
+	_, variant := r.fn(node)
+	nr := NodeReport{ID: ec.ID, Variant: variant, Start: r.clock().Now()}
+	stopWatch := r.watchSlow(ec.ID)
+
+	var value any
+	cleanupErrs := []error{}
+	interrupted := false
+	for attempt := 1; ; attempt++ {
+		aec := *ec
+		aec.Attempt = attempt
+
+		a, v := r.attempt(ctx, &aec, node)
+		nr.Attempts = append(nr.Attempts, a)
+		value = v
+		if err := r.cleanup(node, ec.ID, a); err != nil {
+			cleanupErrs = append(cleanupErrs, err)
+		}
+
+		if a.Status == StatusSucceeded || attempt >= node.retry.attempts() || !node.retry.retries(a.Category) || ctx.Err() != nil {
+			break
+		}
+		r.event(Event{Kind: EventNodeRetrying, Node: ec.ID, Attempt: attempt, Status: a.Status, Err: a.Err})
+
+		if node.retry.Backoff > 0 {
+			select {
+			case <-r.clock().After(node.retry.Backoff):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			interrupted = true
+			break
+		}
+	}
+
+	nr.SlowElapsed = stopWatch()
+	nr.CleanupErr = errors.Join(cleanupErrs...)
+	last := nr.Attempts[len(nr.Attempts)-1]
+	nr.Status, nr.Reason, nr.Category, nr.Err = last.Status, last.Reason, last.Category, last.Err
+	if interrupted {
+		// The run ended while waiting to retry
+		var err error
+		nr.Status, nr.Reason, nr.Category, err = r.interruption(ctx)
+		nr.Err = &NodeError{ID: ec.ID, Attempt: last.Attempt, Category: nr.Category, Err: err}
+	}
+	if nr.Status == StatusSucceeded {
+		var err error
+		if value, err = r.storeResult(ec.ID, node, value); err != nil {
+			nr.Status, nr.Category = StatusFailed, categorize(err)
+			nr.Err = &NodeError{ID: ec.ID, Attempt: last.Attempt, Category: nr.Category, Err: err}
+		}
+	}
+	allowed(node, &nr)
+	nr.End = r.clock().Now()
+	r.finished(nr)
+
+	return completion{report: nr, value: value, satisfied: satisfies(nr)}
+}
+
+// Runs the fn once, giving up on it once its timeout or the run's ctx expires.
+// A fn that ignores the deadline keeps running in the background but its result is discarded.
+func (r *run) attempt(ctx context.Context, ec *ExecutionContext, node *executableNode) (AttemptReport, any) {
+	id := ec.ID
+	r.event(Event{Kind: EventNodeStarted, Node: id, Attempt: ec.Attempt, Status: StatusRunning})
+	if r.cfg.Hooks.OnNodeStart != nil {
+		r.cfg.Hooks.OnNodeStart(id, ec.Attempt)
+	}
+
+	a := AttemptReport{Attempt: ec.Attempt, Start: r.clock().Now()}
+	ec.deadline, ec.clock = r.budget(node, a.Start), r.clock()
+	nctx := ctx
+	if node.timeout > 0 {
+		var cancel context.CancelFunc
+		nctx, cancel = withClockTimeout(ctx, r.clock(), node.timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		value    any
+		err      error
+		panicked bool
+	}
+
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- outcome{err: fmt.Errorf("Panicked: %v", p), panicked: true}
+			}
+		}()
+
+		if err := r.beforeAttempt(nctx, node, ec.Attempt); err != nil {
+			done <- outcome{err: err}
+			return
+		}
+
+		fn, _ := r.fn(node)
+		if fn == nil {
+			done <- outcome{}
+			return
+		}
+		value, err := fn(nctx, ec)
+		done <- outcome{value: value, err: err}
+	}()
+
+	fail := func(status NodeStatus, category ErrorCategory, err error) {
+		a.Status = status
+		a.Category = category
+		a.Err = &NodeError{ID: id, Attempt: ec.Attempt, Category: category, Err: err}
+	}
+
+	// Once the run is canceled, a fn that has already returned counts as finished unless it
+	// returned the cancellation itself. Past the node's own timeout it's timed out regardless.
+	var o outcome
+	returned := true
+	select {
+	case o = <-done:
+	case <-nctx.Done():
+		select {
+		case o = <-done:
+			if ctx.Err() == nil || errors.Is(o.err, ctx.Err()) {
+				returned = false
+			}
+		default:
+			returned = false
+		}
+	}
+
+	var value any
+	switch {
+	case returned && o.panicked:
+		fail(StatusFailed, CategoryPanicked, o.err)
+	case returned && o.err != nil:
+		fail(StatusFailed, categorize(o.err), o.err)
+	case returned:
+		a.Status = StatusSucceeded
+		value = o.value
+	default:
+		switch {
+		case ctx.Err() != nil:
+			status, reason, category, err := r.interruption(ctx)
+			fail(status, category, err)
+			a.Reason = reason
+		case node.onTimeout == OnTimeoutSkip:
+			fail(StatusSkipped, CategoryTimedOut, fmt.Errorf("Timed out after %s: %w", node.timeout, nctx.Err()))
+			a.Reason = ReasonTimeout
+		default:
+			fail(StatusTimedOut, CategoryTimedOut, fmt.Errorf("Timed out after %s: %w", node.timeout, nctx.Err()))
+		}
+	}
+	r.afterAttempt(ctx, node, a)
+	a.End = r.clock().Now()
+
+	r.event(Event{Kind: EventNodeFinished, Node: id, Attempt: a.Attempt, Status: a.Status, Reason: a.Reason, Err: a.Err})
+	if r.cfg.Hooks.OnNodeFinish != nil {
+		r.cfg.Hooks.OnNodeFinish(id, a)
+	}
+	return a, value
+}
+
+func (r *run) finished(nr NodeReport) {
+	if r.silent(nr.ID) {
+		return
+	}
+
+	r.nodeEvent(EventNodeResult, nr)
+	r.observe(nr)
+
+	if r.cfg.Logger != nil {
+		r.cfg.Logger.Printf("%s", nr)
+	}
+
+	if r.cfg.Hooks.OnNodeResult != nil {
+		r.cfg.Hooks.OnNodeResult(nr.ID, nr)
+	}
+}
+
+func (r *run) complete(ctx context.Context, c completion) {
+	*r.report.Nodes[c.report.ID] = c.report
+	switch c.report.Status {
+	case StatusSucceeded:
+		r.retain(c.report.ID, c.value)
+	case StatusFailedAllowed:
+		// Dependents see a nil result, as they would from a node that succeeded with one
+		r.results[c.report.ID] = nil
+	}
+	r.cfg.State.record(c.report, c.value)
+	r.release(ctx, c.report.ID, c.satisfied)
+}
+
+// Counts the finished node against each target and queues the ones that became ready
+func (r *run) release(ctx context.Context, id NodeID, satisfied bool) {
+	if ctx.Err() != nil {
+		return
+	}
+	r.consumed(id)
+
+	for _, target := range sortedIDs(r.peg.nodes[id].targetIDs) {
+		// Already settled before the run started
+		if r.report.Nodes[target].Status != StatusPending {
+			continue
+		}
+
+		_, soft := r.peg.nodes[target].softIDs[id]
+		if !satisfied && !soft {
+			r.blocked[target] = struct{}{}
+		}
+
+		r.pending[target]--
+		if r.pending[target] > 0 {
+			continue
+		}
+
+		if _, ok := r.blocked[target]; ok {
+			r.skip(ctx, target, ReasonUpstreamFailed)
+			continue
+		}
+		r.becameReady(target)
+		r.ready = append(r.ready, target)
+	}
+}
+
+func (r *run) skip(ctx context.Context, id NodeID, reason StatusReason) {
+	now := r.clock().Now()
+	nr := NodeReport{ID: id, Status: StatusSkipped, Reason: reason, Start: now, End: now}
+	*r.report.Nodes[id] = nr
+	r.cfg.State.record(nr, nil)
+	r.finished(nr)
+
+	r.release(ctx, id, false)
+}
+
+func (r *run) stuck() error {
+	unmet := map[NodeID]NodeIDs{}
+
+	for id, nr := range r.report.Nodes {
+		if nr.Status != StatusPending {
+			continue
+		}
+
+		deps := NodeIDs{}
+		for depId := range r.peg.nodes[id].dependencies {
+			if dep, ok := r.report.Nodes[depId]; !ok || dep.Status == StatusPending {
+				deps[depId] = struct{}{}
+			}
+		}
+		unmet[id] = deps
+	}
+
+	if len(unmet) == 0 {
+		return nil
+	}
+
+	for id := range unmet {
+		r.report.Nodes[id].Status = StatusNotRun
+		r.report.Nodes[id].Reason = ReasonNoProgress
+	}
+	return &NoProgressError{Unmet: unmet}
+}
+
+func (r *run) err() error {
+	errs := []error{}
+
+	for _, id := range sortedIDs(r.report.Nodes) {
+		nr := r.report.Nodes[id]
+		if nr.Status == StatusFailed || nr.Status == StatusTimedOut {
+			errs = append(errs, nr.Err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,91 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A layered graph where every node past the first layer depends on one to four nodes from
+// earlier layers, and a tenth of the nodes fail. invoked collects the nodes whose fn ran.
+func faulty(rng *rand.Rand, invoked *sync.Map) (*graph.Graph, map[graph.NodeID]graph.NodeIDs, map[graph.NodeID]bool) {
+	const layers, width = 5, 20
+
+	g := graph.NewGraph("faulty")
+	deps := map[graph.NodeID]graph.NodeIDs{}
+	failing := map[graph.NodeID]bool{}
+	earlier := []graph.NodeID{}
+	for l := 0; l < layers; l++ {
+		layer := []graph.NodeID{}
+		for i := 0; i < width; i++ {
+			id := graph.NodeID(fmt.Sprintf("l%d-%02d", l, i))
+			deps[id] = graph.NodeIDs{}
+			if l > 0 {
+				for n := 1 + rng.Intn(4); n > 0; n-- {
+					deps[id][earlier[rng.Intn(len(earlier))]] = struct{}{}
+				}
+			}
+
+			fails := rng.Intn(10) == 0
+			failing[id] = fails
+			g.Add(graph.NewNode(string(id), deps[id], func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+				invoked.Store(ec.ID, true)
+				if fails {
+					return nil, errors.New("injected")
+				}
+				return nil, nil
+			}))
+			layer = append(layer, id)
+		}
+		earlier = append(earlier, layer...)
+	}
+	return g, deps, failing
+}
+
+func TestNoFnRunsAfterUpstreamFailure(t *testing.T) {
+	iterations := 300
+	if testing.Short() {
+		iterations = 30
+	}
+
+	for i := 0; i < iterations; i++ {
+		rng := rand.New(rand.NewSource(int64(i)))
+		var invoked sync.Map
+		g, deps, failing := faulty(rng, &invoked)
+
+		report, _ := g.CompileToExecutable().Run(ctx(t), graph.WithMaxConcurrency(1+rng.Intn(8)))
+
+		// Whether any ancestor of id ran and failed
+		memo := map[graph.NodeID]bool{}
+		var failedAbove func(id graph.NodeID) bool
+		failedAbove = func(id graph.NodeID) bool {
+			if v, ok := memo[id]; ok {
+				return v
+			}
+			v := false
+			for depId := range deps[id] {
+				_, ran := invoked.Load(depId)
+				v = v || (ran && failing[depId]) || failedAbove(depId)
+			}
+			memo[id] = v
+			return v
+		}
+
+		for id := range deps {
+			_, ran := invoked.Load(id)
+			switch {
+			case failedAbove(id) && ran:
+				t.Fatalf("Seed %d: %s ran after an upstream failure", i, id)
+			case failedAbove(id) && report.Nodes[id].Reason != graph.ReasonUpstreamFailed:
+				t.Fatalf("Seed %d: expected %s skipped as upstream-failed, got %s/%s", i, id, report.Nodes[id].Status, report.Nodes[id].Reason)
+			case !failedAbove(id) && !ran:
+				t.Fatalf("Seed %d: expected %s to run with nothing failed above it, got %s/%s", i, id, report.Nodes[id].Status, report.Nodes[id].Reason)
+			}
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package graph
+
+// Run-level settings every fn can read through its ExecutionContext; they're recorded in the
+// report. Keys given here are added to any set at compile time, replacing ones with the same key.
+func WithRunConfig(config map[string]string) ExecOption {
+	return func(c *ExecConfig) {
+		merged := make(map[string]string, len(c.RunConfig)+len(config))
+		for key, value := range c.RunConfig {
+			merged[key] = value
+		}
+		for key, value := range config {
+			merged[key] = value
+		}
+		c.RunConfig = merged
+	}
+}
+
+// A run config value, or "" when the run didn't set it
+func (ec *ExecutionContext) Config(key string) string {
+	return ec.RunConfig[key]
+}
@@ -0,0 +1,70 @@
+package graph_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestRunConfigChangesResults(t *testing.T) {
+	var greeting string
+	g := graph.NewGraph("greet")
+	g.Add(graph.NewNode("greet", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		if ec.Config("env") == "prod" {
+			greeting = "hello, customers"
+		} else {
+			greeting = "hello, " + ec.Config("env")
+		}
+		return nil, nil
+	}))
+	peg := g.CompileToExecutable()
+
+	for env, want := range map[string]string{"prod": "hello, customers", "staging": "hello, staging"} {
+		report, err := peg.Run(ctx(t), graph.WithRunConfig(map[string]string{"env": env}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if greeting != want {
+			t.Errorf("Expected %q under %s, got %q", want, env, greeting)
+		}
+		if got := report.Config["env"]; got != env {
+			t.Errorf("Expected the report to record env=%s, got %q", env, got)
+		}
+	}
+}
+
+func TestRunConfigLayersOverCompileConfig(t *testing.T) {
+	var seen map[string]string
+	g := graph.NewGraph("layers")
+	g.Add(graph.NewNode("n", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		seen = ec.RunConfig
+		return nil, nil
+	}))
+
+	peg := g.CompileToExecutable(graph.WithRunConfig(map[string]string{"env": "dev", "region": "eu"}))
+	if _, err := peg.Run(ctx(t), graph.WithRunConfig(map[string]string{"env": "prod"})); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[string]string{"env": "prod", "region": "eu"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("Expected %v, got %v", want, seen)
+	}
+}
+
+func TestRunConfigUnsetKey(t *testing.T) {
+	var got string
+	g := graph.NewGraph("unset")
+	g.Add(graph.NewNode("n", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		got = ec.Config("missing")
+		return nil, nil
+	}))
+
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("Expected an unset key to read as \"\", got %q", got)
+	}
+}
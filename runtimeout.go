@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrRunTimeout = errors.New("Run timed out")
+
+// Returned by a run that hit its WithRunTimeout; it also matches context.DeadlineExceeded
+type RunTimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *RunTimeoutError) Error() string {
+	return fmt.Sprintf("%s after %s", ErrRunTimeout, e.Timeout)
+}
+
+func (e *RunTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+func (e *RunTimeoutError) Is(target error) bool {
+	return target == ErrRunTimeout
+}
+
+// Caps the whole run, timed by the run's clock. Nodes it interrupts are TimedOut with reason
+// run-timeout and the run returns a RunTimeoutError.
+func WithRunTimeout(d time.Duration) ExecOption {
+	return func(c *ExecConfig) {
+		c.RunTimeout = d
+	}
+}
+
+// Derives the run's ctx; the returned func releases it
+func (r *run) deadline(ctx context.Context) (context.Context, func()) {
+	r.callerDeadline, _ = ctx.Deadline()
+	if r.cfg.RunTimeout <= 0 {
+		return ctx, func() {}
+	}
+
+	// The ctx reports the deadline like withClockTimeout's, so fns see the same bound
+	// RemainingBudget does
+	deadline := time.Now().Add(r.cfg.RunTimeout)
+	cctx, cancel := context.WithCancelCause(ctx)
+	r.runDeadline = r.clock().Now().Add(r.cfg.RunTimeout)
+	expired := r.clock().After(r.cfg.RunTimeout)
+	go func() {
+		select {
+		case <-expired:
+			cancel(&RunTimeoutError{Timeout: r.cfg.RunTimeout})
+		case <-cctx.Done():
+		}
+	}()
+
+	return &clockTimeoutCtx{Context: cctx, deadline: deadline}, func() { cancel(nil) }
+}
+
+// How a node interrupted by the end of the run's ctx is reported
+func (r *run) interruption(ctx context.Context) (NodeStatus, StatusReason, ErrorCategory, error) {
+	err := context.Cause(ctx)
+	var timeout *RunTimeoutError
+	if errors.As(err, &timeout) {
+		return StatusTimedOut, ReasonRunTimeout, CategoryTimedOut, err
+	}
+	var abort *AbortError
+	if errors.As(err, &abort) {
+		return StatusCanceled, ReasonAborted, CategoryCanceled, err
+	}
+	return StatusCanceled, "", CategoryCanceled, err
+}
@@ -0,0 +1,90 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestRunTimeoutInterruptsTheRun(t *testing.T) {
+	clock := newFakeClock()
+	started := make(chan graph.NodeID, 1)
+	g := graph.NewGraph("slow")
+	g.Add(graph.NewNode("fetch", nil, takes(clock, 10*time.Second)))
+	g.Add(graph.NewNode("process", graph.Deps("fetch"), blockUntilCanceled(started)))
+	g.Add(graph.NewNode("publish", graph.Deps("process"), graph.NoOp()))
+
+	done := runAsync(g.CompileToExecutable(), graph.WithClock(clock), graph.WithRunTimeout(30*time.Second))
+
+	// The run's deadline and fetch
+	clock.waitFor(t, 2)
+	clock.Advance(10 * time.Second)
+	<-started
+	clock.Advance(20 * time.Second)
+
+	res := <-done
+	if !errors.Is(res.err, graph.ErrRunTimeout) || !errors.Is(res.err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a run timeout matching DeadlineExceeded, got %v", res.err)
+	}
+	var terr *graph.RunTimeoutError
+	if !errors.As(res.err, &terr) || terr.Timeout != 30*time.Second {
+		t.Errorf("Expected a RunTimeoutError for 30s, got %v", res.err)
+	}
+
+	report := res.report
+	if got := report.Nodes["fetch"].Status; got != graph.StatusSucceeded {
+		t.Errorf("Expected fetch to succeed, got %s", got)
+	}
+	process := report.Nodes["process"]
+	if process.Status != graph.StatusTimedOut || process.Reason != graph.ReasonRunTimeout {
+		t.Errorf("Expected process timed out by the run, got %s %s", process.Status, process.Reason)
+	}
+	if got := report.Nodes["publish"].Status; got != graph.StatusNotRun {
+		t.Errorf("Expected publish not run, got %s", got)
+	}
+}
+
+func TestRunTimeoutDistinctFromNodeTimeout(t *testing.T) {
+	clock := newFakeClock()
+	g := graph.NewGraph("slow")
+	g.Add(graph.NewNode("slow", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, graph.WithTimeout(time.Second)))
+
+	done := runAsync(g.CompileToExecutable(), graph.WithClock(clock), graph.WithRunTimeout(time.Minute))
+	clock.waitFor(t, 2)
+	clock.Advance(2 * time.Second)
+
+	res := <-done
+	if errors.Is(res.err, graph.ErrRunTimeout) {
+		t.Errorf("Expected the node's own timeout, not the run's, got %v", res.err)
+	}
+	slow := res.report.Nodes["slow"]
+	if slow.Status != graph.StatusTimedOut || slow.Reason == graph.ReasonRunTimeout {
+		t.Errorf("Expected slow timed out on its own, got %s %s", slow.Status, slow.Reason)
+	}
+}
+
+func TestRunTimeoutNotOnCallerCancel(t *testing.T) {
+	started := make(chan graph.NodeID, 1)
+	g := graph.NewGraph("canceled")
+	g.Add(graph.NewNode("n", nil, blockUntilCanceled(started)))
+
+	c, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	report, err := g.CompileToExecutable().Run(c, graph.WithRunTimeout(time.Hour))
+
+	if errors.Is(err, graph.ErrRunTimeout) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the caller's cancellation, got %v", err)
+	}
+	if got := report.Nodes["n"].Status; got != graph.StatusCanceled {
+		t.Errorf("Expected n canceled, got %s", got)
+	}
+}
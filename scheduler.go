@@ -0,0 +1,200 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// What to do with a tick that arrives while a run is still going
+type OverlapPolicy int
+
+const (
+	// Drop the tick
+	OverlapSkip OverlapPolicy = iota
+	// Run once the current run finishes; ticks arriving meanwhile collapse into one
+	OverlapQueue
+	// Start another run alongside
+	OverlapConcurrent
+)
+
+type SchedulerOption func(*Scheduler)
+
+func WithOverlap(policy OverlapPolicy) SchedulerOption {
+	return func(s *Scheduler) {
+		s.overlap = policy
+	}
+}
+
+// Delays each tick by a random duration in [0, max)
+func WithJitter(max time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		s.jitter = max
+	}
+}
+
+func WithSchedulerClock(clock Clock) SchedulerOption {
+	return func(s *Scheduler) {
+		s.clock = clock
+	}
+}
+
+// Options passed to every Run
+func WithRunOptions(opts ...ExecOption) SchedulerOption {
+	return func(s *Scheduler) {
+		s.runOpts = append(s.runOpts, opts...)
+	}
+}
+
+// Called with the outcome of each run, from the goroutine that ran it
+func WithReportHandler(handler func(report *Report, err error)) SchedulerOption {
+	return func(s *Scheduler) {
+		s.onReport = handler
+	}
+}
+
+// Scheduler runs a compiled graph every interval until stopped
+type Scheduler struct {
+	peg      *ParallelizedExecutableGraph
+	interval time.Duration
+	overlap  OverlapPolicy
+	jitter   time.Duration
+	clock    Clock
+	runOpts  []ExecOption
+	onReport func(report *Report, err error)
+	rand     *rand.Rand
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// Fails for an interval that isn't positive, which would start runs back to back
+func NewScheduler(peg *ParallelizedExecutableGraph, interval time.Duration, opts ...SchedulerOption) (*Scheduler, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("Scheduler interval must be positive, got %s", interval)
+	}
+
+	s := &Scheduler{
+		peg:      peg,
+		interval: interval,
+		clock:    realClock{},
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// The first run happens one interval after Start
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return errors.New("Scheduler is already started")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.stopped = make(chan struct{})
+
+	go s.loop(ctx, s.stopped)
+	return nil
+}
+
+// Stops ticking, cancels in-flight runs and waits for them to return
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel, stopped := s.cancel, s.stopped
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	<-stopped
+}
+
+// How long until the kth tick after base. Ticks are laid out from base rather than from the
+// previous one, so the time spent handling each doesn't accumulate into drift, and jitter
+// moves a single tick without shifting the ones after it.
+func (s *Scheduler) until(base time.Time, k int64) time.Duration {
+	at := base.Add(time.Duration(k) * s.interval)
+	if s.jitter > 0 {
+		at = at.Add(time.Duration(s.rand.Int63n(int64(s.jitter))))
+	}
+	return at.Sub(s.clock.Now())
+}
+
+// The tick after k, skipping any whose time has already passed so a stalled clock doesn't
+// fire a burst of them back to back
+func (s *Scheduler) following(base time.Time, k int64) int64 {
+	k++
+	if elapsed := int64(s.clock.Now().Sub(base) / s.interval); elapsed >= k {
+		k = elapsed + 1
+	}
+	return k
+}
+
+func (s *Scheduler) loop(ctx context.Context, stopped chan struct{}) {
+	defer close(stopped)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	finished := make(chan struct{})
+	running, queued := 0, false
+
+	start := func() {
+		running++
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			report, err := s.peg.Run(ctx, s.runOpts...)
+			if s.onReport != nil {
+				s.onReport(report, err)
+			}
+
+			select {
+			case finished <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	base, k := s.clock.Now(), int64(1)
+	tick := s.clock.After(s.until(base, k))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+			k = s.following(base, k)
+			tick = s.clock.After(s.until(base, k))
+
+			switch {
+			case running == 0 || s.overlap == OverlapConcurrent:
+				start()
+			case s.overlap == OverlapQueue:
+				queued = true
+			}
+		case <-finished:
+			running--
+			if queued && running == 0 {
+				queued = false
+				start()
+			}
+		}
+	}
+}
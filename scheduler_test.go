@@ -0,0 +1,175 @@
+package graph_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A scheduler ticking every minute on a fake clock, running a graph whose one node takes took by
+// that clock. Each run's start, as an offset into the test, is sent on starts, and each finished
+// run on finished.
+type scheduled struct {
+	clock    *fakeClock
+	origin   time.Time
+	starts   chan time.Duration
+	finished chan struct{}
+}
+
+func newScheduled(t *testing.T, took time.Duration, opts ...graph.SchedulerOption) *scheduled {
+	t.Helper()
+
+	clock := newFakeClock()
+	sc := &scheduled{clock: clock, origin: clock.Now(), starts: make(chan time.Duration, 16), finished: make(chan struct{}, 16)}
+
+	g := graph.NewGraph("job")
+	g.Add(graph.NewNode("work", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		sc.starts <- clock.Now().Sub(sc.origin)
+		if took > 0 {
+			select {
+			case <-clock.After(took):
+			case <-ctx.Done():
+			}
+		}
+		return nil, nil
+	}))
+
+	opts = append(opts,
+		graph.WithSchedulerClock(clock),
+		graph.WithRunOptions(graph.WithClock(clock)),
+		graph.WithReportHandler(func(report *graph.Report, err error) { sc.finished <- struct{}{} }),
+	)
+	s, err := graph.NewScheduler(g.CompileToExecutable(), time.Minute, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+
+	// The first tick
+	clock.waitFor(t, 1)
+	return sc
+}
+
+func (sc *scheduled) expectStart(t *testing.T, at time.Duration) {
+	t.Helper()
+
+	select {
+	case got := <-sc.starts:
+		if got != at {
+			t.Fatalf("Expected a run at %s, got one at %s", at, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a run at %s, got none", at)
+	}
+}
+
+func (sc *scheduled) expectFinish(t *testing.T) {
+	t.Helper()
+
+	select {
+	case <-sc.finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a run to finish")
+	}
+}
+
+func TestSchedulerSkipDropsOverlappingTick(t *testing.T) {
+	sc := newScheduled(t, 90*time.Second, graph.WithOverlap(graph.OverlapSkip))
+
+	sc.clock.Advance(time.Minute)
+	sc.expectStart(t, time.Minute)
+	// The run's sleep and the 2m tick
+	sc.clock.waitFor(t, 2)
+
+	// Mid-run; the tick is dropped and the 3m one armed
+	sc.clock.Advance(time.Minute)
+	sc.clock.waitFor(t, 2)
+
+	sc.clock.Advance(30 * time.Second)
+	sc.expectFinish(t)
+
+	sc.clock.Advance(30 * time.Second)
+	sc.expectStart(t, 3*time.Minute)
+}
+
+func TestSchedulerQueueRunsOverlappingTickAfter(t *testing.T) {
+	sc := newScheduled(t, 90*time.Second, graph.WithOverlap(graph.OverlapQueue))
+
+	sc.clock.Advance(time.Minute)
+	sc.expectStart(t, time.Minute)
+	sc.clock.waitFor(t, 2)
+
+	sc.clock.Advance(time.Minute)
+	sc.clock.waitFor(t, 2)
+
+	// The queued tick runs as soon as the first run ends
+	sc.clock.Advance(30 * time.Second)
+	sc.expectFinish(t)
+	sc.expectStart(t, 150*time.Second)
+}
+
+func TestSchedulerConcurrentRunsAlongside(t *testing.T) {
+	sc := newScheduled(t, 90*time.Second, graph.WithOverlap(graph.OverlapConcurrent))
+
+	sc.clock.Advance(time.Minute)
+	sc.expectStart(t, time.Minute)
+	sc.clock.waitFor(t, 2)
+
+	sc.clock.Advance(time.Minute)
+	sc.expectStart(t, 2*time.Minute)
+}
+
+func TestSchedulerTicksDoNotDrift(t *testing.T) {
+	sc := newScheduled(t, 0)
+
+	sc.clock.Advance(time.Minute)
+	sc.expectStart(t, time.Minute)
+	sc.expectFinish(t)
+	sc.clock.waitFor(t, 1)
+
+	// The 2m tick is only noticed at 2m30s; the next is still due at 3m
+	sc.clock.Advance(90 * time.Second)
+	sc.expectStart(t, 150*time.Second)
+	sc.expectFinish(t)
+	sc.clock.waitFor(t, 1)
+
+	sc.clock.Advance(30 * time.Second)
+	sc.expectStart(t, 3*time.Minute)
+}
+
+func TestSchedulerJitterStaysInItsSlot(t *testing.T) {
+	sc := newScheduled(t, 0, graph.WithJitter(10*time.Second))
+
+	for k := 1; k <= 5; k++ {
+		slot := time.Duration(k) * time.Minute
+		// Step a second at a time until the tick fires, so it's seen up to a second late
+		for sc.clock.armed() > 0 {
+			sc.clock.Advance(time.Second)
+		}
+
+		select {
+		case at := <-sc.starts:
+			if at < slot || at > slot+10*time.Second {
+				t.Fatalf("Expected tick %d within 10s of %s, got %s", k, slot, at)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected tick %d to start a run", k)
+		}
+		sc.expectFinish(t)
+		sc.clock.waitFor(t, 1)
+	}
+}
+
+func TestSchedulerRejectsIntervals(t *testing.T) {
+	peg := graph.NewGraph("nightly").CompileToExecutable()
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if s, err := graph.NewScheduler(peg, interval); err == nil || s != nil {
+			t.Errorf("Expected an interval of %s refused, got %v", interval, err)
+		}
+	}
+}
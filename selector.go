@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrEmptySelector = errors.New("Dependency selector matches no nodes")
+
+type SelectorMatch int
+
+const (
+	// Validate, Sort and Run fail while the selector matches nothing
+	SelectorRequireMatch SelectorMatch = iota
+	// A selector matching nothing adds no edges
+	SelectorAllowEmpty
+)
+
+type selector struct {
+	key   string
+	value string
+	match SelectorMatch
+}
+
+func (s selector) String() string {
+	return s.key + "=" + s.value
+}
+
+// Makes the node depend on every other node whose metadata has key set to value. The matches are
+// worked out from the graph as it is whenever it's sorted, validated or compiled, so a node added
+// later with the label gains the edge; the edges are hard and otherwise the same as declared ones.
+func WithDependencySelector(key, value string, match ...SelectorMatch) NodeOption {
+	return func(n *Node) {
+		s := selector{key: key, value: value}
+		if len(match) > 0 {
+			s.match = match[0]
+		}
+		n.selectors = append(n.selectors, s)
+	}
+}
+
+// The other nodes the selector matches; the caller holds the lock
+func (g *Graph) selected(node *Node, s selector) NodeIDs {
+	var matches NodeIDs
+	for id, other := range g.nodes {
+		if other == node {
+			continue
+		}
+		if value, ok := other.Metadata[s.key]; ok && value == s.value {
+			if matches == nil {
+				matches = make(NodeIDs)
+			}
+			matches[id] = struct{}{}
+		}
+	}
+	return matches
+}
+
+// Whether one of node's selectors matches other
+func (node *Node) selects(other *Node) bool {
+	if node == other {
+		return false
+	}
+	for _, s := range node.selectors {
+		if value, ok := other.Metadata[s.key]; ok && value == s.value {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Graph) checkSelectors() error {
+	selecting := NodeIDs{}
+	for id, node := range g.nodes {
+		if len(node.selectors) > 0 {
+			selecting[id] = struct{}{}
+		}
+	}
+
+	for _, id := range sortedIDs(selecting) {
+		node := g.nodes[id]
+		for _, s := range node.selectors {
+			if s.match == SelectorRequireMatch && len(g.selected(node, s)) == 0 {
+				return fmt.Errorf("%w: node %s selects %s", ErrEmptySelector, id, s)
+			}
+		}
+	}
+	return nil
+}
+
+func selectorSet(n *Node) map[string]struct{} {
+	set := make(map[string]struct{}, len(n.selectors))
+	for _, s := range n.selectors {
+		set[s.String()] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,141 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Records the order nodes ran in
+type runLog struct {
+	mu  sync.Mutex
+	ids []graph.NodeID
+}
+
+func (l *runLog) fn(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ids = append(l.ids, ec.ID)
+	return nil, nil
+}
+
+func extractors(g *graph.Graph, l *runLog, ids ...graph.NodeID) {
+	for _, id := range ids {
+		g.Add(graph.NewNode(string(id), nil, l.fn, graph.WithMetadata("stage", "extract")))
+	}
+}
+
+func TestSelectorGainsEdgeOnRecompile(t *testing.T) {
+	l := &runLog{}
+	g := graph.NewGraph("etl")
+	extractors(g, l, "orders", "users", "events")
+	g.Add(graph.NewNode("transform", nil, l.fn, graph.WithDependencySelector("stage", "extract")))
+
+	peg := g.CompileToExecutable()
+	extractors(g, l, "refunds")
+	if err := peg.Recompile(g, graph.Diff{Added: graph.Deps("refunds")}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []graph.Edge{
+		{From: "transform", To: "events"},
+		{From: "transform", To: "orders"},
+		{From: "transform", To: "refunds"},
+		{From: "transform", To: "users"},
+	}
+	if got := dotEdges(peg.ToDOT()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the recompiled graph to draw %v, got %v", want, got)
+	}
+	if got := g.Edges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected edges %v, got %v", want, got)
+	}
+
+	if _, err := peg.Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.ids) != 5 || l.ids[4] != "transform" {
+		t.Errorf("Expected transform to run after all four extractors, got %v", l.ids)
+	}
+}
+
+// The same graph with the selector written out as dependencies
+func TestSelectorEdgesMatchExplicitOnes(t *testing.T) {
+	build := func(transform *graph.Node) *graph.Graph {
+		g := graph.NewGraph("etl")
+		extractors(g, &runLog{}, "orders", "users")
+		g.Add(graph.NewNode("audit", nil, graph.NoOp(), graph.WithMetadata("stage", "report")))
+		g.Add(transform)
+		return g
+	}
+	selected := build(graph.NewNode("transform", nil, graph.NoOp(), graph.WithDependencySelector("stage", "extract")))
+	explicit := build(graph.NewNode("transform", graph.Deps("orders", "users"), graph.NoOp()))
+
+	if a, b := selected.Edges(), explicit.Edges(); !reflect.DeepEqual(a, b) {
+		t.Errorf("Expected edges %v, got %v", b, a)
+	}
+	if a, b := graphMLEdgeList(t, selected), graphMLEdgeList(t, explicit); !reflect.DeepEqual(a, b) {
+		t.Errorf("Expected GraphML to draw %v, got %v", b, a)
+	}
+	if a, b := dotEdges(selected.CompileToExecutable().ToDOT()), dotEdges(explicit.CompileToExecutable().ToDOT()); !reflect.DeepEqual(a, b) {
+		t.Errorf("Expected the compiled graph to draw %v, got %v", b, a)
+	}
+}
+
+func TestSelectorMatchingNothing(t *testing.T) {
+	g := graph.NewGraph("etl")
+	g.Add(graph.NewNode("transform", nil, graph.NoOp(), graph.WithDependencySelector("stage", "extract")))
+
+	if err := g.Validate(); !errors.Is(err, graph.ErrEmptySelector) {
+		t.Errorf("Expected Validate to reject the empty selector, got %v", err)
+	}
+	if _, err := g.Sort(); !errors.Is(err, graph.ErrEmptySelector) {
+		t.Errorf("Expected Sort to reject the empty selector, got %v", err)
+	}
+	if _, err := g.CompileToExecutable().Run(ctx(t)); !errors.Is(err, graph.ErrEmptySelector) {
+		t.Errorf("Expected Run to reject the empty selector, got %v", err)
+	}
+
+	allowed := graph.NewGraph("etl")
+	allowed.Add(graph.NewNode("transform", nil, graph.NoOp(), graph.WithDependencySelector("stage", "extract", graph.SelectorAllowEmpty)))
+	if err := allowed.Validate(); err != nil {
+		t.Errorf("Expected an allowed empty selector to pass, got %v", err)
+	}
+}
+
+func TestSelectorCycleCheckedOnAdd(t *testing.T) {
+	g := graph.NewGraph("etl", graph.WithIncrementalCycleCheck())
+	g.Add(graph.NewNode("transform", nil, graph.NoOp(), graph.WithDependencySelector("stage", "extract", graph.SelectorAllowEmpty)))
+
+	_, err := g.Add(graph.NewNode("late", graph.Deps("transform"), graph.NoOp(), graph.WithMetadata("stage", "extract")))
+	if !errors.Is(err, graph.ErrCycle) {
+		t.Fatalf("Expected a labeled node depending on its selector to be a cycle, got %v", err)
+	}
+	if g.Has("late") {
+		t.Error("Expected the node closing the cycle left out")
+	}
+
+	// Removing the selecting node drops its edges from the check
+	if err := g.Remove("transform"); err != nil {
+		t.Fatal(err)
+	}
+	g.Add(graph.NewNode("first", nil, graph.NoOp(), graph.WithMetadata("stage", "extract")))
+	if _, err := g.Add(graph.NewNode("second", graph.Deps("first"), graph.NoOp(), graph.WithMetadata("stage", "extract"))); err != nil {
+		t.Errorf("Expected no cycle once the selector is gone, got %v", err)
+	}
+}
+
+// Adding under the incremental cycle check only looks through the nodes that have selectors
+func BenchmarkIncrementalAddWithSelector(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		g := graph.NewGraph("wide", graph.WithIncrementalCycleCheck())
+		g.Add(graph.NewNode("collect", nil, graph.NoOp(), graph.WithDependencySelector("stage", "extract", graph.SelectorAllowEmpty)))
+		for j := 0; j < 5000; j++ {
+			g.Add(graph.NewNode(fmt.Sprintf("n%d", j), nil, graph.NoOp()))
+		}
+	}
+}
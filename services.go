@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var ErrServiceNotProvided = errors.New("Service not provided")
+
+// Makes value available to every fn in the run through Service[T]. Services are keyed by T, so
+// provide interfaces as the interface type, as in WithService[Store](db). Each run gets the
+// services its own options name; a later value for the same T replaces an earlier one.
+func WithService[T any](value T) ExecOption {
+	return func(c *ExecConfig) {
+		services := make(map[reflect.Type]any, len(c.Services)+1)
+		for typ, service := range c.Services {
+			services[typ] = service
+		}
+		services[serviceType[T]()] = value
+		c.Services = services
+	}
+}
+
+// The run's service of type T
+func Service[T any](ec *ExecutionContext) (T, error) {
+	service, ok := ec.services[serviceType[T]()]
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("%w: node %s asked for %s, which the run wasn't given with WithService", ErrServiceNotProvided, ec.ID, serviceType[T]())
+	}
+	return service.(T), nil
+}
+
+// The static type, so interface types key as themselves rather than as whatever implements them
+func serviceType[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
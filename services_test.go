@@ -0,0 +1,86 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+type store interface {
+	Get(key string) string
+}
+
+type fakeStore map[string]string
+
+func (s fakeStore) Get(key string) string {
+	return s[key]
+}
+
+// A graph whose one node reads key from the run's store
+func lookup(key string, got *sync.Map) *graph.Graph {
+	g := graph.NewGraph("services")
+	g.Add(graph.NewNode("read", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		s, err := graph.Service[store](ec)
+		if err != nil {
+			return nil, err
+		}
+		got.Store(ec.RunID, s.Get(key))
+		return nil, nil
+	}))
+	return g
+}
+
+func TestServiceProvided(t *testing.T) {
+	var got sync.Map
+	report, err := lookup("dsn", &got).CompileToExecutable().Run(ctx(t), graph.WithService[store](fakeStore{"dsn": "postgres://test"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := got.Load(report.RunID); value != "postgres://test" {
+		t.Errorf("Expected the fake store's value, got %v", value)
+	}
+}
+
+func TestServiceMissing(t *testing.T) {
+	var got sync.Map
+	// A store given as its concrete type doesn't satisfy a request for the interface
+	report, err := lookup("dsn", &got).CompileToExecutable().Run(ctx(t), graph.WithService(fakeStore{}))
+	if err == nil {
+		t.Fatal("Expected the run to fail")
+	}
+
+	nerr := report.Nodes["read"].Err
+	if !errors.Is(nerr, graph.ErrServiceNotProvided) {
+		t.Fatalf("Expected ErrServiceNotProvided, got %v", nerr)
+	}
+	for _, want := range []string{"read", "graph_test.store"} {
+		if !strings.Contains(nerr.Error(), want) {
+			t.Errorf("Expected the error to name %s, got %v", want, nerr)
+		}
+	}
+}
+
+func TestServicesIsolatedBetweenRuns(t *testing.T) {
+	var got sync.Map
+	peg := lookup("env", &got).CompileToExecutable(graph.WithService[store](fakeStore{"env": "default"}))
+
+	first, err := peg.Run(ctx(t), graph.WithService[store](fakeStore{"env": "staging"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := peg.Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := got.Load(first.RunID); value != "staging" {
+		t.Errorf("Expected the run's own service to replace the default, got %v", value)
+	}
+	if value, _ := got.Load(second.RunID); value != "default" {
+		t.Errorf("Expected the next run to get the default again, got %v", value)
+	}
+}
@@ -0,0 +1,169 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNodeNotFound = errors.New("Node not found")
+
+type NodeNotFoundError struct {
+	ID NodeID
+}
+
+func (e *NodeNotFoundError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrNodeNotFound, e.ID)
+}
+
+func (e *NodeNotFoundError) Is(target error) bool {
+	return target == ErrNodeNotFound
+}
+
+// Deps builds a dependency set sized for ids. With no ids it returns nil, which reads as an
+// empty set and costs nothing; NewNode and the edge options accept it.
+func Deps(ids ...NodeID) NodeIDs {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	deps := make(NodeIDs, len(ids))
+	for _, id := range ids {
+		deps[id] = struct{}{}
+	}
+	return deps
+}
+
+func (s NodeIDs) Has(id NodeID) bool {
+	_, ok := s[id]
+	return ok
+}
+
+// The set operations below never modify their operands and return nil rather than allocate
+// an empty set
+
+func (s NodeIDs) Union(other NodeIDs) NodeIDs {
+	if len(s) == 0 && len(other) == 0 {
+		return nil
+	}
+
+	union := make(NodeIDs, len(s)+len(other))
+	for id := range s {
+		union[id] = struct{}{}
+	}
+	for id := range other {
+		union[id] = struct{}{}
+	}
+	return union
+}
+
+// Walks the smaller set and allocates on the first id the two share
+func (s NodeIDs) Intersect(other NodeIDs) NodeIDs {
+	small, large := s, other
+	if len(large) < len(small) {
+		small, large = large, small
+	}
+
+	var both NodeIDs
+	for id := range small {
+		if _, ok := large[id]; !ok {
+			continue
+		}
+		if both == nil {
+			both = make(NodeIDs, len(small))
+		}
+		both[id] = struct{}{}
+	}
+	return both
+}
+
+// The ids in s that aren't in other
+func (s NodeIDs) Difference(other NodeIDs) NodeIDs {
+	var diff NodeIDs
+	for id := range s {
+		if _, ok := other[id]; ok {
+			continue
+		}
+		if diff == nil {
+			diff = make(NodeIDs, len(s))
+		}
+		diff[id] = struct{}{}
+	}
+	return diff
+}
+
+// Nil and empty sets are equal
+func (s NodeIDs) Equal(other NodeIDs) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for id := range s {
+		if _, ok := other[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// The ids in lexicographic order
+func SortedFrom(ids NodeIDs) SortedNodeIDs {
+	return sortedIDs(ids)
+}
+
+// The position of id, or -1 if it's missing
+func (s SortedNodeIDs) Index(id NodeID) int {
+	for i, other := range s {
+		if other == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s SortedNodeIDs) Contains(id NodeID) bool {
+	return s.Index(id) >= 0
+}
+
+// Whether a comes before b; a NodeNotFoundError if either is missing
+func (s SortedNodeIDs) Before(a, b NodeID) (bool, error) {
+	i, j := s.Index(a), s.Index(b)
+	if i < 0 {
+		return false, &NodeNotFoundError{ID: a}
+	}
+	if j < 0 {
+		return false, &NodeNotFoundError{ID: b}
+	}
+	return i < j, nil
+}
+
+// A reversed copy, which for a topological order puts dependents before their dependencies
+func (s SortedNodeIDs) Reverse() SortedNodeIDs {
+	reversed := make(SortedNodeIDs, len(s))
+	for i, id := range s {
+		reversed[len(s)-1-i] = id
+	}
+	return reversed
+}
+
+// The ids keep returns true for, in order
+func (s SortedNodeIDs) Filter(keep func(id NodeID) bool) SortedNodeIDs {
+	kept := SortedNodeIDs{}
+	for _, id := range s {
+		if keep(id) {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// A copy of m, or nil when it's empty
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	if len(m) == 0 {
+		return nil
+	}
+
+	c := make(map[K]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
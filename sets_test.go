@@ -0,0 +1,221 @@
+package graph_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestDeps(t *testing.T) {
+	if deps := graph.Deps(); deps != nil {
+		t.Errorf("Expected no ids to give nil, got %v", deps)
+	}
+	deps := graph.Deps("a", "b", "a")
+	if len(deps) != 2 || !deps.Has("a") || !deps.Has("b") || deps.Has("c") {
+		t.Errorf("Expected {a, b}, got %v", deps)
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	ab, bc := graph.Deps("a", "b"), graph.Deps("b", "c")
+	for _, tc := range []struct {
+		name string
+		got  graph.NodeIDs
+		want graph.NodeIDs
+	}{
+		{"union", ab.Union(bc), graph.Deps("a", "b", "c")},
+		{"intersect", ab.Intersect(bc), graph.Deps("b")},
+		{"difference", ab.Difference(bc), graph.Deps("a")},
+		{"union with empty", ab.Union(nil), ab},
+		{"empty union empty", graph.NodeIDs(nil).Union(graph.NodeIDs{}), nil},
+		{"intersect with empty", ab.Intersect(nil), nil},
+		{"disjoint intersect", ab.Intersect(graph.Deps("c")), nil},
+		{"difference with empty", ab.Difference(nil), ab},
+		{"empty difference", graph.NodeIDs(nil).Difference(ab), nil},
+		{"difference with itself", ab.Difference(ab), nil},
+	} {
+		if !reflect.DeepEqual(tc.got, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, tc.got)
+		}
+	}
+
+	// The operands are left alone
+	if !ab.Equal(graph.Deps("a", "b")) || !bc.Equal(graph.Deps("b", "c")) {
+		t.Errorf("Expected the operands unchanged, got %v and %v", ab, bc)
+	}
+}
+
+func TestSetEqual(t *testing.T) {
+	for _, tc := range []struct {
+		a, b graph.NodeIDs
+		want bool
+	}{
+		{nil, graph.NodeIDs{}, true},
+		{graph.Deps("a"), graph.Deps("a"), true},
+		{graph.Deps("a"), graph.Deps("b"), false},
+		{graph.Deps("a"), graph.Deps("a", "b"), false},
+		{graph.Deps("a"), nil, false},
+	} {
+		if got := tc.a.Equal(tc.b); got != tc.want {
+			t.Errorf("Expected %v equal %v to be %t", tc.a, tc.b, tc.want)
+		}
+	}
+}
+
+func TestSetOperationsWithoutAllocating(t *testing.T) {
+	ab, cd := graph.Deps("a", "b"), graph.Deps("c", "d")
+	for name, op := range map[string]func(){
+		"equal":              func() { ab.Equal(cd) },
+		"disjoint intersect": func() { ab.Intersect(cd) },
+		"empty difference":   func() { ab.Difference(ab) },
+		"empty union":        func() { graph.NodeIDs(nil).Union(nil) },
+	} {
+		if allocs := testing.AllocsPerRun(100, op); allocs != 0 {
+			t.Errorf("%s: expected no allocations, got %v", name, allocs)
+		}
+	}
+}
+
+func TestSortedFrom(t *testing.T) {
+	if got := graph.SortedFrom(graph.Deps("c", "a", "b")); !reflect.DeepEqual(got, graph.SortedNodeIDs{"a", "b", "c"}) {
+		t.Errorf("Expected [a b c], got %v", got)
+	}
+	if got := graph.SortedFrom(nil); len(got) != 0 {
+		t.Errorf("Expected nothing from nil, got %v", got)
+	}
+}
+
+func TestSortedNodeIDsIndexAndContains(t *testing.T) {
+	// Not lexicographic, as from a topological sort
+	ids := graph.SortedNodeIDs{"build", "test", "deploy"}
+	for _, tc := range []struct {
+		id    graph.NodeID
+		index int
+	}{
+		{"build", 0},
+		{"test", 1},
+		{"deploy", 2},
+		{"lint", -1},
+	} {
+		if got := ids.Index(tc.id); got != tc.index {
+			t.Errorf("Expected %s at %d, got %d", tc.id, tc.index, got)
+		}
+		if got := ids.Contains(tc.id); got != (tc.index >= 0) {
+			t.Errorf("Expected Contains(%s) to be %t", tc.id, tc.index >= 0)
+		}
+	}
+	if graph.SortedNodeIDs(nil).Contains("build") {
+		t.Error("Expected an empty list to contain nothing")
+	}
+}
+
+func TestSortedNodeIDsBefore(t *testing.T) {
+	ids := graph.SortedNodeIDs{"build", "test", "deploy"}
+	for _, tc := range []struct {
+		a, b graph.NodeID
+		want bool
+	}{
+		{"build", "deploy", true},
+		{"deploy", "build", false},
+		{"test", "test", false},
+	} {
+		if got, err := ids.Before(tc.a, tc.b); err != nil || got != tc.want {
+			t.Errorf("Expected %s before %s to be %t, got %t, %v", tc.a, tc.b, tc.want, got, err)
+		}
+	}
+
+	for _, tc := range []struct{ a, b, missing graph.NodeID }{
+		{"lint", "build", "lint"},
+		{"build", "lint", "lint"},
+		{"lint", "docs", "lint"},
+	} {
+		_, err := ids.Before(tc.a, tc.b)
+		var nerr *graph.NodeNotFoundError
+		if !errors.As(err, &nerr) || nerr.ID != tc.missing || !errors.Is(err, graph.ErrNodeNotFound) {
+			t.Errorf("Expected a NodeNotFoundError for %s, got %v", tc.missing, err)
+		}
+	}
+}
+
+func TestSortedNodeIDsReverse(t *testing.T) {
+	ids := graph.SortedNodeIDs{"build", "test", "deploy"}
+	if got := ids.Reverse(); !reflect.DeepEqual(got, graph.SortedNodeIDs{"deploy", "test", "build"}) {
+		t.Errorf("Expected [deploy test build], got %v", got)
+	}
+	if !reflect.DeepEqual(ids, graph.SortedNodeIDs{"build", "test", "deploy"}) {
+		t.Errorf("Expected a copy, the original became %v", ids)
+	}
+	if got := (graph.SortedNodeIDs{}).Reverse(); len(got) != 0 {
+		t.Errorf("Expected an empty reverse, got %v", got)
+	}
+
+	// Reversing a sort puts dependents first, the order to tear down in
+	teardown := mustSort(t, diamond(t)).Reverse()
+	for _, e := range diamond(t).Edges() {
+		if !before(teardown, e.From, e.To) {
+			t.Errorf("Expected %s torn down before %s in %v", e.From, e.To, teardown)
+		}
+	}
+}
+
+func TestSortedNodeIDsFilter(t *testing.T) {
+	ids := graph.SortedNodeIDs{"build", "test", "deploy"}
+	if got := ids.Filter(func(id graph.NodeID) bool { return id != "test" }); !reflect.DeepEqual(got, graph.SortedNodeIDs{"build", "deploy"}) {
+		t.Errorf("Expected test filtered out keeping the order, got %v", got)
+	}
+	if got := ids.Filter(func(graph.NodeID) bool { return false }); got == nil || len(got) != 0 {
+		t.Errorf("Expected an empty, non-nil list, got %#v", got)
+	}
+}
+
+// n nodes where nine in ten have two dependencies and the rest none
+func sparse(n int) *graph.Graph {
+	g := graph.NewGraph("sparse", graph.WithLazyAdd())
+	for i := 0; i < n; i++ {
+		var deps graph.NodeIDs
+		if i >= 2 && i%10 != 0 {
+			deps = graph.Deps(graph.NodeID(fmt.Sprintf("n%d", i-1)), graph.NodeID(fmt.Sprintf("n%d", i-2)))
+		}
+		g.Add(graph.NewNode(fmt.Sprintf("n%d", i), deps, graph.NoOp()))
+	}
+	return g
+}
+
+func BenchmarkBuildSparse(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sparse(5000)
+	}
+}
+
+func BenchmarkCloneSparse(b *testing.B) {
+	g := sparse(5000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.Clone()
+	}
+}
+
+func BenchmarkCompileSparse(b *testing.B) {
+	g := sparse(5000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.CompileToExecutable()
+	}
+}
+
+func BenchmarkSetOperations(b *testing.B) {
+	ab, bc := graph.Deps("a", "b"), graph.Deps("b", "c")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ab.Union(bc)
+		ab.Intersect(bc)
+		ab.Difference(bc)
+		ab.Equal(bc)
+	}
+}
@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"unsafe"
+)
+
+// Makes Warnings flag nodes that share one fn value and have nothing ordering them, so they
+// may call it at the same time. Sharing is fine when the fn guards its own state; the warning is
+// for auditing the ones that don't. Fns that capture nothing, like top-level functions, count as
+// one value wherever they're used. NoOp is never flagged.
+func WithSharedFnWarnings() GraphOption {
+	return func(g *Graph) {
+		g.sharedFnWarnings = true
+	}
+}
+
+// Identifies a fn value: the closure it points at, not only its code, so two closures made by
+// the same literal differ and one closure handed to several nodes doesn't
+func fnFingerprint(fn NodeFn) uintptr {
+	return uintptr(*(*unsafe.Pointer)(unsafe.Pointer(&fn)))
+}
+
+var noOpFingerprint = fnFingerprint(NoOp())
+
+// One warning per shared fn, naming the nodes that share it and can run alongside another of
+// them. The caller holds the read lock.
+func (g *Graph) sharedFns() []Warning {
+	if !g.sharedFnWarnings {
+		return nil
+	}
+
+	groups := map[uintptr]NodeIDs{}
+	for id, node := range g.nodes {
+		if node.Fn == nil {
+			continue
+		}
+		fp := fnFingerprint(node.Fn)
+		if fp == noOpFingerprint {
+			continue
+		}
+		if groups[fp] == nil {
+			groups[fp] = NodeIDs{}
+		}
+		groups[fp][id] = struct{}{}
+	}
+
+	upstream := map[NodeID]NodeIDs{}
+	var ancestors func(id NodeID) NodeIDs
+	ancestors = func(id NodeID) NodeIDs {
+		if found, ok := upstream[id]; ok {
+			return found
+		}
+		found := NodeIDs{}
+		upstream[id] = found
+		node, ok := g.nodes[id]
+		if !ok {
+			return found
+		}
+		for depId := range g.dependencies(node) {
+			found[depId] = struct{}{}
+			for a := range ancestors(depId) {
+				found[a] = struct{}{}
+			}
+		}
+		return found
+	}
+
+	warnings := []Warning{}
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		ids := sortedIDs(group)
+		concurrent := NodeIDs{}
+		for i, first := range ids {
+			for _, second := range ids[i+1:] {
+				_, before := ancestors(second)[first]
+				_, after := ancestors(first)[second]
+				if !before && !after {
+					concurrent[first] = struct{}{}
+					concurrent[second] = struct{}{}
+				}
+			}
+		}
+		if len(concurrent) == 0 {
+			continue
+		}
+		shared := sortedIDs(concurrent)
+		warnings = append(warnings, Warning{
+			Kind:    WarningSharedFn,
+			Nodes:   shared,
+			Message: fmt.Sprintf("Nodes %s share one fn and can run at the same time", joinIDs(shared, stringLimit)),
+		})
+	}
+	// Groups don't overlap, so their first nodes order them
+	sort.Slice(warnings, func(i, j int) bool { return warnings[i].Nodes[0] < warnings[j].Nodes[0] })
+	return warnings
+}
@@ -0,0 +1,114 @@
+package graph_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A top-level fn: every use of it is the same value
+func uploadAll(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+	return nil, nil
+}
+
+// A closure with state of its own, which each call makes anew
+func counter() graph.NodeFn {
+	var mu sync.Mutex
+	calls := 0
+	return func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return calls, nil
+	}
+}
+
+func TestSharedFnUnordered(t *testing.T) {
+	shared := counter()
+	g := graph.NewGraph("shared", graph.WithSharedFnWarnings())
+	g.Add(graph.NewNode("east", nil, shared))
+	g.Add(graph.NewNode("west", nil, shared))
+
+	warnings := warningsOf(g, graph.WarningSharedFn)
+	if len(warnings) != 1 {
+		t.Fatalf("Expected one warning, got %v", warnings)
+	}
+	if !reflect.DeepEqual(warnings[0].Nodes, []graph.NodeID{"east", "west"}) {
+		t.Errorf("Expected east and west named, got %v", warnings[0].Nodes)
+	}
+	if want := "Nodes east west share one fn and can run at the same time"; warnings[0].Message != want {
+		t.Errorf("Expected %q, got %q", want, warnings[0].Message)
+	}
+}
+
+func TestSharedFnOrdered(t *testing.T) {
+	shared := counter()
+	g := graph.NewGraph("shared", graph.WithSharedFnWarnings())
+	g.Add(graph.NewNode("first", nil, shared))
+	g.Add(graph.NewNode("middle", graph.Deps("first"), counter()))
+	// Ordered after first only through middle
+	g.Add(graph.NewNode("last", graph.Deps("middle"), shared))
+	// A soft edge orders too
+	g.Add(graph.NewNode("after", nil, shared, graph.WithSoftDependency("last")))
+
+	if got := warningsOf(g, graph.WarningSharedFn); len(got) != 0 {
+		t.Errorf("Expected ordered nodes sharing a fn left alone, got %v", got)
+	}
+}
+
+func TestSharedFnNamesEveryNodeThatCanOverlap(t *testing.T) {
+	shared := counter()
+	g := graph.NewGraph("shared", graph.WithSharedFnWarnings())
+	g.Add(graph.NewNode("a", nil, shared))
+	g.Add(graph.NewNode("b", graph.Deps("a"), shared))
+	g.Add(graph.NewNode("c", nil, shared))
+	g.Add(graph.NewNode("x", nil, uploadAll))
+	g.Add(graph.NewNode("y", nil, uploadAll))
+
+	warnings := warningsOf(g, graph.WarningSharedFn)
+	if len(warnings) != 2 {
+		t.Fatalf("Expected a warning per shared fn, got %v", warnings)
+	}
+	// c can run alongside a and alongside b
+	if !reflect.DeepEqual(warnings[0].Nodes, []graph.NodeID{"a", "b", "c"}) {
+		t.Errorf("Expected a, b and c named, got %v", warnings[0].Nodes)
+	}
+	if !reflect.DeepEqual(warnings[1].Nodes, []graph.NodeID{"x", "y"}) {
+		t.Errorf("Expected a top-level fn used twice flagged, got %v", warnings[1].Nodes)
+	}
+}
+
+func TestSharedFnIgnores(t *testing.T) {
+	g := graph.NewGraph("separate", graph.WithSharedFnWarnings())
+	// Closures made by one literal are separate values
+	g.Add(graph.NewNode("east", nil, counter()))
+	g.Add(graph.NewNode("west", nil, counter()))
+	g.Add(graph.NewNode("noop-1", nil, graph.NoOp()))
+	g.Add(graph.NewNode("noop-2", nil, graph.NoOp()))
+	if got := warningsOf(g, graph.WarningSharedFn); len(got) != 0 {
+		t.Errorf("Expected nothing flagged, got %v", got)
+	}
+
+	// Off unless asked for
+	shared := counter()
+	plain := graph.NewGraph("plain")
+	plain.Add(graph.NewNode("east", nil, shared))
+	plain.Add(graph.NewNode("west", nil, shared))
+	if got := warningsOf(plain, graph.WarningSharedFn); len(got) != 0 {
+		t.Errorf("Expected no warning without WithSharedFnWarnings, got %v", got)
+	}
+}
+
+func TestSharedFnKeptByClone(t *testing.T) {
+	shared := counter()
+	g := graph.NewGraph("shared", graph.WithSharedFnWarnings())
+	g.Add(graph.NewNode("east", nil, shared))
+	g.Add(graph.NewNode("west", nil, shared))
+
+	if got := warningsOf(g.Clone(), graph.WarningSharedFn); len(got) != 1 {
+		t.Errorf("Expected the clone to keep the option, got %v", got)
+	}
+}
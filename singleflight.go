@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"context"
+)
+
+type flight struct {
+	done   chan struct{}
+	report *Report
+	err    error
+}
+
+// Runs sharing an in-progress key wait for the first one and get its Report instead of executing again.
+// The key is released once that run finishes.
+func WithSingleFlight(key string) ExecOption {
+	return func(c *ExecConfig) {
+		c.SingleFlightKey = key
+	}
+}
+
+func (peg *ParallelizedExecutableGraph) runOnce(ctx context.Context, key string, run func() (*Report, error)) (*Report, error) {
+	peg.mu.Lock()
+	if f, ok := peg.flights[key]; ok {
+		peg.mu.Unlock()
+
+		select {
+		case <-f.done:
+			return f.report, f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if peg.flights == nil {
+		peg.flights = make(map[string]*flight)
+	}
+	f := &flight{done: make(chan struct{})}
+	peg.flights[key] = f
+	peg.mu.Unlock()
+
+	f.report, f.err = run()
+
+	peg.mu.Lock()
+	delete(peg.flights, key)
+	peg.mu.Unlock()
+	close(f.done)
+
+	return f.report, f.err
+}
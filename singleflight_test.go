@@ -0,0 +1,98 @@
+package graph_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A single node graph whose fn reports each start on started and then waits for release
+func gatedGraph(calls *atomic.Int32, started chan<- struct{}, release <-chan struct{}) *graph.ParallelizedExecutableGraph {
+	g := graph.NewGraph("gated")
+	g.Add(graph.NewNode("work", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		calls.Add(1)
+		started <- struct{}{}
+		<-release
+		return nil, nil
+	}))
+	return g.CompileToExecutable()
+}
+
+type runResult struct {
+	report *graph.Report
+	err    error
+}
+
+func runAsync(peg *graph.ParallelizedExecutableGraph, opts ...graph.ExecOption) <-chan runResult {
+	done := make(chan runResult, 1)
+	go func() {
+		report, err := peg.Run(context.Background(), opts...)
+		done <- runResult{report, err}
+	}()
+	return done
+}
+
+func TestSingleFlightSharesOneRun(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	peg := gatedGraph(&calls, started, release)
+
+	first := runAsync(peg, graph.WithSingleFlight("deploy"))
+	<-started
+	second := runAsync(peg, graph.WithSingleFlight("deploy"))
+	// Give the second run time to find the first in flight
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	a, b := <-first, <-second
+	if a.err != nil || b.err != nil {
+		t.Fatalf("Expected both runs to succeed, got %v and %v", a.err, b.err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected the fn to run once, ran %d times", n)
+	}
+	if a.report != b.report {
+		t.Error("Expected both callers to get the same Report")
+	}
+}
+
+func TestSingleFlightDifferentKeysRunIndependently(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	peg := gatedGraph(&calls, started, release)
+
+	first := runAsync(peg, graph.WithSingleFlight("a"))
+	second := runAsync(peg, graph.WithSingleFlight("b"))
+	// Both are in their fn at once, so neither waited on the other
+	<-started
+	<-started
+	close(release)
+
+	a, b := <-first, <-second
+	if a.report == b.report {
+		t.Error("Expected separate Reports for separate keys")
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("Expected the fn to run twice, ran %d times", n)
+	}
+}
+
+func TestSingleFlightReleasesFinishedKeys(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	close(release)
+	peg := gatedGraph(&calls, started, release)
+
+	for i := 0; i < 2; i++ {
+		if _, err := peg.Run(ctx(t), graph.WithSingleFlight("deploy")); err != nil {
+			t.Fatal(err)
+		}
+		<-started
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("Expected a later run with the same key to execute fresh, ran %d times", n)
+	}
+}
@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"sync"
+	"time"
+)
+
+type SlowNodeWarning struct {
+	// Zero disables the warning
+	Threshold time.Duration
+	// Repeat the warning this often after the first; zero warns once
+	Every    time.Duration
+	Callback func(id NodeID, elapsed time.Duration)
+}
+
+// Calls callback, from its own goroutine, for nodes still running after threshold. The node is left
+// running. Pass every to keep warning at that interval.
+func WithSlowNodeWarning(threshold time.Duration, callback func(id NodeID, elapsed time.Duration), every ...time.Duration) ExecOption {
+	return func(c *ExecConfig) {
+		c.SlowNode = SlowNodeWarning{Threshold: threshold, Callback: callback}
+		if len(every) > 0 {
+			c.SlowNode.Every = every[0]
+		}
+	}
+}
+
+// The clock for the run's timestamps, node and run timeouts, start delays, backoffs and slow-node
+// warnings
+func WithClock(clock Clock) ExecOption {
+	return func(c *ExecConfig) {
+		c.Clock = clock
+	}
+}
+
+func (r *run) clock() Clock {
+	if r.cfg.Clock == nil {
+		return realClock{}
+	}
+	return r.cfg.Clock
+}
+
+// Starts watching a node; the returned func stops the watch, after which the callback never
+// fires, and returns the elapsed time of the last warning.
+func (r *run) watchSlow(id NodeID) func() time.Duration {
+	warning := r.cfg.SlowNode
+	if warning.Threshold <= 0 || warning.Callback == nil {
+		return func() time.Duration { return 0 }
+	}
+
+	clock := r.clock()
+	start := clock.Now()
+	quit := make(chan struct{})
+
+	var mu sync.Mutex
+	stopped := false
+	var peak time.Duration
+
+	go func() {
+		wait := warning.Threshold
+		for {
+			select {
+			case <-clock.After(wait):
+			case <-quit:
+				return
+			}
+
+			mu.Lock()
+			if stopped {
+				mu.Unlock()
+				return
+			}
+			peak = clock.Now().Sub(start)
+			warning.Callback(id, peak)
+			mu.Unlock()
+
+			if warning.Every <= 0 {
+				return
+			}
+			wait = warning.Every
+		}
+	}()
+
+	return func() time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !stopped {
+			stopped = true
+			close(quit)
+		}
+		return peak
+	}
+}
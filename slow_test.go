@@ -0,0 +1,120 @@
+package graph_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Runs a graph whose "stuck" node blocks until release is closed, warning through warnings
+func runStuck(t *testing.T, clock *fakeClock, release chan struct{}, warnings chan time.Duration, every ...time.Duration) <-chan runResult {
+	t.Helper()
+
+	g := graph.NewGraph("stuck")
+	g.Add(graph.NewNode("stuck", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		<-release
+		return nil, nil
+	}))
+
+	warn := func(id graph.NodeID, elapsed time.Duration) {
+		if id != "stuck" {
+			t.Errorf("Expected a warning for stuck, got %s", id)
+		}
+		warnings <- elapsed
+	}
+	return runAsync(g.CompileToExecutable(), graph.WithClock(clock), graph.WithSlowNodeWarning(10*time.Second, warn, every...))
+}
+
+func expectWarning(t *testing.T, warnings chan time.Duration, elapsed time.Duration) {
+	t.Helper()
+
+	select {
+	case got := <-warnings:
+		if got != elapsed {
+			t.Errorf("Expected a warning at %s, got %s", elapsed, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a warning at %s", elapsed)
+	}
+}
+
+func expectNoWarning(t *testing.T, warnings chan time.Duration) {
+	t.Helper()
+
+	select {
+	case got := <-warnings:
+		t.Errorf("Expected no more warnings, got one at %s", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestSlowNodeWarnsOnce(t *testing.T) {
+	clock := newFakeClock()
+	release := make(chan struct{})
+	warnings := make(chan time.Duration, 8)
+	done := runStuck(t, clock, release, warnings)
+
+	clock.waitFor(t, 1)
+	clock.Advance(5 * time.Second)
+	expectNoWarning(t, warnings)
+
+	clock.Advance(6 * time.Second)
+	expectWarning(t, warnings, 11*time.Second)
+
+	clock.Advance(time.Minute)
+	expectNoWarning(t, warnings)
+
+	close(release)
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if got := res.report.Nodes["stuck"].SlowElapsed; got != 11*time.Second {
+		t.Errorf("Expected the report to record 11s, got %s", got)
+	}
+	if got := res.report.Nodes["stuck"].Status; got != graph.StatusSucceeded {
+		t.Errorf("Expected the slow node left to finish, got %s", got)
+	}
+}
+
+func TestSlowNodeWarnsPeriodically(t *testing.T) {
+	clock := newFakeClock()
+	release := make(chan struct{})
+	warnings := make(chan time.Duration, 8)
+	done := runStuck(t, clock, release, warnings, 5*time.Second)
+
+	clock.waitFor(t, 1)
+	clock.Advance(10 * time.Second)
+	expectWarning(t, warnings, 10*time.Second)
+
+	clock.waitFor(t, 1)
+	clock.Advance(5 * time.Second)
+	expectWarning(t, warnings, 15*time.Second)
+
+	clock.waitFor(t, 1)
+	close(release)
+	res := <-done
+	if got := res.report.Nodes["stuck"].SlowElapsed; got != 15*time.Second {
+		t.Errorf("Expected the report to record the last warning, got %s", got)
+	}
+
+	// The watch is stopped even though its timer is still armed
+	clock.Advance(time.Minute)
+	expectNoWarning(t, warnings)
+}
+
+func TestSlowNodeQuickNodeNeverWarns(t *testing.T) {
+	clock := newFakeClock()
+	release := make(chan struct{})
+	close(release)
+	warnings := make(chan time.Duration, 8)
+
+	res := <-runStuck(t, clock, release, warnings)
+	clock.Advance(time.Minute)
+	expectNoWarning(t, warnings)
+	if got := res.report.Nodes["stuck"].SlowElapsed; got != 0 {
+		t.Errorf("Expected no slow elapsed, got %s", got)
+	}
+}
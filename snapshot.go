@@ -0,0 +1,66 @@
+package graph
+
+// Snapshot returns a frozen view of the graph as it is now. The view shares the node map and
+// nodes with g; g copies them lazily the first time it is mutated afterwards, so the view
+// never sees later edits.
+func (g *Graph) Snapshot() *Graph {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.shared = true
+
+	s := &Graph{
+		name:          g.name,
+		namespace:     g.namespace,
+		allowEmpty:    g.allowEmpty,
+		nodes:         g.nodes,
+		frozen:        true,
+		policies:      g.policies,
+		nodeDefaults:  g.nodeDefaults,
+		lazyAdd:       g.lazyAdd,
+		limits:        g.limits,
+		defaultWeight: g.defaultWeight,
+		maxAliasDepth: g.maxAliasDepth,
+		entryPoints:   copyMap(g.entryPoints),
+		transforms:    g.transforms,
+		normalizer:    g.normalizer,
+		// Warnings still flag shared fns
+		sharedFnWarnings: g.sharedFnWarnings,
+	}
+	if len(g.aliases) > 0 {
+		s.aliases = make(map[NodeID]NodeID, len(g.aliases))
+		for alias, target := range g.aliases {
+			s.aliases[alias] = target
+		}
+	}
+	return s
+}
+
+// Called before mutating the node map
+func (g *Graph) own() {
+	if !g.shared {
+		return
+	}
+
+	nodes := make(Nodes, len(g.nodes))
+	borrowed := make(NodeIDs, len(g.nodes))
+	for id, node := range g.nodes {
+		nodes[id] = node
+		borrowed[id] = struct{}{}
+	}
+
+	g.nodes = nodes
+	g.borrowed = borrowed
+	g.shared = false
+}
+
+// Called before mutating a node; returns the graph's own copy of it
+func (g *Graph) ownNode(id NodeID) *Node {
+	g.own()
+
+	if _, ok := g.borrowed[id]; ok {
+		g.nodes[id] = g.nodes[id].clone()
+		delete(g.borrowed, id)
+	}
+	return g.nodes[id]
+}
@@ -0,0 +1,73 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestSnapshotRunsRemovedNode(t *testing.T) {
+	var mu sync.Mutex
+	ran := map[graph.NodeID]bool{}
+	record := func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		ran[ec.ID] = true
+		return nil, nil
+	}
+
+	g := graph.NewGraph("current")
+	g.Add(graph.NewNode("fetch", nil, record))
+	g.Add(graph.NewNode("publish", graph.Deps("fetch"), record))
+
+	snap := g.Snapshot()
+	if err := g.Remove("publish"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Has("publish") {
+		t.Fatal("Expected the original to lose the node")
+	}
+
+	report, err := snap.CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ran["publish"] || report.Nodes["publish"].Status != graph.StatusSucceeded {
+		t.Error("Expected the snapshot to run the node removed from the original")
+	}
+}
+
+func TestSnapshotDoesNotSeeLaterEdits(t *testing.T) {
+	g := diamond(t)
+	snap := g.Snapshot()
+
+	g.Add(graph.NewNode("e", graph.Deps("d"), graph.NoOp()))
+	if err := g.AddEdgeKind("c", "b", graph.EdgeSoft); err != nil {
+		t.Fatal(err)
+	}
+
+	if snap.Has("e") {
+		t.Error("Expected the snapshot not to see the added node")
+	}
+	deps, _ := snap.ResolvedDependencies("c")
+	if len(deps) != 1 {
+		t.Errorf("Expected the snapshot's c to keep one dependency, got %v", deps)
+	}
+	if ids := mustSort(t, snap); len(ids) != 4 {
+		t.Errorf("Expected the snapshot to sort its 4 nodes, got %v", ids)
+	}
+}
+
+func TestSnapshotIsFrozen(t *testing.T) {
+	snap := diamond(t).Snapshot()
+
+	if !snap.Frozen() {
+		t.Error("Expected the snapshot to be frozen")
+	}
+	if _, err := snap.Add(graph.NewNode("e", nil, graph.NoOp())); !errors.Is(err, graph.ErrGraphFrozen) {
+		t.Errorf("Expected ErrGraphFrozen, got %v", err)
+	}
+}
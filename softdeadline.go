@@ -0,0 +1,43 @@
+package graph
+
+import (
+	"time"
+)
+
+// Stops dispatching once the run's clock reaches t: running nodes finish, everything else is
+// NotRun with reason deadline-reached and the report is marked Incomplete. Unlike
+// WithRunTimeout nothing is interrupted, and reaching the deadline isn't an error in itself; the
+// run only returns an error for nodes that failed.
+func WithSoftDeadline(t time.Time) ExecOption {
+	return func(c *ExecConfig) {
+		c.SoftDeadline = t
+	}
+}
+
+// Arms the soft deadline, if the run has one
+func (r *run) armSoftDeadline() {
+	if r.cfg.SoftDeadline.IsZero() {
+		return
+	}
+
+	wait := r.cfg.SoftDeadline.Sub(r.clock().Now())
+	if wait <= 0 {
+		r.draining = true
+		return
+	}
+	r.softDeadline = r.clock().After(wait)
+}
+
+// Whether the run has stopped dispatching because its soft deadline passed
+func (r *run) drained() bool {
+	if r.draining {
+		return true
+	}
+
+	select {
+	case <-r.softDeadline:
+		r.draining = true
+	default:
+	}
+	return r.draining
+}
@@ -0,0 +1,110 @@
+package graph_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// a and b take 10s each by clock, in a chain ending at c
+func timedChain(clock *fakeClock, b graph.NodeFn) *graph.Graph {
+	g := graph.NewGraph("nightly")
+	g.Add(graph.NewNode("a", nil, takes(clock, 10*time.Second)))
+	g.Add(graph.NewNode("b", graph.Deps("a"), b))
+	g.Add(graph.NewNode("c", graph.Deps("b"), graph.NoOp()))
+	return g
+}
+
+func TestSoftDeadlineDrains(t *testing.T) {
+	clock := newFakeClock()
+	hooks, finished := finishes()
+	done := runAsync(timedChain(clock, takes(clock, 10*time.Second)).CompileToExecutable(),
+		graph.WithClock(clock), graph.WithSoftDeadline(clock.Now().Add(15*time.Second)), hooks)
+
+	// The deadline and a
+	clock.waitFor(t, 2)
+	clock.Advance(10 * time.Second)
+	awaitFinish(t, finished, "a")
+	// The deadline passes while b runs; b still gets to finish
+	clock.waitFor(t, 2)
+	clock.Advance(5 * time.Second)
+	clock.Advance(5 * time.Second)
+
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("Expected no error for a run that only ran out of time, got %v", res.err)
+	}
+	report := res.report
+	if !report.Incomplete {
+		t.Error("Expected the report marked incomplete")
+	}
+	if nr := report.Nodes["b"]; nr.Status != graph.StatusSucceeded {
+		t.Errorf("Expected b to finish, got %s/%s", nr.Status, nr.Reason)
+	}
+	if nr := report.Nodes["c"]; nr.Status != graph.StatusNotRun || nr.Reason != graph.ReasonDeadlineReached {
+		t.Errorf("Expected c left as deadline-reached, got %s/%s", nr.Status, nr.Reason)
+	}
+}
+
+func TestSoftDeadlineNotReached(t *testing.T) {
+	clock := newFakeClock()
+	hooks, finished := finishes()
+	done := runAsync(timedChain(clock, takes(clock, 10*time.Second)).CompileToExecutable(),
+		graph.WithClock(clock), graph.WithSoftDeadline(clock.Now().Add(time.Hour)), hooks)
+
+	clock.waitFor(t, 2)
+	clock.Advance(10 * time.Second)
+	awaitFinish(t, finished, "a")
+	clock.waitFor(t, 2)
+	clock.Advance(10 * time.Second)
+
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if res.report.Incomplete {
+		t.Error("Expected a run that finished in time to be complete")
+	}
+	if nr := res.report.Nodes["c"]; nr.Status != graph.StatusSucceeded {
+		t.Errorf("Expected c to run, got %s", nr.Status)
+	}
+}
+
+func TestSoftDeadlineAlreadyPassed(t *testing.T) {
+	clock := newFakeClock()
+	report, err := diamond(t).CompileToExecutable().Run(ctx(t), graph.WithClock(clock), graph.WithSoftDeadline(clock.Now().Add(-time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Incomplete {
+		t.Error("Expected the report marked incomplete")
+	}
+	for id, nr := range report.Nodes {
+		if nr.Reason != graph.ReasonDeadlineReached {
+			t.Errorf("Expected %s left as deadline-reached, got %s/%s", id, nr.Status, nr.Reason)
+		}
+	}
+}
+
+func TestSoftDeadlineStillReportsFailures(t *testing.T) {
+	clock := newFakeClock()
+	boom := errors.New("boom")
+	hooks, finished := finishes()
+	g := timedChain(clock, takes(clock, 10*time.Second))
+	g.Add(graph.NewNode("broken", nil, failWith(boom)))
+	done := runAsync(g.CompileToExecutable(), graph.WithClock(clock), graph.WithSoftDeadline(clock.Now().Add(5*time.Second)), hooks)
+
+	clock.waitFor(t, 2)
+	awaitFinish(t, finished, "broken")
+	clock.Advance(10 * time.Second)
+
+	res := <-done
+	if !errors.Is(res.err, boom) {
+		t.Errorf("Expected the failure returned, got %v", res.err)
+	}
+	if !res.report.Incomplete || res.report.Nodes["b"].Reason != graph.ReasonDeadlineReached {
+		t.Errorf("Expected b left by the deadline, got %s", res.report.Nodes["b"].Reason)
+	}
+}
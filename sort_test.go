@@ -0,0 +1,120 @@
+package graph_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Run under -race: Sort and SortCached read the graph while other goroutines add to it and a
+// compiled copy runs
+func TestSortConcurrentWithAdd(t *testing.T) {
+	g := graph.NewGraph("concurrent")
+	g.Add(graph.NewNode("root", nil, returns(nil)))
+	peg := g.CompileToExecutable()
+
+	const writers, perWriter = 4, 100
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				if _, err := g.Add(graph.NewNode(fmt.Sprintf("n%d_%d", w, i), graph.Deps("root"), returns(nil))); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				ids, err := g.Sort()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if ids[0] != "root" {
+					t.Errorf("Expected root first, got %s", ids[0])
+					return
+				}
+				if _, err := g.SortCached(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := peg.Run(ctx(t)); err != nil {
+			t.Error(err)
+		}
+	}()
+	wg.Wait()
+
+	ids, err := g.SortCached()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != writers*perWriter+1 {
+		t.Errorf("Expected %d nodes, got %d", writers*perWriter+1, len(ids))
+	}
+}
+
+func TestSortCachedInvalidatesOnEdgeChange(t *testing.T) {
+	g := graph.NewGraph("cached")
+	g.Add(graph.NewNode("x", nil, returns(nil)))
+	g.Add(graph.NewNode("y", nil, returns(nil)))
+	if _, err := g.SortCached(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.AddEdge("x", "y"); err != nil {
+		t.Fatal(err)
+	}
+	ids, err := g.SortCached()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !before(ids, "y", "x") {
+		t.Errorf("Expected y before x once x depends on it, got %v", ids)
+	}
+
+	// A cycle must replace the cached order with an error
+	g.AddEdge("y", "x")
+	if _, err := g.SortCached(); err == nil {
+		t.Error("Expected the cycle to invalidate the cached order")
+	}
+}
+
+func TestSortCachedInvalidatesOnAddAndRemove(t *testing.T) {
+	g := diamond(t)
+	ids, _ := g.SortCached()
+	if len(ids) != 4 {
+		t.Fatalf("Expected 4 nodes, got %v", ids)
+	}
+
+	g.Add(graph.NewNode("e", graph.Deps("d"), returns(nil)))
+	if ids, _ := g.SortCached(); len(ids) != 5 || ids[4] != "e" {
+		t.Errorf("Expected e appended after an Add, got %v", ids)
+	}
+
+	g.Remove("e")
+	if ids, _ := g.SortCached(); len(ids) != 4 {
+		t.Errorf("Expected e gone after a Remove, got %v", ids)
+	}
+}
+
+func TestSortCachedReturnsCopies(t *testing.T) {
+	g := diamond(t)
+	ids, _ := g.SortCached()
+	ids[0] = "mutated"
+
+	if again, _ := g.SortCached(); again[0] != "a" {
+		t.Errorf("Expected the cache unaffected by a caller's changes, got %v", again)
+	}
+}
@@ -0,0 +1,111 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var ErrStaleCompile = errors.New("Compiled graph is stale")
+
+type StaleCompileError struct {
+	Compiled string
+	Current  string
+}
+
+func (e *StaleCompileError) Error() string {
+	return fmt.Sprintf("%s: compiled from fingerprint %s but the graph is now %s", ErrStaleCompile, e.Compiled, e.Current)
+}
+
+func (e *StaleCompileError) Is(target error) bool {
+	return target == ErrStaleCompile
+}
+
+type StaleBehavior int
+
+const (
+	// Refuse to run with a StaleCompileError
+	OnStaleRefuse StaleBehavior = iota
+	// Warn through the run's Logger, if any, and run the compiled shape anyway
+	OnStaleWarn
+)
+
+// Checks before the run starts that g still has the shape it had when compiled. Mutations that
+// leave the nodes and edges as they were don't count.
+func WithStalenessCheck(g *Graph, behavior ...StaleBehavior) ExecOption {
+	return func(c *ExecConfig) {
+		c.StaleSource = g
+		c.OnStale = OnStaleRefuse
+
+		if len(behavior) > 0 {
+			c.OnStale = behavior[0]
+		}
+	}
+}
+
+func (peg *ParallelizedExecutableGraph) checkStale(cfg ExecConfig) error {
+	g := cfg.StaleSource
+	if g == nil || !peg.Stale(g) {
+		return nil
+	}
+
+	current := g.Fingerprint()
+	if current == peg.sourceFingerprint {
+		return nil
+	}
+
+	err := &StaleCompileError{Compiled: peg.sourceFingerprint, Current: current}
+	if cfg.OnStale == OnStaleRefuse {
+		return err
+	}
+	if cfg.Logger != nil {
+		cfg.Logger.Printf("%s", err)
+	}
+	return nil
+}
+
+// The Fingerprint of the graph as of the compile or the last Recompile
+func (peg *ParallelizedExecutableGraph) SourceFingerprint() string {
+	return peg.sourceFingerprint
+}
+
+// Identifies the compiled structure: node ids and their edges, soft ones marked
+func (peg *ParallelizedExecutableGraph) Fingerprint() string {
+	return fingerprint(sortedIDs(peg.nodes), func(id NodeID) (NodeIDs, NodeIDs) {
+		return peg.nodes[id].dependencies, peg.nodes[id].softIDs
+	})
+}
+
+// The Fingerprint a compile of the graph would have now
+func (g *Graph) Fingerprint() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.fingerprint()
+}
+
+func (g *Graph) fingerprint() string {
+	return fingerprint(sortedIDs(g.nodes), func(id NodeID) (NodeIDs, NodeIDs) {
+		node := g.nodes[id]
+		soft := make(NodeIDs, len(node.softDependencies))
+		for depId := range node.softDependencies {
+			soft[g.resolve(depId)] = struct{}{}
+		}
+		return g.dependencies(node), soft
+	})
+}
+
+func fingerprint(ids SortedNodeIDs, edges func(id NodeID) (deps NodeIDs, soft NodeIDs)) string {
+	h := sha256.New()
+	for _, id := range ids {
+		deps, soft := edges(id)
+		fmt.Fprintf(h, "%q", id)
+		for _, depId := range sortedIDs(deps) {
+			_, isSoft := soft[depId]
+			fmt.Fprintf(h, " %q:%t", depId, isSoft)
+		}
+		fmt.Fprintln(h)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
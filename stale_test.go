@@ -0,0 +1,96 @@
+package graph_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestStalenessCheckRefuses(t *testing.T) {
+	g := diamond(t)
+	peg := g.CompileToExecutable()
+	if peg.SourceFingerprint() != g.Fingerprint() {
+		t.Fatal("Expected the compile to record the graph's fingerprint")
+	}
+
+	g.Add(graph.NewNode("e", graph.Deps("d"), graph.NoOp()))
+
+	_, err := peg.Run(ctx(t), graph.WithStalenessCheck(g))
+	var serr *graph.StaleCompileError
+	if !errors.As(err, &serr) || !errors.Is(err, graph.ErrStaleCompile) {
+		t.Fatalf("Expected a StaleCompileError, got %v", err)
+	}
+	if serr.Compiled != peg.SourceFingerprint() || serr.Current != g.Fingerprint() {
+		t.Errorf("Expected both fingerprints in the error, got %+v", serr)
+	}
+
+	// Without the check the old shape runs
+	report, err := peg.Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := report.Nodes["e"]; ok || len(report.Nodes) != 4 {
+		t.Errorf("Expected the compiled four nodes to run, got %d", len(report.Nodes))
+	}
+}
+
+func TestStalenessCheckWarns(t *testing.T) {
+	g := diamond(t)
+	peg := g.CompileToExecutable()
+	g.AddEdge("d", "a")
+
+	logger := &recordingLogger{}
+	if _, err := peg.Run(ctx(t), graph.WithStalenessCheck(g, graph.OnStaleWarn), graph.WithLogger(logger)); err != nil {
+		t.Fatalf("Expected a warning rather than an error, got %v", err)
+	}
+
+	warned := false
+	for _, line := range logger.lines {
+		warned = warned || strings.Contains(line, graph.ErrStaleCompile.Error())
+	}
+	if !warned {
+		t.Errorf("Expected a staleness warning, got %v", logger.lines)
+	}
+}
+
+func TestStalenessCheckIgnoresUndoneChanges(t *testing.T) {
+	g := diamond(t)
+	peg := g.CompileToExecutable()
+
+	g.Add(graph.NewNode("e", nil, graph.NoOp()))
+	g.Remove("e")
+	if !peg.Stale(g) {
+		t.Fatal("Expected the mutations to be counted")
+	}
+	if _, err := peg.Run(ctx(t), graph.WithStalenessCheck(g)); err != nil {
+		t.Errorf("Expected the same shape to pass the check, got %v", err)
+	}
+}
+
+func TestStalenessCheckAfterRecompile(t *testing.T) {
+	g := diamond(t)
+	peg := g.CompileToExecutable()
+	g.Add(graph.NewNode("e", graph.Deps("d"), graph.NoOp()))
+
+	if err := peg.Recompile(g, graph.Diff{Added: graph.Deps("e")}); err != nil {
+		t.Fatal(err)
+	}
+	if peg.SourceFingerprint() != g.Fingerprint() {
+		t.Error("Expected Recompile to record the new fingerprint")
+	}
+	if _, err := peg.Run(ctx(t), graph.WithStalenessCheck(g)); err != nil {
+		t.Errorf("Expected the recompiled graph to pass the check, got %v", err)
+	}
+}
+
+func TestStalenessCheckOtherGraph(t *testing.T) {
+	other := diamond(t)
+	other.Add(graph.NewNode("e", nil, graph.NoOp()))
+
+	_, err := diamond(t).CompileToExecutable().Run(ctx(t), graph.WithStalenessCheck(other))
+	if !errors.Is(err, graph.ErrStaleCompile) {
+		t.Errorf("Expected a differently shaped graph to fail the check, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const StateSchemaVersion = 1
+
+var ErrStateMismatch = errors.New("Run state was taken from a different graph")
+
+// RunState follows a run's progress so it can be saved with SnapshotState and picked up again
+// with ResumeFromState after the process dies. Pass it to the run with WithRunState; each run
+// it's given starts it over.
+type RunState struct {
+	mu      sync.Mutex
+	peg     *ParallelizedExecutableGraph
+	status  map[NodeID]NodeStatus
+	results Results
+}
+
+func NewRunState() *RunState {
+	return &RunState{}
+}
+
+func WithRunState(state *RunState) ExecOption {
+	return func(c *ExecConfig) {
+		c.State = state
+	}
+}
+
+func (s *RunState) reset(peg *ParallelizedExecutableGraph) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.peg = peg
+	s.status = make(map[NodeID]NodeStatus, len(peg.nodes))
+	s.results = make(Results)
+}
+
+func (s *RunState) record(nr NodeReport, value any) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status[nr.ID] = nr.Status
+	if nr.Reason == ReasonResultUnavailable {
+		value = resultUnavailable{}
+	}
+	if nr.Status == StatusSucceeded && value != nil {
+		s.results[nr.ID] = value
+	}
+}
+
+type stateJSON struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Fingerprint   string          `json:"fingerprint"`
+	Nodes         []nodeStateJSON `json:"nodes"`
+	Pending       map[NodeID]int  `json:"pending"`
+}
+
+type nodeStateJSON struct {
+	ID     NodeID          `json:"id"`
+	Status NodeStatus      `json:"status"`
+	Result json.RawMessage `json:"result,omitempty"`
+	// Succeeded, but the result couldn't be marshaled
+	ResultUnavailable bool `json:"resultUnavailable,omitempty"`
+}
+
+// SnapshotState encodes what state has recorded of a run of this graph so far: every settled
+// node's status, the results of the succeeded ones as JSON, and how many dependencies each
+// unsettled node is still waiting on. It's safe to call while the run is going.
+func (peg *ParallelizedExecutableGraph) SnapshotState(state *RunState) ([]byte, error) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.peg != peg {
+		return nil, ErrStateMismatch
+	}
+
+	out := stateJSON{
+		SchemaVersion: StateSchemaVersion,
+		Fingerprint:   peg.Fingerprint(),
+		Nodes:         []nodeStateJSON{},
+		Pending:       map[NodeID]int{},
+	}
+	for _, id := range sortedIDs(peg.nodes) {
+		status, ok := state.status[id]
+		if !ok {
+			waiting := 0
+			for depId := range peg.nodes[id].dependencies {
+				if _, ok := state.status[depId]; !ok {
+					waiting++
+				}
+			}
+			out.Pending[id] = waiting
+			continue
+		}
+
+		node := nodeStateJSON{ID: id, Status: status}
+		if value, ok := state.results[id]; ok {
+			if _, ok := value.(resultUnavailable); ok {
+				node.ResultUnavailable = true
+			} else if data, err := json.Marshal(value); err == nil {
+				node.Result = data
+			} else {
+				node.ResultUnavailable = true
+			}
+		}
+		out.Nodes = append(out.Nodes, node)
+	}
+
+	return json.Marshal(out)
+}
+
+// Stands in for a result SnapshotState couldn't save
+type resultUnavailable struct{}
+
+// ResumeFromState compiles g, checks it matches the graph the state was taken from and runs it
+// with the nodes that had succeeded precompleted. Their results come back as decoded JSON, so
+// dependents see maps, slices, float64s and the like rather than the original types; ones that
+// couldn't be saved are missing from dependents' Results and reported as result-unavailable.
+// Every other node runs again.
+func ResumeFromState(ctx context.Context, g *Graph, blob []byte, opts ...ExecOption) (*Report, error) {
+	var in stateJSON
+	if err := json.Unmarshal(blob, &in); err != nil {
+		return nil, err
+	}
+	if in.SchemaVersion > StateSchemaVersion {
+		return nil, fmt.Errorf("Run state schema version %d is newer than the supported version %d", in.SchemaVersion, StateSchemaVersion)
+	}
+
+	peg := g.CompileToExecutable(opts...)
+	if peg.Fingerprint() != in.Fingerprint {
+		return nil, ErrStateMismatch
+	}
+
+	precompleted := map[NodeID]any{}
+	for _, node := range in.Nodes {
+		if node.Status != StatusSucceeded {
+			continue
+		}
+
+		var value any
+		switch {
+		case node.ResultUnavailable:
+			value = resultUnavailable{}
+		case len(node.Result) > 0:
+			if err := json.Unmarshal(node.Result, &value); err != nil {
+				return nil, fmt.Errorf("Node %s has an unreadable result: %w", node.ID, err)
+			}
+		}
+		precompleted[node.ID] = value
+	}
+
+	return peg.Run(ctx, WithPrecompleted(precompleted))
+}
@@ -0,0 +1,110 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A pipeline whose level 0 counts its calls in level0; "orders" returns a result JSON can't
+// encode. join is given by the caller.
+func pipeline(level0 *atomic.Int32, join graph.NodeFn) *graph.Graph {
+	g := graph.NewGraph("pipeline")
+	g.Add(graph.NewNode("users", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		level0.Add(1)
+		return []string{"ann", "bob"}, nil
+	}))
+	g.Add(graph.NewNode("orders", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		level0.Add(1)
+		return func() {}, nil
+	}))
+	g.Add(graph.NewNode("join", graph.Deps("users", "orders"), join))
+	return g
+}
+
+func TestResumeFromStateSkipsLevelZero(t *testing.T) {
+	// The first process gets through level 0 and dies while join runs
+	var first atomic.Int32
+	started := make(chan graph.NodeID, 1)
+	peg := pipeline(&first, blockUntilCanceled(started)).CompileToExecutable()
+	state := graph.NewRunState()
+
+	c, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		peg.Run(c, graph.WithRunState(state))
+	}()
+	<-started
+	blob, err := peg.SnapshotState(state)
+	cancel()
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The restarted process builds everything afresh
+	var second atomic.Int32
+	var seen graph.Results
+	g := pipeline(&second, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		seen = ec.Results
+		return nil, nil
+	})
+
+	report, err := graph.ResumeFromState(ctx(t), g, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Load() != 0 {
+		t.Errorf("Expected level 0 not to run again, got %d calls", second.Load())
+	}
+	if seen == nil {
+		t.Fatal("Expected join to run")
+	}
+	if want := []any{"ann", "bob"}; !reflect.DeepEqual(seen["users"], want) {
+		t.Errorf("Expected the saved users decoded from JSON, got %#v", seen["users"])
+	}
+	if _, ok := seen["orders"]; ok {
+		t.Errorf("Expected the unsaveable orders result missing, got %v", seen["orders"])
+	}
+
+	orders := report.Nodes["orders"]
+	if orders.Status != graph.StatusSucceeded || orders.Reason != graph.ReasonResultUnavailable {
+		t.Errorf("Expected orders succeeded with its result unavailable, got %s %s", orders.Status, orders.Reason)
+	}
+	if got := report.Nodes["users"].Reason; got != graph.ReasonPrecompleted {
+		t.Errorf("Expected users precompleted, got %s", got)
+	}
+}
+
+func TestResumeFromStateDifferentGraph(t *testing.T) {
+	var calls atomic.Int32
+	peg := pipeline(&calls, graph.NoOp()).CompileToExecutable()
+	state := graph.NewRunState()
+	if _, err := peg.Run(ctx(t), graph.WithRunState(state)); err != nil {
+		t.Fatal(err)
+	}
+	blob, err := peg.SnapshotState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := graph.ResumeFromState(ctx(t), diamond(t), blob); !errors.Is(err, graph.ErrStateMismatch) {
+		t.Errorf("Expected ErrStateMismatch for another graph, got %v", err)
+	}
+}
+
+func TestSnapshotStateFromAnotherGraph(t *testing.T) {
+	state := graph.NewRunState()
+	if _, err := diamond(t).CompileToExecutable().Run(ctx(t), graph.WithRunState(state)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := diamond(t).CompileToExecutable().SnapshotState(state); !errors.Is(err, graph.ErrStateMismatch) {
+		t.Errorf("Expected ErrStateMismatch snapshotting another graph's state, got %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Reads the same definitions as ParseJSON, but adds each node as soon as it's decoded instead of
+// reading the whole input first, so peak memory stays close to the size of the graph it builds.
+// Dependencies are checked once every node is in, then the graph is validated. Keys that set up
+// the graph must come before nodes, as WriteJSON writes them; aliases may come after. Errors
+// carry the byte offset they were found near.
+func ParseJSONStream(r io.Reader, fnFactory func(name string) NodeFn, opts ...LoadOption) (*Graph, error) {
+	cfg := loadConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	s := &definitionStream{dec: json.NewDecoder(r), cfg: cfg, fnFactory: fnFactory}
+	if cfg.lax == nil {
+		s.dec.DisallowUnknownFields()
+	}
+	if err := s.read(); err != nil {
+		return nil, fmt.Errorf("Near byte %d: %w", s.offset, err)
+	}
+	if err := s.finish(); err != nil {
+		return nil, err
+	}
+	return s.g, nil
+}
+
+type definitionStream struct {
+	dec       *json.Decoder
+	cfg       loadConfig
+	fnFactory func(name string) NodeFn
+	// Where the value being read starts
+	offset int64
+	header definitionJSON
+	g      *Graph
+	// The lazy-add setting the graph was configured with, restored once it's loaded
+	lazyAdd bool
+}
+
+func (s *definitionStream) read() error {
+	if err := s.delim('{'); err != nil {
+		return err
+	}
+
+	for s.dec.More() {
+		s.offset = s.dec.InputOffset()
+		token, err := s.dec.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+
+		s.offset = s.dec.InputOffset()
+		// Decoding matches keys case-insensitively too
+		switch strings.ToLower(key) {
+		case "nodes":
+			if s.g != nil {
+				return fmt.Errorf("Definition lists nodes more than once")
+			}
+			err = s.nodes()
+		case "aliases":
+			err = s.dec.Decode(&s.header.Aliases)
+		case "schemaversion":
+			err = s.setting(key, &s.header.SchemaVersion)
+			if err == nil && s.header.SchemaVersion > DefinitionSchemaVersion {
+				err = fmt.Errorf("Definition schema version %d is newer than the supported version %d", s.header.SchemaVersion, DefinitionSchemaVersion)
+			}
+		case "name":
+			err = s.setting(key, &s.header.Name)
+		case "namespace":
+			err = s.setting(key, &s.header.Namespace)
+		case "allowempty":
+			err = s.setting(key, &s.header.AllowEmpty)
+		case "defaultedgeweight":
+			err = s.setting(key, &s.header.DefaultEdgeWeight)
+		case "defaults":
+			err = s.setting(key, &s.header.Defaults)
+		default:
+			if s.cfg.lax == nil {
+				return fmt.Errorf("Unknown key %q", key)
+			}
+			s.cfg.lax.Printf("Ignoring unknown key %s", key)
+			err = s.dec.Decode(&json.RawMessage{})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	s.offset = s.dec.InputOffset()
+	if err := s.delim('}'); err != nil {
+		return err
+	}
+	if s.g == nil {
+		// No nodes to stream; the graph is only its settings
+		return s.start()
+	}
+	return nil
+}
+
+func (s *definitionStream) delim(want json.Delim) error {
+	token, err := s.dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != want {
+		return fmt.Errorf("Expected %s, found %v", want, token)
+	}
+	return nil
+}
+
+// Reads a key that sets up the graph, which can't change once nodes are being added to it
+func (s *definitionStream) setting(key string, v any) error {
+	if s.g != nil {
+		return fmt.Errorf("Key %s must come before nodes", key)
+	}
+	return s.dec.Decode(v)
+}
+
+// Makes the graph from the settings read so far
+func (s *definitionStream) start() error {
+	if _, ok := definitionDecoders[s.header.SchemaVersion]; !ok {
+		return fmt.Errorf("Unknown definition schema version %d", s.header.SchemaVersion)
+	}
+
+	g, err := s.header.newGraph(s.cfg)
+	if err != nil {
+		return err
+	}
+	s.g = g
+	s.lazyAdd = g.lazyAdd
+	g.lazyAdd = true
+	return nil
+}
+
+// Decodes and adds the nodes one at a time
+func (s *definitionStream) nodes() error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	if err := s.delim('['); err != nil {
+		return err
+	}
+
+	for i := 0; s.dec.More(); i++ {
+		s.offset = s.dec.InputOffset()
+		def, err := s.node(i)
+		if err != nil {
+			return err
+		}
+		if s.g.exists(s.g.normalize(def.ID)) {
+			return fmt.Errorf("Definition lists node %s more than once", def.ID)
+		}
+
+		node, err := def.node(s.fnFactory, s.cfg)
+		if err != nil {
+			return err
+		}
+		if _, err := s.g.Add(node); err != nil {
+			return err
+		}
+	}
+
+	s.offset = s.dec.InputOffset()
+	return s.delim(']')
+}
+
+// The ith node in the current format, whatever version it was written in
+func (s *definitionStream) node(i int) (nodeDefinitionJSON, error) {
+	if s.header.SchemaVersion < 2 {
+		def := nodeDefinitionV1JSON{}
+		err := s.decodeNode(i, &def)
+		return def.current(), err
+	}
+	def := nodeDefinitionJSON{}
+	err := s.decodeNode(i, &def)
+	return def, err
+}
+
+func (s *definitionStream) decodeNode(i int, def any) error {
+	if s.cfg.lax == nil {
+		return s.dec.Decode(def)
+	}
+
+	var raw json.RawMessage
+	if err := s.dec.Decode(&raw); err != nil {
+		return err
+	}
+	for _, key := range unknownKeys(raw, reflect.TypeOf(def), fmt.Sprintf("nodes[%d]", i)) {
+		s.cfg.lax.Printf("Ignoring unknown key %s", key)
+	}
+	return json.Unmarshal(raw, def)
+}
+
+// Adds the aliases, then checks what adding the nodes one by one couldn't
+func (s *definitionStream) finish() error {
+	g := s.g
+
+	g.mu.Lock()
+	_, aliases, err := g.normalizedAll(nil, s.header.Aliases)
+	g.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	// Aliases may point to aliases, so add each once its target is in
+	for len(aliases) > 0 {
+		added := 0
+		for _, alias := range sortedIDs(aliases) {
+			if !g.exists(aliases[alias]) {
+				continue
+			}
+			if err := g.Alias(alias, aliases[alias]); err != nil {
+				return err
+			}
+			delete(aliases, alias)
+			added++
+		}
+		if added == 0 {
+			alias := sortedIDs(aliases)[0]
+			return fmt.Errorf("Alias %s points to missing node %s", alias, aliases[alias])
+		}
+	}
+
+	g.mu.Lock()
+	g.lazyAdd = s.lazyAdd
+	err = g.missingDependency()
+	g.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return g.Validate()
+}
@@ -0,0 +1,190 @@
+package graph_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func streamJSON(t *testing.T, def string, opts ...graph.LoadOption) *graph.Graph {
+	t.Helper()
+
+	g, err := graph.ParseJSONStream(strings.NewReader(def), noOps, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestStreamLoadsLikeParseJSON(t *testing.T) {
+	v1, err := os.ReadFile(filepath.Join("testdata", "definition_v1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, def := range map[string]string{"current": writeJSON(t, attributed(t)), "v1": string(v1)} {
+		t.Run(name, func(t *testing.T) {
+			parsed, streamed := parseJSON(t, def), streamJSON(t, def)
+
+			if streamed.Fingerprint() != parsed.Fingerprint() {
+				t.Error("Expected the same graph from both loaders")
+			}
+			if want, got := parsed.WeightedEdges(), streamed.WeightedEdges(); !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected edges %v, got %v", want, got)
+			}
+			for _, id := range mustSort(t, parsed) {
+				if want, got := effective(t, parsed, id), effective(t, streamed, id); !reflect.DeepEqual(got, want) {
+					t.Errorf("Expected %s to load with\n%+v\ngot\n%+v", id, want, got)
+				}
+			}
+			if want, got := writeJSON(t, parsed), writeJSON(t, streamed); got != want {
+				t.Errorf("Expected the streamed graph written as\n%s\ngot\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestStreamForwardReferences(t *testing.T) {
+	g := streamJSON(t, `{"schemaVersion": 2, "name": "etl", "nodes": [
+		{"id": "load", "dependencies": ["warehouse-in"]},
+		{"id": "transform", "dependencies": ["extract"]},
+		{"id": "extract"}
+	], "aliases": {"warehouse-in": "staged", "staged": "transform"}}`)
+
+	if deps, _ := g.ResolvedDependencies("load"); !deps.Equal(graph.Deps("transform")) {
+		t.Errorf("Expected load to resolve through both aliases, got %v", deps)
+	}
+	if ids := mustSort(t, g); !before(ids, "extract", "transform") || !before(ids, "transform", "load") {
+		t.Errorf("Expected the nodes ordered, got %v", ids)
+	}
+
+	// Lazy adding lasts only as long as the load
+	if _, err := g.Add(graph.NewNode("report", graph.Deps("missing"), graph.NoOp())); err == nil {
+		t.Error("Expected the loaded graph to check dependencies as nodes are added again")
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, def, err string
+	}{
+		{
+			"missing dependency",
+			`{"schemaVersion": 2, "name": "g", "nodes": [{"id": "a", "dependencies": ["b"]}]}`,
+			"Node a is missing dependency b",
+		},
+		{
+			"cycle",
+			`{"schemaVersion": 2, "name": "g", "nodes": [{"id": "a", "dependencies": ["b"]}, {"id": "b", "dependencies": ["a"]}]}`,
+			// Either node can be met first
+			"Detected cycle on ",
+		},
+		{
+			"duplicate node",
+			`{"schemaVersion": 2, "name": "g", "nodes": [{"id": "a"}, {"id": "a"}]}`,
+			"Near byte 55: Definition lists node a more than once",
+		},
+		{
+			"setting after nodes",
+			`{"schemaVersion": 2, "nodes": [{"id": "a"}], "name": "g"}`,
+			"Near byte 51: Key name must come before nodes",
+		},
+		{
+			"unknown key",
+			`{"schemaVersion": 2, "owner": "data", "name": "g", "nodes": []}`,
+			"Near byte 28: Unknown key \"owner\"",
+		},
+		{
+			"newer schema",
+			`{"schemaVersion": 3, "name": "g", "nodes": []}`,
+			"Definition schema version 3 is newer than the supported version 2",
+		},
+		{
+			"dangling alias",
+			`{"schemaVersion": 2, "name": "g", "nodes": [{"id": "a"}], "aliases": {"b": "missing"}}`,
+			"Alias b points to missing node missing",
+		},
+		{
+			"truncated",
+			`{"schemaVersion": 2, "name": "g", "nodes": [{"id": "a"}, {"id": `,
+			"Near byte 55: unexpected EOF",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := graph.ParseJSONStream(strings.NewReader(tc.def), noOps); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Expected %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestStreamLax(t *testing.T) {
+	logger := &recordingLogger{}
+	g := streamJSON(t, `{"schemaVersion": 2, "name": "g", "owner": "data", "nodes": [
+		{"id": "a", "attributes": {"queue": 3}}
+	]}`, graph.LaxLoad(logger))
+
+	want := []string{"Ignoring unknown key owner", "Ignoring unknown key nodes[0].attributes.queue"}
+	if !reflect.DeepEqual(logger.lines, want) {
+		t.Errorf("Expected %q logged in the order read, got %q", want, logger.lines)
+	}
+	if !g.Has("a") {
+		t.Error("Expected the known parts loaded")
+	}
+}
+
+func TestStreamSettingsOnly(t *testing.T) {
+	g := streamJSON(t, `{"schemaVersion": 2, "name": "empty", "allowEmpty": true}`)
+	if ids := mustSort(t, g); len(ids) != 0 || !strings.Contains(writeJSON(t, g), `"name": "empty"`) {
+		t.Errorf("Expected an empty graph named empty, got %v", ids)
+	}
+}
+
+// A chain of n nodes, each with a little metadata, as WriteJSON would write it
+func largeDefinition(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"schemaVersion": 2, "name": "large", "nodes": [`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"id": "node-%d", "attributes": {"metadata": {"team": "data", "shard": "%d"}}`, i, i%16)
+		if i > 0 {
+			fmt.Fprintf(&b, `, "dependencies": ["node-%d"]`, i-1)
+		}
+		b.WriteString("}")
+	}
+	b.WriteString("]}")
+	return b.Bytes()
+}
+
+// B/op compares what each loader allocates on the way to the same graph
+func BenchmarkDefinitionLoad(b *testing.B) {
+	for _, n := range []int{10000, 100000} {
+		def := largeDefinition(n)
+
+		for _, loader := range []struct {
+			name string
+			load func(r *bytes.Reader) (*graph.Graph, error)
+		}{
+			{"parse", func(r *bytes.Reader) (*graph.Graph, error) { return graph.ParseJSON(r, noOps) }},
+			{"stream", func(r *bytes.Reader) (*graph.Graph, error) { return graph.ParseJSONStream(r, noOps) }},
+		} {
+			b.Run(fmt.Sprintf("%s-%d", loader.name, n), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := loader.load(bytes.NewReader(def)); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/node")
+			})
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// How many entries String lists before summarising the rest
+const stringLimit = 10
+
+func joinIDs(ids SortedNodeIDs, limit int) string {
+	parts := make([]string, 0, limit+1)
+
+	for i, id := range ids {
+		if i == limit {
+			parts = append(parts, fmt.Sprintf("... and %d more", len(ids)-limit))
+			break
+		}
+		parts = append(parts, string(id))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (n *Node) String() string {
+	return n.describe(n.Dependencies)
+}
+
+func (n *Node) describe(dependencies NodeIDs) string {
+	deps := sortedIDs(dependencies)
+	if len(deps) == 0 {
+		return fmt.Sprintf("%s:", n.Identifier())
+	}
+	return fmt.Sprintf("%s: %s", n.Identifier(), joinIDs(deps, stringLimit))
+}
+
+func (n *Node) GoString() string {
+	deps := sortedIDs(n.Dependencies)
+	quoted := make([]string, len(deps))
+	for i, dep := range deps {
+		quoted[i] = fmt.Sprintf("%q: {}", dep)
+	}
+
+	return fmt.Sprintf("&Node{Name: %q, Dependencies: NodeIDs{%s}}", n.Name, strings.Join(quoted, ", "))
+}
+
+func (g *Graph) edgeCount() int {
+	count := 0
+	for _, node := range g.nodes {
+		count += len(g.dependencies(node))
+	}
+	return count
+}
+
+func (g *Graph) String() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Graph %s: %d nodes, %d edges", g.name, len(g.nodes), g.edgeCount())
+
+	ids := sortedIDs(g.nodes)
+	for i, id := range ids {
+		if i == stringLimit {
+			fmt.Fprintf(&b, "\n  ... and %d more", len(ids)-stringLimit)
+			break
+		}
+		node := g.nodes[id]
+		fmt.Fprintf(&b, "\n  %s", node.describe(g.dependencies(node)))
+	}
+
+	return b.String()
+}
+
+func (g *Graph) GoString() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return fmt.Sprintf("&Graph{name: %q, nodes: %d, edges: %d}", g.name, len(g.nodes), g.edgeCount())
+}
+
+func (nr NodeReport) String() string {
+	s := fmt.Sprintf("%s %s %s", nr.ID, nr.Status, nr.Duration())
+	if len(nr.Attempts) > 1 {
+		s += fmt.Sprintf(" after %d attempts", len(nr.Attempts))
+	}
+	if nr.StartDelay > 0 {
+		s += fmt.Sprintf(", started %s late", nr.StartDelay)
+	}
+	if nr.Reason != "" {
+		s += fmt.Sprintf(" (%s)", nr.Reason)
+	}
+	if nr.Err != nil {
+		s += ": " + nr.Err.Error()
+	}
+	return s
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Report %s: %d nodes in %s", r.Graph, len(r.Nodes), r.Duration())
+
+	counts := []string{}
+	for status := StatusPending; status <= StatusAwaitingApproval; status++ {
+		if n := len(r.WithStatus(status)); n > 0 {
+			counts = append(counts, fmt.Sprintf("%s: %d", status, n))
+		}
+	}
+	if len(counts) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(counts, ", "))
+	}
+	if r.Incomplete {
+		b.WriteString(", incomplete")
+	}
+	if r.AbortReason != "" {
+		fmt.Fprintf(&b, ", aborted: %s", r.AbortReason)
+	}
+	if len(r.Config) > 0 {
+		settings := []string{}
+		for _, key := range sortedKeys(r.Config) {
+			settings = append(settings, key+"="+r.Config[key])
+		}
+		fmt.Fprintf(&b, "\n  config: %s", strings.Join(settings, ", "))
+	}
+
+	ids := sortedIDs(r.Nodes)
+	for i, id := range ids {
+		if i == stringLimit {
+			fmt.Fprintf(&b, "\n  ... and %d more", len(ids)-stringLimit)
+			break
+		}
+		fmt.Fprintf(&b, "\n  %s", r.Nodes[id])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package graph_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestGraphString(t *testing.T) {
+	g := diamond(t)
+	golden(t, "graph_string.golden", fmt.Sprintf("%v\n%#v\n", g, g))
+}
+
+func TestNodeString(t *testing.T) {
+	n := graph.NewNode("deploy", graph.Deps("test", "build"), graph.NoOp())
+	golden(t, "node_string.golden", fmt.Sprintf("%v\n%#v\n", n, n))
+}
+
+func TestReportString(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	report := &graph.Report{
+		Graph: "diamond",
+		Start: start,
+		End:   start.Add(3 * time.Second),
+		Nodes: map[graph.NodeID]*graph.NodeReport{
+			"a": {ID: "a", Status: graph.StatusSucceeded, Start: start, End: start.Add(time.Second)},
+			"b": {ID: "b", Status: graph.StatusFailed, Start: start.Add(time.Second), End: start.Add(2 * time.Second), Err: errors.New("boom")},
+			"c": {ID: "c", Status: graph.StatusSkipped, Reason: graph.ReasonUpstreamFailed},
+		},
+	}
+	golden(t, "report_string.golden", report.String()+"\n")
+}
+
+func TestGraphStringTruncates(t *testing.T) {
+	g := graph.NewGraph("wide")
+	g.Add(graph.NewNode("root", nil, graph.NoOp()))
+	for i := 0; i < 50; i++ {
+		g.Add(graph.NewNode(fmt.Sprintf("leaf-%02d", i), graph.Deps("root"), graph.NoOp()))
+	}
+
+	s := g.String()
+	if !strings.HasPrefix(s, "Graph wide: 51 nodes, 50 edges") {
+		t.Errorf("Expected the header to count everything, got %q", strings.SplitN(s, "\n", 2)[0])
+	}
+	if !strings.HasSuffix(s, "... and 41 more") {
+		t.Errorf("Expected the listing to be cut short, got %q", s)
+	}
+	if lines := strings.Count(s, "\n"); lines != 11 {
+		t.Errorf("Expected 10 listed nodes and a summary line, got %d lines", lines)
+	}
+}
+
+func TestNodeStringTruncates(t *testing.T) {
+	deps := graph.NodeIDs{}
+	for i := 0; i < 25; i++ {
+		deps[graph.NodeID(fmt.Sprintf("dep-%02d", i))] = struct{}{}
+	}
+
+	s := graph.NewNode("fan-in", deps, graph.NoOp()).String()
+	if want := "fan-in: dep-00 dep-01 dep-02 dep-03 dep-04 dep-05 dep-06 dep-07 dep-08 dep-09 ... and 15 more"; s != want {
+		t.Errorf("Expected %q, got %q", want, s)
+	}
+}
@@ -0,0 +1,123 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// SortSubset orders ids, dependencies first, without sorting the rest of the graph. Only the
+// order between the given nodes counts, including order implied through nodes left out: in
+// a <- b <- c, the subset {a, c} sorts a before c. A cycle through the given nodes is reported
+// as a CycleError.
+func (g *Graph) SortSubset(ids NodeIDs) (SortedNodeIDs, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	ids = g.normalizeIDs(ids)
+
+	for _, id := range sortedIDs(ids) {
+		if _, ok := g.nodes[id]; !ok {
+			return nil, fmt.Errorf("Node %s does not exist", id)
+		}
+	}
+
+	// The subset nodes each one depends on, walking only through nodes outside the subset
+	before := make(map[NodeID]NodeIDs, len(ids))
+	pending := make(map[NodeID]int, len(ids))
+	dependents := make(map[NodeID]SortedNodeIDs, len(ids))
+	for _, id := range sortedIDs(ids) {
+		before[id] = make(NodeIDs)
+
+		seen := NodeIDs{}
+		stack := []NodeID{id}
+		for len(stack) > 0 {
+			at := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			for depId := range g.dependencies(g.nodes[at]) {
+				if _, ok := seen[depId]; ok {
+					continue
+				}
+				seen[depId] = struct{}{}
+
+				if _, ok := ids[depId]; ok {
+					before[id][depId] = struct{}{}
+					continue
+				}
+				if _, ok := g.nodes[depId]; ok {
+					stack = append(stack, depId)
+				}
+			}
+		}
+
+		pending[id] = len(before[id])
+		for depId := range before[id] {
+			dependents[depId] = append(dependents[depId], id)
+		}
+	}
+
+	sorted := SortedNodeIDs{}
+	queue := SortedNodeIDs{}
+	for _, id := range sortedIDs(ids) {
+		if pending[id] == 0 {
+			queue = append(queue, id)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		sorted = append(sorted, id)
+
+		for _, dependent := range dependents[id] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	if len(sorted) < len(ids) {
+		err := stuckCycle(pending, func(id NodeID) NodeIDs { return before[id] })
+		if cycle, ok := err.(*CycleError); ok {
+			cycle.Path = g.expandSubsetPath(cycle.Path, ids)
+		}
+		return nil, err
+	}
+	return sorted, nil
+}
+
+// Fills in the nodes outside the subset between each consecutive pair on path
+func (g *Graph) expandSubsetPath(path []NodeID, ids NodeIDs) []NodeID {
+	expanded := []NodeID{path[0]}
+	for i := 1; i < len(path); i++ {
+		from, to := path[i-1], path[i]
+
+		parent := map[NodeID]NodeID{}
+		stack := []NodeID{from}
+		for len(stack) > 0 {
+			at := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			for _, depId := range sortedIDs(g.dependencies(g.nodes[at])) {
+				if _, ok := parent[depId]; ok {
+					continue
+				}
+				parent[depId] = at
+
+				if depId == to {
+					stack = nil
+					break
+				}
+				if _, ok := ids[depId]; !ok {
+					stack = append(stack, depId)
+				}
+			}
+		}
+
+		between := []NodeID{}
+		for at := parent[to]; at != from; at = parent[at] {
+			between = append([]NodeID{at}, between...)
+		}
+		expanded = append(append(expanded, between...), to)
+	}
+	return expanded
+}
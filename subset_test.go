@@ -0,0 +1,80 @@
+package graph_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestSortSubsetTransitive(t *testing.T) {
+	g := chain(t, 4)
+	ids, err := g.SortSubset(graph.Deps("n3", "n0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (graph.SortedNodeIDs{"n0", "n3"}); !reflect.DeepEqual(ids, want) {
+		t.Errorf("Expected %v from the constraint through n1 and n2, got %v", want, ids)
+	}
+}
+
+func TestSortSubsetUnrelated(t *testing.T) {
+	// b and c don't constrain each other; d still needs both
+	g := diamond(t)
+	ids, err := g.SortSubset(graph.Deps("d", "c", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || ids[2] != "d" {
+		t.Errorf("Expected b and c in some order, then d, got %v", ids)
+	}
+}
+
+func TestSortSubsetUnknownID(t *testing.T) {
+	if _, err := diamond(t).SortSubset(graph.Deps("a", "missing")); err == nil {
+		t.Error("Expected an unknown id to error")
+	}
+}
+
+func TestSortSubsetCycle(t *testing.T) {
+	// a and c form a cycle only through b, which isn't in the subset
+	g := graph.NewGraph("cyclic", graph.WithLazyAdd())
+	g.Add(graph.NewNode("a", graph.Deps("c"), graph.NoOp()))
+	g.Add(graph.NewNode("b", graph.Deps("a"), graph.NoOp()))
+	g.Add(graph.NewNode("c", graph.Deps("b"), graph.NoOp()))
+	g.Add(graph.NewNode("d", nil, graph.NoOp()))
+
+	_, err := g.SortSubset(graph.Deps("a", "c", "d"))
+	expectCyclePath(t, g, err, "", "")
+
+	var cerr *graph.CycleError
+	errors.As(err, &cerr)
+	found := false
+	for _, id := range cerr.Path {
+		found = found || id == "b"
+	}
+	if !found {
+		t.Errorf("Expected the path to include b, which the cycle passes through, got %v", cerr.Path)
+	}
+}
+
+func TestSortSubsetIgnoresOutsideCycle(t *testing.T) {
+	// The cycle between b and c has nothing to do with a and d
+	g := graph.NewGraph("cyclic", graph.WithLazyAdd())
+	g.Add(graph.NewNode("a", nil, graph.NoOp()))
+	g.Add(graph.NewNode("b", graph.Deps("c"), graph.NoOp()))
+	g.Add(graph.NewNode("c", graph.Deps("b"), graph.NoOp()))
+	g.Add(graph.NewNode("d", graph.Deps("a"), graph.NoOp()))
+	if _, err := g.Sort(); err == nil {
+		t.Fatal("Expected the whole graph not to sort")
+	}
+
+	ids, err := g.SortSubset(graph.Deps("a", "d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (graph.SortedNodeIDs{"a", "d"}); !reflect.DeepEqual(ids, want) {
+		t.Errorf("Expected %v, got %v", want, ids)
+	}
+}
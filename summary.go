@@ -0,0 +1,211 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+type SummaryOption func(*summaryConfig)
+
+type summaryConfig struct {
+	slowest     int
+	errorLength int
+	skipped     bool
+}
+
+// How many of the slowest nodes the summary lists; 5 by default, zero for none
+func ShowSlowest(k int) SummaryOption {
+	return func(c *summaryConfig) {
+		c.slowest = k
+	}
+}
+
+// Cuts node errors down to n characters; 200 by default, zero for no limit
+func TruncateErrors(n int) SummaryOption {
+	return func(c *summaryConfig) {
+		c.errorLength = n
+	}
+}
+
+// Lists skipped nodes with their reasons, which are left out by default
+func ListSkipped() SummaryOption {
+	return func(c *summaryConfig) {
+		c.skipped = true
+	}
+}
+
+type summary struct {
+	title        string
+	counts       [][2]string
+	slowest      [][2]string
+	failed       [][2]string
+	skipped      [][2]string
+	critical     string
+	criticalTime time.Duration
+	listSkipped  bool
+}
+
+// succeeded, failed, aborted, canceled or incomplete
+func (r *Report) outcome() string {
+	switch {
+	case r.AbortReason != "":
+		return "aborted"
+	case len(r.WithStatus(StatusFailed))+len(r.WithStatus(StatusTimedOut)) > 0:
+		return "failed"
+	case len(r.WithStatus(StatusCanceled)) > 0:
+		return "canceled"
+	case r.Incomplete || len(r.WithStatus(StatusNotRun)) > 0:
+		return "incomplete"
+	}
+	return "succeeded"
+}
+
+func (r *Report) summarize(opts []SummaryOption) summary {
+	cfg := summaryConfig{slowest: 5, errorLength: 200}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	s := summary{
+		title:        fmt.Sprintf("Run %s: %s in %s", strings.Join(strings.Fields(r.Graph+" "+r.RunID), " "), r.outcome(), r.Duration()),
+		critical:     strings.Join(idStrings(r.CriticalPath), " -> "),
+		criticalTime: r.CriticalPathDuration,
+		listSkipped:  cfg.skipped,
+	}
+
+	for status := StatusPending; status <= StatusAwaitingApproval; status++ {
+		if n := len(r.WithStatus(status)); n > 0 {
+			s.counts = append(s.counts, [2]string{status.String(), fmt.Sprint(n)})
+		}
+	}
+
+	ran := SortedNodeIDs{}
+	for _, id := range sortedIDs(r.Nodes) {
+		if len(r.Nodes[id].Attempts) > 0 {
+			ran = append(ran, id)
+		}
+	}
+	sort.SliceStable(ran, func(i, j int) bool {
+		return r.Nodes[ran[i]].Duration() > r.Nodes[ran[j]].Duration()
+	})
+	for i, id := range ran {
+		if i == cfg.slowest {
+			break
+		}
+		s.slowest = append(s.slowest, [2]string{string(id), r.Nodes[id].Duration().String()})
+	}
+
+	for _, id := range sortedIDs(r.Nodes) {
+		nr := r.Nodes[id]
+		switch nr.Status {
+		case StatusFailed, StatusTimedOut, StatusFailedAllowed:
+			s.failed = append(s.failed, [2]string{string(id), truncate(oneLine(errorString(nr.Err)), cfg.errorLength)})
+		case StatusSkipped:
+			s.skipped = append(s.skipped, [2]string{string(id), string(nr.Reason)})
+		}
+	}
+	return s
+}
+
+func idStrings(ids []NodeID) []string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = string(id)
+	}
+	return s
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
+
+// WriteSummary writes a plain-text summary of the run: its outcome and wall time, how many nodes
+// ended in each status, the slowest nodes, every failed node with its error and the critical
+// path. The same report always renders the same way.
+func (r *Report) WriteSummary(w io.Writer, opts ...SummaryOption) error {
+	s := r.summarize(opts)
+
+	var b strings.Builder
+	b.WriteString(s.title + "\n")
+
+	counts := make([]string, len(s.counts))
+	for i, c := range s.counts {
+		counts[i] = c[0] + " " + c[1]
+	}
+	fmt.Fprintf(&b, "Nodes: %s\n", strings.Join(counts, ", "))
+
+	section := func(heading string, rows [][2]string, format string) {
+		if len(rows) == 0 {
+			return
+		}
+		b.WriteString(heading + ":\n")
+		for _, row := range rows {
+			if row[1] == "" {
+				fmt.Fprintf(&b, "  %s\n", row[0])
+			} else {
+				fmt.Fprintf(&b, "  "+format+"\n", row[0], row[1])
+			}
+		}
+	}
+	section("Slowest", s.slowest, "%s %s")
+	section("Failed", s.failed, "%s: %s")
+	if s.listSkipped {
+		section("Skipped", s.skipped, "%s (%s)")
+	}
+
+	if s.critical != "" {
+		fmt.Fprintf(&b, "Critical path (%s): %s\n", s.criticalTime, s.critical)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownCell(s string) string {
+	return strings.ReplaceAll(oneLine(s), "|", `\|`)
+}
+
+// WriteMarkdown writes the WriteSummary content as Markdown, with a table per section
+func (r *Report) WriteMarkdown(w io.Writer, opts ...SummaryOption) error {
+	s := r.summarize(opts)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n", markdownCell(s.title))
+
+	table := func(heading, left, right string, rows [][2]string) {
+		if len(rows) == 0 {
+			return
+		}
+		if heading != "" {
+			fmt.Fprintf(&b, "\n### %s\n", heading)
+		}
+		fmt.Fprintf(&b, "\n| %s | %s |\n| --- | --- |\n", left, right)
+		for _, row := range rows {
+			fmt.Fprintf(&b, "| %s | %s |\n", markdownCell(row[0]), markdownCell(row[1]))
+		}
+	}
+	table("", "Status", "Nodes", s.counts)
+	table("Slowest", "Node", "Duration", s.slowest)
+	table("Failed", "Node", "Error", s.failed)
+	if s.listSkipped {
+		table("Skipped", "Node", "Reason", s.skipped)
+	}
+
+	if s.critical != "" {
+		fmt.Fprintf(&b, "\n### Critical path\n\n%s (%s)\n", markdownCell(s.critical), s.criticalTime)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
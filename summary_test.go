@@ -0,0 +1,79 @@
+package graph_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A finished run of extract -> transform -> load, with a lookup next to transform that failed
+// and a notify step skipped because of it
+func etlReport() *graph.Report {
+	ran := func(id graph.NodeID, status graph.NodeStatus, from, to time.Duration, err error) *graph.NodeReport {
+		nr := &graph.NodeReport{ID: id, Status: status, Start: reportStart.Add(from), End: reportStart.Add(to), Err: err}
+		nr.Attempts = []graph.AttemptReport{{Attempt: 1, Status: status, Err: err, Start: nr.Start, End: nr.End}}
+		return nr
+	}
+
+	return &graph.Report{
+		Graph: "etl",
+		RunID: "run-7",
+		Start: reportStart,
+		End:   reportStart.Add(9 * time.Second),
+		Nodes: map[graph.NodeID]*graph.NodeReport{
+			"extract":   ran("extract", graph.StatusSucceeded, 0, 2*time.Second, nil),
+			"transform": ran("transform", graph.StatusSucceeded, 2*time.Second, 7*time.Second, nil),
+			"load":      ran("load", graph.StatusSucceeded, 7*time.Second, 9*time.Second, nil),
+			"lookup": ran("lookup", graph.StatusFailed, 2*time.Second, 3*time.Second,
+				errors.New("Node lookup failed: query rejected:\n  column | \"region\" does not exist in table customers")),
+			"notify": {ID: "notify", Status: graph.StatusSkipped, Reason: graph.ReasonUpstreamFailed},
+		},
+		CriticalPath:         []graph.NodeID{"extract", "transform", "load"},
+		CriticalPathDuration: 9 * time.Second,
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	for name, opts := range map[string][]graph.SummaryOption{
+		"summary.golden":         nil,
+		"summary_options.golden": {graph.ShowSlowest(2), graph.TruncateErrors(30), graph.ListSkipped()},
+	} {
+		var b bytes.Buffer
+		if err := etlReport().WriteSummary(&b, opts...); err != nil {
+			t.Fatal(err)
+		}
+		golden(t, name, b.String())
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	for name, opts := range map[string][]graph.SummaryOption{
+		"summary.md.golden":         nil,
+		"summary_options.md.golden": {graph.ShowSlowest(2), graph.TruncateErrors(30), graph.ListSkipped()},
+	} {
+		var b bytes.Buffer
+		if err := etlReport().WriteMarkdown(&b, opts...); err != nil {
+			t.Fatal(err)
+		}
+		golden(t, name, b.String())
+	}
+}
+
+func TestWriteSummaryDeterministic(t *testing.T) {
+	report := etlReport()
+	// Ties in duration fall back to id order
+	report.Nodes["load"].End = report.Nodes["load"].Start.Add(time.Second)
+
+	var first bytes.Buffer
+	report.WriteSummary(&first)
+	for i := 0; i < 20; i++ {
+		var b bytes.Buffer
+		report.WriteSummary(&b)
+		if b.String() != first.String() {
+			t.Fatalf("Expected the same summary every time, got\n%s\nthen\n%s", first.String(), b.String())
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"fmt"
+)
+
+type TeardownOption func(*teardownConfig)
+
+type teardownConfig struct {
+	// Fail instead of tearing down with NoOp when a node has no teardown fn
+	requireFns bool
+}
+
+// Makes Teardown fail when a node to tear down has no fn in the map, instead of giving it NoOp.
+// Markers and approval nodes never need one.
+func RequireTeardownFns() TeardownOption {
+	return func(c *teardownConfig) {
+		c.requireFns = true
+	}
+}
+
+// Builds the graph that undoes a run of g: the nodes report shows succeeded, each depending on
+// the nodes that depended on it, so what was created last is torn down first. Nodes that failed,
+// were skipped or never ran are left out, and ordering through them is kept. Each node runs its
+// fn from fns, or NoOp when it has none; markers and approval nodes stay markers. The teardown
+// graph is named after g with a "-teardown" suffix and keeps its namespace and node metadata.
+func (g *Graph) Teardown(report *Report, fns map[NodeID]NodeFn, opts ...TeardownOption) (*Graph, error) {
+	cfg := teardownConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	order, err := g.sort()
+	if err != nil {
+		return nil, err
+	}
+
+	created := NodeIDs{}
+	for id, nr := range report.Nodes {
+		if nr.Status != StatusSucceeded {
+			continue
+		}
+		if _, ok := g.nodes[id]; !ok {
+			return nil, fmt.Errorf("Node %s is in the report but not the graph", id)
+		}
+		created[id] = struct{}{}
+	}
+
+	// The nearest created nodes each node waits on, looking through the ones that weren't created
+	nearest := map[NodeID]NodeIDs{}
+	for _, id := range order {
+		found := NodeIDs{}
+		for depId := range g.dependencies(g.nodes[id]) {
+			if _, ok := created[depId]; ok {
+				found[depId] = struct{}{}
+				continue
+			}
+			for through := range nearest[depId] {
+				found[through] = struct{}{}
+			}
+		}
+		nearest[id] = found
+	}
+
+	// The original order backwards puts every node after the ones that now come before it
+	teardown := NewGraph(g.name+"-teardown", WithNamespace(g.namespace))
+	dependents := map[NodeID]NodeIDs{}
+	for i := len(order) - 1; i >= 0; i-- {
+		id := order[i]
+		if _, ok := created[id]; !ok {
+			continue
+		}
+
+		node := g.nodes[id]
+		nodeOpts := []NodeOption{}
+		for _, key := range sortedKeys(node.Metadata) {
+			nodeOpts = append(nodeOpts, WithMetadata(key, node.Metadata[key]))
+		}
+
+		fn, ok := fns[id]
+		switch {
+		case ok:
+		case node.marker || node.approval:
+			nodeOpts = append(nodeOpts, AsMarker())
+		case cfg.requireFns:
+			return nil, fmt.Errorf("Node %s has no teardown fn", id)
+		default:
+			fn = NoOp()
+		}
+
+		if _, err := teardown.Add(NewNode(string(id), copyMap(dependents[id]), fn, nodeOpts...)); err != nil {
+			return nil, err
+		}
+		for depId := range nearest[id] {
+			if dependents[depId] == nil {
+				dependents[depId] = NodeIDs{}
+			}
+			dependents[depId][id] = struct{}{}
+		}
+	}
+	return teardown, nil
+}
@@ -0,0 +1,130 @@
+package graph_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// network feeds db and cache; cache fails, so warm is skipped; app needs db, and done marks the
+// end. audit runs after cache whether or not it worked.
+func provisioned(t *testing.T) (*graph.Graph, *graph.Report) {
+	t.Helper()
+
+	g := graph.NewGraph("stack", graph.WithNamespace("infra"))
+	g.Add(graph.NewNode("network", nil, graph.NoOp(), graph.WithMetadata("kind", "vpc")))
+	g.Add(graph.NewNode("db", graph.Deps("network"), graph.NoOp()))
+	g.Add(graph.NewNode("cache", graph.Deps("network"), failWith(errors.New("quota exceeded"))))
+	g.Add(graph.NewNode("warm", graph.Deps("cache"), graph.NoOp()))
+	g.Add(graph.NewNode("audit", nil, graph.NoOp(), graph.WithSoftDependency("cache")))
+	g.Add(graph.NewNode("app", graph.Deps("db"), graph.NoOp()))
+	g.Add(graph.NewNode("done", graph.Deps("app"), graph.NoOp(), graph.AsMarker()))
+
+	report, _ := g.CompileToExecutable().Run(ctx(t))
+	if report.Nodes["cache"].Status != graph.StatusFailed || report.Nodes["audit"].Status != graph.StatusSucceeded {
+		t.Fatalf("Expected cache to fail and audit to run anyway, got %v", report.Nodes)
+	}
+	return g, report
+}
+
+func TestTeardownSkipsFailedBranch(t *testing.T) {
+	g, report := provisioned(t)
+	l := &runLog{}
+	fns := map[graph.NodeID]graph.NodeFn{"network": l.fn, "db": l.fn, "app": l.fn, "audit": l.fn, "cache": l.fn, "warm": l.fn}
+
+	teardown, err := g.Teardown(report, fns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := mustSort(t, teardown); len(ids) != 5 || ids.Contains("cache") || ids.Contains("warm") {
+		t.Errorf("Expected only the created nodes, got %v", ids)
+	}
+	want := []graph.Edge{
+		{From: "app", To: "done"},
+		{From: "db", To: "app"},
+		{From: "network", To: "audit"},
+		{From: "network", To: "db"},
+	}
+	if got := teardown.Edges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the reversed edges %v, got %v", want, got)
+	}
+
+	teardownReport, err := teardown.CompileToExecutable().Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if teardownReport.Graph != "stack-teardown" {
+		t.Errorf("Expected the teardown graph named stack-teardown, got %s", teardownReport.Graph)
+	}
+	if len(l.ids) != 4 || !before(graph.SortedNodeIDs(l.ids), "app", "db") || !before(graph.SortedNodeIDs(l.ids), "db", "network") || !before(graph.SortedNodeIDs(l.ids), "audit", "network") {
+		t.Errorf("Expected the teardown fns to run in reverse dependency order, got %v", l.ids)
+	}
+}
+
+func TestTeardownOrdersThroughNodesNotCreated(t *testing.T) {
+	g := graph.NewGraph("chain")
+	g.Add(graph.NewNode("bucket", nil, graph.NoOp()))
+	g.Add(graph.NewNode("policy", graph.Deps("bucket"), failWith(errors.New("denied"))))
+	g.Add(graph.NewNode("upload", nil, graph.NoOp(), graph.WithSoftDependency("policy")))
+	report, _ := g.CompileToExecutable().Run(ctx(t))
+
+	teardown, err := g.Teardown(report, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// upload came after bucket through policy, so it's torn down first
+	if want := []graph.Edge{{From: "bucket", To: "upload"}}; !reflect.DeepEqual(teardown.Edges(), want) {
+		t.Errorf("Expected %v, got %v", want, teardown.Edges())
+	}
+}
+
+func TestTeardownKeepsSettings(t *testing.T) {
+	g, report := provisioned(t)
+	teardown, err := g.Teardown(report, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if network := mustGet(t, teardown, "network"); network.Metadata["kind"] != "vpc" {
+		t.Errorf("Expected the metadata kept, got %v", network.Metadata)
+	}
+	views := map[graph.NodeID]graph.NodeView{}
+	teardown.All()(func(id graph.NodeID, v graph.NodeView) bool {
+		views[id] = v
+		return true
+	})
+	if !views["done"].IsMarker() || views["app"].IsMarker() {
+		t.Error("Expected the marker to stay a marker")
+	}
+	if teardown.Namespace() != "infra" {
+		t.Errorf("Expected the namespace kept, got %q", teardown.Namespace())
+	}
+}
+
+func TestTeardownMissingFns(t *testing.T) {
+	g, report := provisioned(t)
+	fns := map[graph.NodeID]graph.NodeFn{"network": graph.NoOp(), "db": graph.NoOp(), "app": graph.NoOp()}
+
+	if _, err := g.Teardown(report, fns, graph.RequireTeardownFns()); err == nil || err.Error() != "Node audit has no teardown fn" {
+		t.Errorf("Expected audit's missing fn reported, got %v", err)
+	}
+	fns["audit"] = graph.NoOp()
+	if _, err := g.Teardown(report, fns, graph.RequireTeardownFns()); err != nil {
+		t.Errorf("Expected the marker to need no fn, got %v", err)
+	}
+}
+
+func TestTeardownReportMismatch(t *testing.T) {
+	g, report := provisioned(t)
+	other := graph.NewGraph("other")
+	other.Add(graph.NewNode("network", nil, graph.NoOp()))
+
+	if _, err := other.Teardown(report, nil); err == nil {
+		t.Error("Expected a report naming nodes the graph doesn't have to be refused")
+	}
+	if _, err := g.Teardown(&graph.Report{Nodes: map[graph.NodeID]*graph.NodeReport{}}, nil); err != nil {
+		t.Errorf("Expected an empty report to give an empty teardown, got %v", err)
+	}
+}
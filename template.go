@@ -0,0 +1,157 @@
+package graph
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var placeholder = regexp.MustCompile(`\{([A-Za-z0-9_.-]+)\}`)
+
+// Binds a template node's fn for one set of params
+type TemplateFnFactory func(params map[string]string) NodeFn
+
+type templateNode struct {
+	node    *Node
+	factory TemplateFnFactory
+}
+
+// A Template is a graph whose node names, dependencies, edge weights, selectors, metadata and
+// name may contain {param} placeholders, filled in by Instantiate.
+type Template struct {
+	name  string
+	opts  []GraphOption
+	nodes []templateNode
+	names NodeIDs
+}
+
+func NewTemplate(name string, opts ...GraphOption) *Template {
+	return &Template{name: name, opts: opts, names: make(NodeIDs)}
+}
+
+// Nodes are instantiated in the order they're added, so dependencies must be added first.
+// When factory is nil the node's own Fn is used.
+func (t *Template) Add(node *Node, factory TemplateFnFactory) error {
+	id := node.Identifier()
+	if _, ok := t.names[id]; ok {
+		return fmt.Errorf("Node with id %s already exists", id)
+	}
+
+	t.names[id] = struct{}{}
+	t.nodes = append(t.nodes, templateNode{node: node, factory: factory})
+	return nil
+}
+
+func substitute(s string, params map[string]string) (string, error) {
+	var missing string
+	out := placeholder.ReplaceAllStringFunc(s, func(match string) string {
+		key := match[1 : len(match)-1]
+		value, ok := params[key]
+		if !ok && missing == "" {
+			missing = key
+		}
+		return value
+	})
+
+	if missing != "" {
+		return "", fmt.Errorf("Unresolved placeholder {%s} in %q", missing, s)
+	}
+	return out, nil
+}
+
+func substituteIDs(ids NodeIDs, params map[string]string) (NodeIDs, error) {
+	out := make(NodeIDs, len(ids))
+	for id := range ids {
+		s, err := substitute(string(id), params)
+		if err != nil {
+			return nil, err
+		}
+		out[NodeID(s)] = struct{}{}
+	}
+	return out, nil
+}
+
+// A copy of m with its keys substituted
+func substituteKeys[V any](m map[NodeID]V, params map[string]string) (map[NodeID]V, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	out := make(map[NodeID]V, len(m))
+	for id, value := range m {
+		s, err := substitute(string(id), params)
+		if err != nil {
+			return nil, err
+		}
+		out[NodeID(s)] = value
+	}
+	return out, nil
+}
+
+func (t *Template) Instantiate(params map[string]string) (*Graph, error) {
+	name, err := substitute(t.name, params)
+	if err != nil {
+		return nil, err
+	}
+
+	g := NewGraph(name, t.opts...)
+	for _, tn := range t.nodes {
+		node, err := tn.instantiate(params)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := g.Add(node); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+func (tn templateNode) instantiate(params map[string]string) (*Node, error) {
+	node := tn.node.clone()
+
+	name, err := substitute(node.Name, params)
+	if err != nil {
+		return nil, err
+	}
+	node.Name = name
+
+	for _, set := range []*NodeIDs{&node.Dependencies, &node.softDependencies, &node.optionalDependencies} {
+		if *set, err = substituteIDs(*set, params); err != nil {
+			return nil, err
+		}
+	}
+
+	if node.duplicates, err = substituteKeys(node.duplicates, params); err != nil {
+		return nil, err
+	}
+	if node.weights, err = substituteKeys(node.weights, params); err != nil {
+		return nil, err
+	}
+	for i, s := range node.selectors {
+		if node.selectors[i].key, err = substitute(s.key, params); err != nil {
+			return nil, err
+		}
+		if node.selectors[i].value, err = substitute(s.value, params); err != nil {
+			return nil, err
+		}
+	}
+
+	metadata := make(map[string]string, len(node.Metadata))
+	for key, value := range node.Metadata {
+		k, err := substitute(key, params)
+		if err != nil {
+			return nil, err
+		}
+		if metadata[k], err = substitute(value, params); err != nil {
+			return nil, err
+		}
+	}
+	node.Metadata = metadata
+
+	if tn.factory != nil {
+		node.Fn = tn.factory(params)
+	}
+	return node, nil
+}
@@ -0,0 +1,130 @@
+package graph_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func tenantTemplate(t *testing.T, mu *sync.Mutex, ran map[string]string) *graph.Template {
+	t.Helper()
+
+	ingest := func(params map[string]string) graph.NodeFn {
+		return func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			ran[string(ec.ID)] = params["tenant"] + " in " + ec.Metadata["bucket"]
+			return nil, nil
+		}
+	}
+
+	tmpl := graph.NewTemplate("{tenant}-pipeline")
+	for _, add := range []struct {
+		node    *graph.Node
+		factory graph.TemplateFnFactory
+	}{
+		{graph.NewNode("{tenant}-ingest", nil, nil, graph.WithMetadata("bucket", "s3://{tenant}-raw")), ingest},
+		{graph.NewNode("{tenant}-report", graph.Deps("{tenant}-ingest"), graph.NoOp()), nil},
+	} {
+		if err := tmpl.Add(add.node, add.factory); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tmpl
+}
+
+func TestTemplateInstantiateAndMerge(t *testing.T) {
+	var mu sync.Mutex
+	ran := map[string]string{}
+	tmpl := tenantTemplate(t, &mu, ran)
+
+	acme, err := tmpl.Instantiate(map[string]string{"tenant": "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	globex, err := tmpl.Instantiate(map[string]string{"tenant": "globex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(acme.String(), "Graph acme-pipeline:") {
+		t.Errorf("Expected the graph name to be substituted, got %q", acme)
+	}
+	deps, _ := acme.ResolvedDependencies("acme-report")
+	if !deps.Has("acme-ingest") {
+		t.Errorf("Expected the dependency to be substituted too, got %v", deps)
+	}
+
+	all := graph.NewGraph("tenants")
+	for _, g := range []*graph.Graph{acme, globex} {
+		if err := all.Merge(g); err != nil {
+			t.Fatalf("Expected the instances not to collide, got %v", err)
+		}
+	}
+	if ids := mustSort(t, all); len(ids) != 4 {
+		t.Errorf("Expected 4 nodes, got %v", ids)
+	}
+
+	if _, err := all.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"acme-ingest": "acme in s3://acme-raw", "globex-ingest": "globex in s3://globex-raw"}
+	for id, s := range want {
+		if ran[id] != s {
+			t.Errorf("Expected %s to run with its own params and metadata, got %q", id, ran[id])
+		}
+	}
+}
+
+func TestTemplateUnresolvedPlaceholder(t *testing.T) {
+	var mu sync.Mutex
+	tmpl := tenantTemplate(t, &mu, map[string]string{})
+
+	_, err := tmpl.Instantiate(map[string]string{"region": "eu"})
+	if err == nil || !strings.Contains(err.Error(), "{tenant}") {
+		t.Errorf("Expected the missing param to be named, got %v", err)
+	}
+}
+
+func TestTemplateSelectorsAndWeights(t *testing.T) {
+	// Weights are only set through AddEdge, so the template node comes from a graph
+	src := graph.NewGraph("src")
+	src.Add(graph.NewNode("{tenant}-ingest", nil, graph.NoOp(), graph.WithMetadata("tenant", "{tenant}")))
+	src.Add(graph.NewNode("{tenant}-report", nil, graph.NoOp()))
+	if err := src.AddEdge("{tenant}-report", "{tenant}-ingest", 2.5); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := graph.NewTemplate("{tenant}-pipeline")
+	for _, node := range []*graph.Node{
+		mustGet(t, src, "{tenant}-ingest"),
+		mustGet(t, src, "{tenant}-report"),
+		graph.NewNode("{tenant}-notify", nil, graph.NoOp(), graph.WithDependencySelector("tenant", "{tenant}")),
+	} {
+		if err := tmpl.Add(node, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all := graph.NewGraph("tenants")
+	for _, tenant := range []string{"acme", "globex"} {
+		g, err := tmpl.Instantiate(map[string]string{"tenant": tenant})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := all.Merge(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if w, err := all.EdgeWeight("acme-report", "acme-ingest"); err != nil || w != 2.5 {
+		t.Errorf("Expected the weight kept on the substituted edge, got %v, %v", w, err)
+	}
+	deps, _ := all.ResolvedDependencies("acme-notify")
+	if !deps.Equal(graph.Deps("acme-ingest")) {
+		t.Errorf("Expected the selector to match only its own tenant, got %v", deps)
+	}
+}
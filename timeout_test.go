@@ -0,0 +1,107 @@
+package graph_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// A graph whose "slow" node sleeps a minute, by clock, past its one second timeout, with
+// "after" depending on it
+func sleepPastTimeout(clock *fakeClock, behavior graph.TimeoutBehavior, seen *graph.Results) *graph.Graph {
+	g := graph.NewGraph("timeouts")
+	g.Add(graph.NewNode("slow", nil, func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		select {
+		case <-clock.After(time.Minute):
+			return "late", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}, graph.WithTimeout(time.Second, behavior)))
+	g.Add(graph.NewNode("after", graph.Deps("slow"), func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+		*seen = ec.Results
+		return nil, nil
+	}))
+	return g
+}
+
+func runPastTimeout(t *testing.T, behavior graph.TimeoutBehavior) (*graph.Report, error, graph.Results, map[graph.NodeID]graph.NodeStatus) {
+	t.Helper()
+
+	clock := newFakeClock()
+	var seen graph.Results
+	g := sleepPastTimeout(clock, behavior, &seen)
+
+	var mu sync.Mutex
+	hooked := map[graph.NodeID]graph.NodeStatus{}
+	hooks := graph.Hooks{OnNodeResult: func(id graph.NodeID, result graph.NodeReport) {
+		mu.Lock()
+		defer mu.Unlock()
+		hooked[id] = result.Status
+	}}
+
+	type outcome struct {
+		report *graph.Report
+		err    error
+	}
+	done := make(chan outcome)
+	go func() {
+		report, err := g.CompileToExecutable().Run(context.Background(), graph.WithClock(clock), graph.WithHooks(hooks))
+		done <- outcome{report, err}
+	}()
+
+	// The node's timeout and its own sleep
+	clock.waitFor(t, 2)
+	clock.Advance(2 * time.Second)
+
+	o := <-done
+	return o.report, o.err, seen, hooked
+}
+
+func TestTimeoutFailsByDefault(t *testing.T) {
+	report, err, seen, hooked := runPastTimeout(t, graph.OnTimeoutFail)
+	if err == nil {
+		t.Fatal("Expected the run to fail")
+	}
+
+	slow := report.Nodes["slow"]
+	if slow.Status != graph.StatusTimedOut {
+		t.Errorf("Expected slow to be TimedOut, got %s", slow.Status)
+	}
+	if after := report.Nodes["after"]; after.Status != graph.StatusSkipped || after.Reason != graph.ReasonUpstreamFailed {
+		t.Errorf("Expected after to be skipped for an upstream failure, got %s (%s)", after.Status, after.Reason)
+	}
+	if seen != nil {
+		t.Error("Expected after not to run")
+	}
+	if hooked["slow"] != graph.StatusTimedOut {
+		t.Errorf("Expected the result hook to see TimedOut, got %s", hooked["slow"])
+	}
+}
+
+func TestTimeoutSkip(t *testing.T) {
+	report, err, seen, hooked := runPastTimeout(t, graph.OnTimeoutSkip)
+	if err != nil {
+		t.Fatalf("Expected the run to succeed, got %v", err)
+	}
+
+	slow := report.Nodes["slow"]
+	if slow.Status != graph.StatusSkipped || slow.Reason != graph.ReasonTimeout {
+		t.Errorf("Expected slow to be skipped on timeout, got %s (%s)", slow.Status, slow.Reason)
+	}
+	if after := report.Nodes["after"]; after.Status != graph.StatusSucceeded {
+		t.Errorf("Expected after to run, got %s", after.Status)
+	}
+	if seen == nil {
+		t.Fatal("Expected after to run")
+	}
+	if _, ok := seen["slow"]; ok {
+		t.Error("Expected no result from the skipped node")
+	}
+	if hooked["slow"] != graph.StatusSkipped {
+		t.Errorf("Expected the result hook to see Skipped, got %s", hooked["slow"])
+	}
+}
@@ -0,0 +1,59 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// A graph-to-graph rewrite run when compiling. It may change the graph it's given, which is a copy,
+// and return it or return another graph altogether.
+type Transform func(g *Graph) (*Graph, error)
+
+// Runs ts in order on a copy of the graph whenever it's compiled, and compiles what the last one
+// returns; the graph itself is never changed. A failing transform fails every run of the compiled
+// graph, like a broken graph does. Staleness is still judged against the graph itself.
+func WithTransforms(ts ...Transform) GraphOption {
+	return func(g *Graph) {
+		g.transforms = append(g.transforms, ts...)
+	}
+}
+
+// The names of the transforms applied when the graph was compiled, in order: the fn's name as
+// the runtime reports it, less its import path
+func (peg *ParallelizedExecutableGraph) AppliedTransforms() []string {
+	return append([]string(nil), peg.transforms...)
+}
+
+func transformName(t Transform) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(t).Pointer())
+	if fn == nil {
+		return "transform"
+	}
+	name := fn.Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// The graph the transforms leave, the names of those that ran and the error that stopped them.
+// The caller holds the read lock.
+func (g *Graph) transformed() (*Graph, []string, error) {
+	out := g.clone()
+	// The copy compiles as is
+	out.transforms = nil
+
+	applied := []string{}
+	for _, t := range g.transforms {
+		name := transformName(t)
+		next, err := t(out)
+		if err == nil && next == nil {
+			err = fmt.Errorf("Returned no graph")
+		}
+		if err != nil {
+			return g, applied, fmt.Errorf("Transform %s: %w", name, err)
+		}
+		applied = append(applied, name)
+		out = next
+	}
+	return out, applied, nil
+}
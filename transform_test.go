@@ -0,0 +1,135 @@
+package graph_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Puts a log-<id> node ahead of every node nothing depends on
+func logBeforeLeaves(g *graph.Graph) (*graph.Graph, error) {
+	depended := graph.NodeIDs{}
+	for _, e := range g.Edges() {
+		depended[e.To] = struct{}{}
+	}
+	ids, err := g.Sort()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if depended.Has(id) {
+			continue
+		}
+		logID := "log-" + string(id)
+		if _, err := g.Add(graph.NewNode(logID, nil, graph.NoOp())); err != nil {
+			return nil, err
+		}
+		if err := g.AddEdge(id, graph.NodeID(logID)); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func rejectEverything(g *graph.Graph) (*graph.Graph, error) {
+	return nil, errors.New("not today")
+}
+
+func returnsNothing(g *graph.Graph) (*graph.Graph, error) {
+	return nil, nil
+}
+
+// Throws the graph away for a new one with a single node
+func startOver(g *graph.Graph) (*graph.Graph, error) {
+	out := graph.NewGraph("replaced")
+	_, err := out.Add(graph.NewNode("only", nil, graph.NoOp()))
+	return out, err
+}
+
+func TestTransformInjectsLogNodes(t *testing.T) {
+	g := diamond(t, graph.WithTransforms(logBeforeLeaves))
+	peg := g.CompileToExecutable()
+
+	want := append(g.Edges(), graph.Edge{From: "d", To: "log-d"})
+	if got := dotEdges(peg.ToDOT()); !reflect.DeepEqual(got, sortEdges(want)) {
+		t.Errorf("Expected the compiled graph to draw %v, got %v", sortEdges(want), got)
+	}
+	if g.Has("log-d") {
+		t.Error("Expected the graph itself unchanged")
+	}
+	if got := peg.AppliedTransforms(); !reflect.DeepEqual(got, []string{"go_graph_test.logBeforeLeaves"}) {
+		t.Errorf("Expected the transform's name recorded, got %v", got)
+	}
+
+	report, err := peg.Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logged, leaf := report.Nodes["log-d"], report.Nodes["d"]
+	if logged == nil || logged.Status != graph.StatusSucceeded || leaf.Start.Before(logged.End) {
+		t.Errorf("Expected log-d to run before d, got %+v and %+v", logged, leaf)
+	}
+}
+
+func TestTransformsRunInOrder(t *testing.T) {
+	g := diamond(t, graph.WithTransforms(logBeforeLeaves, startOver))
+	peg := g.CompileToExecutable()
+
+	if got := peg.AppliedTransforms(); !reflect.DeepEqual(got, []string{"go_graph_test.logBeforeLeaves", "go_graph_test.startOver"}) {
+		t.Errorf("Expected both transforms in order, got %v", got)
+	}
+	report, err := peg.Run(ctx(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := report.Nodes["only"]; !ok || len(report.Nodes) != 1 {
+		t.Errorf("Expected the graph the last transform returned, got %v", report.Nodes)
+	}
+}
+
+func TestTransformFailure(t *testing.T) {
+	for _, tc := range []struct {
+		transform graph.Transform
+		err       string
+	}{
+		{rejectEverything, "Transform go_graph_test.rejectEverything: not today"},
+		{returnsNothing, "Transform go_graph_test.returnsNothing: Returned no graph"},
+	} {
+		g := diamond(t, graph.WithTransforms(logBeforeLeaves, tc.transform))
+		fingerprint := g.Fingerprint()
+		peg := g.CompileToExecutable()
+
+		if got := peg.AppliedTransforms(); !reflect.DeepEqual(got, []string{"go_graph_test.logBeforeLeaves"}) {
+			t.Errorf("Expected only the transform before the failure applied, got %v", got)
+		}
+		if _, err := peg.Run(ctx(t)); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Expected the run to fail with %q, got %v", tc.err, err)
+		}
+		if g.Fingerprint() != fingerprint || g.Has("log-d") {
+			t.Error("Expected the failed transforms to leave the graph alone")
+		}
+	}
+}
+
+func TestTransformsRefuseRecompile(t *testing.T) {
+	g := diamond(t, graph.WithTransforms(logBeforeLeaves))
+	peg := g.CompileToExecutable()
+	g.Add(graph.NewNode("e", graph.Deps("d"), graph.NoOp()))
+
+	if err := peg.Recompile(g, graph.Diff{Added: graph.Deps("e")}); err == nil {
+		t.Error("Expected a graph with transforms to need a full compile")
+	}
+	if got := dotEdges(g.CompileToExecutable().ToDOT()); !reflect.DeepEqual(got, sortEdges(append(g.Edges(), graph.Edge{From: "e", To: "log-e"}))) {
+		t.Errorf("Expected compiling again to move the log node to the new leaf, got %v", got)
+	}
+}
+
+func TestTransformsKeptByClone(t *testing.T) {
+	clone := diamond(t, graph.WithTransforms(logBeforeLeaves)).Clone()
+	if got := clone.CompileToExecutable().AppliedTransforms(); len(got) != 1 {
+		t.Errorf("Expected the clone to keep its transforms, got %v", got)
+	}
+}
@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// Gives the node an alternative fn that runs instead of Fn when a run selects the variant
+func WithFnVariant(name string, fn NodeFn) NodeOption {
+	return func(n *Node) {
+		if n.variants == nil {
+			n.variants = make(map[string]NodeFn)
+		}
+		n.variants[name] = fn
+	}
+}
+
+// Runs each node's fn for the named variant, or its Fn when it has none. Naming a variant no
+// node defines fails the run before anything executes.
+func WithVariant(name string) ExecOption {
+	return func(c *ExecConfig) {
+		c.Variant = name
+	}
+}
+
+func (peg *ParallelizedExecutableGraph) checkVariant(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	for _, node := range peg.nodes {
+		if _, ok := node.variants[name]; ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("Variant %s is not defined by any node", name)
+}
+
+// The fn to invoke for the node, and the variant it belongs to; "" is the default
+func (r *run) fn(node *executableNode) (NodeFn, string) {
+	if fn, ok := node.variants[r.cfg.Variant]; ok && r.cfg.Variant != "" {
+		return fn, r.cfg.Variant
+	}
+	return node.fn, ""
+}
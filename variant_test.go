@@ -0,0 +1,68 @@
+package graph_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// Nodes whose fns record which implementation ran; only deploy has a mock
+func deployment(ran *sync.Map) *graph.Graph {
+	impl := func(name string) graph.NodeFn {
+		return func(ctx context.Context, ec *graph.ExecutionContext) (any, error) {
+			ran.Store(ec.ID, name)
+			return nil, nil
+		}
+	}
+
+	g := graph.NewGraph("deployment")
+	g.Add(graph.NewNode("build", nil, impl("real")))
+	g.Add(graph.NewNode("deploy", graph.Deps("build"), impl("real"), graph.WithFnVariant("mock", impl("mock"))))
+	return g
+}
+
+func TestVariantSelectsWhereDefined(t *testing.T) {
+	var ran sync.Map
+	report, err := deployment(&ran).CompileToExecutable().Run(ctx(t), graph.WithVariant("mock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := ran.Load(graph.NodeID("deploy")); got != "mock" {
+		t.Errorf("Expected deploy's mock to run, got %v", got)
+	}
+	if got, _ := ran.Load(graph.NodeID("build")); got != "real" {
+		t.Errorf("Expected build to fall back to its default, got %v", got)
+	}
+	if got := report.Nodes["deploy"].Variant; got != "mock" {
+		t.Errorf("Expected the report to record the mock, got %q", got)
+	}
+	if got := report.Nodes["build"].Variant; got != "" {
+		t.Errorf("Expected the report to record the default, got %q", got)
+	}
+}
+
+func TestVariantDefaultWithoutSelection(t *testing.T) {
+	var ran sync.Map
+	if _, err := deployment(&ran).CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ran.Load(graph.NodeID("deploy")); got != "real" {
+		t.Errorf("Expected the default fn, got %v", got)
+	}
+}
+
+func TestVariantUnknownFailsBeforeRunning(t *testing.T) {
+	var ran sync.Map
+	_, err := deployment(&ran).CompileToExecutable().Run(ctx(t), graph.WithVariant("dry-run"))
+	if err == nil {
+		t.Fatal("Expected an error for a variant no node defines")
+	}
+
+	ran.Range(func(key, value any) bool {
+		t.Errorf("Expected nothing to run, got %v", key)
+		return true
+	})
+}
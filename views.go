@@ -0,0 +1,80 @@
+package graph
+
+import (
+	"time"
+)
+
+// A read-only view of a node in a graph. It has no fields to set, and everything it returns is a
+// copy, so holding one can't change the graph. Views handed out by All keep showing the node as
+// it was when the loop started.
+type NodeView struct {
+	node *Node
+}
+
+func (v NodeView) ID() NodeID {
+	return v.node.Identifier()
+}
+
+// Declared dependencies, hard and soft, sorted by id; optional ones are listed apart
+func (v NodeView) Dependencies() SortedNodeIDs {
+	return sortedIDs(v.node.Dependencies)
+}
+
+func (v NodeView) DependencyKind(id NodeID) EdgeKind {
+	return v.node.DependencyKind(id)
+}
+
+func (v NodeView) OptionalDependencies() SortedNodeIDs {
+	return sortedIDs(v.node.optionalDependencies)
+}
+
+func (v NodeView) Metadata() map[string]string {
+	return copyMap(v.node.Metadata)
+}
+
+func (v NodeView) Timeout() time.Duration {
+	return v.node.timeout
+}
+
+func (v NodeView) IsMarker() bool {
+	return v.node.marker
+}
+
+func (v NodeView) IsApproval() bool {
+	return v.node.approval
+}
+
+// All yields every node in id order as a NodeView, as the graph was when the loop started. No
+// lock is held while the loop body runs, so the body may call back into the graph, changes
+// included; the views it's given don't see them. The loop may stop at any point.
+//
+// The signature is iter.Seq2's, so from Go 1.23 on the graph can be ranged over directly.
+func (g *Graph) All() func(yield func(NodeID, NodeView) bool) {
+	return func(yield func(NodeID, NodeView) bool) {
+		g.mu.Lock()
+		// Shared like a snapshot's, so changes copy the nodes they touch rather than altering these
+		g.shared = true
+		nodes := g.nodes
+		ids := sortedIDs(nodes)
+		g.mu.Unlock()
+
+		for _, id := range ids {
+			if !yield(id, NodeView{node: nodes[id]}) {
+				return
+			}
+		}
+	}
+}
+
+// EdgesSeq yields what Edges returns, one edge at a time and in the same order. The edges are
+// collected before the first is yielded, so as with All the loop body may call back into the
+// graph. The signature is iter.Seq's.
+func (g *Graph) EdgesSeq() func(yield func(Edge) bool) {
+	return func(yield func(Edge) bool) {
+		for _, e := range g.Edges() {
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package graph_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func allIDs(g *graph.Graph) []graph.NodeID {
+	ids := []graph.NodeID{}
+	g.All()(func(id graph.NodeID, v graph.NodeView) bool {
+		ids = append(ids, id)
+		return true
+	})
+	return ids
+}
+
+func TestAllOrderStable(t *testing.T) {
+	names := []string{"deploy", "build", "lint", "test", "docs", "package", "audit", "notify"}
+	want := []graph.NodeID{"audit", "build", "deploy", "docs", "lint", "notify", "package", "test"}
+
+	for seed := int64(0); seed < 20; seed++ {
+		shuffled := append([]string{}, names...)
+		rand.New(rand.NewSource(seed)).Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+		g := graph.NewGraph("ci")
+		for _, name := range shuffled {
+			g.Add(graph.NewNode(name, nil, graph.NoOp()))
+		}
+		for run := 0; run < 3; run++ {
+			if got := allIDs(g); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Expected %v whatever the insertion order, got %v", want, got)
+			}
+		}
+	}
+}
+
+func TestAllViews(t *testing.T) {
+	g := graph.NewGraph("views")
+	g.Add(graph.NewNode("a", nil, graph.NoOp(), graph.AsMarker()))
+	g.Add(graph.NewNode("opt", nil, graph.NoOp()))
+	g.Add(graph.NewNode("b", graph.Deps("a"), graph.NoOp(),
+		graph.WithSoftDependency("opt"),
+		graph.WithOptionalDependency("cache"),
+		graph.WithMetadata("team", "data"),
+		graph.WithTimeout(time.Minute),
+	))
+
+	views := map[graph.NodeID]graph.NodeView{}
+	g.All()(func(id graph.NodeID, v graph.NodeView) bool {
+		if v.ID() != id {
+			t.Errorf("Expected the view of %s, got %s", id, v.ID())
+		}
+		views[id] = v
+		return true
+	})
+
+	b := views["b"]
+	if got := b.Dependencies(); !reflect.DeepEqual(got, graph.SortedNodeIDs{"a", "opt"}) {
+		t.Errorf("Expected b's dependencies [a opt], got %v", got)
+	}
+	if b.DependencyKind("opt") != graph.EdgeSoft || b.DependencyKind("a") != graph.EdgeHard {
+		t.Error("Expected the edge kinds shown")
+	}
+	if got := b.OptionalDependencies(); !reflect.DeepEqual(got, graph.SortedNodeIDs{"cache"}) {
+		t.Errorf("Expected the optional dependency listed apart, got %v", got)
+	}
+	if b.Metadata()["team"] != "data" || b.Timeout() != time.Minute {
+		t.Errorf("Expected b's settings, got %v and %s", b.Metadata(), b.Timeout())
+	}
+	if !views["a"].IsMarker() || b.IsMarker() || b.IsApproval() {
+		t.Error("Expected only a to be a marker")
+	}
+}
+
+func TestNodeViewCannotChangeTheGraph(t *testing.T) {
+	// Nothing to assign to: every field is unexported
+	view := reflect.TypeOf(graph.NodeView{})
+	for i := 0; i < view.NumField(); i++ {
+		if view.Field(i).IsExported() {
+			t.Errorf("Expected NodeView to have no settable fields, found %s", view.Field(i).Name)
+		}
+	}
+
+	g := diamond(t)
+	g.Add(graph.NewNode("e", graph.Deps("d"), graph.NoOp(), graph.WithMetadata("team", "data")))
+	fingerprint := g.Fingerprint()
+	g.All()(func(id graph.NodeID, v graph.NodeView) bool {
+		if deps := v.Dependencies(); len(deps) > 0 {
+			deps[0] = "tampered"
+		}
+		if metadata := v.Metadata(); metadata != nil {
+			metadata["team"] = "tampered"
+		}
+		return true
+	})
+	if g.Fingerprint() != fingerprint {
+		t.Error("Expected what the views return to be copies")
+	}
+}
+
+func TestAllBodyMayCallBack(t *testing.T) {
+	g := diamond(t)
+	done := make(chan struct{})
+	seen := []graph.NodeID{}
+	go func() {
+		defer close(done)
+		g.All()(func(id graph.NodeID, v graph.NodeView) bool {
+			seen = append(seen, id)
+			switch id {
+			case "a":
+				g.Add(graph.NewNode("e", graph.Deps("d"), graph.NoOp()))
+				g.AddEdge("c", "b")
+			case "c":
+				// The view shows c as the loop found it
+				if !reflect.DeepEqual(v.Dependencies(), graph.SortedNodeIDs{"a"}) {
+					t.Errorf("Expected the view unchanged by the edge added mid-loop, got %v", v.Dependencies())
+				}
+			}
+			return true
+		})
+		g.EdgesSeq()(func(e graph.Edge) bool {
+			g.Has(e.From)
+			g.AddEdge("e", "a")
+			return true
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the loop bodies to call into the graph without deadlocking")
+	}
+	if !reflect.DeepEqual(seen, []graph.NodeID{"a", "b", "c", "d"}) {
+		t.Errorf("Expected the nodes as they were when the loop started, got %v", seen)
+	}
+	if !g.Has("e") || !mustGet(t, g, "c").Dependencies.Has("b") {
+		t.Error("Expected the changes made in the loop kept")
+	}
+}
+
+func TestAllStopsEarly(t *testing.T) {
+	calls := 0
+	diamond(t).All()(func(id graph.NodeID, v graph.NodeView) bool {
+		calls++
+		return id != "b"
+	})
+	if calls != 2 {
+		t.Errorf("Expected the loop to stop after b, got %d calls", calls)
+	}
+
+	edges := 0
+	diamond(t).EdgesSeq()(func(e graph.Edge) bool {
+		edges++
+		return false
+	})
+	if edges != 1 {
+		t.Errorf("Expected EdgesSeq to stop after one edge, got %d", edges)
+	}
+}
+
+func TestEdgesSeqMatchesEdges(t *testing.T) {
+	g := diamond(t)
+	got := []graph.Edge{}
+	g.EdgesSeq()(func(e graph.Edge) bool {
+		got = append(got, e)
+		return true
+	})
+	if !reflect.DeepEqual(got, g.Edges()) {
+		t.Errorf("Expected %v, got %v", g.Edges(), got)
+	}
+}
@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Stamps the node with the time it became ready, the first time it does
+func (r *run) becameReady(id NodeID) {
+	if r.readyAt == nil {
+		r.readyAt = make(map[NodeID]time.Time, len(r.peg.nodes))
+	}
+	if _, ok := r.readyAt[id]; !ok {
+		r.readyAt[id] = r.clock().Now()
+	}
+}
+
+// Sets ReadyWait and QueueWait from the timestamps the node has
+func (nr *NodeReport) fillWaits(runStart time.Time) {
+	if nr.ReadyAt.IsZero() {
+		return
+	}
+	nr.ReadyWait = nr.ReadyAt.Sub(runStart)
+	if !nr.DispatchedAt.IsZero() {
+		nr.QueueWait = nr.Start.Sub(nr.ReadyAt) - nr.StartDelay
+	}
+}
+
+// The pth percentile, from 0 to 100, of ReadyWait and of QueueWait over the nodes whose fn was
+// dispatched, by nearest rank; zero when none were
+func (r *Report) WaitPercentiles(p float64) (readyWait, queueWait time.Duration) {
+	ready, queue := []time.Duration{}, []time.Duration{}
+	for _, nr := range r.Nodes {
+		if nr.DispatchedAt.IsZero() {
+			continue
+		}
+		ready = append(ready, nr.ReadyWait)
+		queue = append(queue, nr.QueueWait)
+	}
+	return percentile(ready, p), percentile(queue, p)
+}
+
+func percentile(ds []time.Duration, p float64) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(ds))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(ds) {
+		rank = len(ds)
+	}
+	return ds[rank-1]
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,136 @@
+package graph_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func TestQueueWaitForASlot(t *testing.T) {
+	clock := newFakeClock()
+	t0 := clock.Now()
+	g := graph.NewGraph("roots")
+	g.Add(graph.NewNode("east", nil, takes(clock, 10*time.Second)))
+	g.Add(graph.NewNode("west", nil, takes(clock, 10*time.Second)))
+
+	done := runAsync(g.CompileToExecutable(), graph.WithClock(clock), graph.WithMaxConcurrency(1))
+	clock.waitFor(t, 1)
+	clock.Advance(10 * time.Second)
+	clock.waitFor(t, 1)
+	clock.Advance(10 * time.Second)
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+
+	first, second := res.report.Nodes["east"], res.report.Nodes["west"]
+	if second.Start.Before(first.Start) {
+		first, second = second, first
+	}
+	for _, nr := range []*graph.NodeReport{first, second} {
+		if !nr.ReadyAt.Equal(t0) || nr.ReadyWait != 0 {
+			t.Errorf("Expected %s ready as the run started, got t0+%s", nr.ID, nr.ReadyAt.Sub(t0))
+		}
+		if !nr.DispatchedAt.Equal(nr.Start) {
+			t.Errorf("Expected %s to start as it was dispatched, got %s and %s", nr.ID, nr.DispatchedAt, nr.Start)
+		}
+	}
+	if first.QueueWait != 0 {
+		t.Errorf("Expected %s to get the slot straight away, got %s", first.ID, first.QueueWait)
+	}
+	if d := first.End.Sub(first.Start); second.QueueWait != d || d != 10*time.Second {
+		t.Errorf("Expected %s to queue for %s's 10s, got %s", second.ID, first.ID, second.QueueWait)
+	}
+}
+
+func TestReadyWaitForDependencies(t *testing.T) {
+	clock := newFakeClock()
+	g := graph.NewGraph("chain")
+	g.Add(graph.NewNode("extract", nil, takes(clock, time.Minute)))
+	g.Add(graph.NewNode("load", graph.Deps("extract"), graph.NoOp(), graph.WithStartDelay(30*time.Second)))
+
+	done := runAsync(g.CompileToExecutable(), graph.WithClock(clock))
+	clock.waitFor(t, 1)
+	clock.Advance(time.Minute)
+	clock.waitFor(t, 1)
+	clock.Advance(30 * time.Second)
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+
+	load := res.report.Nodes["load"]
+	if load.ReadyWait != time.Minute {
+		t.Errorf("Expected load to wait a minute on extract, got %s", load.ReadyWait)
+	}
+	// The start delay isn't queueing
+	if load.QueueWait != 0 || load.StartDelay != 30*time.Second {
+		t.Errorf("Expected no queue wait beside the 30s delay, got %s and %s", load.QueueWait, load.StartDelay)
+	}
+}
+
+func TestWaitsForNodesThatNeverRan(t *testing.T) {
+	g := graph.NewGraph("failing")
+	g.Add(graph.NewNode("a", nil, failWith(errors.New("boom"))))
+	g.Add(graph.NewNode("b", graph.Deps("a"), graph.NoOp()))
+	report, _ := g.CompileToExecutable().Run(ctx(t))
+
+	b := report.Nodes["b"]
+	if b.Status != graph.StatusSkipped || !b.DispatchedAt.IsZero() || b.QueueWait != 0 {
+		t.Errorf("Expected the skipped node never dispatched, got %+v", b)
+	}
+	if a := report.Nodes["a"]; a.ReadyAt.IsZero() || a.DispatchedAt.IsZero() {
+		t.Error("Expected the failed node's timestamps kept")
+	}
+}
+
+func TestWaitPercentiles(t *testing.T) {
+	report := &graph.Report{Nodes: map[graph.NodeID]*graph.NodeReport{}}
+	for i, d := range []time.Duration{4, 1, 3, 2} {
+		id := graph.NodeID(rune('a' + i))
+		report.Nodes[id] = &graph.NodeReport{ID: id, DispatchedAt: time.Unix(1, 0), ReadyWait: d * time.Second, QueueWait: d * time.Millisecond}
+	}
+	// Never dispatched, so left out
+	report.Nodes["skipped"] = &graph.NodeReport{ID: "skipped", ReadyWait: time.Hour}
+
+	for p, want := range map[float64]time.Duration{0: 1, 25: 1, 50: 2, 75: 3, 100: 4} {
+		ready, queue := report.WaitPercentiles(p)
+		if ready != want*time.Second || queue != want*time.Millisecond {
+			t.Errorf("Expected p%v to be %ds and %dms, got %s and %s", p, want, want, ready, queue)
+		}
+	}
+	if ready, queue := (&graph.Report{}).WaitPercentiles(50); ready != 0 || queue != 0 {
+		t.Errorf("Expected zero with no nodes, got %s and %s", ready, queue)
+	}
+}
+
+func TestWaitsJSON(t *testing.T) {
+	clock := newFakeClock()
+	g := graph.NewGraph("roots")
+	g.Add(graph.NewNode("east", nil, takes(clock, time.Second)))
+	g.Add(graph.NewNode("west", nil, takes(clock, time.Second)))
+	done := runAsync(g.CompileToExecutable(), graph.WithClock(clock), graph.WithMaxConcurrency(1))
+	clock.waitFor(t, 1)
+	clock.Advance(time.Second)
+	clock.waitFor(t, 1)
+	clock.Advance(time.Second)
+	res := <-done
+
+	data, err := json.Marshal(res.report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded graph.Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for id, nr := range res.report.Nodes {
+		got := decoded.Nodes[id]
+		if !got.ReadyAt.Equal(nr.ReadyAt) || !got.DispatchedAt.Equal(nr.DispatchedAt) || got.ReadyWait != nr.ReadyWait || got.QueueWait != nr.QueueWait {
+			t.Errorf("Expected %s's waits to survive JSON, got %+v", id, got)
+		}
+	}
+}
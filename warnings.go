@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"fmt"
+)
+
+type WarningKind int
+
+const (
+	// The same dependency was declared more than once
+	WarningDuplicateEdge WarningKind = iota
+	// The dependency is already implied through another dependency
+	WarningRedundantEdge
+	// No entry point reaches the nodes; see MarkEntryPoint
+	WarningUnreachable
+	// Unordered nodes share one fn; see WithSharedFnWarnings
+	WarningSharedFn
+)
+
+type Warning struct {
+	Kind WarningKind
+	// The dependent first, then the dependency
+	Nodes   []NodeID
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Message
+}
+
+func (n *Node) countDuplicate(id NodeID) {
+	if n.duplicates == nil {
+		n.duplicates = make(map[NodeID]int)
+	}
+	n.duplicates[id]++
+}
+
+// Warnings flag things that are harmless but usually a sign of a bug in whatever generated
+// the graph. They never block Sort or Run.
+func (g *Graph) Warnings() []Warning {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	warnings := []Warning{}
+
+	for _, id := range sortedIDs(g.nodes) {
+		node := g.nodes[id]
+
+		for _, depId := range sortedIDs(node.duplicates) {
+			warnings = append(warnings, Warning{
+				Kind:    WarningDuplicateEdge,
+				Nodes:   []NodeID{id, depId},
+				Message: fmt.Sprintf("Node %s declares dependency %s %d times", id, depId, node.duplicates[depId]+1),
+			})
+		}
+
+		deps := g.dependencies(node)
+		implied := g.implied(deps)
+		for _, depId := range sortedIDs(deps) {
+			if via, ok := implied[depId]; ok {
+				warnings = append(warnings, Warning{
+					Kind:    WarningRedundantEdge,
+					Nodes:   []NodeID{id, depId},
+					Message: fmt.Sprintf("Node %s depends on %s, which it already reaches through %s", id, depId, via),
+				})
+			}
+		}
+	}
+
+	if unreachable := g.unreachable(); len(unreachable) > 0 {
+		warnings = append(warnings, unreachableWarning(unreachable))
+	}
+	return append(warnings, g.sharedFns()...)
+}
+
+// Everything reachable from deps without using the direct edges, mapped to the dep it was reached through
+func (g *Graph) implied(deps NodeIDs) map[NodeID]NodeID {
+	implied := map[NodeID]NodeID{}
+
+	var walk func(id NodeID, via NodeID)
+	walk = func(id NodeID, via NodeID) {
+		node, ok := g.nodes[id]
+		if !ok {
+			return
+		}
+
+		for depId := range g.dependencies(node) {
+			if _, seen := implied[depId]; seen {
+				continue
+			}
+			implied[depId] = via
+			walk(depId, via)
+		}
+	}
+
+	for _, depId := range sortedIDs(deps) {
+		walk(depId, depId)
+	}
+	return implied
+}
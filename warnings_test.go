@@ -0,0 +1,65 @@
+package graph_test
+
+import (
+	"reflect"
+	"testing"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+func warningsOf(g *graph.Graph, kind graph.WarningKind) []graph.Warning {
+	found := []graph.Warning{}
+	for _, w := range g.Warnings() {
+		if w.Kind == kind {
+			found = append(found, w)
+		}
+	}
+	return found
+}
+
+func TestWarningDuplicateEdge(t *testing.T) {
+	g := graph.NewGraph("generated")
+	g.Add(graph.NewNode("build", nil, graph.NoOp()))
+	g.Add(graph.NewNode("lint", nil, graph.NoOp()))
+	g.Add(graph.NewNode("test", graph.Deps("build"), graph.NoOp(), graph.WithOptionalDependency("lint"), graph.WithOptionalDependency("lint")))
+	if err := g.AddEdge("test", "build"); err != nil {
+		t.Fatal(err)
+	}
+
+	dups := warningsOf(g, graph.WarningDuplicateEdge)
+	if len(dups) != 2 {
+		t.Fatalf("Expected a warning for each duplicated dependency, got %v", dups)
+	}
+	if !reflect.DeepEqual(dups[0].Nodes, []graph.NodeID{"test", "build"}) || dups[0].Message != "Node test declares dependency build 2 times" {
+		t.Errorf("Expected the warning to name test and build, got %+v", dups[0])
+	}
+	if !reflect.DeepEqual(dups[1].Nodes, []graph.NodeID{"test", "lint"}) {
+		t.Errorf("Expected the warning to name test and lint, got %+v", dups[1])
+	}
+}
+
+func TestWarningRedundantEdge(t *testing.T) {
+	g := graph.NewGraph("generated")
+	g.Add(graph.NewNode("c", nil, graph.NoOp()))
+	g.Add(graph.NewNode("b", graph.Deps("c"), graph.NoOp()))
+	g.Add(graph.NewNode("a", graph.Deps("b", "c"), graph.NoOp()))
+
+	redundant := warningsOf(g, graph.WarningRedundantEdge)
+	if len(redundant) != 1 {
+		t.Fatalf("Expected one redundant edge, got %v", redundant)
+	}
+	w := redundant[0]
+	if !reflect.DeepEqual(w.Nodes, []graph.NodeID{"a", "c"}) || w.String() != "Node a depends on c, which it already reaches through b" {
+		t.Errorf("Expected a -> c to be flagged as reached through b, got %+v", w)
+	}
+
+	if _, err := g.CompileToExecutable().Run(ctx(t)); err != nil {
+		t.Errorf("Expected warnings not to block the run, got %v", err)
+	}
+}
+
+func TestWarningsCleanGraph(t *testing.T) {
+	if warnings := diamond(t).Warnings(); len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+}
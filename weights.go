@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"fmt"
+	"time"
+)
+
+// The weight of edges AddEdge isn't given one for, including every edge declared through
+// Dependencies; zero unless set
+func WithDefaultEdgeWeight(weight float64) GraphOption {
+	return func(g *Graph) {
+		g.defaultWeight = weight
+	}
+}
+
+// Adds each edge's weight times perUnit to the report's critical path, so a costly hand-off
+// between two nodes can make their chain the critical one
+func WithWeightedCriticalPath(perUnit time.Duration) ExecOption {
+	return func(c *ExecConfig) {
+		c.EdgeWeightCost = perUnit
+	}
+}
+
+type WeightedEdge struct {
+	Edge
+	Kind   EdgeKind
+	Weight float64
+}
+
+// The weight of the edge from -> to; to may be an alias
+func (g *Graph) EdgeWeight(from, to NodeID) (float64, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	from, to = g.normalize(from), g.normalize(to)
+
+	node, ok := g.nodes[from]
+	if !ok {
+		return 0, fmt.Errorf("Node %s does not exist", from)
+	}
+
+	declared, ok := g.declared(node)[g.resolve(to)]
+	if !ok {
+		return 0, fmt.Errorf("Node %s does not depend on %s", from, to)
+	}
+	return g.weight(node, declared), nil
+}
+
+// Every edge in the graph, sorted by From then To
+func (g *Graph) WeightedEdges() []WeightedEdge {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	edges := []WeightedEdge{}
+	for _, id := range sortedIDs(g.nodes) {
+		node := g.nodes[id]
+		for _, e := range g.nodeEdges(id) {
+			edges = append(edges, WeightedEdge{
+				Edge:   e.Edge,
+				Kind:   node.DependencyKind(e.declared),
+				Weight: g.weight(node, e.declared),
+			})
+		}
+	}
+	return edges
+}
+
+// The node's resolved dependencies, each mapped to the id it was declared as
+func (g *Graph) declared(node *Node) map[NodeID]NodeID {
+	declared := map[NodeID]NodeID{}
+	for depId := range g.dependencies(node) {
+		declared[depId] = depId
+	}
+	for alias, target := range g.aliased(node) {
+		_, hard := node.Dependencies[target]
+		_, optional := node.optionalDependencies[target]
+		if !hard && !optional {
+			declared[target] = alias
+		}
+	}
+	return declared
+}
+
+func (g *Graph) weight(node *Node, declared NodeID) float64 {
+	if weight, ok := node.weights[declared]; ok {
+		return weight
+	}
+	return g.defaultWeight
+}
+
+// Nonzero weights by resolved dependency, for the compiled node
+func (g *Graph) edgeWeights(node *Node, deps NodeIDs) map[NodeID]float64 {
+	if len(node.weights) == 0 && g.defaultWeight == 0 {
+		return nil
+	}
+
+	weights := map[NodeID]float64{}
+	for depId, declared := range g.declared(node) {
+		if _, ok := deps[depId]; !ok {
+			continue
+		}
+		if weight := g.weight(node, declared); weight != 0 {
+			weights[depId] = weight
+		}
+	}
+	return weights
+}
@@ -0,0 +1,114 @@
+package graph_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	graph "github.com/moonmoon1919/go_graph"
+)
+
+// a and b both feed c; the edge to b weighs 3
+func weighted(t *testing.T, nodeFn func(id string) graph.NodeFn, opts ...graph.GraphOption) *graph.Graph {
+	t.Helper()
+
+	g := graph.NewGraph("weighted", opts...)
+	g.Add(graph.NewNode("a", nil, nodeFn("a")))
+	g.Add(graph.NewNode("b", nil, nodeFn("b")))
+	g.Add(graph.NewNode("c", graph.Deps("a"), nodeFn("c")))
+	if err := g.AddEdge("c", "b", 3); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestEdgeWeight(t *testing.T) {
+	g := weighted(t, noOps)
+
+	if w, err := g.EdgeWeight("c", "b"); err != nil || w != 3 {
+		t.Errorf("Expected c -> b to weigh 3, got %v, %v", w, err)
+	}
+	if w, err := g.EdgeWeight("c", "a"); err != nil || w != 0 {
+		t.Errorf("Expected an unweighted edge to weigh 0, got %v, %v", w, err)
+	}
+	if _, err := g.EdgeWeight("a", "b"); err == nil {
+		t.Error("Expected an error for an edge that doesn't exist")
+	}
+	if _, err := g.EdgeWeight("missing", "a"); err == nil {
+		t.Error("Expected an error for a node that doesn't exist")
+	}
+}
+
+func TestDefaultEdgeWeight(t *testing.T) {
+	g := weighted(t, noOps, graph.WithDefaultEdgeWeight(1.5))
+
+	got := []float64{}
+	for _, e := range g.WeightedEdges() {
+		got = append(got, e.Weight)
+	}
+	// Sorted by From then To: c -> a, c -> b
+	if want := []float64{1.5, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected weights %v, got %v", want, got)
+	}
+}
+
+func TestWeightedEdges(t *testing.T) {
+	edges := weighted(t, noOps).WeightedEdges()
+
+	want := []graph.WeightedEdge{
+		{Edge: graph.Edge{From: "c", To: "a"}, Kind: graph.EdgeHard},
+		{Edge: graph.Edge{From: "c", To: "b"}, Kind: graph.EdgeHard, Weight: 3},
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Errorf("Expected %v, got %v", want, edges)
+	}
+}
+
+func TestWeightedCriticalPath(t *testing.T) {
+	clock := newFakeClock()
+	// a takes longer than b, but b's hand-off to c costs more
+	durations := map[string]time.Duration{"a": 20 * time.Second, "b": 10 * time.Second, "c": 10 * time.Second}
+	g := weighted(t, func(id string) graph.NodeFn { return takes(clock, durations[id]) })
+	hooks, finished := finishes()
+
+	run := func(opts ...graph.ExecOption) *graph.Report {
+		done := runAsync(g.CompileToExecutable(), append(opts, graph.WithClock(clock), hooks)...)
+		clock.waitFor(t, 2)
+		clock.Advance(10 * time.Second)
+		awaitFinish(t, finished, "b")
+		clock.Advance(10 * time.Second)
+		clock.waitFor(t, 1)
+		clock.Advance(10 * time.Second)
+		return (<-done).report
+	}
+
+	plain := run()
+	if want := []graph.NodeID{"a", "c"}; !reflect.DeepEqual(plain.CriticalPath, want) {
+		t.Errorf("Expected %v without weights, got %v", want, plain.CriticalPath)
+	}
+
+	priced := run(graph.WithWeightedCriticalPath(5 * time.Second))
+	if want := []graph.NodeID{"b", "c"}; !reflect.DeepEqual(priced.CriticalPath, want) {
+		t.Errorf("Expected %v with weights, got %v", want, priced.CriticalPath)
+	}
+	// 10s for b, 3 × 5s for its edge and 10s for c
+	if priced.CriticalPathDuration != 35*time.Second {
+		t.Errorf("Expected a 35s critical path, got %s", priced.CriticalPathDuration)
+	}
+}
+
+func TestWeightsInGraphML(t *testing.T) {
+	var b bytes.Buffer
+	if err := weighted(t, noOps).WriteGraphML(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(b.String(), `<data key="weight">3</data>`) {
+		t.Errorf("Expected the weight as edge data, got %s", b.String())
+	}
+	if strings.Count(b.String(), `<data key="weight">`) != 1 {
+		t.Errorf("Expected zero weights left out, got %s", b.String())
+	}
+}